@@ -35,7 +35,7 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
@@ -73,7 +73,7 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
@@ -113,13 +113,13 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
@@ -159,74 +159,73 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/consumedFoodItems/{date}": {
-            "get": {
-                "description": "Get all consumed food items for a specific date and profile. If no profile ID is provided, the active profile is used.",
+        "/consumedFoodItems/copy": {
+            "post": {
+                "description": "Copy the entries of a date, or a subset of them, to another date or profile. With target_end_date the entries are copied to every day of the range, optionally only on some weekdays. Every copy is logged like a new entry with the current food item or dish: food items are added to an existing entry of the same food item on the target date, dishes are logged with their current ingredients. Entries of deleted food items or dishes cannot be copied. If no source profile ID is provided, the active profile is used.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "consumedFoodItems"
                 ],
-                "summary": "Get consumed food items by date",
+                "summary": "Copy consumed food items",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Date in YYYY-MM-DD format",
-                        "name": "date",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Profile ID",
-                        "name": "profile_id",
-                        "in": "query"
+                        "description": "Source and target of the copy",
+                        "name": "copy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.CopyConsumedFoodItemsRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/types.ConsumedFoodItemWithDetails"
-                                }
-                            }
+                            "$ref": "#/definitions/types.ConsumedCopyResult"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/consumedFoodItems/{id}": {
-            "put": {
-                "description": "Update a consumed food item by ID",
+        "/consumedFoodItems/dish": {
+            "post": {
+                "description": "Log a dish or a fraction of it as consumed. The ingredients are stored as snapshot, so later changes to the dish don't change the diary. If no ProfileID is provided, the active profile will be used.",
                 "consumes": [
                     "application/json"
                 ],
@@ -236,22 +235,15 @@ const docTemplate = `{
                 "tags": [
                     "consumedFoodItems"
                 ],
-                "summary": "Update consumed food item",
+                "summary": "Log a dish",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Consumed food item ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated consumed food item data",
-                        "name": "consumedItem",
+                        "description": "Dish to log",
+                        "name": "consumedDish",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.ConsumedFoodItemRequest"
+                            "$ref": "#/definitions/types.ConsumedDishRequest"
                         }
                     }
                 ],
@@ -265,39 +257,46 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a consumed food item by ID",
+            }
+        },
+        "/consumedFoodItems/reprice": {
+            "post": {
+                "description": "Apply the current nutrition values of a food item to already consumed entries, including logged dishes containing it. Consumed entries keep the values from the time they were logged otherwise. Without a date range the whole history is updated, without a ProfileID all profiles are updated.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "consumedFoodItems"
                 ],
-                "summary": "Delete consumed food item",
+                "summary": "Reprice history",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Consumed food item ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Food item and range to reprice",
+                        "name": "reprice",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.RepriceHistoryRequest"
+                        }
                     }
                 ],
                 "responses": {
@@ -307,139 +306,252 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/dishes": {
+        "/consumedFoodItems/summary": {
             "get": {
-                "description": "Get a list of all dishes",
+                "description": "Get the intake of every day in a date range, each compared with the targets in effect on that day. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "dishes"
+                    "consumedFoodItems"
+                ],
+                "summary": "Get daily summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get all dishes",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/types.Dish"
-                                }
+                                "$ref": "#/definitions/types.DailySummary"
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create a new dish with ingredients",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/consumedFoodItems/summary/tags": {
+            "get": {
+                "description": "Break down the intake in a date range by the tags of the logged food items and dishes, e.g. to see how much comes from snacks. An entry with several tags counts for each of its tags. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "dishes"
+                    "consumedFoodItems"
                 ],
-                "summary": "Create a new dish",
+                "summary": "Get intake by tag",
                 "parameters": [
                     {
-                        "description": "Dish to create",
-                        "name": "dish",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.DishRequest"
-                        }
+                        "type": "string",
+                        "description": "First day (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.Dish"
+                            "$ref": "#/definitions/types.TagIntakeSummary"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/dishes/{id}": {
+        "/consumedFoodItems/{date}": {
             "get": {
-                "description": "Get a dish by ID",
+                "description": "Get a page of the consumed food items for a specific date and profile, by default all of them with the latest first. Sort keys are insert_date, name and kcal. The unit and kind filters apply. The total number of matching entries is returned in the X-Total-Count header. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "dishes"
+                    "consumedFoodItems"
                 ],
-                "summary": "Get a dish",
+                "summary": "Get consumed food items by date",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Dish ID",
-                        "name": "id",
+                        "description": "Date in YYYY-MM-DD format",
+                        "name": "date",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First creation day (YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last creation day (YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "incomplete",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only diary entries of food items (\"food\") or dishes (\"dish\")",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries, 0 for all",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "\"asc\" or \"desc\", defaults to asc for name and desc otherwise",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key, the keys depend on the list",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries with this serving quantity unit (\"g\" or \"ml\")",
+                        "name": "unit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.DishWithDetailedItems"
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/types.ConsumedFoodItemWithDetails"
+                                }
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/consumedFoodItems/{id}": {
             "put": {
-                "description": "Update a dish by ID",
+                "description": "Update a consumed food item by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -447,24 +559,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "dishes"
+                    "consumedFoodItems"
                 ],
-                "summary": "Update a dish",
+                "summary": "Update consumed food item",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Dish ID",
+                        "description": "Consumed food item ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated dish data",
-                        "name": "dish",
+                        "description": "Updated consumed food item data",
+                        "name": "consumedItem",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.DishRequest"
+                            "$ref": "#/definitions/types.ConsumedFoodItemRequest"
                         }
                     }
                 ],
@@ -478,36 +590,36 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Delete a dish by ID",
+                "description": "Delete a consumed food item by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "dishes"
+                    "consumedFoodItems"
                 ],
-                "summary": "Delete a dish",
+                "summary": "Delete consumed food item",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Dish ID",
+                        "description": "Consumed food item ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -523,86 +635,117 @@ const docTemplate = `{
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/dishes/{id}/convertToFoodItem": {
-            "post": {
-                "description": "Convert a dish to a food item",
+        "/dishes": {
+            "get": {
+                "description": "Get a page of the dishes with their ingredients, by default all of them with the newest first. Sort keys are name, created_at, last_updated and kcal (per portion). Dishes without ingredients are incomplete. The total number of matching dishes is returned in the X-Total-Count header.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "dishes"
                 ],
-                "summary": "Convert dish to food item",
+                "summary": "Get all dishes",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Dish ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "First creation day (YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last creation day (YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "incomplete",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only diary entries of food items (\"food\") or dishes (\"dish\")",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries, 0 for all",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "\"asc\" or \"desc\", defaults to asc for name and desc otherwise",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key, the keys depend on the list",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries with this serving quantity unit (\"g\" or \"ml\")",
+                        "name": "unit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/types.Dish"
+                                }
+                            }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    }
-                }
-            }
-        },
-        "/dropbox/autosync": {
-            "get": {
-                "description": "Get the autosync status of the Dropbox account",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "database"
-                ],
-                "summary": "Get Dropbox autosync status",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Set the autosync status of the Dropbox account",
+                "description": "Create a new dish with ingredients",
                 "consumes": [
                     "application/json"
                 ],
@@ -610,17 +753,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "database"
+                    "dishes"
                 ],
-                "summary": "Set Dropbox autosync status",
+                "summary": "Create a new dish",
                 "parameters": [
                     {
-                        "description": "Autosync status",
-                        "name": "autosync",
+                        "description": "Dish to create",
+                        "name": "dish",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.DropboxAutosyncRequest"
+                            "$ref": "#/definitions/types.DishRequest"
                         }
                     }
                 ],
@@ -628,60 +771,96 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.Dish"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/dropbox/downloadDatabase": {
-            "post": {
-                "description": "Download the database from Dropbox",
+        "/dishes/nutrition/{id}": {
+            "get": {
+                "description": "Get the nutrition values of a dish per 100g of cooked food and per portion",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "database"
+                    "dishes"
+                ],
+                "summary": "Get dish nutrition",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dish ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Download database from Dropbox",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.DishNutrition"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/dropbox/logout": {
-            "post": {
-                "description": "Logout from Dropbox",
+        "/dishes/tags/{id}": {
+            "put": {
+                "description": "Replace the tags of a dish, e.g. \"homemade\". Tags are stored in lower case.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "authentication"
+                    "dishes"
+                ],
+                "summary": "Set dish tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dish ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags of the dish",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.TagsRequest"
+                        }
+                    }
                 ],
-                "summary": "Logout from Dropbox",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -689,44 +868,69 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/dropbox/status": {
+        "/dishes/{id}": {
             "get": {
-                "description": "Get the authentication status of the Dropbox account",
+                "description": "Get a dish by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "authentication"
+                    "dishes"
+                ],
+                "summary": "Get a dish",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dish ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get Dropbox authentication status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.DishWithDetailedItems"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            }
-        },
-        "/dropbox/sync": {
-            "post": {
-                "description": "Sync the database with Dropbox",
+            },
+            "put": {
+                "description": "Update a dish by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -734,17 +938,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "database"
+                    "dishes"
                 ],
-                "summary": "Sync with Dropbox",
+                "summary": "Update a dish",
                 "parameters": [
                     {
-                        "description": "Force sync",
-                        "name": "force",
+                        "type": "string",
+                        "description": "Dish ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated dish data",
+                        "name": "dish",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.ForceSyncRequest"
+                            "$ref": "#/definitions/types.DishRequest"
                         }
                     }
                 ],
@@ -755,37 +966,42 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            }
-        },
-        "/dropbox/token": {
-            "post": {
-                "description": "Exchange a Dropbox authorization code for an access token",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "delete": {
+                "description": "Delete a dish by ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "authentication"
+                    "dishes"
                 ],
-                "summary": "Exchange Dropbox token",
+                "summary": "Delete a dish",
                 "parameters": [
                     {
-                        "description": "Dropbox authorization code and code verifier",
-                        "name": "token",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "object"
-                        }
+                        "type": "string",
+                        "description": "Dish ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -795,31 +1011,40 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/dropbox/uploadDatabase": {
+        "/dishes/{id}/convertToFoodItem": {
             "post": {
-                "description": "Upload the database to Dropbox",
+                "description": "Convert a dish to a food item",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "database"
+                    "dishes"
+                ],
+                "summary": "Convert dish to food item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dish ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Upload database to Dropbox",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -827,41 +1052,48 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/foodItems/all": {
+        "/dropbox/autosync": {
             "get": {
-                "description": "Get a list of all food items",
+                "description": "Get the autosync status of the Dropbox account",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
+                    "database"
                 ],
-                "summary": "Get all food items",
+                "summary": "Get Dropbox autosync status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/types.PersistentFoodItem"
-                            }
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            }
-        },
-        "/foodItems/check-and-insert": {
+            },
             "post": {
-                "description": "Check if a food item with the given barcode exists and insert it if not",
+                "description": "Set the autosync status of the Dropbox account",
                 "consumes": [
                     "application/json"
                 ],
@@ -869,17 +1101,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
+                    "database"
                 ],
-                "summary": "Check if food item exists and insert if not",
+                "summary": "Set Dropbox autosync status",
                 "parameters": [
                     {
-                        "description": "Food item to check and insert",
-                        "name": "foodItem",
+                        "description": "Autosync status",
+                        "name": "autosync",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.PersistentFoodItem"
+                            "$ref": "#/definitions/types.DropboxAutosyncRequest"
                         }
                     }
                 ],
@@ -893,42 +1125,28 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/foodItems/manually-add": {
+        "/dropbox/downloadDatabase": {
             "post": {
-                "description": "Manually add a new food item",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Download the database from Dropbox",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
-                ],
-                "summary": "Manually add a food item",
-                "parameters": [
-                    {
-                        "description": "Food item to add",
-                        "name": "foodItem",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.PersistentFoodItem"
-                        }
-                    }
+                    "database"
                 ],
+                "summary": "Download database from Dropbox",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -936,40 +1154,25 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/foodItems/reset/{barcode}": {
+        "/dropbox/logout": {
             "post": {
-                "description": "Reset a food item to its default values by barcode",
+                "description": "Logout from Dropbox",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
-                ],
-                "summary": "Reset a food item to default values",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Food item barcode",
-                        "name": "barcode",
-                        "in": "path",
-                        "required": true
-                    }
+                    "authentication"
                 ],
+                "summary": "Logout from Dropbox",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -977,55 +1180,28 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/foodItems/search": {
+        "/dropbox/status": {
             "get": {
-                "description": "Search for food items by name",
+                "description": "Get the authentication status of the Dropbox account",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
-                ],
-                "summary": "Search food items",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Search query",
-                        "name": "query",
-                        "in": "query",
-                        "required": true
-                    }
+                    "authentication"
                 ],
+                "summary": "Get Dropbox authentication status",
                 "responses": {
                     "200": {
                         "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/types.PersistentFoodItem"
-                                }
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/gin.H"
                         }
@@ -1033,46 +1209,39 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/foodItems/searchOpenFoodFacts": {
-            "get": {
-                "description": "Search for food items on OpenFoodFacts by name",
+        "/dropbox/sync": {
+            "post": {
+                "description": "Sync the database with Dropbox",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
+                    "database"
                 ],
-                "summary": "Search food items on OpenFoodFacts",
+                "summary": "Sync with Dropbox",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Search query",
-                        "name": "query",
-                        "in": "query",
-                        "required": true
+                        "description": "Force sync",
+                        "name": "force",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.ForceSyncRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/types.PersistentFoodItem"
-                                }
-                            }
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/gin.H"
                         }
@@ -1080,29 +1249,34 @@ const docTemplate = `{
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/foodItems/servingQuantity/{barcode}": {
-            "get": {
-                "description": "Get the serving quantity of a food item by barcode",
+        "/dropbox/token": {
+            "post": {
+                "description": "Exchange a Dropbox authorization code for an access token",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
+                    "authentication"
                 ],
-                "summary": "Get serving quantity by barcode",
+                "summary": "Exchange Dropbox token",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Food item barcode",
-                        "name": "barcode",
-                        "in": "path",
-                        "required": true
+                        "description": "Dropbox authorization code and code verifier",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
                     }
                 ],
                 "responses": {
@@ -1112,52 +1286,31 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/foodItems/{barcode}": {
-            "put": {
-                "description": "Update a food item by barcode",
-                "consumes": [
-                    "application/json"
-                ],
+        "/dropbox/uploadDatabase": {
+            "post": {
+                "description": "Upload the database to Dropbox",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "foodItems"
-                ],
-                "summary": "Update a food item",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Food item barcode",
-                        "name": "barcode",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated food item data",
-                        "name": "foodItem",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.PersistentFoodItem"
-                        }
-                    }
+                    "database"
                 ],
+                "summary": "Upload database to Dropbox",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -1165,69 +1318,113 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a food item by barcode",
+            }
+        },
+        "/foodItems/all": {
+            "get": {
+                "description": "Get a page of the food items, by default all of them with the newest first. Sort keys are name, created_at, last_updated and kcal. Food items without a real name, without nutrition values or without a serving quantity are incomplete. The total number of matching food items is returned in the X-Total-Count header.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "foodItems"
                 ],
-                "summary": "Delete a food item",
+                "summary": "Get all food items",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Food item barcode",
-                        "name": "barcode",
-                        "in": "path",
-                        "required": true
+                        "description": "First creation day (YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last creation day (YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "incomplete",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only diary entries of food items (\"food\") or dishes (\"dish\")",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries, 0 for all",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "\"asc\" or \"desc\", defaults to asc for name and desc otherwise",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key, the keys depend on the list",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries with this serving quantity unit (\"g\" or \"ml\")",
+                        "name": "unit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.PersistentFoodItem"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/nutrition/calculate": {
+        "/foodItems/check-and-insert": {
             "post": {
-                "description": "Calculate daily nutrition targets based on user metrics",
+                "description": "Check if a food item with the given barcode exists and insert it if not",
                 "consumes": [
                     "application/json"
                 ],
@@ -1235,17 +1432,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "nutrition"
+                    "foodItems"
                 ],
-                "summary": "Calculate nutrition targets",
+                "summary": "Check if food item exists and insert if not",
                 "parameters": [
                     {
-                        "description": "User metrics for calculation",
-                        "name": "request",
+                        "description": "Food item to check and insert",
+                        "name": "foodItem",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.NutritionCalculationRequest"
+                            "$ref": "#/definitions/types.PersistentFoodItem"
                         }
                     }
                 ],
@@ -1253,7 +1450,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.NutritionCalculationResponse"
+                            "$ref": "#/definitions/gin.H"
                         }
                     },
                     "400": {
@@ -1261,13 +1458,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/types.ApiResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
                     }
                 }
             }
         },
-        "/nutrition/calculate-from-calories-and-weight": {
+        "/foodItems/check-insert-and-consume-batch": {
             "post": {
-                "description": "Calculate daily nutrition targets based on user metrics",
+                "description": "Adds missing food items from OpenFoodFacts and logs all items of the batch. By default the batch is logged in one transaction and fails as a whole. With partial set, every item is logged on its own and the results report which items failed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1275,17 +1478,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "nutrition"
+                    "consumedFoodItems"
                 ],
-                "summary": "Calculate nutrition targets",
+                "summary": "Check, insert and consume a batch of food items",
                 "parameters": [
                     {
-                        "description": "User metrics for calculation",
-                        "name": "request",
+                        "description": "Consumed food items",
+                        "name": "batch",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.NutritionCalculationRequest"
+                            "$ref": "#/definitions/types.BatchConsumedFoodItemRequest"
                         }
                     }
                 ],
@@ -1293,7 +1496,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.NutritionCalculationResponse"
+                            "$ref": "#/definitions/types.BatchConsumeResponse"
                         }
                     },
                     "400": {
@@ -1301,138 +1504,150 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/types.ApiResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
                     }
                 }
             }
         },
-        "/profiles": {
+        "/foodItems/duplicates": {
             "get": {
-                "description": "Get a list of all profiles",
+                "description": "Suggest groups of food items that are likely the same product: similar names and near-identical nutrition values. The suggested target is the food item logged most often.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "profiles"
+                    "foodItems"
                 ],
-                "summary": "Get all profiles",
+                "summary": "Find duplicate food items",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "type": "array",
-                                "items": {
-                                    "$ref": "#/definitions/types.Profile"
-                                }
+                                "$ref": "#/definitions/types.DuplicateFoodItemGroup"
                             }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create a new profile",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/foodItems/favorites": {
+            "get": {
+                "description": "Get the food items pinned by a profile. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "profiles"
+                    "foodItems"
                 ],
-                "summary": "Create a profile",
+                "summary": "Get favorite food items",
                 "parameters": [
                     {
-                        "description": "Profile to create",
-                        "name": "profile",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.Profile"
-                        }
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.FoodItemUsage"
+                            }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/profiles/active": {
-            "get": {
-                "description": "Get the currently active profile",
+        "/foodItems/favorites/{barcode}": {
+            "post": {
+                "description": "Pin a food item for a profile. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "profiles"
+                    "foodItems"
+                ],
+                "summary": "Add favorite food item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get active profile",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.Profile"
+                            "$ref": "#/definitions/gin.H"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             },
-            "post": {
-                "description": "Set the active profile",
-                "consumes": [
-                    "application/json"
-                ],
+            "delete": {
+                "description": "Unpin a food item for a profile. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "profiles"
+                    "foodItems"
                 ],
-                "summary": "Set active profile",
+                "summary": "Remove favorite food item",
                 "parameters": [
                     {
-                        "description": "Profile to set as active",
-                        "name": "profile",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.ActiveProfileRequest"
-                        }
+                        "type": "string",
+                        "description": "Barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1442,69 +1657,79 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/profiles/single/{id}": {
+        "/foodItems/frequent": {
             "get": {
-                "description": "Get a profile by ID. If no profile ID is provided, the active profile is used.",
+                "description": "Get the food items a profile logged most often in the last days. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "profiles"
+                    "foodItems"
                 ],
-                "summary": "Get a profile",
+                "summary": "Get frequently logged food items",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Profile ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "name": "profile_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Period in days (default 90)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of food items (default 30)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.Profile"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.FoodItemUsage"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update a profile by ID. If no profile ID is provided, the active profile is used.",
+            }
+        },
+        "/foodItems/manually-add": {
+            "post": {
+                "description": "Manually add a new food item",
                 "consumes": [
                     "application/json"
                 ],
@@ -1512,24 +1737,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "profiles"
+                    "foodItems"
                 ],
-                "summary": "Update a profile",
+                "summary": "Manually add a food item",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Profile ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated profile data",
-                        "name": "profile",
+                        "description": "Food item to add",
+                        "name": "foodItem",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.Profile"
+                            "$ref": "#/definitions/types.PersistentFoodItem"
                         }
                     }
                 ],
@@ -1543,92 +1761,73 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a profile by ID",
+            }
+        },
+        "/foodItems/merge": {
+            "post": {
+                "description": "Replace the source food items by the target food item in one transaction. Diary entries, dish ingredients, logged dish ingredients, meal plan entries, favorites and portions are moved to the target, then the source food items are deleted. Logged entries keep their nutrition values. Without apply the merge is only previewed.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "profiles"
+                    "foodItems"
                 ],
-                "summary": "Delete a profile",
+                "summary": "Merge food items",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Profile ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Target and source food items",
+                        "name": "merge",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.MergeFoodItemsRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.FoodItemMergeResult"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/gin.H"
-                        }
-                    }
-                }
-            }
-        },
-        "/scanners": {
-            "get": {
-                "description": "List all available scanners",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "scanners"
-                ],
-                "summary": "List scanners",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/scanners/active": {
-            "post": {
-                "description": "Set the active scanner",
+        "/foodItems/portions/{barcode}": {
+            "put": {
+                "description": "Replace the named portions of a food item, e.g. 1 slice = 28 g. Portions can be used as unit when logging food or adding it to a dish.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1636,17 +1835,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "scanners"
+                    "foodItems"
                 ],
-                "summary": "Set active scanner",
+                "summary": "Set food portions",
                 "parameters": [
                     {
-                        "description": "Scanner to set as active",
-                        "name": "scanner",
+                        "type": "string",
+                        "description": "Food item barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Named portions of the food item",
+                        "name": "portions",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.ActiveScannerRequest"
+                            "$ref": "#/definitions/types.FoodPortionsRequest"
                         }
                     }
                 ],
@@ -1660,79 +1866,90 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/settings": {
+        "/foodItems/recent": {
             "get": {
-                "description": "Get user settings by profile ID. If no profile ID is provided, the active profile is used.",
+                "description": "Get the food items a profile logged most recently, with the quantity of the latest entry. If no profile ID is provided, the active profile is used.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "foodItems"
                 ],
-                "summary": "Get user settings",
+                "summary": "Get recently logged food items",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Profile ID",
                         "name": "profile_id",
-                        "in": "query",
-                        "required": true
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of food items (default 30)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/types.UserSettings"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.FoodItemUsage"
+                            }
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/foodItems/reset/{barcode}": {
             "post": {
-                "description": "Save user settings. If no profile ID is provided, the active profile is used.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Reset a food item to its default values by barcode",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "settings"
+                    "foodItems"
                 ],
-                "summary": "Save user settings",
+                "summary": "Reset a food item to default values",
                 "parameters": [
                     {
-                        "description": "User settings",
-                        "name": "settings",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/types.UserSettings"
-                        }
+                        "type": "string",
+                        "description": "Food item barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -1742,66 +1959,273 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
             }
         },
-        "/weightTracking": {
+        "/foodItems/search": {
             "get": {
-                "description": "Get the weight tracking status",
+                "description": "Search for food items by name or barcode. All words of the query have to match in any order, each word as prefix. Umlauts also match their spelled out form, e.g. \"Müsli\" is found by \"musli\" and \"muesli\". If nothing matches, similar words are searched to tolerate typos. With boost, favorites and food items the profile logged often are ranked higher.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "weightTracking"
+                    "foodItems"
+                ],
+                "summary": "Search food items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only food items with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Rank favorites and frequently logged food items higher",
+                        "name": "boost",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID for boosting, defaults to the active profile",
+                        "name": "profile_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (default 15)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get weight tracking status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/types.PersistentFoodItem"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/gin.H"
+                            "$ref": "#/definitions/types.ApiResponse"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Set the weight tracking status",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/foodItems/searchOpenFoodFacts": {
+            "get": {
+                "description": "Search for food items on OpenFoodFacts by name",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "weightTracking"
+                    "foodItems"
                 ],
-                "summary": "Set weight tracking status",
+                "summary": "Search food items on OpenFoodFacts",
                 "parameters": [
                     {
-                        "description": "Weight tracking status",
-                        "name": "weightTracking",
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/types.PersistentFoodItem"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/foodItems/servingQuantity/{barcode}": {
+            "get": {
+                "description": "Get the serving quantity of a food item by barcode",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "foodItems"
+                ],
+                "summary": "Get serving quantity by barcode",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Food item barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/foodItems/tags/{barcode}": {
+            "put": {
+                "description": "Replace the tags of a food item, e.g. \"dairy\" or \"snacks\". Tags are stored in lower case.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "foodItems"
+                ],
+                "summary": "Set food item tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Food item barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags of the food item",
+                        "name": "tags",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/types.WeightTrackingRequest"
+                            "$ref": "#/definitions/types.TagsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/foodItems/{barcode}": {
+            "put": {
+                "description": "Update a food item by barcode",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "foodItems"
+                ],
+                "summary": "Update a food item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Food item barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated food item data",
+                        "name": "foodItem",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.PersistentFoodItem"
                         }
                     }
                 ],
@@ -1812,112 +2236,3144 @@ const docTemplate = `{
                             "$ref": "#/definitions/gin.H"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a food item by barcode. Food items which are ingredients of dishes or planned in the meal plan are not deleted, the conflict error names the dishes or planned meals.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "foodItems"
+                ],
+                "summary": "Delete a food item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Food item barcode",
+                        "name": "barcode",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/gin.H"
                         }
-                    }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mealplan": {
+            "get": {
+                "description": "Get the planned food items and dishes of every day in a date range with their projected nutrition. The totals of each day are compared with the targets in effect on that day. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mealplan"
+                ],
+                "summary": "Get meal plan",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.MealPlanDay"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Plan a food item or a dish for a meal. Food items are planned in servings or in another unit, which is converted to servings. Dishes are planned in portions. If no profile ID is provided, the active profile is used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mealplan"
+                ],
+                "summary": "Create meal plan entry",
+                "parameters": [
+                    {
+                        "description": "Plan entry",
+                        "name": "entry",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.MealPlanEntryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mealplan/report": {
+            "get": {
+                "description": "Compare the planned with the logged calories and macros of every day in a date range. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mealplan"
+                ],
+                "summary": "Get meal plan report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First day (YYYY-MM-DD)",
+                        "name": "start_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last day (YYYY-MM-DD)",
+                        "name": "end_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.MealPlanReportDay"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mealplan/{id}": {
+            "put": {
+                "description": "Replace the date, meal, food item or dish and quantity of a plan entry",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mealplan"
+                ],
+                "summary": "Update meal plan entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Plan entry",
+                        "name": "entry",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.MealPlanEntryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a plan entry. The diary entry of an eaten plan entry is kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mealplan"
+                ],
+                "summary": "Delete meal plan entry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mealplan/{id}/eaten": {
+            "post": {
+                "description": "Log a plan entry as consumed on its date. The planned quantity is used unless another quantity is provided. Food items are added to an existing entry of the same food item on that date.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mealplan"
+                ],
+                "summary": "Mark meal plan entry as eaten",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Plan entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Optional quantity",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/types.MarkMealPlanEntryEatenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/nutrition/calculate": {
+            "post": {
+                "description": "Calculate daily nutrition targets based on user metrics",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nutrition"
+                ],
+                "summary": "Calculate nutrition targets",
+                "parameters": [
+                    {
+                        "description": "User metrics for calculation",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.NutritionCalculationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.NutritionCalculationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/nutrition/calculate-from-calories-and-weight": {
+            "post": {
+                "description": "Calculate daily nutrition targets based on user metrics",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nutrition"
+                ],
+                "summary": "Calculate nutrition targets",
+                "parameters": [
+                    {
+                        "description": "User metrics for calculation",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.NutritionCalculationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.NutritionCalculationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles": {
+            "get": {
+                "description": "Get a page of the profiles, by default all of them with the newest first. Sort keys are name and created_at. The total number of matching profiles is returned in the X-Total-Count header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profiles"
+                ],
+                "summary": "Get all profiles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First creation day (YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last creation day (YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "name": "incomplete",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only diary entries of food items (\"food\") or dishes (\"dish\")",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries, 0 for all",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of entries to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "\"asc\" or \"desc\", defaults to asc for name and desc otherwise",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key, the keys depend on the list",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries with this serving quantity unit (\"g\" or \"ml\")",
+                        "name": "unit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/types.Profile"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new profile",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profiles"
+                ],
+                "summary": "Create a profile",
+                "parameters": [
+                    {
+                        "description": "Profile to create",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Profile"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/active": {
+            "get": {
+                "description": "Get the currently active profile",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profiles"
+                ],
+                "summary": "Get active profile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Profile"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Set the active profile",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profiles"
+                ],
+                "summary": "Set active profile",
+                "parameters": [
+                    {
+                        "description": "Profile to set as active",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.ActiveProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profiles/single/{id}": {
+            "get": {
+                "description": "Get a profile by ID. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profiles"
+                ],
+                "summary": "Get a profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Profile"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update a profile by ID. If no profile ID is provided, the active profile is used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profiles"
+                ],
+                "summary": "Update a profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated profile data",
+                        "name": "profile",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.Profile"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a profile by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "profiles"
+                ],
+                "summary": "Delete a profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scanners": {
+            "get": {
+                "description": "List all available scanners",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scanners"
+                ],
+                "summary": "List scanners",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/scanners/active": {
+            "post": {
+                "description": "Set the active scanner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "scanners"
+                ],
+                "summary": "Set active scanner",
+                "parameters": [
+                    {
+                        "description": "Scanner to set as active",
+                        "name": "scanner",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.ActiveScannerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/search/local": {
+            "get": {
+                "description": "Search for food items and dishes by name or barcode, with the same matching and ranking as the food item search",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "search"
+                ],
+                "summary": "Search food items and dishes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search query",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only food items and dishes with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Rank favorites and frequently logged food items higher",
+                        "name": "boost",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID for boosting, defaults to the active profile",
+                        "name": "profile_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of results (default 15)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.SearchResult"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings": {
+            "get": {
+                "description": "Get user settings by profile ID. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get user settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.UserSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Save user settings. If no profile ID is provided, the active profile is used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Save user settings",
+                "parameters": [
+                    {
+                        "description": "User settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.UserSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/adaptive-tdee": {
+            "get": {
+                "description": "Get whether the daily recalculation uses the TDEE estimated from logged intake and weight trend instead of the BMR formula",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get adaptive TDEE status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Set whether the daily recalculation uses the TDEE estimated from logged intake and weight trend. Estimates with low confidence fall back to the BMR formula.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Set adaptive TDEE status",
+                "parameters": [
+                    {
+                        "description": "Adaptive TDEE status",
+                        "name": "adaptiveTDEE",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.AdaptiveTDEERequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/goal-strategy": {
+            "get": {
+                "description": "Get the goal strategy of a profile. Returns the default strategy if none is configured. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get goal strategy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.GoalStrategy"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Save the goal strategy of a profile. The macro split of the current targets, the saved settings and the daily recalculation follow the strategy. If no profile ID is provided, the active profile is used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Save goal strategy",
+                "parameters": [
+                    {
+                        "description": "Goal strategy",
+                        "name": "strategy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.GoalStrategyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete the goal strategy of a profile. Saved settings keep their macros as entered and the daily recalculation uses the default strategy. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Delete goal strategy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/goal-strategy/presets": {
+            "get": {
+                "description": "Get the available goal strategy presets with their macro split",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get goal strategy presets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "$ref": "#/definitions/types.GoalStrategy"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/target-history": {
+            "get": {
+                "description": "Get the changes of the targets of a profile, ordered by the date they took effect. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get target history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.TargetHistoryEntry"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/target-overrides": {
+            "get": {
+                "description": "Get the target overrides of a profile, the newest first. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get target overrides",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.TargetOverride"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create targets replacing the user settings on certain weekdays (e.g. training days), in a date range (e.g. a diet break) or both. Date range overrides take precedence over weekday overrides, the newest override wins otherwise. If no profile ID is provided, the active profile is used.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Create target override",
+                "parameters": [
+                    {
+                        "description": "Target override",
+                        "name": "override",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.TargetOverrideRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/target-overrides/{id}": {
+            "put": {
+                "description": "Replace the weekdays, date range and targets of a target override",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Update target override",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Target override ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target override",
+                        "name": "override",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.TargetOverrideRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a target override",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Delete target override",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Target override ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/targets/{date}": {
+            "get": {
+                "description": "Get the targets of a profile in effect on a date, including target overrides for weekdays and date ranges and earlier target changes. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get targets for a date",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Date (YYYY-MM-DD)",
+                        "name": "date",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.DailyTargets"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/tdee-estimate": {
+            "get": {
+                "description": "Estimate the total daily energy expenditure from the logged intake and the weight trend over a rolling window ending yesterday. If no profile ID is provided, the active profile is used.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Estimate TDEE",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Profile ID",
+                        "name": "profile_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Window in days (14-180, default 28)",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.TDEEEstimate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/webhooks": {
+            "get": {
+                "description": "Get the outgoing webhooks of this device. Their secrets are not returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.Webhook"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add an outgoing webhook. The events are posted as JSON with the headers X-Nutrack-Event (e.g. \"consumed_food_item.created\"), X-Nutrack-Delivery and, if a secret is set, X-Nutrack-Signature (\"sha256=\" and the hex encoded HMAC-SHA256 of the body). Events are entities like \"weight\" or entity.action names like \"consumed_food_item.created\", \"daily_target.reached\", \"weight.created\", \"database.conflict\" or \"scanner.offline\". Logging a food item again on the same day adds to its entry, which is a \"consumed_food_item.updated\" event. Failed deliveries are retried with an increasing delay.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Create webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/webhooks/deliveries": {
+            "get": {
+                "description": "Get the latest webhook deliveries, the latest first. Deliveries are kept for 30 days. Deliveries still pending when the backend restarts are marked as failed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Get webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only the deliveries of this webhook",
+                        "name": "webhook_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/webhooks/{id}": {
+            "put": {
+                "description": "Replace the URL, events, profiles and state of a webhook. The secret is kept if it is omitted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Update webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an outgoing webhook",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/settings/webhooks/{id}/test": {
+            "post": {
+                "description": "Post a \"webhook.test\" event to a webhook once, even if it is disabled, and return the delivery",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Test webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "description": "Get all tags in use with the number of food items and dishes using them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get all tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.TagUsage"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/weightTracking": {
+            "get": {
+                "description": "Get the weight tracking status",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "weightTracking"
+                ],
+                "summary": "Get weight tracking status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Set the weight tracking status",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "weightTracking"
+                ],
+                "summary": "Set weight tracking status",
+                "parameters": [
+                    {
+                        "description": "Weight tracking status",
+                        "name": "weightTracking",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/types.WeightTrackingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/gin.H"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/types.ApiResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "gin.H": {
+            "type": "object",
+            "additionalProperties": {}
+        },
+        "types.ActiveProfileRequest": {
+            "type": "object",
+            "properties": {
+                "profile_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ActiveScannerRequest": {
+            "type": "object",
+            "properties": {
+                "path": {
+                    "description": "Use pointer to handle null values",
+                    "type": "string"
+                }
+            }
+        },
+        "types.AdaptiveTDEERequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "types.ApiResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code of the error, see ErrorCode",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.ErrorCode"
+                        }
+                    ]
+                },
+                "data": {},
+                "error": {
+                    "type": "string"
+                },
+                "field": {
+                    "description": "Invalid field of validation errors",
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "types.AutoRecalculateNutritionValuesRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "types.BatchConsumeResponse": {
+            "type": "object",
+            "properties": {
+                "failed": {
+                    "description": "Number of items which were not logged, only in partial mode",
+                    "type": "integer"
+                },
+                "logged": {
+                    "description": "Number of logged items",
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.BatchItemResult"
+                    }
+                }
+            }
+        },
+        "types.BatchConsumedFoodItemRequest": {
+            "type": "object",
+            "properties": {
+                "force_sync": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ConsumedFoodItemRequest"
+                    }
+                },
+                "partial": {
+                    "description": "Log the valid items even if others fail, by default a failing item fails the whole batch",
+                    "type": "boolean"
+                }
+            }
+        },
+        "types.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "code": {
+                    "$ref": "#/definitions/types.ErrorCode"
+                },
+                "consumed_id": {
+                    "description": "Diary entry of logged items",
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/types.BatchItemStatus"
+                }
+            }
+        },
+        "types.BatchItemStatus": {
+            "type": "string",
+            "enum": [
+                "inserted",
+                "logged",
+                "merged",
+                "lookup_failed",
+                "validation_error",
+                "error"
+            ],
+            "x-enum-comments": {
+                "BatchItemFailed": "The item was not logged because of another error",
+                "BatchItemInserted": "The food item was added from OpenFoodFacts and logged",
+                "BatchItemLogged": "The known food item was logged as new diary entry",
+                "BatchItemLookupFailed": "OpenFoodFacts had no data, an empty food item was added and logged",
+                "BatchItemMerged": "The quantity was added to the diary entry of the same food item and date",
+                "BatchItemValidationError": "The item is invalid and was not logged"
+            },
+            "x-enum-varnames": [
+                "BatchItemInserted",
+                "BatchItemLogged",
+                "BatchItemMerged",
+                "BatchItemLookupFailed",
+                "BatchItemValidationError",
+                "BatchItemFailed"
+            ]
+        },
+        "types.ConsumedCopyResult": {
+            "type": "object",
+            "properties": {
+                "dates": {
+                    "description": "Dates entries were copied to",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "inserted": {
+                    "description": "New diary entries",
+                    "type": "integer"
+                },
+                "merged": {
+                    "description": "Entries added to an existing entry of the same food item",
+                    "type": "integer"
+                }
+            }
+        },
+        "types.ConsumedDishIngredient": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "calories_per_100g": {
+                    "type": "number"
+                },
+                "carbs_per_100g": {
+                    "type": "number"
+                },
+                "fat_per_100g": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "protein_per_100g": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "description": "in the serving quantity unit of the food item",
+                    "type": "number"
+                }
+            }
+        },
+        "types.ConsumedDishRequest": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "dish_id": {
+                    "type": "string"
+                },
+                "force_sync": {
+                    "type": "boolean"
+                },
+                "portions": {
+                    "description": "Number of portions, fractions are allowed",
+                    "type": "number"
+                },
+                "profile_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.ConsumedFoodItemRequest": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "consumed_quantity": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "force_sync": {
+                    "type": "boolean"
+                },
+                "profile_id": {
+                    "type": "string"
+                },
+                "unit": {
+                    "description": "Unit of the consumed quantity: \"serving\" (default), \"g\", \"ml\" or a portion name",
+                    "type": "string"
+                }
+            }
+        },
+        "types.ConsumedFoodItemWithDetails": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "calories_per_100g": {
+                    "type": "number"
+                },
+                "carbs_per_100g": {
+                    "type": "number"
+                },
+                "consumed_quantity": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "dish_id": {
+                    "type": "string"
+                },
+                "fat_per_100g": {
+                    "type": "number"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ingredients": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.ConsumedDishIngredient"
+                    }
+                },
+                "insert_date": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "protein_per_100g": {
+                    "type": "number"
+                },
+                "serving_quantity": {
+                    "type": "number"
+                },
+                "serving_quantity_unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.CopyConsumedFoodItemsRequest": {
+            "type": "object",
+            "properties": {
+                "entry_ids": {
+                    "description": "Optional subset of the entries of the source date",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "force_sync": {
+                    "type": "boolean"
+                },
+                "source_date": {
+                    "type": "string"
+                },
+                "source_profile_id": {
+                    "description": "Defaults to the active profile",
+                    "type": "string"
+                },
+                "target_date": {
+                    "type": "string"
+                },
+                "target_end_date": {
+                    "description": "Optional, copies to every day from the target date to this date",
+                    "type": "string"
+                },
+                "target_profile_id": {
+                    "description": "Defaults to the source profile",
+                    "type": "string"
+                },
+                "weekdays": {
+                    "description": "Optional, only copies to these weekdays (0 = Sunday) of the date range",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "types.DailySummary": {
+            "type": "object",
+            "properties": {
+                "calories": {
+                    "type": "number"
+                },
+                "carbs": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "integer"
+                },
+                "fat": {
+                    "type": "number"
+                },
+                "proteins": {
+                    "type": "number"
+                },
+                "remaining_calories": {
+                    "description": "Negative if the target was exceeded",
+                    "type": "number"
+                },
+                "targets": {
+                    "$ref": "#/definitions/types.DailyTargets"
+                }
+            }
+        },
+        "types.DailyTargets": {
+            "type": "object",
+            "properties": {
+                "calories": {
+                    "type": "number"
+                },
+                "carbs": {
+                    "type": "number"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "fat": {
+                    "type": "number"
+                },
+                "override_id": {
+                    "description": "Set if a target override applies",
+                    "type": "string"
+                },
+                "override_name": {
+                    "description": "Set if a target override applies",
+                    "type": "string"
+                },
+                "proteins": {
+                    "type": "number"
+                },
+                "source": {
+                    "description": "\"settings\", \"history\", \"weekday\" or \"date_range\"",
+                    "type": "string"
+                }
+            }
+        },
+        "types.DetailedDishItem": {
+            "type": "object",
+            "properties": {
+                "food_item": {
+                    "$ref": "#/definitions/types.PersistentFoodItem"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "unit": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.Dish": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "description": "Optional Barcode",
+                    "type": "string"
+                },
+                "cooked_weight": {
+                    "description": "Optional measured weight of the cooked dish in g",
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_updated": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "portions": {
+                    "description": "Optional number of portions the dish yields",
+                    "type": "integer"
+                },
+                "tags": {
+                    "description": "User-defined tags like \"homemade\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "types.DishNutrition": {
+            "type": "object",
+            "properties": {
+                "calories_per_100g": {
+                    "type": "number"
+                },
+                "calories_per_portion": {
+                    "type": "number"
+                },
+                "carbs_per_100g": {
+                    "type": "number"
+                },
+                "carbs_per_portion": {
+                    "type": "number"
+                },
+                "cooked_weight": {
+                    "type": "number"
+                },
+                "dish_id": {
+                    "type": "string"
+                },
+                "fat_per_100g": {
+                    "type": "number"
+                },
+                "fat_per_portion": {
+                    "type": "number"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "portion_weight": {
+                    "type": "number"
+                },
+                "portions": {
+                    "type": "integer"
+                },
+                "protein_per_100g": {
+                    "type": "number"
+                },
+                "protein_per_portion": {
+                    "type": "number"
+                },
+                "raw_weight": {
+                    "type": "number"
+                }
+            }
+        },
+        "types.DishRequest": {
+            "type": "object",
+            "properties": {
+                "dish": {
+                    "type": "object",
+                    "properties": {
+                        "barcode": {
+                            "type": "string"
+                        },
+                        "cooked_weight": {
+                            "type": "number"
+                        },
+                        "name": {
+                            "type": "string"
+                        },
+                        "portions": {
+                            "type": "integer"
+                        },
+                        "tags": {
+                            "description": "Optional, on update the tags are only replaced if given",
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "properties": {
+                            "barcode": {
+                                "type": "string"
+                            },
+                            "quantity": {
+                                "type": "number"
+                            },
+                            "unit": {
+                                "description": "Optional, \"g\", \"ml\", \"serving\" or a portion name",
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "types.DishWithDetailedItems": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "cooked_weight": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "dish_items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.DetailedDishItem"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_updated": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "portions": {
+                    "type": "integer"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "types.DropboxAutosyncRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "types.DuplicateFoodItemGroup": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.PersistentFoodItem"
+                    }
+                },
+                "similarity": {
+                    "description": "Lowest name similarity between linked items, between 0 and 1",
+                    "type": "number"
+                },
+                "suggested_target": {
+                    "description": "Barcode of the item to keep, the one logged most often",
+                    "type": "string"
+                }
+            }
+        },
+        "types.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "INVALID_REQUEST",
+                "VALIDATION_ERROR",
+                "NOT_FOUND",
+                "CONFLICT",
+                "SYNC_CONFLICT",
+                "UPSTREAM_UNAVAILABLE",
+                "RATE_LIMITED",
+                "INTERNAL_ERROR"
+            ],
+            "x-enum-comments": {
+                "ErrorCodeConflict": "Conflicts with existing data",
+                "ErrorCodeInternal": "Any other error",
+                "ErrorCodeInvalidRequest": "Malformed request body or parameters",
+                "ErrorCodeNotFound": "Missing food item, dish, profile, ...",
+                "ErrorCodeRateLimited": "OpenFoodFacts or Dropbox rejected too many requests, or the client sent them",
+                "ErrorCodeSyncConflict": "Local and Dropbox database were both changed",
+                "ErrorCodeUpstreamUnavailable": "OpenFoodFacts or Dropbox cannot be reached",
+                "ErrorCodeValidation": "Invalid value of a field"
+            },
+            "x-enum-varnames": [
+                "ErrorCodeInvalidRequest",
+                "ErrorCodeValidation",
+                "ErrorCodeNotFound",
+                "ErrorCodeConflict",
+                "ErrorCodeSyncConflict",
+                "ErrorCodeUpstreamUnavailable",
+                "ErrorCodeRateLimited",
+                "ErrorCodeInternal"
+            ]
+        },
+        "types.FoodItemMergeResult": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "description": "False for a preview",
+                    "type": "boolean"
+                },
+                "consumed_entries": {
+                    "description": "Diary entries of the source food items",
+                    "type": "integer"
+                },
+                "dish_ingredients": {
+                    "description": "Ingredients of dishes",
+                    "type": "integer"
+                },
+                "favorites": {
+                    "type": "integer"
+                },
+                "logged_dish_ingredients": {
+                    "description": "Ingredients of logged dishes",
+                    "type": "integer"
+                },
+                "meal_plan_entries": {
+                    "type": "integer"
+                },
+                "portions": {
+                    "description": "Portions moved to the target, portions with an existing name are dropped",
+                    "type": "integer"
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.PersistentFoodItem"
+                    }
+                },
+                "tags": {
+                    "description": "Tags added to the target",
+                    "type": "integer"
+                },
+                "target": {
+                    "$ref": "#/definitions/types.PersistentFoodItem"
+                }
+            }
+        },
+        "types.FoodItemUsage": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "carbohydrates_100g": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "density": {
+                    "description": "Optional density in g/ml for converting between g and ml",
+                    "type": "number"
+                },
+                "energy-kcal_100g": {
+                    "type": "number"
+                },
+                "fat_100g": {
+                    "type": "number"
+                },
+                "favorite": {
+                    "type": "boolean"
+                },
+                "last_logged": {
+                    "description": "Insert date of the latest diary entry, empty if never logged",
+                    "type": "string"
+                },
+                "last_quantity": {
+                    "description": "Consumed quantity of the latest diary entry",
+                    "type": "number"
+                },
+                "last_updated": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "portions": {
+                    "description": "Named portions like \"slice\" or \"cup\"",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.FoodPortion"
+                    }
+                },
+                "proteins_100g": {
+                    "type": "number"
+                },
+                "serving_quantity": {
+                    "type": "number"
+                },
+                "serving_quantity_unit": {
+                    "type": "string"
+                },
+                "source_dish_id": {
+                    "description": "Set if the food item was converted from a dish",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "User-defined tags like \"dairy\" or \"snacks\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "times_logged": {
+                    "description": "Number of diary entries in the considered period",
+                    "type": "integer"
+                }
+            }
+        },
+        "types.FoodPortion": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Amount of one portion in Unit",
+                    "type": "number"
+                },
+                "unit": {
+                    "description": "\"g\" or \"ml\"",
+                    "type": "string"
+                }
+            }
+        },
+        "types.FoodPortionsRequest": {
+            "type": "object",
+            "properties": {
+                "portions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.FoodPortion"
+                    }
+                }
+            }
+        },
+        "types.ForceSyncRequest": {
+            "type": "object",
+            "properties": {
+                "force": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "types.GoalStrategy": {
+            "type": "object",
+            "properties": {
+                "bmr_formula": {
+                    "description": "\"mifflin-st-jeor\", \"harris-benedict\" or \"katch-mcardle\"",
+                    "type": "string"
+                },
+                "body_fat_percentage": {
+                    "description": "Required for Katch-McArdle",
+                    "type": "number"
+                },
+                "carbs": {
+                    "description": "Only used for the custom preset",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MacroTarget"
+                        }
+                    ]
+                },
+                "fat": {
+                    "description": "Only used for the custom preset",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MacroTarget"
+                        }
+                    ]
+                },
+                "min_carbs": {
+                    "description": "Minimum carbs in g per day, 0 for no minimum",
+                    "type": "number"
+                },
+                "preset": {
+                    "description": "\"balanced\", \"high-protein\", \"keto\" or \"custom\"",
+                    "type": "string"
+                },
+                "protein": {
+                    "description": "Only used for the custom preset",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MacroTarget"
+                        }
+                    ]
+                }
+            }
+        },
+        "types.GoalStrategyRequest": {
+            "type": "object",
+            "properties": {
+                "bmr_formula": {
+                    "description": "\"mifflin-st-jeor\", \"harris-benedict\" or \"katch-mcardle\"",
+                    "type": "string"
+                },
+                "body_fat_percentage": {
+                    "description": "Required for Katch-McArdle",
+                    "type": "number"
+                },
+                "carbs": {
+                    "description": "Only used for the custom preset",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MacroTarget"
+                        }
+                    ]
+                },
+                "fat": {
+                    "description": "Only used for the custom preset",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MacroTarget"
+                        }
+                    ]
+                },
+                "min_carbs": {
+                    "description": "Minimum carbs in g per day, 0 for no minimum",
+                    "type": "number"
+                },
+                "preset": {
+                    "description": "\"balanced\", \"high-protein\", \"keto\" or \"custom\"",
+                    "type": "string"
+                },
+                "profile_id": {
+                    "type": "string"
+                },
+                "protein": {
+                    "description": "Only used for the custom preset",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.MacroTarget"
+                        }
+                    ]
                 }
             }
-        }
-    },
-    "definitions": {
-        "gin.H": {
-            "type": "object",
-            "additionalProperties": {}
         },
-        "types.ActiveProfileRequest": {
+        "types.MacroTarget": {
             "type": "object",
             "properties": {
-                "profile_id": {
+                "mode": {
+                    "description": "\"percent\" of calories, \"g_per_kg\" body weight, \"grams\" or \"rest\" of the calories",
                     "type": "string"
+                },
+                "value": {
+                    "description": "Unused for \"rest\"",
+                    "type": "number"
                 }
             }
         },
-        "types.ActiveScannerRequest": {
+        "types.MarkMealPlanEntryEatenRequest": {
             "type": "object",
             "properties": {
-                "path": {
-                    "description": "Use pointer to handle null values",
-                    "type": "string"
+                "force_sync": {
+                    "type": "boolean"
+                },
+                "quantity": {
+                    "description": "Optional, defaults to the planned servings or portions",
+                    "type": "number"
                 }
             }
         },
-        "types.ApiResponse": {
+        "types.MealPlanDay": {
             "type": "object",
             "properties": {
-                "data": {},
-                "error": {
+                "date": {
                     "type": "string"
                 },
-                "message": {
-                    "type": "string"
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.MealPlanEntry"
+                    }
                 },
-                "success": {
-                    "type": "boolean"
+                "planned": {
+                    "$ref": "#/definitions/types.NutritionTotals"
+                },
+                "remaining_calories": {
+                    "description": "Calories left after the planned entries, negative if the plan exceeds the target",
+                    "type": "number"
+                },
+                "targets": {
+                    "$ref": "#/definitions/types.DailyTargets"
                 }
             }
         },
-        "types.AutoRecalculateNutritionValuesRequest": {
+        "types.MealPlanEntry": {
             "type": "object",
             "properties": {
-                "enabled": {
+                "barcode": {
+                    "type": "string"
+                },
+                "consumed_id": {
+                    "description": "Diary entry of an eaten plan entry",
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "dish_id": {
+                    "type": "string"
+                },
+                "eaten": {
                     "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "meal": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "nutrition": {
+                    "$ref": "#/definitions/types.NutritionTotals"
+                },
+                "profile_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Servings of the food item or portions of the dish",
+                    "type": "number"
+                },
+                "weight": {
+                    "description": "Planned amount in the base unit of the food item or in grams of the dish",
+                    "type": "number"
                 }
             }
         },
-        "types.ConsumedFoodItemRequest": {
+        "types.MealPlanEntryRequest": {
             "type": "object",
             "properties": {
                 "barcode": {
+                    "description": "Either a food item or a dish is planned",
                     "type": "string"
                 },
-                "consumed_quantity": {
+                "date": {
+                    "type": "string"
+                },
+                "dish_id": {
+                    "type": "string"
+                },
+                "force_sync": {
+                    "type": "boolean"
+                },
+                "meal": {
+                    "description": "\"breakfast\", \"lunch\", \"dinner\" or \"snack\"",
+                    "type": "string"
+                },
+                "profile_id": {
+                    "description": "Defaults to the active profile",
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Servings of the food item or portions of the dish, defaults to 1",
                     "type": "number"
                 },
+                "unit": {
+                    "description": "Unit of the quantity of a food item: \"serving\" (default), \"g\", \"ml\" or a portion name",
+                    "type": "string"
+                }
+            }
+        },
+        "types.MealPlanReportDay": {
+            "type": "object",
+            "properties": {
+                "actual": {
+                    "$ref": "#/definitions/types.NutritionTotals"
+                },
                 "date": {
                     "type": "string"
                 },
+                "difference": {
+                    "description": "Actual minus planned",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.NutritionTotals"
+                        }
+                    ]
+                },
+                "eaten_entries": {
+                    "description": "Plan entries marked as eaten",
+                    "type": "integer"
+                },
+                "logged_entries": {
+                    "type": "integer"
+                },
+                "planned": {
+                    "$ref": "#/definitions/types.NutritionTotals"
+                },
+                "planned_entries": {
+                    "type": "integer"
+                },
+                "targets": {
+                    "$ref": "#/definitions/types.DailyTargets"
+                }
+            }
+        },
+        "types.MergeFoodItemsRequest": {
+            "type": "object",
+            "properties": {
+                "apply": {
+                    "description": "False only previews the merge",
+                    "type": "boolean"
+                },
                 "force_sync": {
                     "type": "boolean"
                 },
-                "profile_id": {
+                "source_barcodes": {
+                    "description": "Food items to merge into the target and delete",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "target_barcode": {
+                    "description": "Food item to keep",
                     "type": "string"
                 }
             }
         },
-        "types.ConsumedFoodItemWithDetails": {
+        "types.NutritionCalculationRequest": {
             "type": "object",
             "properties": {
-                "barcode": {
+                "activityLevel": {
+                    "description": "0-4 (sedentary to very active)",
+                    "type": "integer"
+                },
+                "age": {
+                    "description": "in years",
+                    "type": "integer"
+                },
+                "gender": {
+                    "description": "\"male\", \"female\", or \"other\"",
                     "type": "string"
                 },
-                "calories_per_100g": {
+                "height": {
+                    "description": "in cm",
                     "type": "number"
                 },
-                "carbs_per_100g": {
+                "profile_id": {
+                    "description": "Optional, use the goal strategy of the profile",
+                    "type": "string"
+                },
+                "strategy": {
+                    "description": "Optional goal strategy",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.GoalStrategy"
+                        }
+                    ]
+                },
+                "weeklyWeightChange": {
+                    "description": "in kg per week",
                     "type": "number"
                 },
-                "consumed_quantity": {
+                "weight": {
+                    "description": "in kg",
                     "type": "number"
+                }
+            }
+        },
+        "types.NutritionCalculationResponse": {
+            "type": "object",
+            "properties": {
+                "calories": {
+                    "description": "Daily calorie target",
+                    "type": "integer"
                 },
-                "date": {
+                "carbs": {
+                    "description": "Daily carbs target in grams",
+                    "type": "integer"
+                },
+                "fat": {
+                    "description": "Daily fat target in grams",
+                    "type": "integer"
+                },
+                "proteins": {
+                    "description": "Daily protein target in grams",
+                    "type": "integer"
+                }
+            }
+        },
+        "types.NutritionTotals": {
+            "type": "object",
+            "properties": {
+                "calories": {
+                    "type": "number"
+                },
+                "carbs": {
+                    "type": "number"
+                },
+                "fat": {
+                    "type": "number"
+                },
+                "proteins": {
+                    "type": "number"
+                }
+            }
+        },
+        "types.PersistentFoodItem": {
+            "type": "object",
+            "properties": {
+                "barcode": {
                     "type": "string"
                 },
-                "fat_per_100g": {
+                "carbohydrates_100g": {
                     "type": "number"
                 },
-                "id": {
+                "created_at": {
                     "type": "string"
                 },
-                "insert_date": {
+                "density": {
+                    "description": "Optional density in g/ml for converting between g and ml",
+                    "type": "number"
+                },
+                "energy-kcal_100g": {
+                    "type": "number"
+                },
+                "fat_100g": {
+                    "type": "number"
+                },
+                "last_updated": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
-                "protein_per_100g": {
+                "portions": {
+                    "description": "Named portions like \"slice\" or \"cup\"",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.FoodPortion"
+                    }
+                },
+                "proteins_100g": {
                     "type": "number"
                 },
                 "serving_quantity": {
@@ -1925,209 +5381,318 @@ const docTemplate = `{
                 },
                 "serving_quantity_unit": {
                     "type": "string"
+                },
+                "source_dish_id": {
+                    "description": "Set if the food item was converted from a dish",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "User-defined tags like \"dairy\" or \"snacks\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "types.DetailedDishItem": {
+        "types.Profile": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.RepriceHistoryRequest": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "type": "string"
+                },
+                "end_date": {
+                    "description": "Optional, last date to update",
+                    "type": "string"
+                },
+                "force_sync": {
+                    "type": "boolean"
+                },
+                "profile_id": {
+                    "description": "Optional, all profiles if empty",
+                    "type": "string"
+                },
+                "start_date": {
+                    "description": "Optional, first date to update",
+                    "type": "string"
+                }
+            }
+        },
+        "types.SearchResult": {
             "type": "object",
             "properties": {
+                "dish": {
+                    "$ref": "#/definitions/types.DishNutrition"
+                },
                 "food_item": {
                     "$ref": "#/definitions/types.PersistentFoodItem"
                 },
-                "quantity": {
-                    "type": "number"
+                "id": {
+                    "description": "Barcode of the food item or ID of the dish",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "\"food_item\" or \"dish\"",
+                    "type": "string"
                 }
             }
         },
-        "types.Dish": {
+        "types.TDEEEstimate": {
             "type": "object",
             "properties": {
-                "barcode": {
-                    "description": "Optional Barcode",
+                "average_intake": {
+                    "description": "Average kcal per logged day",
+                    "type": "number"
+                },
+                "confidence": {
+                    "description": "\"none\", \"low\", \"medium\" or \"high\"",
                     "type": "string"
                 },
-                "created_at": {
+                "confidence_score": {
+                    "description": "Between 0 and 1",
+                    "type": "number"
+                },
+                "end_date": {
                     "type": "string"
                 },
-                "id": {
+                "formula_tdee": {
+                    "description": "Maintenance calories from BMR and activity level, 0 if the settings are incomplete",
+                    "type": "number"
+                },
+                "logged_days": {
+                    "description": "Days with logged food",
+                    "type": "integer"
+                },
+                "message": {
                     "type": "string"
                 },
-                "last_updated": {
+                "profile_id": {
                     "type": "string"
                 },
-                "name": {
+                "start_date": {
                     "type": "string"
+                },
+                "sufficient_data": {
+                    "description": "False if there are too few logged days or weight entries for an estimate",
+                    "type": "boolean"
+                },
+                "tdee": {
+                    "description": "Estimated maintenance calories, 0 if there is not enough data",
+                    "type": "number"
+                },
+                "weight_entries": {
+                    "description": "Days with a tracked weight",
+                    "type": "integer"
+                },
+                "weight_trend": {
+                    "description": "Weight change in kg per week according to the trend",
+                    "type": "number"
+                },
+                "window_days": {
+                    "type": "integer"
                 }
             }
         },
-        "types.DishRequest": {
+        "types.TagIntake": {
             "type": "object",
             "properties": {
-                "dish": {
-                    "type": "object",
-                    "properties": {
-                        "barcode": {
-                            "type": "string"
-                        },
-                        "name": {
-                            "type": "string"
-                        }
-                    }
+                "calories": {
+                    "type": "number"
                 },
-                "items": {
-                    "type": "array",
-                    "items": {
-                        "type": "object",
-                        "properties": {
-                            "barcode": {
-                                "type": "string"
-                            },
-                            "quantity": {
-                                "type": "number"
-                            }
-                        }
-                    }
+                "carbs": {
+                    "type": "number"
+                },
+                "entries": {
+                    "type": "integer"
+                },
+                "fat": {
+                    "type": "number"
+                },
+                "proteins": {
+                    "type": "number"
+                },
+                "share": {
+                    "description": "Percentage of the total calories",
+                    "type": "number"
+                },
+                "tag": {
+                    "type": "string"
                 }
             }
         },
-        "types.DishWithDetailedItems": {
+        "types.TagIntakeSummary": {
             "type": "object",
             "properties": {
-                "barcode": {
+                "end_date": {
                     "type": "string"
                 },
-                "created_at": {
+                "entries": {
+                    "type": "integer"
+                },
+                "start_date": {
                     "type": "string"
                 },
-                "dish_items": {
+                "tags": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/types.DetailedDishItem"
+                        "$ref": "#/definitions/types.TagIntake"
                     }
                 },
-                "id": {
-                    "type": "string"
-                },
-                "last_updated": {
-                    "type": "string"
+                "total": {
+                    "$ref": "#/definitions/types.NutritionTotals"
                 },
-                "name": {
-                    "type": "string"
+                "untagged": {
+                    "description": "Entries without any tag",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/types.TagIntake"
+                        }
+                    ]
                 }
             }
         },
-        "types.DropboxAutosyncRequest": {
+        "types.TagUsage": {
             "type": "object",
             "properties": {
-                "enabled": {
-                    "type": "boolean"
+                "dishes": {
+                    "type": "integer"
+                },
+                "food_items": {
+                    "type": "integer"
+                },
+                "tag": {
+                    "type": "string"
                 }
             }
         },
-        "types.ForceSyncRequest": {
+        "types.TagsRequest": {
             "type": "object",
             "properties": {
-                "force": {
-                    "type": "boolean"
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "types.NutritionCalculationRequest": {
+        "types.TargetHistoryEntry": {
             "type": "object",
             "properties": {
-                "activityLevel": {
-                    "description": "0-4 (sedentary to very active)",
-                    "type": "integer"
+                "calories": {
+                    "type": "number"
                 },
-                "age": {
-                    "description": "in years",
-                    "type": "integer"
+                "carbs": {
+                    "type": "number"
                 },
-                "gender": {
-                    "description": "\"male\", \"female\", or \"other\"",
+                "effective_date": {
                     "type": "string"
                 },
-                "height": {
-                    "description": "in cm",
-                    "type": "number"
-                },
-                "weeklyWeightChange": {
-                    "description": "in kg per week",
+                "fat": {
                     "type": "number"
                 },
-                "weight": {
-                    "description": "in kg",
+                "proteins": {
                     "type": "number"
                 }
             }
         },
-        "types.NutritionCalculationResponse": {
+        "types.TargetOverride": {
             "type": "object",
             "properties": {
                 "calories": {
-                    "description": "Daily calorie target",
-                    "type": "integer"
+                    "description": "Targets which are not set are taken from the user settings",
+                    "type": "number"
                 },
                 "carbs": {
-                    "description": "Daily carbs target in grams",
-                    "type": "integer"
-                },
-                "fat": {
-                    "description": "Daily fat target in grams",
-                    "type": "integer"
-                },
-                "proteins": {
-                    "description": "Daily protein target in grams",
-                    "type": "integer"
-                }
-            }
-        },
-        "types.PersistentFoodItem": {
-            "type": "object",
-            "properties": {
-                "barcode": {
-                    "type": "string"
-                },
-                "carbohydrates_100g": {
                     "type": "number"
                 },
                 "created_at": {
                     "type": "string"
                 },
-                "energy-kcal_100g": {
-                    "type": "number"
+                "end_date": {
+                    "description": "Optional last day (YYYY-MM-DD)",
+                    "type": "string"
                 },
-                "fat_100g": {
+                "fat": {
                     "type": "number"
                 },
-                "last_updated": {
+                "id": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
-                "proteins_100g": {
-                    "type": "number"
+                "profile_id": {
+                    "type": "string"
                 },
-                "serving_quantity": {
+                "proteins": {
                     "type": "number"
                 },
-                "serving_quantity_unit": {
+                "start_date": {
+                    "description": "Optional first day (YYYY-MM-DD)",
                     "type": "string"
+                },
+                "weekdays": {
+                    "description": "0 = Sunday to 6 = Saturday, empty for every day",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
-        "types.Profile": {
+        "types.TargetOverrideRequest": {
             "type": "object",
             "properties": {
-                "created_at": {
-                    "type": "string"
+                "calories": {
+                    "type": "number"
                 },
-                "id": {
+                "carbs": {
+                    "type": "number"
+                },
+                "end_date": {
+                    "description": "YYYY-MM-DD",
                     "type": "string"
                 },
+                "fat": {
+                    "type": "number"
+                },
                 "name": {
                     "type": "string"
+                },
+                "profile_id": {
+                    "type": "string"
+                },
+                "proteins": {
+                    "type": "number"
+                },
+                "start_date": {
+                    "description": "YYYY-MM-DD",
+                    "type": "string"
+                },
+                "weekdays": {
+                    "description": "0 = Sunday to 6 = Saturday",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
@@ -2166,6 +5731,124 @@ const docTemplate = `{
                 }
             }
         },
+        "types.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "has_secret": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "profile_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "Error of the last attempt",
+                    "type": "string"
+                },
+                "event": {
+                    "description": "entity.action, e.g. \"consumed_food_item.created\"",
+                    "type": "string"
+                },
+                "event_seq": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "response_status": {
+                    "description": "HTTP status of the last attempt",
+                    "type": "integer"
+                },
+                "status": {
+                    "$ref": "#/definitions/types.WebhookDeliveryStatus"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.WebhookDeliveryStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "delivered",
+                "failed"
+            ],
+            "x-enum-comments": {
+                "WebhookDeliveryDelivered": "The receiver answered with a 2xx status",
+                "WebhookDeliveryFailed": "All attempts failed or the receiver rejected the event",
+                "WebhookDeliveryPending": "Not yet delivered, attempts remain"
+            },
+            "x-enum-varnames": [
+                "WebhookDeliveryPending",
+                "WebhookDeliveryDelivered",
+                "WebhookDeliveryFailed"
+            ]
+        },
+        "types.WebhookRequest": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "Defaults to true",
+                    "type": "boolean"
+                },
+                "events": {
+                    "description": "Entities or entity.action names, e.g. \"consumed_food_item.created\", empty for all events",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "profile_ids": {
+                    "description": "Profiles of the events, empty for all",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Key of the HMAC signature, kept on updates if omitted",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
         "types.WeightTrackingRequest": {
             "type": "object",
             "properties": {
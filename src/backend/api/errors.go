@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"nutrack/backend/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps the error codes to HTTP status codes
+var errorStatus = map[types.ErrorCode]int{
+	types.ErrorCodeInvalidRequest:      http.StatusBadRequest,
+	types.ErrorCodeValidation:          http.StatusBadRequest,
+	types.ErrorCodeNotFound:            http.StatusNotFound,
+	types.ErrorCodeConflict:            http.StatusConflict,
+	types.ErrorCodeSyncConflict:        http.StatusConflict,
+	types.ErrorCodeUpstreamUnavailable: http.StatusServiceUnavailable,
+	types.ErrorCodeRateLimited:         http.StatusTooManyRequests,
+	types.ErrorCodeInternal:            http.StatusInternalServerError,
+}
+
+// writeError writes the error response of err with the HTTP status of its error code. The
+// message of internal errors is prefixed, e.g. with "Failed to create dish: ".
+func writeError(c *gin.Context, prefix string, err error) {
+	code := types.ErrorCodeOf(err)
+	message := err.Error()
+	if code == types.ErrorCodeInternal {
+		message = prefix + message
+	}
+
+	c.JSON(errorStatus[code], types.ApiResponse{
+		Success: false,
+		Error:   message,
+		Code:    code,
+		Field:   types.ErrorField(err),
+	})
+}
+
+// writeInvalidRequest writes the error response of a request body or query which cannot be read
+func writeInvalidRequest(c *gin.Context, message string) {
+	writeError(c, "", types.NewInvalidRequestError("%s", message))
+}
+
+// writeUnknownEndpoint writes the error response of requests without a route
+func writeUnknownEndpoint(c *gin.Context) {
+	writeError(c, "", types.NewNotFoundError("unknown endpoint: %s", strings.TrimPrefix(c.Request.URL.Path, "/api")))
+}
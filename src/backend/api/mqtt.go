@@ -0,0 +1,419 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/service"
+	"nutrack/backend/types"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	mqttDefaultTopicPrefix     = "nutrack"
+	mqttDefaultDiscoveryPrefix = "homeassistant"
+	mqttDefaultClientID        = "nutrack"
+	mqttPublishTimeout         = 10 * time.Second
+	mqttCommandTimeout         = time.Minute // Product lookups of unknown barcodes can be slow
+	mqttDayCheckInterval       = time.Minute // Today's totals are republished after midnight
+	mqttQoS                    = byte(1)
+)
+
+// mqttSensor is a Home Assistant sensor of a profile, read from the today topic
+type mqttSensor struct {
+	Key           string
+	Name          string
+	ValueTemplate string
+	Unit          string
+	Icon          string
+}
+
+var mqttSensors = []mqttSensor{
+	{"calories", "Calories", "{{ value_json.calories | round(0) }}", "kcal", "mdi:fire"},
+	{"proteins", "Proteins", "{{ value_json.proteins | round(1) }}", "g", "mdi:food-steak"},
+	{"carbs", "Carbs", "{{ value_json.carbs | round(1) }}", "g", "mdi:bread-slice"},
+	{"fat", "Fat", "{{ value_json.fat | round(1) }}", "g", "mdi:water"},
+	{"remaining_calories", "Remaining calories", "{{ value_json.remaining_calories | round(0) }}", "kcal", "mdi:scale-balance"},
+	{"target_calories", "Calorie target", "{{ value_json.targets.calories | round(0) }}", "kcal", "mdi:target"},
+}
+
+// mqttLogCommand is a log command, the diary entry request of the REST API with an optional
+// requestId which is returned in the result
+type mqttLogCommand struct {
+	types.ConsumedFoodItemRequest
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// mqttLogResult is the outcome of a log command, like an item of a consumption batch
+type mqttLogResult struct {
+	types.BatchItemResult
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// mqttScan is the last logged diary entry of a profile
+type mqttScan struct {
+	ID               string  `json:"id"`
+	Barcode          string  `json:"barcode"`
+	Name             string  `json:"name"`
+	ConsumedQuantity float64 `json:"consumed_quantity"`
+	Date             string  `json:"date"`
+	Action           string  `json:"action"` // "created", or "updated" if the quantity was added to an entry
+	Time             string  `json:"time"`
+}
+
+// mqttBridge publishes the state of the profiles as retained topics and logs food items
+// received on the command topic
+type mqttBridge struct {
+	router          *Router
+	client          mqtt.Client
+	topicPrefix     string
+	discoveryPrefix string
+
+	profilesMutex sync.Mutex
+	profiles      map[string]string // Names of the profiles with published topics by ID
+}
+
+// startMQTT connects to the broker in MQTT_BROKER if it is set, the integration is optional.
+// The connection is retried in the background until the broker is reachable.
+func (r *Router) startMQTT() {
+	broker := os.Getenv("MQTT_BROKER")
+	if broker == "" {
+		return
+	}
+
+	bridge := &mqttBridge{
+		router:          r,
+		topicPrefix:     strings.TrimSuffix(envOrDefault("MQTT_TOPIC_PREFIX", mqttDefaultTopicPrefix), "/"),
+		discoveryPrefix: strings.TrimSuffix(envOrDefault("MQTT_DISCOVERY_PREFIX", mqttDefaultDiscoveryPrefix), "/"),
+		profiles:        make(map[string]string),
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(envOrDefault("MQTT_CLIENT_ID", mqttDefaultClientID)).
+		SetUsername(os.Getenv("MQTT_USERNAME")).
+		SetPassword(service.GetSecret("MQTT_PASSWORD")).
+		SetWill(bridge.statusTopic(), "offline", mqttQoS, true).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetOnConnectHandler(bridge.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("MQTT connection lost: %v", err)
+		})
+	bridge.client = mqtt.NewClient(options)
+
+	log.Printf("Connecting to MQTT broker %s", broker)
+	bridge.client.Connect()
+	go bridge.forwardEvents()
+}
+
+func envOrDefault(key string, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (b *mqttBridge) statusTopic() string {
+	return b.topicPrefix + "/status"
+}
+
+func (b *mqttBridge) commandTopic() string {
+	return b.topicPrefix + "/command/log"
+}
+
+func (b *mqttBridge) resultTopic() string {
+	return b.topicPrefix + "/command/result"
+}
+
+func (b *mqttBridge) profileTopic(profileID string, name string) string {
+	return b.topicPrefix + "/" + profileID + "/" + name
+}
+
+func (b *mqttBridge) discoveryTopic(profileID string, key string) string {
+	return fmt.Sprintf("%s/sensor/nutrack_%s_%s/config", b.discoveryPrefix, profileID, key)
+}
+
+// onConnect is called after every (re)connect. Retained messages may have been lost by the
+// broker, so the whole state is published again.
+func (b *mqttBridge) onConnect(client mqtt.Client) {
+	log.Println("Connected to MQTT broker")
+
+	b.publish(b.statusTopic(), true, "online")
+	token := client.Subscribe(b.commandTopic(), mqttQoS, func(_ mqtt.Client, message mqtt.Message) {
+		// The client delivers messages one by one, a slow product lookup must not block it
+		go b.handleLogCommand(message.Payload())
+	})
+	if token.WaitTimeout(mqttPublishTimeout) && token.Error() != nil {
+		log.Printf("Failed to subscribe to MQTT topic %s: %v", b.commandTopic(), token.Error())
+	}
+
+	b.publishAllProfiles(true)
+}
+
+// forwardEvents republishes the state of the profiles affected by change events, and of all
+// profiles when the day changes
+func (b *mqttBridge) forwardEvents() {
+	subscription := messaging.Subscribe(0, messaging.EventFilter{Entities: []string{
+		messaging.EntityConsumedFoodItem,
+		messaging.EntityProfile,
+		messaging.EntityUserSettings,
+		messaging.EntityDailyTarget,
+		messaging.EntityDatabase,
+	}})
+	defer subscription.Close()
+
+	dayCheck := time.NewTicker(mqttDayCheckInterval)
+	defer dayCheck.Stop()
+	today := time.Now().Format("2006-01-02")
+
+	for {
+		select {
+		case <-subscription.Notify():
+			if !b.client.IsConnectionOpen() {
+				// Everything is published on connect
+				subscription.Next()
+				continue
+			}
+
+			all := false
+			profileIDs := make(map[string]bool)
+			for _, event := range subscription.Next() {
+				if event.Entity == messaging.EntityConsumedFoodItem && event.ProfileID != "" &&
+					(event.Action == messaging.ActionCreated || event.Action == messaging.ActionUpdated) {
+					b.publishScan(event)
+				}
+				// Events without a profile concern all of them, e.g. a replaced database
+				if event.ProfileID == "" || event.Entity == messaging.EntityProfile {
+					all = true
+				} else {
+					profileIDs[event.ProfileID] = true
+				}
+			}
+
+			if all {
+				b.publishAllProfiles(false)
+				continue
+			}
+			for profileID := range profileIDs {
+				b.publishToday(profileID)
+			}
+		case <-dayCheck.C:
+			if day := time.Now().Format("2006-01-02"); day != today {
+				today = day
+				if b.client.IsConnectionOpen() {
+					b.publishAllProfiles(false)
+				}
+			}
+		}
+	}
+}
+
+// publishAllProfiles publishes today's totals of every profile, and the discovery configs of
+// new and renamed profiles or with withDiscovery of all. The retained topics of deleted
+// profiles are cleared.
+func (b *mqttBridge) publishAllProfiles(withDiscovery bool) {
+	profiles, _, err := b.router.foodService.GetAllProfiles(types.ListQuery{})
+	if err != nil {
+		log.Printf("Failed to get profiles for MQTT: %v", err)
+		return
+	}
+
+	b.profilesMutex.Lock()
+	published := b.profiles
+	b.profiles = make(map[string]string, len(profiles))
+	for _, profile := range profiles {
+		b.profiles[profile.ID] = profile.Name
+	}
+	b.profilesMutex.Unlock()
+
+	for _, profile := range profiles {
+		if name, ok := published[profile.ID]; withDiscovery || !ok || name != profile.Name {
+			b.publishDiscovery(profile.ID, profile.Name)
+		}
+		b.publishToday(profile.ID)
+	}
+	for profileID := range published {
+		if _, ok := b.profiles[profileID]; !ok {
+			b.clearProfile(profileID)
+		}
+	}
+}
+
+// publishToday publishes today's totals, targets and remaining calories of a profile
+func (b *mqttBridge) publishToday(profileID string) {
+	today := time.Now().Format("2006-01-02")
+	summaries, err := b.router.foodService.GetDailySummary(profileID, today, today)
+	if err != nil {
+		log.Printf("Failed to get today's summary of profile %s for MQTT: %v", profileID, err)
+		return
+	}
+	if len(summaries) == 0 {
+		return
+	}
+
+	summary := summaries[0]
+	b.publishJSON(b.profileTopic(profileID, "today"), true, summary)
+	b.publish(b.profileTopic(profileID, "remaining_calories"), true, fmt.Sprintf("%.0f", summary.Remaining))
+}
+
+// publishScan publishes the diary entry of a consumption event as the last scan of its
+// profile. Entries of other days than today are not scans and are skipped.
+func (b *mqttBridge) publishScan(event messaging.Event) {
+	if len(event.IDs) == 0 {
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	entries, _, err := b.router.foodService.GetConsumedFoodItemsByDate(today, event.ProfileID, types.ListQuery{})
+	if err != nil {
+		log.Printf("Failed to get diary entries of profile %s for MQTT: %v", event.ProfileID, err)
+		return
+	}
+
+	// A batch publishes one event for all entries, the last one is the latest scan
+	id := event.IDs[len(event.IDs)-1]
+	for _, entry := range entries {
+		if entry.ID != id {
+			continue
+		}
+		b.publishJSON(b.profileTopic(event.ProfileID, "last_scan"), true, mqttScan{
+			ID:               entry.ID,
+			Barcode:          entry.Barcode,
+			Name:             entry.Name,
+			ConsumedQuantity: entry.ConsumedQuantity,
+			Date:             entry.Date,
+			Action:           event.Action,
+			Time:             event.Time.Format(time.RFC3339),
+		})
+		return
+	}
+}
+
+// publishDiscovery publishes the Home Assistant discovery configs of the sensors of a
+// profile, which are grouped as one device
+func (b *mqttBridge) publishDiscovery(profileID string, profileName string) {
+	device := map[string]interface{}{
+		"identifiers":  []string{"nutrack_" + profileID},
+		"name":         "Nutrack " + profileName,
+		"manufacturer": "Nutrack",
+		"model":        "Profile",
+	}
+
+	for _, sensor := range mqttSensors {
+		b.publishJSON(b.discoveryTopic(profileID, sensor.Key), true, map[string]interface{}{
+			"name":                sensor.Name,
+			"unique_id":           "nutrack_" + profileID + "_" + sensor.Key,
+			"state_topic":         b.profileTopic(profileID, "today"),
+			"value_template":      sensor.ValueTemplate,
+			"unit_of_measurement": sensor.Unit,
+			"state_class":         "measurement",
+			"icon":                sensor.Icon,
+			"availability_topic":  b.statusTopic(),
+			"device":              device,
+		})
+	}
+
+	b.publishJSON(b.discoveryTopic(profileID, "last_scan"), true, map[string]interface{}{
+		"name":                  "Last scan",
+		"unique_id":             "nutrack_" + profileID + "_last_scan",
+		"state_topic":           b.profileTopic(profileID, "last_scan"),
+		"value_template":        "{{ value_json.name if value_json.name else value_json.barcode }}",
+		"json_attributes_topic": b.profileTopic(profileID, "last_scan"),
+		"icon":                  "mdi:barcode-scan",
+		"availability_topic":    b.statusTopic(),
+		"device":                device,
+	})
+}
+
+// clearProfile removes the retained topics and discovery configs of a deleted profile
+func (b *mqttBridge) clearProfile(profileID string) {
+	for _, sensor := range mqttSensors {
+		b.publish(b.discoveryTopic(profileID, sensor.Key), true, "")
+	}
+	b.publish(b.discoveryTopic(profileID, "last_scan"), true, "")
+	for _, name := range []string{"today", "remaining_calories", "last_scan"} {
+		b.publish(b.profileTopic(profileID, name), true, "")
+	}
+}
+
+// handleLogCommand logs a food item like a consumption batch of one item in partial mode.
+// The payload is a JSON diary entry request or just a barcode, the quantity defaults to one
+// serving. The result is published on the result topic.
+func (b *mqttBridge) handleLogCommand(payload []byte) {
+	var command mqttLogCommand
+	trimmed := strings.TrimSpace(string(payload))
+	if strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(payload, &command); err != nil {
+			b.publishLogResult(command, types.NewInvalidRequestError("Invalid JSON log command"))
+			return
+		}
+	} else {
+		command.Barcode = trimmed
+	}
+	if command.ConsumedQuantity == 0 {
+		command.ConsumedQuantity = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mqttCommandTimeout)
+	defer cancel()
+	results, err := b.router.foodService.CheckInsertAndConsumeBatch(ctx, types.BatchConsumedFoodItemRequest{
+		Items:     []types.ConsumedFoodItemRequest{command.ConsumedFoodItemRequest},
+		ForceSync: command.ForceSync,
+		Partial:   true,
+	})
+	if err != nil {
+		b.publishLogResult(command, err)
+		return
+	}
+	for _, result := range results {
+		b.publishJSON(b.resultTopic(), false, mqttLogResult{BatchItemResult: result, RequestId: command.RequestId})
+	}
+}
+
+// publishLogResult publishes a log command which failed as a whole
+func (b *mqttBridge) publishLogResult(command mqttLogCommand, err error) {
+	log.Printf("MQTT log command for barcode %q failed: %v", command.Barcode, err)
+
+	status := types.BatchItemFailed
+	if types.HasErrorCode(err, types.ErrorCodeValidation) {
+		status = types.BatchItemValidationError
+	}
+	b.publishJSON(b.resultTopic(), false, mqttLogResult{
+		BatchItemResult: types.BatchItemResult{
+			Barcode: command.Barcode,
+			Status:  status,
+			Error:   err.Error(),
+			Code:    types.ErrorCodeOf(err),
+			Field:   types.ErrorField(err),
+		},
+		RequestId: command.RequestId,
+	})
+}
+
+func (b *mqttBridge) publishJSON(topic string, retained bool, payload interface{}) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Failed to encode MQTT message for %s: %v", topic, err)
+		return
+	}
+	b.publish(topic, retained, encoded)
+}
+
+func (b *mqttBridge) publish(topic string, retained bool, payload interface{}) {
+	token := b.client.Publish(topic, mqttQoS, retained, payload)
+	if !token.WaitTimeout(mqttPublishTimeout) {
+		log.Printf("Timed out publishing MQTT message to %s", topic)
+		return
+	}
+	if err := token.Error(); err != nil {
+		log.Printf("Failed to publish MQTT message to %s: %v", topic, err)
+	}
+}
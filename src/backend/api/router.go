@@ -10,11 +10,15 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"nutrack/backend/data"
 	"nutrack/backend/messaging"
@@ -46,7 +50,7 @@ func init() {
 			apiHost = envHost
 		}
 	}
-	fmt.Println("API Host for Swagger:", apiHost)
+	log.Println("API Host for Swagger:", apiHost)
 
 	// Initialize allowed origins
 	additionalIPs := os.Getenv("ALLOWED_IPS")
@@ -61,7 +65,7 @@ func init() {
 		allowedOrigins = append(allowedOrigins, "http://localhost:3000")
 	}
 
-	fmt.Println("Allowed Origins:", allowedOrigins)
+	log.Println("Allowed Origins:", allowedOrigins)
 }
 
 type (
@@ -96,90 +100,43 @@ func (r *Router) SetupAndRunApiServer() {
 	config.AllowOrigins = allowedOrigins
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
 	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
+	config.ExposeHeaders = []string{totalCountHeader}
 	config.AllowCredentials = true
 	r.engine.Use(cors.New(config))
 
 	r.engine.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
+	r.engine.NoRoute(writeUnknownEndpoint)
 	api := r.engine.Group("/api")
-	{
-		api.POST("/foodItems/check-and-insert", r.checkAndInsertFoodItem)
-		api.POST("/foodItems/check-insert-and-consume", r.checkInsertAndConsume)
-		api.POST("/foodItems/check-insert-and-consume-batch", r.checkInsertAndConsumeBatch)
-		api.POST("/foodItems/manually-add", r.manuallyAddFoodItem)
-		api.GET("/foodItems/all", r.getAllFoodItems)
-		api.PUT("/foodItems/:barcode", r.updateFoodItem)
-		api.DELETE("/foodItems/:barcode", r.deleteFoodItem)
-		api.POST("/foodItems/reset/:barcode", r.resetFoodItem)
-		api.GET("/foodItems/servingQuantity/:barcode", r.getServingQuantityByBarcode)
-		api.GET("/foodItems/search", r.searchFoodItems)
-		api.POST("/consumedFoodItems", r.postConsumedFoodItem)
-		api.DELETE("/consumedFoodItems/:id", r.deleteConsumedFoodItem)
-		api.GET("/consumedFoodItems/:date", r.getConsumedFoodItemsByDate)
-		api.PUT("/consumedFoodItems/:id", r.updateConsumedFoodItem)
-
-		api.POST("/settings", r.saveUserSettings)
-		api.GET("/settings", r.getUserSettings)
-
-		api.POST("/dishes", r.createDish)
-		api.DELETE("/dishes/:id", r.deleteDish)
-		api.PUT("/dishes/:id", r.updateDish)
-		api.GET("/dishes/:id", r.getDish)
-		api.GET("/dishes", r.getAllDishes)
-		api.POST("/dishes/convert-to-food-item/:id", r.convertDishToFoodItem)
-
-		api.GET("/profiles", r.getAllProfiles)
-		api.POST("/profiles", r.createProfile)
-		api.POST("/profiles/active", r.setActiveProfile)
-		api.GET("/profiles/active", r.getActiveProfile)
-		api.GET("/profiles/single/:id", r.getProfile)
-		api.PUT("/profiles/single/:id", r.updateProfile)
-		api.DELETE("/profiles/single/:id", r.deleteProfile)
-
-		api.GET("/search", r.searchOpenFoodFacts)
-		api.GET("/sse", setupSSE)
-
-		api.POST("/dropbox/token", r.handleDropboxToken)
-		api.GET("/dropbox/status", r.handleDropboxStatus)
-		api.POST("/dropbox/logout", r.handleDropboxLogout)
-		api.POST("/dropbox/upload-database", r.handleDropboxUploadDatabase)
-		api.GET("/dropbox/download-database", r.handleDropboxDownloadDatabase)
-		api.GET("/dropbox/autosync", r.handleGetDropboxAutosync)
-		api.POST("/dropbox/autosync", r.handleSetDropboxAutosync)
-
-		// Nutrition calculation endpoints
-		api.POST("/nutrition/calculate", r.calculateNutrition)
-		api.POST("/dropbox/sync", r.handleDropboxSync)
-		api.GET("/settings/weighttracking", r.handleGetWeightTracking)
-		api.POST("/settings/weighttracking", r.handleSetWeightTracking)
-		api.GET("/settings/auto-recalculate-nutrition-values", r.handleGetAutoRecalculateNutritionValues)
-		api.POST("/settings/auto-recalculate-nutrition-values", r.handleSetAutoRecalculateNutritionValues)
-		api.POST("/settings/calculate-nutrients", r.calculateNutrition)
-		api.POST("/settings/calculate-from-calories-and-weight", r.calculateNutritionFromCaloriesAndWeight)
-
-		// Scanner endpoints
-		api.GET("/scanners", r.listScanners)
-		api.POST("/scanners/active", r.setActiveScanner)
-
-	}
+	r.registerRoutes(api)
+	// The event streams are not operations of the route table, standard IO clients receive
+	// the events on stdout. WebSocket clients send requests on the same connection.
+	api.GET("/sse", setupSSE)
+	api.GET("/ws", newDispatchRouter(r.foodService).serveWebSocket)
+	r.startMQTT()
 
 	println("Running API server on port 8080")
 	r.engine.Run(":8080")
 }
 
+// setupSSE streams the change events, see messaging.Event. The optional query parameters
+// profile_id and entity select the events of some profiles and entities.
 func setupSSE(c *gin.Context) {
+	subscription, ok := subscribeToEvents(c)
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("Access-Control-Allow-Headers", "Content-Type")
 	origin := c.GetHeader("Origin")
 	allowedOrigin := ""
-	for _, allowed := range allowedOrigins {
-		if allowed == origin {
-			allowedOrigin = allowed
-			break
-		}
+	if isAllowedOrigin(origin) {
+		allowedOrigin = origin
 	}
 
 	println("SSE Origin:", allowedOrigin)
@@ -193,61 +150,128 @@ func setupSSE(c *gin.Context) {
 	c.Header("Access-Control-Allow-Credentials", "true")
 	c.Header("Access-Control-Allow-Methods", "GET")
 
-	// Create a buffered channel to handle multiple messages
-	clientChan := make(chan string, 10)
-	messaging.AddSSEClient(clientChan)
 	println("SSE Client added")
 
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// Send the headers right away, EventSource reports the connection as open on them
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
 	c.Stream(func(w io.Writer) bool {
 		select {
-		case msg, ok := <-clientChan:
-			if !ok {
-				println("Channel closed")
-				return false
-			}
-
-			// Process this message
-			c.SSEvent("message", msg)
-
-			// Check for any additional pending messages without blocking
-			// This ensures we process message bursts efficiently
-			processingMessages := true
-			for processingMessages {
-				select {
-				case additionalMsg, ok := <-clientChan:
-					if !ok {
-						println("Channel closed during batch processing")
-						return false
-					}
-					c.SSEvent("message", additionalMsg)
-				default:
-					// No more messages waiting, continue normal flow
-					processingMessages = false
+		case <-subscription.Notify():
+			for _, event := range subscription.Next() {
+				if err := writeSSEEvent(w, event); err != nil {
+					log.Printf("Failed to send SSE event: %v", err)
+					return false
 				}
 			}
-
 			return true
+		case <-heartbeat.C:
+			// Comments keep proxies from closing idle streams
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
 		case <-c.Request.Context().Done():
 			println("Client disconnected")
-			messaging.RemoveSSEClient(clientChan)
 			return false
 		}
 	})
 }
 
+// subscribeToEvents subscribes to the events selected by the query parameters of an event
+// stream. It writes the error response of invalid parameters and returns false.
+func subscribeToEvents(c *gin.Context) (*messaging.Subscription, bool) {
+	// Replay the events a reconnecting client missed. EventSource sends the ID of the last
+	// received event in the Last-Event-ID header, new connections may pass it as parameter.
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	var lastSeq uint64
+	if lastEventID != "" {
+		seq, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeError(c, "", types.NewValidationError("Last-Event-ID", "invalid event ID %s", lastEventID))
+			return nil, false
+		}
+		lastSeq = seq
+	}
+
+	// Clients may only follow some profiles and entities, e.g.
+	// /api/sse?profile_id=<id>&entity=consumed_food_item,user_settings
+	filter := messaging.EventFilter{
+		ProfileIDs: queryList(c, "profile_id"),
+		Entities:   queryList(c, "entity"),
+	}
+	for _, entity := range filter.Entities {
+		if !messaging.IsEntity(entity) {
+			writeError(c, "", types.NewValidationError("entity", "unknown entity %s", entity))
+			return nil, false
+		}
+	}
+
+	return messaging.Subscribe(lastSeq, filter), true
+}
+
+func isAllowedOrigin(origin string) bool {
+	for _, allowed := range allowedOrigins {
+		if allowed == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// queryList returns the values of a repeated or comma separated query parameter
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, param := range c.QueryArray(key) {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// sseHeartbeatInterval is the interval of the heartbeat comments of idle SSE streams
+const sseHeartbeatInterval = 15 * time.Second
+
+// writeSSEEvent writes an event with its sequence number as ID
+func writeSSEEvent(w io.Writer, event messaging.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", event.Seq, data)
+	return err
+}
+
 // @Summary Get all food items
-// @Description Get a list of all food items
+// @Description Get a page of the food items, by default all of them with the newest first. Sort keys are name, created_at, last_updated and kcal. Food items without a real name, without nutrition values or without a serving quantity are incomplete. The total number of matching food items is returned in the X-Total-Count header.
 // @Tags foodItems
 // @Produce  json
+// @Param query query types.ListQuery false "Page, sort order and filters"
 // @Success 200 {array} types.PersistentFoodItem
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /foodItems/all [get]
 func (r *Router) getAllFoodItems(c *gin.Context) {
-	foodItems, err := r.foodService.GetAllFoodItems()
+	query, ok := bindListQuery(c)
+	if !ok {
+		return
+	}
+
+	foodItems, total, err := r.foodService.GetAllFoodItems(query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve food items"})
+		writeError(c, "Failed to retrieve food items: ", err)
 		return
 	}
 
+	c.Header(totalCountHeader, strconv.Itoa(total))
 	c.JSON(http.StatusOK, foodItems)
 }
 
@@ -257,19 +281,15 @@ func (r *Router) getAllFoodItems(c *gin.Context) {
 // @Produce json
 // @Param barcode path string true "Food item barcode"
 // @Success 200 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /foodItems/servingQuantity/{barcode} [get]
 func (r *Router) getServingQuantityByBarcode(c *gin.Context) {
 	barcode := c.Param("barcode")
 
 	servingQuantity, err := r.foodService.GetServingQuantityByBarcode(barcode)
 	if err != nil {
-		if err.Error() == fmt.Sprintf("no food item found with barcode %s", barcode) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve serving quantity"})
-		}
+		writeError(c, "Failed to retrieve serving quantity: ", err)
 		return
 	}
 
@@ -286,46 +306,110 @@ func (r *Router) getServingQuantityByBarcode(c *gin.Context) {
 // @Param barcode path string true "Food item barcode"
 // @Param foodItem body types.PersistentFoodItem true "Updated food item data"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /foodItems/{barcode} [put]
 func (r *Router) updateFoodItem(c *gin.Context) {
 	barcode := c.Param("barcode")
 	var updateData map[string]interface{}
 	if err := c.BindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
 	err := r.foodService.UpdateFoodItem(barcode, updateData)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update food item"})
+		writeError(c, "Failed to update food item: ", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Food item updated successfully"})
 }
 
+// @Summary Set food portions
+// @Description Replace the named portions of a food item, e.g. 1 slice = 28 g. Portions can be used as unit when logging food or adding it to a dish.
+// @Tags foodItems
+// @Accept json
+// @Produce json
+// @Param barcode path string true "Food item barcode"
+// @Param portions body types.FoodPortionsRequest true "Named portions of the food item"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/portions/{barcode} [put]
+func (r *Router) setFoodPortions(c *gin.Context) {
+	barcode := c.Param("barcode")
+	var request types.FoodPortionsRequest
+	if err := c.BindJSON(&request); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	portions := make([]data.FoodPortion, len(request.Portions))
+	for i, portion := range request.Portions {
+		portions[i] = data.FoodPortion{
+			Name:     portion.Name,
+			Quantity: portion.Quantity,
+			Unit:     portion.Unit,
+		}
+	}
+
+	err := r.foodService.SetFoodPortions(barcode, portions)
+	if err != nil {
+		writeError(c, "Failed to set food portions: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Food portions updated successfully"})
+}
+
+// @Summary Set food item tags
+// @Description Replace the tags of a food item, e.g. "dairy" or "snacks". Tags are stored in lower case.
+// @Tags foodItems
+// @Accept json
+// @Produce json
+// @Param barcode path string true "Food item barcode"
+// @Param tags body types.TagsRequest true "Tags of the food item"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/tags/{barcode} [put]
+func (r *Router) setFoodItemTags(c *gin.Context) {
+	barcode := c.Param("barcode")
+	var request types.TagsRequest
+	if err := c.BindJSON(&request); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	err := r.foodService.SetFoodItemTags(c.Request.Context(), barcode, request.Tags)
+	if err != nil {
+		writeError(c, "Failed to set tags: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Food item tags updated successfully"})
+}
+
 // @Summary Delete a food item
-// @Description Delete a food item by barcode
+// @Description Delete a food item by barcode. Food items which are ingredients of dishes or planned in the meal plan are not deleted, the conflict error names the dishes or planned meals.
 // @Tags foodItems
 // @Produce json
 // @Param barcode path string true "Food item barcode"
 // @Success 200 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 409 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /foodItems/{barcode} [delete]
 func (r *Router) deleteFoodItem(c *gin.Context) {
 	barcode := c.Param("barcode")
 
 	err := r.foodService.DeleteFoodItem(barcode)
 	if err != nil {
-		if err.Error() == fmt.Sprintf("no food item found with barcode %s", barcode) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete food item"})
-		}
+		writeError(c, "Failed to delete food item: ", err)
 		return
 	}
 
@@ -338,15 +422,15 @@ func (r *Router) deleteFoodItem(c *gin.Context) {
 // @Produce json
 // @Param barcode path string true "Food item barcode"
 // @Success 200 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /foodItems/reset/{barcode} [post]
 func (r *Router) resetFoodItem(c *gin.Context) {
 	barcode := c.Param("barcode")
 
-	err := r.foodService.ResetFoodItem(barcode)
+	err := r.foodService.ResetFoodItem(c.Request.Context(), barcode)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -360,19 +444,19 @@ func (r *Router) resetFoodItem(c *gin.Context) {
 // @Produce json
 // @Param foodItem body types.PersistentFoodItem true "Food item to check and insert"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /foodItems/check-and-insert [post]
 func (r *Router) checkAndInsertFoodItem(c *gin.Context) {
 	var request types.BarcodeRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
-	err := r.foodService.CheckAndInsertFoodItem(request.Barcode)
+	err := r.foodService.CheckAndInsertFoodItem(c.Request.Context(), request.Barcode)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check and insert food item"})
+		writeError(c, "Failed to check and insert food item: ", err)
 		return
 	}
 
@@ -386,20 +470,20 @@ func (r *Router) checkAndInsertFoodItem(c *gin.Context) {
 // @Produce json
 // @Param foodItem body types.PersistentFoodItem true "Food item to add"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /foodItems/manually-add [post]
 func (r *Router) manuallyAddFoodItem(c *gin.Context) {
 	var newItem data.PersistentFoodItem
 
 	if err := c.BindJSON(&newItem); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
-	err := r.foodService.ManuallyAddFoodItem(newItem)
+	err := r.foodService.ManuallyAddFoodItem(c.Request.Context(), newItem)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -413,224 +497,1134 @@ func (r *Router) manuallyAddFoodItem(c *gin.Context) {
 // @Produce json
 // @Param consumedItem body types.ConsumedFoodItemRequest true "Consumed food item to add"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /consumedFoodItems [post]
 func (r *Router) postConsumedFoodItem(c *gin.Context) {
 	var request types.ConsumedFoodItemRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	err := r.foodService.PostConsumedFoodItem(c.Request.Context(), request)
+	if err != nil {
+		writeError(c, "Failed to post consumed food item: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Food item consumed successfully"})
+}
+
+// @Summary Log a dish
+// @Description Log a dish or a fraction of it as consumed. The ingredients are stored as snapshot, so later changes to the dish don't change the diary. If no ProfileID is provided, the active profile will be used.
+// @Tags consumedFoodItems
+// @Accept json
+// @Produce json
+// @Param consumedDish body types.ConsumedDishRequest true "Dish to log"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/dish [post]
+func (r *Router) postConsumedDish(c *gin.Context) {
+	var request types.ConsumedDishRequest
+	if err := c.BindJSON(&request); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	err := r.foodService.PostConsumedDish(c.Request.Context(), request)
+	if err != nil {
+		writeError(c, "Failed to log dish: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dish consumed successfully"})
+}
+
+// @Summary Get daily summary
+// @Description Get the intake of every day in a date range, each compared with the targets in effect on that day. If no profile ID is provided, the active profile is used.
+// @Tags consumedFoodItems
+// @Produce json
+// @Param start_date query string true "First day (YYYY-MM-DD)"
+// @Param end_date query string true "Last day (YYYY-MM-DD)"
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {array} types.DailySummary
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/summary [get]
+func (r *Router) getDailySummary(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	profileID := c.Query("profile_id")
+
+	summary, err := r.foodService.GetDailySummary(profileID, startDate, endDate)
+	if err != nil {
+		writeError(c, "Failed to get daily summary: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// @Summary Get intake by tag
+// @Description Break down the intake in a date range by the tags of the logged food items and dishes, e.g. to see how much comes from snacks. An entry with several tags counts for each of its tags. If no profile ID is provided, the active profile is used.
+// @Tags consumedFoodItems
+// @Produce json
+// @Param start_date query string true "First day (YYYY-MM-DD)"
+// @Param end_date query string true "Last day (YYYY-MM-DD)"
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {object} types.TagIntakeSummary
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/summary/tags [get]
+func (r *Router) getIntakeByTag(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	profileID := c.Query("profile_id")
+
+	summary, err := r.foodService.GetIntakeByTag(profileID, startDate, endDate)
+	if err != nil {
+		writeError(c, "Failed to get intake by tag: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// @Summary Copy consumed food items
+// @Description Copy the entries of a date, or a subset of them, to another date or profile. With target_end_date the entries are copied to every day of the range, optionally only on some weekdays. Every copy is logged like a new entry with the current food item or dish: food items are added to an existing entry of the same food item on the target date, dishes are logged with their current ingredients. Entries of deleted food items or dishes cannot be copied. If no source profile ID is provided, the active profile is used.
+// @Tags consumedFoodItems
+// @Accept json
+// @Produce json
+// @Param copy body types.CopyConsumedFoodItemsRequest true "Source and target of the copy"
+// @Success 200 {object} types.ConsumedCopyResult
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/copy [post]
+func (r *Router) copyConsumedFoodItems(c *gin.Context) {
+	var request types.CopyConsumedFoodItemsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
+		return
+	}
+
+	result, err := r.foodService.CopyConsumedFoodItems(c.Request.Context(), request)
+	if err != nil {
+		writeError(c, "Failed to copy consumed food items: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Reprice history
+// @Description Apply the current nutrition values of a food item to already consumed entries, including logged dishes containing it. Consumed entries keep the values from the time they were logged otherwise. Without a date range the whole history is updated, without a ProfileID all profiles are updated.
+// @Tags consumedFoodItems
+// @Accept json
+// @Produce json
+// @Param reprice body types.RepriceHistoryRequest true "Food item and range to reprice"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/reprice [post]
+func (r *Router) repriceHistory(c *gin.Context) {
+	var request types.RepriceHistoryRequest
+	if err := c.BindJSON(&request); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	updated, err := r.foodService.RepriceHistory(request)
+	if err != nil {
+		writeError(c, "Failed to reprice history: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "History repriced successfully", "updated": updated})
+}
+
+// @Summary Delete consumed food item
+// @Description Delete a consumed food item by ID
+// @Tags consumedFoodItems
+// @Produce json
+// @Param id path string true "Consumed food item ID"
+// @Success 200 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/{id} [delete]
+func (r *Router) deleteConsumedFoodItem(c *gin.Context) {
+	id := c.Param("id")
+
+	err := r.foodService.DeleteConsumedFoodItem(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, "Failed to delete consumed food item: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Consumed food item deleted successfully"})
+}
+
+// @Summary Get consumed food items by date
+// @Description Get a page of the consumed food items for a specific date and profile, by default all of them with the latest first. Sort keys are insert_date, name and kcal. The unit and kind filters apply. The total number of matching entries is returned in the X-Total-Count header. If no profile ID is provided, the active profile is used.
+// @Tags consumedFoodItems
+// @Produce json
+// @Param date path string true "Date in YYYY-MM-DD format"
+// @Param profile_id query string false "Profile ID"
+// @Param query query types.ListQuery false "Page, sort order and filters"
+// @Success 200 {array} []types.ConsumedFoodItemWithDetails
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/{date} [get]
+func (r *Router) getConsumedFoodItemsByDate(c *gin.Context) {
+	dateStr := c.Param("date")
+	profileID := c.Query("profile_id")
+	query, ok := bindListQuery(c)
+	if !ok {
+		return
+	}
+
+	consumedItems, total, err := r.foodService.GetConsumedFoodItemsByDate(dateStr, profileID, query)
+	if err != nil {
+		writeError(c, "Failed to retrieve consumed food items: ", err)
+		return
+	}
+
+	c.Header(totalCountHeader, strconv.Itoa(total))
+
+	if len(consumedItems) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "No consumed food items found for the given date", "items": []data.ConsumedFoodItemWithDetails{}})
+		return
+	}
+
+	c.JSON(http.StatusOK, consumedItems)
+}
+
+// @Summary Update consumed food item
+// @Description Update a consumed food item by ID
+// @Tags consumedFoodItems
+// @Accept json
+// @Produce json
+// @Param id path string true "Consumed food item ID"
+// @Param consumedItem body types.ConsumedFoodItemRequest true "Updated consumed food item data"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /consumedFoodItems/{id} [put]
+func (r *Router) updateConsumedFoodItem(c *gin.Context) {
+	id := c.Param("id")
+	var updateData map[string]interface{}
+	if err := c.BindJSON(&updateData); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	err := r.foodService.UpdateConsumedFoodItem(c.Request.Context(), id, updateData)
+	if err != nil {
+		writeError(c, "Failed to update consumed food item: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Consumed food item updated successfully"})
+}
+
+// @Summary Search food items
+// @Description Search for food items by name or barcode. All words of the query have to match in any order, each word as prefix. Umlauts also match their spelled out form, e.g. "Müsli" is found by "musli" and "muesli". If nothing matches, similar words are searched to tolerate typos. With boost, favorites and food items the profile logged often are ranked higher.
+// @Tags foodItems
+// @Produce json
+// @Param q query string true "Search query"
+// @Param tag query string false "Only food items with this tag"
+// @Param boost query bool false "Rank favorites and frequently logged food items higher"
+// @Param profile_id query string false "Profile ID for boosting, defaults to the active profile"
+// @Param limit query int false "Maximum number of results (default 15)"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {array} []types.PersistentFoodItem
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/search [get]
+func (r *Router) searchFoodItems(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		writeError(c, "", types.NewValidationError("q", "Search query is required"))
+		return
+	}
+
+	boost := c.Query("boost") == "true"
+	profileID := c.Query("profile_id")
+	limit, offset, err := queryPage(c)
+	if err != nil {
+		writeError(c, "", err)
+		return
+	}
+
+	items, err := r.foodService.SearchFoodItems(query, c.Query("tag"), boost, profileID, limit, offset)
+	if err != nil {
+		writeError(c, "Failed to search food items: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary Search food items and dishes
+// @Description Search for food items and dishes by name or barcode, with the same matching and ranking as the food item search
+// @Tags search
+// @Produce json
+// @Param q query string true "Search query"
+// @Param tag query string false "Only food items and dishes with this tag"
+// @Param boost query bool false "Rank favorites and frequently logged food items higher"
+// @Param profile_id query string false "Profile ID for boosting, defaults to the active profile"
+// @Param limit query int false "Maximum number of results (default 15)"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {array} types.SearchResult
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /search/local [get]
+func (r *Router) searchLocal(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		writeError(c, "", types.NewValidationError("q", "Search query is required"))
+		return
+	}
+
+	boost := c.Query("boost") == "true"
+	profileID := c.Query("profile_id")
+	limit, offset, err := queryPage(c)
+	if err != nil {
+		writeError(c, "", err)
+		return
+	}
+
+	results, err := r.foodService.Search(query, c.Query("tag"), boost, profileID, limit, offset)
+	if err != nil {
+		writeError(c, "Failed to search food items and dishes: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// @Summary Find duplicate food items
+// @Description Suggest groups of food items that are likely the same product: similar names and near-identical nutrition values. The suggested target is the food item logged most often.
+// @Tags foodItems
+// @Produce json
+// @Success 200 {array} types.DuplicateFoodItemGroup
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/duplicates [get]
+func (r *Router) findDuplicateFoodItems(c *gin.Context) {
+	groups, err := r.foodService.FindDuplicateFoodItems()
+	if err != nil {
+		writeError(c, "Failed to find duplicate food items: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// @Summary Merge food items
+// @Description Replace the source food items by the target food item in one transaction. Diary entries, dish ingredients, logged dish ingredients, meal plan entries, favorites and portions are moved to the target, then the source food items are deleted. Logged entries keep their nutrition values. Without apply the merge is only previewed.
+// @Tags foodItems
+// @Accept json
+// @Produce json
+// @Param merge body types.MergeFoodItemsRequest true "Target and source food items"
+// @Success 200 {object} types.FoodItemMergeResult
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/merge [post]
+func (r *Router) mergeFoodItems(c *gin.Context) {
+	var request types.MergeFoodItemsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
+		return
+	}
+
+	result, err := r.foodService.MergeFoodItems(c.Request.Context(), request)
+	if err != nil {
+		writeError(c, "Failed to merge food items: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get recently logged food items
+// @Description Get the food items a profile logged most recently, with the quantity of the latest entry. If no profile ID is provided, the active profile is used.
+// @Tags foodItems
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Param limit query int false "Maximum number of food items (default 30)"
+// @Success 200 {array} types.FoodItemUsage
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/recent [get]
+func (r *Router) getRecentFoodItems(c *gin.Context) {
+	profileID := c.Query("profile_id")
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		writeError(c, "", err)
+		return
+	}
+
+	items, err := r.foodService.GetRecentFoodItems(profileID, limit)
+	if err != nil {
+		writeError(c, "Failed to get recent food items: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary Get frequently logged food items
+// @Description Get the food items a profile logged most often in the last days. If no profile ID is provided, the active profile is used.
+// @Tags foodItems
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Param days query int false "Period in days (default 90)"
+// @Param limit query int false "Maximum number of food items (default 30)"
+// @Success 200 {array} types.FoodItemUsage
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/frequent [get]
+func (r *Router) getFrequentFoodItems(c *gin.Context) {
+	profileID := c.Query("profile_id")
+	days, err := queryInt(c, "days")
+	if err != nil {
+		writeError(c, "", err)
+		return
+	}
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		writeError(c, "", err)
+		return
+	}
+
+	items, err := r.foodService.GetFrequentFoodItems(profileID, days, limit)
+	if err != nil {
+		writeError(c, "Failed to get frequent food items: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary Get favorite food items
+// @Description Get the food items pinned by a profile. If no profile ID is provided, the active profile is used.
+// @Tags foodItems
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {array} types.FoodItemUsage
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/favorites [get]
+func (r *Router) getFavoriteFoodItems(c *gin.Context) {
+	profileID := c.Query("profile_id")
+
+	items, err := r.foodService.GetFavoriteFoodItems(profileID)
+	if err != nil {
+		writeError(c, "Failed to get favorite food items: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary Add favorite food item
+// @Description Pin a food item for a profile. If no profile ID is provided, the active profile is used.
+// @Tags foodItems
+// @Produce json
+// @Param barcode path string true "Barcode"
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/favorites/{barcode} [post]
+func (r *Router) addFavoriteFoodItem(c *gin.Context) {
+	barcode := c.Param("barcode")
+	profileID := c.Query("profile_id")
+
+	err := r.foodService.AddFavoriteFoodItem(c.Request.Context(), profileID, barcode)
+	if err != nil {
+		writeError(c, "Failed to add favorite food item: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Favorite food item added successfully"})
+}
+
+// @Summary Remove favorite food item
+// @Description Unpin a food item for a profile. If no profile ID is provided, the active profile is used.
+// @Tags foodItems
+// @Produce json
+// @Param barcode path string true "Barcode"
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/favorites/{barcode} [delete]
+func (r *Router) removeFavoriteFoodItem(c *gin.Context) {
+	barcode := c.Param("barcode")
+	profileID := c.Query("profile_id")
+
+	err := r.foodService.RemoveFavoriteFoodItem(c.Request.Context(), profileID, barcode)
+	if err != nil {
+		writeError(c, "Failed to remove favorite food item: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Favorite food item removed successfully"})
+}
+
+// queryInt returns an optional integer query parameter, 0 if it is missing
+func queryInt(c *gin.Context, name string) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, types.NewValidationError(name, "%s must be a number", name)
+	}
+	return number, nil
+}
+
+// totalCountHeader carries the total number of entries of a paginated list
+const totalCountHeader = "X-Total-Count"
+
+// bindListQuery reads the page, sort order and filters of a list. Writes a bad request
+// response and returns false if the query is invalid.
+func bindListQuery(c *gin.Context) (types.ListQuery, bool) {
+	var query types.ListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		writeInvalidRequest(c, "Invalid list query: "+err.Error())
+		return types.ListQuery{}, false
+	}
+	return query, true
+}
+
+// queryPage reads the optional limit and offset query parameters
+func queryPage(c *gin.Context) (int, int, error) {
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err := queryInt(c, "offset")
+	if err != nil {
+		return 0, 0, err
+	}
+	return limit, offset, nil
+}
+
+// @Summary Search food items on OpenFoodFacts
+// @Description Search for food items on OpenFoodFacts by name
+// @Tags foodItems
+// @Produce json
+// @Param query query string true "Search query"
+// @Success 200 {array} []types.PersistentFoodItem
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/searchOpenFoodFacts [get]
+func (r *Router) searchOpenFoodFacts(c *gin.Context) {
+	searchTerm := c.Query("q")
+	if searchTerm == "" {
+		writeError(c, "", types.NewValidationError("q", "Search query is required"))
+		return
+	}
+
+	items, err := r.foodService.SearchOpenFoodFacts(searchTerm)
+	if err != nil {
+		writeError(c, "Failed to search OpenFoodFacts: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// @Summary Save user settings
+// @Description Save user settings. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param settings body types.UserSettings true "User settings"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings [post]
+func (r *Router) saveUserSettings(c *gin.Context) {
+	var request struct {
+		Weight             float64 `json:"weight"`
+		Height             float64 `json:"height"`
+		Calories           float64 `json:"calories"`
+		Proteins           float64 `json:"proteins"`
+		Carbs              float64 `json:"carbs"`
+		Fat                float64 `json:"fat"`
+		BirthDate          string  `json:"birth_date"`
+		Gender             string  `json:"gender"`
+		ActivityLevel      int     `json:"activity_level"`
+		WeeklyWeightChange float64 `json:"weekly_weight_change"`
+		ProfileID          string  `json:"profile_id"`
+	}
+
+	if err := c.BindJSON(&request); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	// convert the request to UserSettings
+	settings := data.UserSettings{
+		Weight:             request.Weight,
+		Height:             request.Height,
+		Calories:           request.Calories,
+		Proteins:           request.Proteins,
+		Carbs:              request.Carbs,
+		Fat:                request.Fat,
+		BirthDate:          request.BirthDate,
+		Gender:             request.Gender,
+		ActivityLevel:      request.ActivityLevel,
+		WeeklyWeightChange: request.WeeklyWeightChange,
+	}
+
+	err := r.foodService.SaveUserSettings(settings, request.ProfileID)
+	if err != nil {
+		writeError(c, "Failed to save user settings: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User settings saved successfully"})
+}
+
+// @Summary Get goal strategy
+// @Description Get the goal strategy of a profile. Returns the default strategy if none is configured. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {object} types.GoalStrategy
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/goal-strategy [get]
+func (r *Router) getGoalStrategy(c *gin.Context) {
+	profileID := c.Query("profile_id")
+
+	strategy, err := r.foodService.GetGoalStrategy(profileID)
+	if err != nil {
+		writeError(c, "Failed to get goal strategy: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, strategy)
+}
+
+// @Summary Save goal strategy
+// @Description Save the goal strategy of a profile. The macro split of the current targets, the saved settings and the daily recalculation follow the strategy. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param strategy body types.GoalStrategyRequest true "Goal strategy"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/goal-strategy [post]
+func (r *Router) saveGoalStrategy(c *gin.Context) {
+	var request types.GoalStrategyRequest
+	if err := c.BindJSON(&request); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	err := r.foodService.SaveGoalStrategy(request.ProfileID, *toDataGoalStrategy(&request.GoalStrategy))
+	if err != nil {
+		writeError(c, "Failed to save goal strategy: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Goal strategy saved successfully"})
+}
+
+// @Summary Delete goal strategy
+// @Description Delete the goal strategy of a profile. Saved settings keep their macros as entered and the daily recalculation uses the default strategy. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/goal-strategy [delete]
+func (r *Router) deleteGoalStrategy(c *gin.Context) {
+	profileID := c.Query("profile_id")
+
+	err := r.foodService.DeleteGoalStrategy(profileID)
+	if err != nil {
+		writeError(c, "Failed to delete goal strategy: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Goal strategy deleted successfully"})
+}
+
+// @Summary Get goal strategy presets
+// @Description Get the available goal strategy presets with their macro split
+// @Tags settings
+// @Produce json
+// @Success 200 {object} map[string]types.GoalStrategy
+// @Router /settings/goal-strategy/presets [get]
+func (r *Router) getGoalStrategyPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, service.GetGoalStrategyPresets())
+}
+
+// @Summary Get targets for a date
+// @Description Get the targets of a profile in effect on a date, including target overrides for weekdays and date ranges and earlier target changes. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Produce json
+// @Param date path string true "Date (YYYY-MM-DD)"
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {object} types.DailyTargets
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/targets/{date} [get]
+func (r *Router) getTargetsForDate(c *gin.Context) {
+	date := c.Param("date")
+	profileID := c.Query("profile_id")
+
+	targets, err := r.foodService.GetTargetsForDate(profileID, date)
+	if err != nil {
+		writeError(c, "Failed to get targets: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, targets)
+}
+
+// @Summary Get target history
+// @Description Get the changes of the targets of a profile, ordered by the date they took effect. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {array} types.TargetHistoryEntry
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/target-history [get]
+func (r *Router) getTargetHistory(c *gin.Context) {
+	profileID := c.Query("profile_id")
+
+	history, err := r.foodService.GetTargetHistory(profileID)
+	if err != nil {
+		writeError(c, "Failed to get target history: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// @Summary Get target overrides
+// @Description Get the target overrides of a profile, the newest first. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {array} types.TargetOverride
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/target-overrides [get]
+func (r *Router) getTargetOverrides(c *gin.Context) {
+	profileID := c.Query("profile_id")
+
+	overrides, err := r.foodService.GetTargetOverrides(profileID)
+	if err != nil {
+		writeError(c, "Failed to get target overrides: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+// @Summary Create target override
+// @Description Create targets replacing the user settings on certain weekdays (e.g. training days), in a date range (e.g. a diet break) or both. Date range overrides take precedence over weekday overrides, the newest override wins otherwise. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param override body types.TargetOverrideRequest true "Target override"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/target-overrides [post]
+func (r *Router) createTargetOverride(c *gin.Context) {
+	var request types.TargetOverrideRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
+		return
+	}
+
+	id, err := r.foodService.CreateTargetOverride(request)
+	if err != nil {
+		writeError(c, "Failed to create target override: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Target override created successfully", "id": id})
+}
+
+// @Summary Update target override
+// @Description Replace the weekdays, date range and targets of a target override
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param id path string true "Target override ID"
+// @Param override body types.TargetOverrideRequest true "Target override"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/target-overrides/{id} [put]
+func (r *Router) updateTargetOverride(c *gin.Context) {
+	id := c.Param("id")
+
+	var request types.TargetOverrideRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
+		return
+	}
+
+	err := r.foodService.UpdateTargetOverride(id, request)
+	if err != nil {
+		writeError(c, "Failed to update target override: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Target override updated successfully"})
+}
+
+// @Summary Delete target override
+// @Description Delete a target override
+// @Tags settings
+// @Produce json
+// @Param id path string true "Target override ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/target-overrides/{id} [delete]
+func (r *Router) deleteTargetOverride(c *gin.Context) {
+	id := c.Param("id")
+
+	err := r.foodService.DeleteTargetOverride(id)
+	if err != nil {
+		writeError(c, "Failed to delete target override: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Target override deleted successfully"})
+}
+
+// @Summary Get webhooks
+// @Description Get the outgoing webhooks of this device. Their secrets are not returned.
+// @Tags settings
+// @Produce json
+// @Success 200 {array} types.Webhook
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/webhooks [get]
+func (r *Router) getWebhooks(c *gin.Context) {
+	webhooks, err := r.foodService.GetWebhooks()
+	if err != nil {
+		writeError(c, "Failed to get webhooks: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// @Summary Create webhook
+// @Description Add an outgoing webhook. The events are posted as JSON with the headers X-Nutrack-Event (e.g. "consumed_food_item.created"), X-Nutrack-Delivery and, if a secret is set, X-Nutrack-Signature ("sha256=" and the hex encoded HMAC-SHA256 of the body). Events are entities like "weight" or entity.action names like "consumed_food_item.created", "daily_target.reached", "weight.created", "database.conflict" or "scanner.offline". Logging a food item again on the same day adds to its entry, which is a "consumed_food_item.updated" event. Failed deliveries are retried with an increasing delay.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param webhook body types.WebhookRequest true "Webhook"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/webhooks [post]
+func (r *Router) createWebhook(c *gin.Context) {
+	var request types.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
+		return
+	}
+
+	id, err := r.foodService.CreateWebhook(request)
+	if err != nil {
+		writeError(c, "Failed to create webhook: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook created successfully", "id": id})
+}
+
+// @Summary Update webhook
+// @Description Replace the URL, events, profiles and state of a webhook. The secret is kept if it is omitted.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param webhook body types.WebhookRequest true "Webhook"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/webhooks/{id} [put]
+func (r *Router) updateWebhook(c *gin.Context) {
+	id := c.Param("id")
+
+	var request types.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
+		return
+	}
+
+	if err := r.foodService.UpdateWebhook(id, request); err != nil {
+		writeError(c, "Failed to update webhook: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook updated successfully"})
+}
+
+// @Summary Delete webhook
+// @Description Delete an outgoing webhook
+// @Tags settings
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/webhooks/{id} [delete]
+func (r *Router) deleteWebhook(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := r.foodService.DeleteWebhook(id); err != nil {
+		writeError(c, "Failed to delete webhook: ", err)
 		return
 	}
 
-	err := r.foodService.PostConsumedFoodItem(request)
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// @Summary Test webhook
+// @Description Post a "webhook.test" event to a webhook once, even if it is disabled, and return the delivery
+// @Tags settings
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} types.WebhookDelivery
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/webhooks/{id}/test [post]
+func (r *Router) testWebhook(c *gin.Context) {
+	id := c.Param("id")
+
+	delivery, err := r.foodService.TestWebhook(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post consumed food item: " + err.Error()})
+		writeError(c, "Failed to test webhook: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Food item consumed successfully"})
+	c.JSON(http.StatusOK, delivery)
 }
 
-// @Summary Delete consumed food item
-// @Description Delete a consumed food item by ID
-// @Tags consumedFoodItems
+// @Summary Get webhook deliveries
+// @Description Get the latest webhook deliveries, the latest first. Deliveries are kept for 30 days. Deliveries still pending when the backend restarts are marked as failed.
+// @Tags settings
 // @Produce json
-// @Param id path string true "Consumed food item ID"
-// @Success 200 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
-// @Router /consumedFoodItems/{id} [delete]
-func (r *Router) deleteConsumedFoodItem(c *gin.Context) {
-	id := c.Param("id")
-
-	err := r.foodService.DeleteConsumedFoodItem(id)
+// @Param webhook_id query string false "Only the deliveries of this webhook"
+// @Success 200 {array} types.WebhookDelivery
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/webhooks/deliveries [get]
+func (r *Router) getWebhookDeliveries(c *gin.Context) {
+	deliveries, err := r.foodService.GetWebhookDeliveries(c.Query("webhook_id"))
 	if err != nil {
-		if err.Error() == fmt.Sprintf("no consumed food item found with id %s", id) {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete consumed food item"})
-		}
+		writeError(c, "Failed to get webhook deliveries: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Consumed food item deleted successfully"})
+	c.JSON(http.StatusOK, deliveries)
 }
 
-// @Summary Get consumed food items by date
-// @Description Get all consumed food items for a specific date and profile. If no profile ID is provided, the active profile is used.
-// @Tags consumedFoodItems
+// @Summary Get meal plan
+// @Description Get the planned food items and dishes of every day in a date range with their projected nutrition. The totals of each day are compared with the targets in effect on that day. If no profile ID is provided, the active profile is used.
+// @Tags mealplan
 // @Produce json
-// @Param date path string true "Date in YYYY-MM-DD format"
+// @Param start_date query string true "First day (YYYY-MM-DD)"
+// @Param end_date query string true "Last day (YYYY-MM-DD)"
 // @Param profile_id query string false "Profile ID"
-// @Success 200 {array} []types.ConsumedFoodItemWithDetails
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
-// @Router /consumedFoodItems/{date} [get]
-func (r *Router) getConsumedFoodItemsByDate(c *gin.Context) {
-	dateStr := c.Param("date")
+// @Success 200 {array} types.MealPlanDay
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /mealplan [get]
+func (r *Router) getMealPlan(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
 	profileID := c.Query("profile_id")
 
-	consumedItems, err := r.foodService.GetConsumedFoodItemsByDate(dateStr, profileID)
+	plan, err := r.foodService.GetMealPlan(c.Request.Context(), profileID, startDate, endDate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to retrieve consumed food items: %v", err)})
+		writeError(c, "Failed to get meal plan: ", err)
 		return
 	}
 
-	if len(consumedItems) == 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "No consumed food items found for the given date", "items": []data.ConsumedFoodItemWithDetails{}})
+	c.JSON(http.StatusOK, plan)
+}
+
+// @Summary Get meal plan report
+// @Description Compare the planned with the logged calories and macros of every day in a date range. If no profile ID is provided, the active profile is used.
+// @Tags mealplan
+// @Produce json
+// @Param start_date query string true "First day (YYYY-MM-DD)"
+// @Param end_date query string true "Last day (YYYY-MM-DD)"
+// @Param profile_id query string false "Profile ID"
+// @Success 200 {array} types.MealPlanReportDay
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /mealplan/report [get]
+func (r *Router) getMealPlanReport(c *gin.Context) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+	profileID := c.Query("profile_id")
+
+	report, err := r.foodService.GetMealPlanReport(c.Request.Context(), profileID, startDate, endDate)
+	if err != nil {
+		writeError(c, "Failed to get meal plan report: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, consumedItems)
+	c.JSON(http.StatusOK, report)
 }
 
-// @Summary Update consumed food item
-// @Description Update a consumed food item by ID
-// @Tags consumedFoodItems
+// @Summary Create meal plan entry
+// @Description Plan a food item or a dish for a meal. Food items are planned in servings or in another unit, which is converted to servings. Dishes are planned in portions. If no profile ID is provided, the active profile is used.
+// @Tags mealplan
 // @Accept json
 // @Produce json
-// @Param id path string true "Consumed food item ID"
-// @Param consumedItem body types.ConsumedFoodItemRequest true "Updated consumed food item data"
+// @Param entry body types.MealPlanEntryRequest true "Plan entry"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
-// @Router /consumedFoodItems/{id} [put]
-func (r *Router) updateConsumedFoodItem(c *gin.Context) {
-	id := c.Param("id")
-	var updateData map[string]interface{}
-	if err := c.BindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /mealplan [post]
+func (r *Router) createMealPlanEntry(c *gin.Context) {
+	var request types.MealPlanEntryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
 		return
 	}
 
-	err := r.foodService.UpdateConsumedFoodItem(id, updateData)
+	id, err := r.foodService.CreateMealPlanEntry(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update consumed food item"})
+		writeError(c, "Failed to create meal plan entry: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Consumed food item updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan entry created successfully", "id": id})
 }
 
-// @Summary Search food items
-// @Description Search for food items by name
-// @Tags foodItems
+// @Summary Update meal plan entry
+// @Description Replace the date, meal, food item or dish and quantity of a plan entry
+// @Tags mealplan
+// @Accept json
 // @Produce json
-// @Param query query string true "Search query"
-// @Success 200 {array} []types.PersistentFoodItem
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
-// @Router /foodItems/search [get]
-func (r *Router) searchFoodItems(c *gin.Context) {
-	query := c.Query("q")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+// @Param id path string true "Plan entry ID"
+// @Param entry body types.MealPlanEntryRequest true "Plan entry"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /mealplan/{id} [put]
+func (r *Router) updateMealPlanEntry(c *gin.Context) {
+	id := c.Param("id")
+
+	var request types.MealPlanEntryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
 		return
 	}
 
-	items, err := r.foodService.SearchFoodItems(query)
+	err := r.foodService.UpdateMealPlanEntry(c.Request.Context(), id, request)
 	if err != nil {
-		if strings.Contains(err.Error(), "must be at least") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search food items"})
+		writeError(c, "Failed to update meal plan entry: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan entry updated successfully"})
 }
 
-// @Summary Search food items on OpenFoodFacts
-// @Description Search for food items on OpenFoodFacts by name
-// @Tags foodItems
+// @Summary Delete meal plan entry
+// @Description Delete a plan entry. The diary entry of an eaten plan entry is kept.
+// @Tags mealplan
 // @Produce json
-// @Param query query string true "Search query"
-// @Success 200 {array} []types.PersistentFoodItem
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
-// @Router /foodItems/searchOpenFoodFacts [get]
-func (r *Router) searchOpenFoodFacts(c *gin.Context) {
-	searchTerm := c.Query("q")
-	if searchTerm == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
-		return
-	}
+// @Param id path string true "Plan entry ID"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /mealplan/{id} [delete]
+func (r *Router) deleteMealPlanEntry(c *gin.Context) {
+	id := c.Param("id")
 
-	items, err := r.foodService.SearchOpenFoodFacts(searchTerm)
+	err := r.foodService.DeleteMealPlanEntry(c.Request.Context(), id)
 	if err != nil {
-		if strings.Contains(err.Error(), "must be at least") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-		if strings.Contains(err.Error(), "failed to fetch") {
-			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "OpenFoodFacts service is currently unavailable"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search OpenFoodFacts"})
+		writeError(c, "Failed to delete meal plan entry: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan entry deleted successfully"})
 }
 
-// @Summary Save user settings
-// @Description Save user settings. If no profile ID is provided, the active profile is used.
-// @Tags settings
+// @Summary Mark meal plan entry as eaten
+// @Description Log a plan entry as consumed on its date. The planned quantity is used unless another quantity is provided. Food items are added to an existing entry of the same food item on that date.
+// @Tags mealplan
 // @Accept json
 // @Produce json
-// @Param settings body types.UserSettings true "User settings"
+// @Param id path string true "Plan entry ID"
+// @Param request body types.MarkMealPlanEntryEatenRequest false "Optional quantity"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
-// @Router /settings [post]
-func (r *Router) saveUserSettings(c *gin.Context) {
-	var request struct {
-		Weight             float64 `json:"weight"`
-		Height             float64 `json:"height"`
-		Calories           float64 `json:"calories"`
-		Proteins           float64 `json:"proteins"`
-		Carbs              float64 `json:"carbs"`
-		Fat                float64 `json:"fat"`
-		BirthDate          string  `json:"birth_date"`
-		Gender             string  `json:"gender"`
-		ActivityLevel      int     `json:"activity_level"`
-		WeeklyWeightChange float64 `json:"weekly_weight_change"`
-		ProfileID          string  `json:"profile_id"`
-	}
-
-	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
-	}
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /mealplan/{id}/eaten [post]
+func (r *Router) markMealPlanEntryEaten(c *gin.Context) {
+	id := c.Param("id")
 
-	// convert the request to UserSettings
-	settings := data.UserSettings{
-		Weight:             request.Weight,
-		Height:             request.Height,
-		Calories:           request.Calories,
-		Proteins:           request.Proteins,
-		Carbs:              request.Carbs,
-		Fat:                request.Fat,
-		BirthDate:          request.BirthDate,
-		Gender:             request.Gender,
-		ActivityLevel:      request.ActivityLevel,
-		WeeklyWeightChange: request.WeeklyWeightChange,
+	var request types.MarkMealPlanEntryEatenRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			writeInvalidRequest(c, err.Error())
+			return
+		}
 	}
 
-	err := r.foodService.SaveUserSettings(settings, request.ProfileID)
+	consumedID, err := r.foodService.MarkMealPlanEntryEaten(c.Request.Context(), id, request)
 	if err != nil {
-		if strings.Contains(err.Error(), "no profile found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save user settings"})
-		}
+		writeError(c, "Failed to mark meal plan entry as eaten: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User settings saved successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan entry marked as eaten", "consumed_id": consumedID})
+}
+
+// toDataGoalStrategy converts a goal strategy of a request, nil stays nil
+func toDataGoalStrategy(strategy *types.GoalStrategy) *data.GoalStrategy {
+	if strategy == nil {
+		return nil
+	}
+	return &data.GoalStrategy{
+		Preset:            strategy.Preset,
+		BMRFormula:        strategy.BMRFormula,
+		BodyFatPercentage: strategy.BodyFatPercentage,
+		Protein:           data.MacroTarget{Mode: strategy.Protein.Mode, Value: strategy.Protein.Value},
+		Fat:               data.MacroTarget{Mode: strategy.Fat.Mode, Value: strategy.Fat.Value},
+		Carbs:             data.MacroTarget{Mode: strategy.Carbs.Mode, Value: strategy.Carbs.Value},
+		MinCarbs:          strategy.MinCarbs,
+	}
 }
 
 // @Summary Get user settings
@@ -639,15 +1633,15 @@ func (r *Router) saveUserSettings(c *gin.Context) {
 // @Produce json
 // @Param profile_id query string true "Profile ID"
 // @Success 200 {object} types.UserSettings
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /settings [get]
 func (r *Router) getUserSettings(c *gin.Context) {
 	profileID := c.Query("profile_id")
 
 	settings, err := r.foodService.GetUserSettings(profileID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to get user settings: %v", err)})
+		writeError(c, "Failed to get user settings: ", err)
 		return
 	}
 
@@ -661,39 +1655,64 @@ func (r *Router) getUserSettings(c *gin.Context) {
 // @Produce json
 // @Param consumedFoodItem body types.ConsumedFoodItemRequest true "Consumed food item"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /consumedFoodItems/checkInsertAndConsume [post]
 func (r *Router) checkInsertAndConsume(c *gin.Context) {
 	var request types.ConsumedFoodItemRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
-	err := r.foodService.CheckInsertAndConsume(request)
+	err := r.foodService.CheckInsertAndConsume(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check/insert and consume food item"})
+		writeError(c, "Failed to check/insert and consume food item: ", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Food item checked/inserted and consumed successfully"})
 }
 
+// @Summary Check, insert and consume a batch of food items
+// @Description Adds missing food items from OpenFoodFacts and logs all items of the batch. By default the batch is logged in one transaction and fails as a whole. With partial set, every item is logged on its own and the results report which items failed.
+// @Tags consumedFoodItems
+// @Accept json
+// @Produce json
+// @Param batch body types.BatchConsumedFoodItemRequest true "Consumed food items"
+// @Success 200 {object} types.BatchConsumeResponse
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /foodItems/check-insert-and-consume-batch [post]
 func (r *Router) checkInsertAndConsumeBatch(c *gin.Context) {
 	var request types.BatchConsumedFoodItemRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
-	err := r.foodService.CheckInsertAndConsumeBatch(request)
+	results, err := r.foodService.CheckInsertAndConsumeBatch(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to batch check/insert and consume food items"})
+		writeError(c, "Failed to batch check/insert and consume food items: ", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Food items batch checked/inserted and consumed successfully"})
+	response := types.BatchConsumeResponse{
+		Message: "Food items batch checked/inserted and consumed successfully",
+		Results: results,
+	}
+	for _, result := range results {
+		if result.ConsumedID != "" {
+			response.Logged++
+		} else {
+			response.Failed++
+		}
+	}
+	if response.Failed > 0 {
+		response.Message = fmt.Sprintf("Logged %d of %d food items", response.Logged, len(results))
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // @Summary Create a new dish
@@ -703,19 +1722,19 @@ func (r *Router) checkInsertAndConsumeBatch(c *gin.Context) {
 // @Produce json
 // @Param dish body types.DishRequest true "Dish to create"
 // @Success 200 {object} types.Dish
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dishes [post]
 func (r *Router) createDish(c *gin.Context) {
 	var request types.DishRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
-	err := r.foodService.CreateDish(request)
+	err := r.foodService.CreateDish(c.Request.Context(), request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create dish"})
+		writeError(c, "Failed to create dish: ", err)
 		return
 	}
 
@@ -728,15 +1747,15 @@ func (r *Router) createDish(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Dish ID"
 // @Success 200 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dishes/{id} [delete]
 func (r *Router) deleteDish(c *gin.Context) {
 	dishID := c.Param("id")
 
-	err := r.foodService.DeleteDish(dishID)
+	err := r.foodService.DeleteDish(c.Request.Context(), dishID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete dish"})
+		writeError(c, "Failed to delete dish: ", err)
 		return
 	}
 
@@ -751,21 +1770,21 @@ func (r *Router) deleteDish(c *gin.Context) {
 // @Param id path string true "Dish ID"
 // @Param dish body types.DishRequest true "Updated dish data"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dishes/{id} [put]
 func (r *Router) updateDish(c *gin.Context) {
 	dishID := c.Param("id")
 	var request types.DishRequest
 	if err := c.BindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
-	err := r.foodService.UpdateDish(dishID, request)
+	err := r.foodService.UpdateDish(c.Request.Context(), dishID, request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dish"})
+		writeError(c, "Failed to update dish: ", err)
 		return
 	}
 
@@ -778,15 +1797,15 @@ func (r *Router) updateDish(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Dish ID"
 // @Success 200 {object} types.DishWithDetailedItems
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dishes/{id} [get]
 func (r *Router) getDish(c *gin.Context) {
 	id := c.Param("id")
 
 	dish, items, err := r.foodService.GetDish(id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dish"})
+		writeError(c, "Failed to retrieve dish: ", err)
 		return
 	}
 
@@ -794,19 +1813,28 @@ func (r *Router) getDish(c *gin.Context) {
 }
 
 // @Summary Get all dishes
-// @Description Get a list of all dishes
+// @Description Get a page of the dishes with their ingredients, by default all of them with the newest first. Sort keys are name, created_at, last_updated and kcal (per portion). Dishes without ingredients are incomplete. The total number of matching dishes is returned in the X-Total-Count header.
 // @Tags dishes
 // @Produce json
+// @Param query query types.ListQuery false "Page, sort order and filters"
 // @Success 200 {array} []types.Dish
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dishes [get]
 func (r *Router) getAllDishes(c *gin.Context) {
-	dishes, err := r.foodService.GetAllDishes()
+	query, ok := bindListQuery(c)
+	if !ok {
+		return
+	}
+
+	dishes, total, err := r.foodService.GetAllDishes(query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dishes"})
+		writeError(c, "Failed to retrieve dishes: ", err)
 		return
 	}
 
+	c.Header(totalCountHeader, strconv.Itoa(total))
+
 	if len(dishes) == 0 {
 		c.JSON(http.StatusOK, gin.H{"message": "No dishes found", "dishes": []data.DishWithDetailedItems{}})
 		return
@@ -815,25 +1843,88 @@ func (r *Router) getAllDishes(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"dishes": dishes})
 }
 
+// @Summary Set dish tags
+// @Description Replace the tags of a dish, e.g. "homemade". Tags are stored in lower case.
+// @Tags dishes
+// @Accept json
+// @Produce json
+// @Param id path string true "Dish ID"
+// @Param tags body types.TagsRequest true "Tags of the dish"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /dishes/tags/{id} [put]
+func (r *Router) setDishTags(c *gin.Context) {
+	id := c.Param("id")
+	var request types.TagsRequest
+	if err := c.BindJSON(&request); err != nil {
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	err := r.foodService.SetDishTags(c.Request.Context(), id, request.Tags)
+	if err != nil {
+		writeError(c, "Failed to set tags: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dish tags updated successfully"})
+}
+
+// @Summary Get all tags
+// @Description Get all tags in use with the number of food items and dishes using them
+// @Tags tags
+// @Produce json
+// @Success 200 {array} types.TagUsage
+// @Failure 500 {object} types.ApiResponse
+// @Router /tags [get]
+func (r *Router) getTags(c *gin.Context) {
+	tags, err := r.foodService.GetTags()
+	if err != nil {
+		writeError(c, "Failed to retrieve tags: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// @Summary Get dish nutrition
+// @Description Get the nutrition values of a dish per 100g of cooked food and per portion
+// @Tags dishes
+// @Produce json
+// @Param id path string true "Dish ID"
+// @Success 200 {object} types.DishNutrition
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /dishes/nutrition/{id} [get]
+func (r *Router) getDishNutrition(c *gin.Context) {
+	id := c.Param("id")
+
+	nutrition, err := r.foodService.GetDishNutrition(id)
+	if err != nil {
+		writeError(c, "Failed to calculate dish nutrition: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, nutrition)
+}
+
 // @Summary Convert dish to food item
 // @Description Convert a dish to a food item
 // @Tags dishes
 // @Produce json
 // @Param id path string true "Dish ID"
 // @Success 200 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dishes/{id}/convertToFoodItem [post]
 func (r *Router) convertDishToFoodItem(c *gin.Context) {
 	dishID := c.Param("id")
 
-	err := r.foodService.ConvertDishToFoodItem(dishID)
+	err := r.foodService.ConvertDishToFoodItem(c.Request.Context(), dishID)
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert dish to food item"})
-		}
+		writeError(c, "Failed to convert dish to food item: ", err)
 		return
 	}
 
@@ -841,19 +1932,28 @@ func (r *Router) convertDishToFoodItem(c *gin.Context) {
 }
 
 // @Summary Get all profiles
-// @Description Get a list of all profiles
+// @Description Get a page of the profiles, by default all of them with the newest first. Sort keys are name and created_at. The total number of matching profiles is returned in the X-Total-Count header.
 // @Tags profiles
 // @Produce json
+// @Param query query types.ListQuery false "Page, sort order and filters"
 // @Success 200 {array} []types.Profile
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /profiles [get]
 func (r *Router) getAllProfiles(c *gin.Context) {
-	profiles, err := r.foodService.GetAllProfiles()
+	query, ok := bindListQuery(c)
+	if !ok {
+		return
+	}
+
+	profiles, total, err := r.foodService.GetAllProfiles(query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profiles"})
+		writeError(c, "Failed to retrieve profiles: ", err)
 		return
 	}
 
+	c.Header(totalCountHeader, strconv.Itoa(total))
+
 	if len(profiles) == 0 {
 		c.JSON(http.StatusOK, gin.H{"profiles": []data.Profile{}})
 		return
@@ -868,19 +1968,15 @@ func (r *Router) getAllProfiles(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Profile ID"
 // @Success 200 {object} types.Profile
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /profiles/single/{id} [get]
 func (r *Router) getProfile(c *gin.Context) {
 	profileID := c.Param("id")
 
 	profile, err := r.foodService.GetProfile(profileID)
 	if err != nil {
-		if strings.Contains(err.Error(), "no profile found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve profile"})
-		}
+		writeError(c, "Failed to retrieve profile: ", err)
 		return
 	}
 
@@ -894,19 +1990,19 @@ func (r *Router) getProfile(c *gin.Context) {
 // @Produce json
 // @Param profile body types.Profile true "Profile to create"
 // @Success 201 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /profiles [post]
 func (r *Router) createProfile(c *gin.Context) {
 	var profile data.Profile
 	if err := c.BindJSON(&profile); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
 	profileID, err := r.foodService.CreateProfile(profile.Name)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create profile"})
+		writeError(c, "Failed to create profile: ", err)
 		return
 	}
 
@@ -927,9 +2023,9 @@ func (r *Router) createProfile(c *gin.Context) {
 // @Param id path string true "Profile ID"
 // @Param profile body types.Profile true "Updated profile data"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /profiles/single/{id} [put]
 func (r *Router) updateProfile(c *gin.Context) {
 	profileID := c.Param("id")
@@ -938,17 +2034,13 @@ func (r *Router) updateProfile(c *gin.Context) {
 	}
 
 	if err := c.BindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
 	err := r.foodService.UpdateProfile(profileID, updateData.Name)
 	if err != nil {
-		if strings.Contains(err.Error(), "no profile found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
-		}
+		writeError(c, "Failed to update profile: ", err)
 		return
 	}
 
@@ -961,19 +2053,15 @@ func (r *Router) updateProfile(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Profile ID"
 // @Success 200 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /profiles/single/{id} [delete]
 func (r *Router) deleteProfile(c *gin.Context) {
 	profileID := c.Param("id")
 
 	err := r.foodService.DeleteProfile(profileID)
 	if err != nil {
-		if strings.Contains(err.Error(), "no profile found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete profile"})
-		}
+		writeError(c, "Failed to delete profile: ", err)
 		return
 	}
 
@@ -987,9 +2075,9 @@ func (r *Router) deleteProfile(c *gin.Context) {
 // @Produce json
 // @Param profile body types.ActiveProfileRequest true "Profile to set as active"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /profiles/active [post]
 func (r *Router) setActiveProfile(c *gin.Context) {
 
@@ -997,21 +2085,21 @@ func (r *Router) setActiveProfile(c *gin.Context) {
 	// Log request body
 	body, _ := io.ReadAll(c.Request.Body)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
-	fmt.Printf("Request body for setActiveProfile: %s\n", string(body))
+	log.Printf("Request body for setActiveProfile: %s", string(body))
 
 	if err := c.BindJSON(&request); err != nil {
-		fmt.Printf("Error binding JSON: %v\n", err)
-		fmt.Printf("Request body: %s\n", string(body))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		log.Printf("Error binding JSON: %v", err)
+		log.Printf("Request body: %s", string(body))
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
-	fmt.Printf("Setting active profile to: %s\n", request.ProfileID)
+	log.Printf("Setting active profile to: %s", request.ProfileID)
 
 	err := r.foodService.SetActiveProfile(request.ProfileID)
 	if err != nil {
-		fmt.Printf("Error setting active profile: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set active profile"})
+		log.Printf("Error setting active profile: %v", err)
+		writeError(c, "Failed to set active profile: ", err)
 		return
 	}
 
@@ -1023,13 +2111,13 @@ func (r *Router) setActiveProfile(c *gin.Context) {
 // @Tags profiles
 // @Produce json
 // @Success 200 {object} types.Profile
-// @Failure 404 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 404 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /profiles/active [get]
 func (r *Router) getActiveProfile(c *gin.Context) {
 	profile, err := r.foodService.GetProfile("")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get active profile details"})
+		writeError(c, "Failed to get active profile details: ", err)
 		return
 	}
 
@@ -1047,19 +2135,19 @@ func (r *Router) getActiveProfile(c *gin.Context) {
 // @Produce json
 // @Param token body object true "Dropbox authorization code and code verifier"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/token [post]
 func (r *Router) handleDropboxToken(c *gin.Context) {
 	var request types.TokenRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeInvalidRequest(c, err.Error())
 		return
 	}
 
 	_, err := r.foodService.ExchangeToken(request.Code, request.CodeVerifier)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1071,12 +2159,12 @@ func (r *Router) handleDropboxToken(c *gin.Context) {
 // @Tags authentication
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/status [get]
 func (r *Router) handleDropboxStatus(c *gin.Context) {
 	isAuthenticated, err := r.foodService.GetAuthenticationStatus()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1088,12 +2176,12 @@ func (r *Router) handleDropboxStatus(c *gin.Context) {
 // @Tags authentication
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/logout [post]
 func (r *Router) handleDropboxLogout(c *gin.Context) {
 	err := r.foodService.Logout()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1105,12 +2193,12 @@ func (r *Router) handleDropboxLogout(c *gin.Context) {
 // @Tags database
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/uploadDatabase [post]
 func (r *Router) handleDropboxUploadDatabase(c *gin.Context) {
 	result, err := r.foodService.UploadDatabase()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1125,12 +2213,12 @@ func (r *Router) handleDropboxUploadDatabase(c *gin.Context) {
 // @Tags database
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/downloadDatabase [post]
 func (r *Router) handleDropboxDownloadDatabase(c *gin.Context) {
 	result, err := r.foodService.DownloadDatabase()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1145,7 +2233,7 @@ func (r *Router) handleDropboxDownloadDatabase(c *gin.Context) {
 // @Tags database
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/autosync [get]
 func (r *Router) handleGetDropboxAutosync(c *gin.Context) {
 	enabled := r.foodService.GetAutoSync()
@@ -1159,19 +2247,19 @@ func (r *Router) handleGetDropboxAutosync(c *gin.Context) {
 // @Produce json
 // @Param autosync body types.DropboxAutosyncRequest true "Autosync status"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/autosync [post]
 func (r *Router) handleSetDropboxAutosync(c *gin.Context) {
 	var request types.DropboxAutosyncRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeInvalidRequest(c, err.Error())
 		return
 	}
 
 	err := r.foodService.SetAutoSync(request.Enabled)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1183,7 +2271,7 @@ func (r *Router) handleSetDropboxAutosync(c *gin.Context) {
 // @Tags weightTracking
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /weightTracking [get]
 func (r *Router) handleGetWeightTracking(c *gin.Context) {
 	enabled := r.foodService.GetWeightTracking()
@@ -1197,22 +2285,21 @@ func (r *Router) handleGetWeightTracking(c *gin.Context) {
 // @Produce json
 // @Param weightTracking body types.WeightTrackingRequest true "Weight tracking status"
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /weightTracking [post]
 func (r *Router) handleSetWeightTracking(c *gin.Context) {
 	var request types.WeightTrackingRequest
-	fmt.Printf("Received request: %s\n", c.Request.Body)
 	body, _ := io.ReadAll(c.Request.Body)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
-	fmt.Printf("Request body: %s\n", string(body))
+	log.Printf("Request body: %s", string(body))
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeInvalidRequest(c, err.Error())
 		return
 	}
 
 	err := r.foodService.SetWeightTracking(request.Enabled)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1224,7 +2311,7 @@ func (r *Router) handleSetWeightTracking(c *gin.Context) {
 // @Tags autoRecalculateNutritionValues
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /autoRecalculateNutritionValues [get]
 func (r *Router) handleGetAutoRecalculateNutritionValues(c *gin.Context) {
 	enabled := r.foodService.GetAutoRecalculateNutritionValues()
@@ -1238,24 +2325,89 @@ func (r *Router) handleGetAutoRecalculateNutritionValues(c *gin.Context) {
 // @Produce json
 // @Param autoRecalculateNutritionValues body types.AutoRecalculateNutritionValuesRequest true "Auto recalculate nutrition values status"
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /autoRecalculateNutritionValues [post]
 func (r *Router) handleSetAutoRecalculateNutritionValues(c *gin.Context) {
 	var request types.AutoRecalculateNutritionValuesRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeInvalidRequest(c, err.Error())
 		return
 	}
 
 	err := r.foodService.SetAutoRecalculateNutritionValues(request.Enabled)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Auto recalculate nutrition values setting updated"})
 }
 
+// @Summary Get adaptive TDEE status
+// @Description Get whether the daily recalculation uses the TDEE estimated from logged intake and weight trend instead of the BMR formula
+// @Tags settings
+// @Produce json
+// @Success 200 {object} gin.H
+// @Router /settings/adaptive-tdee [get]
+func (r *Router) handleGetAdaptiveTDEE(c *gin.Context) {
+	enabled := r.foodService.GetAdaptiveTDEE()
+	c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+}
+
+// @Summary Set adaptive TDEE status
+// @Description Set whether the daily recalculation uses the TDEE estimated from logged intake and weight trend. Estimates with low confidence fall back to the BMR formula.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param adaptiveTDEE body types.AdaptiveTDEERequest true "Adaptive TDEE status"
+// @Success 200 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/adaptive-tdee [post]
+func (r *Router) handleSetAdaptiveTDEE(c *gin.Context) {
+	var request types.AdaptiveTDEERequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeInvalidRequest(c, err.Error())
+		return
+	}
+
+	err := r.foodService.SetAdaptiveTDEE(request.Enabled)
+	if err != nil {
+		writeError(c, "", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Adaptive TDEE setting updated"})
+}
+
+// @Summary Estimate TDEE
+// @Description Estimate the total daily energy expenditure from the logged intake and the weight trend over a rolling window ending yesterday. If no profile ID is provided, the active profile is used.
+// @Tags settings
+// @Produce json
+// @Param profile_id query string false "Profile ID"
+// @Param days query int false "Window in days (14-180, default 28)"
+// @Success 200 {object} types.TDEEEstimate
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
+// @Router /settings/tdee-estimate [get]
+func (r *Router) getTDEEEstimate(c *gin.Context) {
+	profileID := c.Query("profile_id")
+
+	days, err := queryInt(c, "days")
+	if err != nil {
+		writeError(c, "", err)
+		return
+	}
+
+	estimate, err := r.foodService.EstimateTDEE(profileID, days)
+	if err != nil {
+		writeError(c, "Failed to estimate TDEE: ", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, estimate)
+}
+
 // CalculateNutrition handles the nutrition calculation request
 // @Summary Calculate nutrition targets
 // @Description Calculate daily nutrition targets based on user metrics
@@ -1269,33 +2421,31 @@ func (r *Router) handleSetAutoRecalculateNutritionValues(c *gin.Context) {
 func (r *Router) calculateNutrition(c *gin.Context) {
 	var req types.NutritionCalculationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, types.ApiResponse{
-			Success: false,
-			Error:   "Invalid request body",
-		})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
 	// Validate activity level
 	if req.ActivityLevel < 0 || req.ActivityLevel > 4 {
-		c.JSON(http.StatusBadRequest, types.ApiResponse{
-			Success: false,
-			Error:   "Activity level must be between 0 and 4",
-		})
+		writeError(c, "", types.NewValidationError("activity_level", "Activity level must be between 0 and 4"))
 		return
 	}
 
 	// Validate gender
 	if req.Gender != "male" && req.Gender != "female" && req.Gender != "other" {
-		c.JSON(http.StatusBadRequest, types.ApiResponse{
-			Success: false,
-			Error:   "Gender must be 'male', 'female', or 'other'",
-		})
+		writeError(c, "", types.NewValidationError("gender", "Gender must be 'male', 'female', or 'other'"))
+		return
+	}
+
+	strategy, err := r.foodService.ResolveGoalStrategy(toDataGoalStrategy(req.Strategy), req.ProfileID)
+	if err != nil {
+		writeError(c, "Failed to resolve goal strategy: ", err)
 		return
 	}
 
 	// Calculate nutrition
-	result := r.foodService.CalculateNutrition(
+	result := r.foodService.CalculateNutritionWithStrategy(
+		strategy,
 		req.Weight,
 		req.Height,
 		req.Age,
@@ -1320,15 +2470,19 @@ func (r *Router) calculateNutrition(c *gin.Context) {
 func (r *Router) calculateNutritionFromCaloriesAndWeight(c *gin.Context) {
 	var req types.NutritionCalculationFromCaloriesAndWeightRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, types.ApiResponse{
-			Success: false,
-			Error:   "Invalid request body",
-		})
+		writeInvalidRequest(c, "Invalid request body")
+		return
+	}
+
+	strategy, err := r.foodService.ResolveGoalStrategy(toDataGoalStrategy(req.Strategy), req.ProfileID)
+	if err != nil {
+		writeError(c, "Failed to resolve goal strategy: ", err)
 		return
 	}
 
 	// Calculate nutrition
-	result := r.foodService.CalculateNutritionFromCaloriesAndWeight(
+	result := r.foodService.CalculateNutritionFromCaloriesAndWeightWithStrategy(
+		strategy,
 		req.Calories,
 		req.Weight,
 	)
@@ -1343,7 +2497,7 @@ func (r *Router) calculateNutritionFromCaloriesAndWeight(c *gin.Context) {
 // @Produce json
 // @Param force body types.ForceSyncRequest true "Force sync"
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /dropbox/sync [post]
 func (r *Router) handleDropboxSync(c *gin.Context) {
 	var request types.ForceSyncRequest
@@ -1353,7 +2507,7 @@ func (r *Router) handleDropboxSync(c *gin.Context) {
 
 	err := r.foodService.SyncToDropbox(request.Force)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, "", err)
 		return
 	}
 
@@ -1365,16 +2519,16 @@ func (r *Router) handleDropboxSync(c *gin.Context) {
 // @Tags scanners
 // @Produce json
 // @Success 200 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 500 {object} types.ApiResponse
 // @Router /scanners [get]
 func (r *Router) listScanners(c *gin.Context) {
 	scanners, err := r.foodService.ListScanners()
 	if err != nil {
-		fmt.Printf("Error listing scanners: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error listing scanners: %v", err)
+		writeError(c, "", err)
 		return
 	}
-	fmt.Printf("Found scanners: %+v\n", scanners)
+	log.Printf("Found scanners: %+v", scanners)
 	c.JSON(http.StatusOK, scanners)
 }
 
@@ -1385,13 +2539,13 @@ func (r *Router) listScanners(c *gin.Context) {
 // @Produce json
 // @Param scanner body types.ActiveScannerRequest true "Scanner to set as active"
 // @Success 200 {object} gin.H
-// @Failure 400 {object} gin.H
-// @Failure 500 {object} gin.H
+// @Failure 400 {object} types.ApiResponse
+// @Failure 500 {object} types.ApiResponse
 // @Router /scanners/active [post]
 func (r *Router) setActiveScanner(c *gin.Context) {
 	var request types.ActiveScannerRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		writeInvalidRequest(c, "Invalid request body")
 		return
 	}
 
@@ -1402,12 +2556,12 @@ func (r *Router) setActiveScanner(c *gin.Context) {
 		pathValue = *request.Path
 	}
 
-	fmt.Printf("Setting active scanner with path: %s\n", pathValue)
+	log.Printf("Setting active scanner with path: %s", pathValue)
 	if err := r.foodService.SetActiveScanner(pathValue); err != nil {
-		fmt.Printf("Error setting active scanner: %v\n", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		log.Printf("Error setting active scanner: %v", err)
+		writeError(c, "", err)
 		return
 	}
-	fmt.Printf("Active scanner set successfully\n")
+	log.Println("Active scanner set successfully")
 	c.JSON(http.StatusOK, gin.H{"message": "Active scanner set"})
 }
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/http"
+
+	"nutrack/backend/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// route is an operation of the API with its path below /api. The route table is the only
+// definition of the operations: it is registered with gin for the REST API and dispatched
+// in-process by the standard IO and WebSocket handlers, so all transports share the handlers
+// with their request binding, validation and error responses.
+type route struct {
+	method  string
+	path    string
+	handler gin.HandlerFunc
+}
+
+// routes returns the route table of the API
+func (r *Router) routes() []route {
+	return []route{
+		{http.MethodPost, "/foodItems/check-and-insert", r.checkAndInsertFoodItem},
+		{http.MethodPost, "/foodItems/check-insert-and-consume", r.checkInsertAndConsume},
+		{http.MethodPost, "/foodItems/check-insert-and-consume-batch", r.checkInsertAndConsumeBatch},
+		{http.MethodPost, "/foodItems/manually-add", r.manuallyAddFoodItem},
+		{http.MethodGet, "/foodItems/all", r.getAllFoodItems},
+		{http.MethodPut, "/foodItems/:barcode", r.updateFoodItem},
+		{http.MethodDelete, "/foodItems/:barcode", r.deleteFoodItem},
+		{http.MethodPost, "/foodItems/reset/:barcode", r.resetFoodItem},
+		{http.MethodGet, "/foodItems/servingQuantity/:barcode", r.getServingQuantityByBarcode},
+		{http.MethodPut, "/foodItems/portions/:barcode", r.setFoodPortions},
+		{http.MethodPut, "/foodItems/tags/:barcode", r.setFoodItemTags},
+		{http.MethodGet, "/foodItems/search", r.searchFoodItems},
+		{http.MethodGet, "/foodItems/recent", r.getRecentFoodItems},
+		{http.MethodGet, "/foodItems/frequent", r.getFrequentFoodItems},
+		{http.MethodGet, "/foodItems/duplicates", r.findDuplicateFoodItems},
+		{http.MethodPost, "/foodItems/merge", r.mergeFoodItems},
+		{http.MethodGet, "/foodItems/favorites", r.getFavoriteFoodItems},
+		{http.MethodPost, "/foodItems/favorites/:barcode", r.addFavoriteFoodItem},
+		{http.MethodDelete, "/foodItems/favorites/:barcode", r.removeFavoriteFoodItem},
+		{http.MethodPost, "/consumedFoodItems", r.postConsumedFoodItem},
+		{http.MethodPost, "/consumedFoodItems/dish", r.postConsumedDish},
+		{http.MethodPost, "/consumedFoodItems/reprice", r.repriceHistory},
+		{http.MethodPost, "/consumedFoodItems/copy", r.copyConsumedFoodItems},
+		{http.MethodDelete, "/consumedFoodItems/:id", r.deleteConsumedFoodItem},
+		{http.MethodGet, "/consumedFoodItems/summary", r.getDailySummary},
+		{http.MethodGet, "/consumedFoodItems/summary/tags", r.getIntakeByTag},
+		{http.MethodGet, "/consumedFoodItems/:date", r.getConsumedFoodItemsByDate},
+		{http.MethodPut, "/consumedFoodItems/:id", r.updateConsumedFoodItem},
+
+		{http.MethodPost, "/settings", r.saveUserSettings},
+		{http.MethodGet, "/settings", r.getUserSettings},
+
+		{http.MethodPost, "/dishes", r.createDish},
+		{http.MethodDelete, "/dishes/:id", r.deleteDish},
+		{http.MethodPut, "/dishes/:id", r.updateDish},
+		{http.MethodGet, "/dishes/:id", r.getDish},
+		{http.MethodGet, "/dishes", r.getAllDishes},
+		{http.MethodPost, "/dishes/convert-to-food-item/:id", r.convertDishToFoodItem},
+		{http.MethodGet, "/dishes/nutrition/:id", r.getDishNutrition},
+		{http.MethodPut, "/dishes/tags/:id", r.setDishTags},
+
+		{http.MethodGet, "/tags", r.getTags},
+
+		{http.MethodGet, "/profiles", r.getAllProfiles},
+		{http.MethodPost, "/profiles", r.createProfile},
+		{http.MethodPost, "/profiles/active", r.setActiveProfile},
+		{http.MethodGet, "/profiles/active", r.getActiveProfile},
+		{http.MethodGet, "/profiles/single/:id", r.getProfile},
+		{http.MethodPut, "/profiles/single/:id", r.updateProfile},
+		{http.MethodDelete, "/profiles/single/:id", r.deleteProfile},
+
+		{http.MethodGet, "/search", r.searchOpenFoodFacts},
+		{http.MethodGet, "/search/local", r.searchLocal},
+
+		{http.MethodPost, "/dropbox/token", r.handleDropboxToken},
+		{http.MethodGet, "/dropbox/status", r.handleDropboxStatus},
+		{http.MethodPost, "/dropbox/logout", r.handleDropboxLogout},
+		{http.MethodPost, "/dropbox/upload-database", r.handleDropboxUploadDatabase},
+		{http.MethodGet, "/dropbox/download-database", r.handleDropboxDownloadDatabase},
+		{http.MethodGet, "/dropbox/autosync", r.handleGetDropboxAutosync},
+		{http.MethodPost, "/dropbox/autosync", r.handleSetDropboxAutosync},
+
+		// Nutrition calculation endpoints
+		{http.MethodPost, "/nutrition/calculate", r.calculateNutrition},
+		{http.MethodPost, "/dropbox/sync", r.handleDropboxSync},
+		{http.MethodGet, "/settings/weighttracking", r.handleGetWeightTracking},
+		{http.MethodPost, "/settings/weighttracking", r.handleSetWeightTracking},
+		{http.MethodGet, "/settings/auto-recalculate-nutrition-values", r.handleGetAutoRecalculateNutritionValues},
+		{http.MethodPost, "/settings/auto-recalculate-nutrition-values", r.handleSetAutoRecalculateNutritionValues},
+		{http.MethodPost, "/settings/calculate-nutrients", r.calculateNutrition},
+		{http.MethodPost, "/settings/calculate-from-calories-and-weight", r.calculateNutritionFromCaloriesAndWeight},
+		{http.MethodGet, "/settings/goal-strategy", r.getGoalStrategy},
+		{http.MethodPost, "/settings/goal-strategy", r.saveGoalStrategy},
+		{http.MethodDelete, "/settings/goal-strategy", r.deleteGoalStrategy},
+		{http.MethodGet, "/settings/goal-strategy/presets", r.getGoalStrategyPresets},
+		{http.MethodGet, "/settings/tdee-estimate", r.getTDEEEstimate},
+		{http.MethodGet, "/settings/adaptive-tdee", r.handleGetAdaptiveTDEE},
+		{http.MethodPost, "/settings/adaptive-tdee", r.handleSetAdaptiveTDEE},
+		{http.MethodGet, "/settings/targets/:date", r.getTargetsForDate},
+		{http.MethodGet, "/settings/target-history", r.getTargetHistory},
+		{http.MethodGet, "/settings/target-overrides", r.getTargetOverrides},
+		{http.MethodPost, "/settings/target-overrides", r.createTargetOverride},
+		{http.MethodPut, "/settings/target-overrides/:id", r.updateTargetOverride},
+		{http.MethodDelete, "/settings/target-overrides/:id", r.deleteTargetOverride},
+		{http.MethodGet, "/settings/webhooks", r.getWebhooks},
+		{http.MethodPost, "/settings/webhooks", r.createWebhook},
+		{http.MethodGet, "/settings/webhooks/deliveries", r.getWebhookDeliveries},
+		{http.MethodPut, "/settings/webhooks/:id", r.updateWebhook},
+		{http.MethodDelete, "/settings/webhooks/:id", r.deleteWebhook},
+		{http.MethodPost, "/settings/webhooks/:id/test", r.testWebhook},
+
+		// Meal plan endpoints
+		{http.MethodGet, "/mealplan", r.getMealPlan},
+		{http.MethodPost, "/mealplan", r.createMealPlanEntry},
+		{http.MethodGet, "/mealplan/report", r.getMealPlanReport},
+		{http.MethodPut, "/mealplan/:id", r.updateMealPlanEntry},
+		{http.MethodDelete, "/mealplan/:id", r.deleteMealPlanEntry},
+		{http.MethodPost, "/mealplan/:id/eaten", r.markMealPlanEntryEaten},
+
+		// Scanner endpoints
+		{http.MethodGet, "/scanners", r.listScanners},
+		{http.MethodPost, "/scanners/active", r.setActiveScanner},
+	}
+}
+
+// registerRoutes registers all routes of the route table in a router group
+func (r *Router) registerRoutes(group *gin.RouterGroup) {
+	for _, route := range r.routes() {
+		group.Handle(route.method, route.path, route.handler)
+	}
+}
+
+// newDispatchRouter returns a router with only the route table, which dispatches the requests
+// of the standard IO and WebSocket transports. Streams like /api/sse are not part of it.
+func newDispatchRouter(foodService *service.FoodService) *Router {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	engine.NoRoute(writeUnknownEndpoint)
+
+	router := &Router{
+		engine:      engine,
+		foodService: foodService,
+	}
+	router.registerRoutes(engine.Group("/api"))
+	return router
+}
@@ -2,17 +2,24 @@ package api
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"log"
-	"nutrack/backend/data"
+	"net/http"
+	"net/url"
 	"nutrack/backend/service"
 	"nutrack/backend/types"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/gin-gonic/gin"
 )
 
+// Request is the envelope of requests of the standard IO and WebSocket transports
 type Request struct {
 	Type      string      `json:"type"`
 	Method    string      `json:"method"`
@@ -21,14 +28,18 @@ type Request struct {
 	RequestId string      `json:"requestId"`
 }
 
+// Response is the envelope of the answers to requests, requestId is the one of the request
 type Response struct {
 	Type      string      `json:"type"`
 	Data      interface{} `json:"data"`
+	Total     *int        `json:"total,omitempty"` // Total number of matching entries of a paginated list
 	RequestId string      `json:"requestId"`
 }
 
+// StandardIOHandler answers requests read line by line from stdin on stdout. The requests
+// are dispatched to the route table of the REST API, see routes.go.
 type StandardIOHandler struct {
-	foodService *service.FoodService
+	router *Router
 }
 
 func NewStandardIOHandler() *StandardIOHandler {
@@ -36,13 +47,19 @@ func NewStandardIOHandler() *StandardIOHandler {
 	if err != nil {
 		panic(fmt.Sprintf("Failed to create food service: %v", err))
 	}
+
+	// Stdout carries the responses, gin must not log to it
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = os.Stderr
+
 	return &StandardIOHandler{
-		foodService: foodService,
+		router: newDispatchRouter(foodService),
 	}
 }
 
 func (h *StandardIOHandler) Start() {
 	log.Println("StandardIO handler started - waiting for input")
+	h.router.startMQTT()
 	scanner := bufio.NewScanner(os.Stdin)
 	for scanner.Scan() {
 		input := scanner.Text()
@@ -57,701 +74,222 @@ func (h *StandardIOHandler) Start() {
 func (h *StandardIOHandler) HandleStandardIOInput(input string) {
 	var request Request
 	if err := json.Unmarshal([]byte(input), &request); err != nil {
-		h.sendErrorResponse("Invalid JSON request format", "")
+		h.sendResponse(errorResponse(types.NewInvalidRequestError("Invalid JSON request format"), ""))
 		return
 	}
 
 	log.Printf("Request received [%s]: %+v\n", request.RequestId, request)
+	h.sendResponse(h.router.dispatch(context.Background(), request))
+}
 
-	if request.Type != "request" {
-		h.sendErrorResponse("Invalid request type", request.RequestId)
-		return
-	}
-
-	requestDataMap, ok := request.Data.(map[string]interface{})
-	if !ok {
-		h.sendErrorResponse("Invalid request data format", request.RequestId)
-		return
-	}
-
-	urlParams, ok := requestDataMap["urlParams"].([]interface{})
-	if !ok {
-		h.sendErrorResponse("Invalid urlParams format", request.RequestId)
-		return
+func (h *StandardIOHandler) sendResponse(response Response) {
+	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
+		log.Printf("Error encoding response for request %s: %v\n", response.RequestId, err)
+		fallbackResponse := fmt.Sprintf(`{"type":"response","data":{"error":{"message":"Internal server error","code":"INTERNAL_ERROR"}},"requestId":"%s"}`, response.RequestId)
+		fmt.Println(fallbackResponse)
 	}
+}
 
-	parts := strings.Split(request.Endpoint, "/")
-	if len(parts) < 2 {
-		h.sendErrorResponse("Invalid endpoint", request.RequestId)
-		return
+// dispatch answers a request in the envelope of the standard IO and WebSocket transports
+// with the route of the REST API
+func (r *Router) dispatch(ctx context.Context, request Request) Response {
+	if request.Type != "request" {
+		return errorResponse(types.NewInvalidRequestError("Invalid request type"), request.RequestId)
 	}
 
-	response, err := h.processRequest(parts[1], request.Method, request, requestDataMap, urlParams)
+	httpRequest, err := newHTTPRequest(request)
 	if err != nil {
-		h.sendErrorResponse(err.Error(), request.RequestId)
-		return
+		return errorResponse(err, request.RequestId)
 	}
 
-	h.sendResponse(response, request.RequestId)
+	recorder := newResponseRecorder()
+	r.engine.ServeHTTP(recorder, httpRequest.WithContext(ctx))
+	return recordedResponse(recorder, request.RequestId)
 }
 
-func (h *StandardIOHandler) processRequest(endpoint string, method string, request Request, requestDataMap map[string]interface{}, urlParams []interface{}) (interface{}, error) {
-	switch endpoint {
-	case "/foodItems/check-and-insert":
-		barcode := requestDataMap["barcode"].(string)
-
-		err := h.foodService.CheckAndInsertFoodItem(barcode)
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{"message": "Food item inserted successfully", "exists": false}, nil
-	case "/foodItems/check-insert-and-consume":
-		barcode := requestDataMap["barcode"].(string)
-
-		var consumedRequest types.ConsumedFoodItemRequest
-		consumedRequest.Barcode = barcode
-
-		err := h.foodService.CheckInsertAndConsume(consumedRequest)
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{"message": "Food item checked/inserted and consumed successfully"}, nil
+// newHTTPRequest converts a standard IO request into the request of a route. The url
+// parameters are appended to the endpoint as path segments, parameters in the form
+// "key=value" are query parameters. The data is sent as JSON body, its plain values are
+// query parameters as well, e.g. the date or profile_id of GET requests.
+func newHTTPRequest(request Request) (*http.Request, error) {
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		return nil, types.NewInvalidRequestError("method is required")
+	}
+	if !strings.HasPrefix(request.Endpoint, "/") {
+		return nil, types.NewInvalidRequestError("invalid endpoint: %s", request.Endpoint)
+	}
 
-	case "/foodItems/check-insert-and-consume-batch":
-		// Extrahiere die Items aus der Anfrage
-		items, ok := requestDataMap["items"].([]interface{})
+	requestData := map[string]interface{}{}
+	if request.Data != nil {
+		dataMap, ok := request.Data.(map[string]interface{})
 		if !ok {
-			return nil, errors.New("invalid items format")
+			return nil, types.NewInvalidRequestError("invalid request data format")
 		}
-
-		// Erstelle die Batch-Anfrage
-		var batchRequest types.BatchConsumedFoodItemRequest
-
-		// Konvertiere jedes Item in der Liste
-		for _, item := range items {
-			itemMap, ok := item.(map[string]interface{})
-			if !ok {
-				return nil, errors.New("invalid item format in batch")
-			}
-
-			var consumedItem types.ConsumedFoodItemRequest
-
-			// Barcode ist erforderlich
-			barcode, ok := itemMap["barcode"].(string)
-			if !ok || barcode == "" {
-				return nil, errors.New("barcode is required for each item")
-			}
-			consumedItem.Barcode = barcode
-
-			// Weitere Felder, falls vorhanden
-			if quantity, ok := itemMap["consumed_quantity"].(float64); ok {
-				consumedItem.ConsumedQuantity = quantity
-			}
-
-			if date, ok := itemMap["date"].(string); ok {
-				consumedItem.Date = date
-			}
-
-			if profileID, ok := itemMap["profile_id"].(string); ok {
-				consumedItem.ProfileID = profileID
-			}
-
-			batchRequest.Items = append(batchRequest.Items, consumedItem)
-		}
-
-		// ForceSync-Parameter, falls vorhanden
-		if forceSync, ok := requestDataMap["force_sync"].(bool); ok {
-			batchRequest.ForceSync = forceSync
-		}
-
-		// Verarbeite die Batch-Anfrage
-		err := h.foodService.CheckInsertAndConsumeBatch(batchRequest)
-		if err != nil {
-			return nil, err
-		}
-
-		return map[string]interface{}{"message": "Food items batch checked/inserted and consumed successfully"}, nil
-
-	case "/foodItems/all":
-		foodItems, err := h.foodService.GetAllFoodItems()
-		if err != nil {
-			return nil, err
-		} else {
-			return foodItems, nil
-		}
-
-	case "/foodItems/manually-add":
-		requestData, err := json.Marshal(requestDataMap)
-		if err != nil {
-			return nil, err
-		}
-
-		var foodItemData data.PersistentFoodItem
-		if err := json.Unmarshal(requestData, &foodItemData); err != nil {
-			return nil, err
+		for key, value := range dataMap {
+			requestData[key] = value
 		}
+	}
 
-		err = h.foodService.ManuallyAddFoodItem(foodItemData)
-		if err != nil {
-			return nil, err
-		} else {
-			return map[string]interface{}{"message": "Food item added successfully"}, nil
-		}
-
-	case "/foodItems":
-		switch method {
-		case "PUT":
-			barcode, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid barcode")
-			}
-			updateData, ok := requestDataMap["data"].(map[string]interface{})
-			if !ok {
-				return nil, errors.New("invalid update data")
-			}
-
-			err := h.foodService.UpdateFoodItem(barcode, updateData)
-			if err != nil {
-				return nil, err
-			} else {
-				return map[string]interface{}{"message": "Food item updated successfully"}, nil
-			}
-		case "DELETE":
-			barcode, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid barcode")
-			}
-
-			err := h.foodService.DeleteFoodItem(barcode)
-			if err != nil {
-				return nil, err
-			} else {
-				return map[string]interface{}{"message": "Food item deleted successfully"}, nil
+	var urlParams []interface{}
+	if params, ok := requestData["urlParams"]; ok {
+		delete(requestData, "urlParams")
+		if params != nil {
+			if urlParams, ok = params.([]interface{}); !ok {
+				return nil, types.NewInvalidRequestError("invalid urlParams format")
 			}
-		default:
-			return nil, fmt.Errorf("unknown method: %s", method)
-		}
-
-	case "/foodItems/reset":
-		barcode, ok := urlParams[0].(string)
-		if !ok {
-			return nil, errors.New("invalid barcode")
-		}
-
-		err := h.foodService.ResetFoodItem(barcode)
-		if err != nil {
-			return nil, err
-		} else {
-			return map[string]interface{}{"message": "Food item reset successfully"}, nil
-		}
-
-	case "/foodItems/servingQuantity":
-		barcode, ok := requestDataMap["barcode"].(string)
-		if !ok {
-			return nil, errors.New("invalid barcode")
-		}
-
-		servingQuantity, err := h.foodService.GetServingQuantityByBarcode(barcode)
-		if err != nil {
-			return nil, err
-		} else {
-			return map[string]interface{}{"servingQuantity": servingQuantity}, nil
 		}
+	}
 
-	case "/foodItems/search":
-		query, ok := urlParams[0].(string)
+	path := "/api" + strings.TrimPrefix(strings.TrimSuffix(request.Endpoint, "/"), "/api")
+	query := url.Values{}
+	for _, param := range urlParams {
+		value, ok := plainValue(param)
 		if !ok {
-			return nil, errors.New("invalid query")
-		}
-
-		query = query[3:]
-		log.Printf("Searching for food items: %s\n", query)
-		results, err := h.foodService.SearchFoodItems(query)
-		if err != nil {
-			return nil, err
-		} else {
-			return results, nil
-		}
-
-	case "/consumedFoodItems":
-		switch method {
-		case "POST":
-			requestData, err := json.Marshal(requestDataMap)
-			if err != nil {
-				return nil, err
-			}
-
-			var consumedRequest types.ConsumedFoodItemRequest
-			if err := json.Unmarshal(requestData, &consumedRequest); err != nil {
-				return nil, err
-			}
-
-			if consumedRequest.ProfileID == "" {
-				return nil, errors.New("ProfileID is required")
-			}
-
-			err = h.foodService.PostConsumedFoodItem(consumedRequest)
-			if err != nil {
-				return nil, err
-			}
-			return map[string]interface{}{"message": "Food item consumed successfully"}, nil
-		case "DELETE":
-			id, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid id")
-			}
-			err := h.foodService.DeleteConsumedFoodItem(id)
-			if err != nil {
-				return nil, err
-			} else {
-				return map[string]interface{}{"message": "Food item deleted successfully"}, nil
-			}
-		case "GET":
-			dateStr := urlParams[0].(string)
-			profileID, ok := requestDataMap["ProfileID"].(string)
-			if !ok || profileID == "" {
-				return nil, errors.New("ProfileID is required")
-			}
-
-			consumedItems, err := h.foodService.GetConsumedFoodItemsByDate(dateStr, profileID)
-			if err != nil {
-				return nil, err
-			}
-			return consumedItems, nil
-		case "PUT":
-			var consumedRequest types.ConsumedFoodItemRequest
-
-			id, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid id")
-			}
-			requestData, err := json.Marshal(request.Data)
-			if err != nil {
-				return nil, err
-			}
-
-			if err := json.Unmarshal(requestData, &consumedRequest); err != nil {
-				return nil, err
-			}
-
-			err = h.foodService.UpdateConsumedFoodItem(id, requestDataMap)
-			if err != nil {
-				return nil, err
-			} else {
-				return map[string]interface{}{"message": "Food item updated successfully"}, nil
-			}
-		default:
-			return nil, fmt.Errorf("unknown method: %s", method)
-		}
-	case "/settings":
-		switch method {
-		case "GET":
-			profileID, ok := requestDataMap["ProfileID"].(string)
-			if !ok || profileID == "" {
-				return nil, errors.New("ProfileID is required")
-			}
-
-			settings, err := h.foodService.GetUserSettings(profileID)
-			if err != nil {
-				return nil, err
-			}
-			return settings, nil
-		case "POST":
-			requestData, err := json.Marshal(requestDataMap)
-			if err != nil {
-				return nil, err
-			}
-
-			var settingsRequest data.UserSettings
-			if err := json.Unmarshal(requestData, &settingsRequest); err != nil {
-				return nil, err
-			}
-
-			profileID, ok := requestDataMap["ProfileID"].(string)
-			if !ok || profileID == "" {
-				return nil, errors.New("ProfileID is required")
-			}
-
-			err = h.foodService.SaveUserSettings(settingsRequest, profileID)
-			if err != nil {
-				return nil, err
-			}
-			return map[string]interface{}{"message": "Settings saved successfully"}, nil
-		default:
-			return nil, fmt.Errorf("unknown method: %s", method)
+			return nil, types.NewInvalidRequestError("invalid url parameter")
 		}
-	case "/dishes":
-		switch method {
-		case "POST":
-			var dishRequest types.DishRequest
-			requestData, err := json.Marshal(request.Data)
-			if err != nil {
-				return nil, err
-			}
-
-			if err := json.Unmarshal(requestData, &dishRequest); err != nil {
-				return nil, err
-			}
-
-			err = h.foodService.CreateDish(dishRequest)
-			if err != nil {
-				return nil, err
-			} else {
-				return map[string]interface{}{"message": "Dish created successfully"}, nil
-			}
-		case "DELETE":
-			dishID, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid dish ID")
-			}
-
-			err := h.foodService.DeleteDish(dishID)
-			if err != nil {
-				return nil, err
-			} else {
-				return map[string]interface{}{"message": "Dish deleted successfully"}, nil
-			}
-		case "PUT":
-			dishID, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid dish ID")
-			}
-			var dishRequest types.DishRequest
-			requestData, err := json.Marshal(request.Data)
+		if strings.Contains(value, "=") {
+			values, err := url.ParseQuery(value)
 			if err != nil {
-				return nil, err
+				return nil, types.NewInvalidRequestError("invalid url parameter: %s", value)
 			}
-
-			if err := json.Unmarshal(requestData, &dishRequest); err != nil {
-				return nil, err
+			for key, list := range values {
+				query[key] = append(query[key], list...)
 			}
-
-			err = h.foodService.UpdateDish(dishID, dishRequest)
-			if err != nil {
-				return nil, err
-			} else {
-				return map[string]interface{}{"message": "Dish updated successfully"}, nil
-			}
-		case "GET":
-			if len(urlParams) == 0 {
-				dishes, err := h.foodService.GetAllDishes()
-				if err != nil {
-					return nil, err
-				} else {
-					if len(dishes) == 0 {
-						return map[string]interface{}{
-							"message": "No dishes found",
-							"dishes":  []data.DishWithDetailedItems{},
-						}, nil
-					} else {
-						return map[string]interface{}{"dishes": dishes}, nil
-					}
-				}
-			} else {
-				dishID, ok := urlParams[0].(string)
-				if !ok {
-					return nil, errors.New("invalid dish ID")
-				}
-
-				dish, items, err := h.foodService.GetDish(dishID)
-				if err != nil {
-					return nil, err
-				} else {
-					return map[string]interface{}{"dish": dish, "items": items}, nil
-				}
-			}
-		default:
-			return nil, fmt.Errorf("unknown method: %s", method)
+			continue
 		}
+		path += "/" + url.PathEscape(value)
+	}
 
-	case "/dishes/convert-to-food-item":
-		dishID, ok := urlParams[0].(string)
-		if !ok {
-			return nil, errors.New("invalid dish ID")
+	for key, value := range requestData {
+		if query.Has(key) {
+			continue
 		}
-
-		err := h.foodService.ConvertDishToFoodItem(dishID)
-		if err != nil {
-			return nil, err
-		} else {
-			return map[string]interface{}{"message": "Dish successfully converted to food item"}, nil
+		if plain, ok := plainValue(value); ok {
+			query.Set(key, plain)
 		}
+	}
 
-	case "/profiles":
-		switch method {
-		case "GET":
-			if len(urlParams) == 0 {
-				profiles, err := h.foodService.GetAllProfiles()
-				if err != nil {
-					return nil, err
-				}
-				return map[string]interface{}{"profiles": profiles}, nil
-			} else {
-				profileID, ok := urlParams[0].(string)
-				if !ok {
-					return nil, errors.New("invalid profile ID")
-				}
-				profile, err := h.foodService.GetProfile(profileID)
-				if err != nil {
-					return nil, err
-				}
-				return profile, nil
-			}
-
-		case "POST":
-			name, ok := requestDataMap["name"].(string)
-			if !ok || name == "" {
-				return nil, errors.New("profile name is required")
-			}
-
-			profileID, err := h.foodService.CreateProfile(name)
-			if err != nil {
-				return nil, err
-			}
-			return map[string]interface{}{"message": "Profile created successfully", "profileID": profileID}, nil
-
-		case "PUT":
-			profileID, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid profile ID")
-			}
-
-			name, ok := requestDataMap["name"].(string)
-			if !ok || name == "" {
-				return nil, errors.New("profile name is required")
-			}
-
-			err := h.foodService.UpdateProfile(profileID, name)
-			if err != nil {
-				return nil, err
-			}
-			return map[string]interface{}{"message": "Profile updated successfully"}, nil
-
-		case "DELETE":
-			profileID, ok := urlParams[0].(string)
-			if !ok {
-				return nil, errors.New("invalid profile ID")
-			}
-
-			err := h.foodService.DeleteProfile(profileID)
-			if err != nil {
-				return nil, err
-			}
-			return map[string]interface{}{"message": "Profile deleted successfully"}, nil
-		default:
-			return nil, fmt.Errorf("unknown method: %s", method)
-		}
-	case "/profiles/active":
-		switch method {
-		case "GET":
-			profileID := h.foodService.GetActiveProfile()
-
-			if profileID == "" {
-				return map[string]interface{}{
-					"profile_id": "",
-					"active":     false,
-				}, nil
-			}
-
-			// Hole die Profilinformationen, wenn ein aktives Profil vorhanden ist
-			profile, err := h.foodService.GetProfile(profileID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get active profile details: %v", err)
-			}
-
-			return map[string]interface{}{
-				"profile_id": profileID,
-				"name":       profile.Name,
-				"active":     true,
-			}, nil
-
-		case "POST":
-			profileID, ok := requestDataMap["ProfileID"].(string)
-			if !ok || profileID == "" {
-				return nil, errors.New("ProfileID is required")
-			}
-
-			err := h.foodService.SetActiveProfile(profileID)
-			if err != nil {
-				return nil, err
-			}
-
-			return map[string]interface{}{"message": "Active profile set successfully"}, nil
-
-		default:
-			return nil, fmt.Errorf("unknown method: %s", method)
-		}
-	case "/dropbox/status":
-		if method != "GET" {
-			return nil, fmt.Errorf("method %s not allowed for %s", method, endpoint)
-		}
-		isAuthenticated, err := h.foodService.GetAuthenticationStatus()
+	var body io.Reader
+	if method != http.MethodGet && method != http.MethodHead && len(requestData) > 0 {
+		encoded, err := json.Marshal(requestData)
 		if err != nil {
-			return nil, err
+			return nil, types.NewInvalidRequestError("invalid request data format")
 		}
-		return map[string]interface{}{"isAuthenticated": isAuthenticated}, nil
+		body = bytes.NewReader(encoded)
+	}
 
-	case "/dropbox/token":
-		if method != "POST" {
-			return nil, fmt.Errorf("method %s not allowed for %s", method, endpoint)
-		}
-		code, ok := requestDataMap["code"].(string)
-		if !ok || code == "" {
-			return nil, errors.New("code is required")
-		}
-		codeVerifier, ok := requestDataMap["codeVerifier"].(string)
-		if !ok || codeVerifier == "" {
-			return nil, errors.New("codeVerifier is required")
-		}
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	httpRequest, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, types.NewInvalidRequestError("invalid request: %v", err)
+	}
+	if body != nil {
+		httpRequest.Header.Set("Content-Type", "application/json")
+	}
+	return httpRequest, nil
+}
 
-		_, err := h.foodService.ExchangeToken(code, codeVerifier)
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{"message": "Token exchange successful"}, nil
+// plainValue formats strings, numbers and booleans as parameter value
+func plainValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
 
-	case "/dropbox/logout":
-		if method != "POST" {
-			return nil, fmt.Errorf("method %s not allowed for %s", method, endpoint)
-		}
-		err := h.foodService.Logout()
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{"message": "Logged out successfully"}, nil
+// responseRecorder captures the response of a route dispatched for a standard IO request
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
 
-	case "/dropbox/upload-database":
-		if method != "POST" {
-			return nil, fmt.Errorf("method %s not allowed for %s", method, endpoint)
-		}
-		result, err := h.foodService.UploadDatabase()
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{
-			"success": result.Success,
-			"status":  result.Status,
-		}, nil
-
-	case "/dropbox/download-database":
-		if method != "GET" {
-			return nil, fmt.Errorf("method %s not allowed for %s", method, endpoint)
-		}
-		result, err := h.foodService.DownloadDatabase()
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{
-			"success": result.Success,
-			"status":  result.Status,
-		}, nil
-
-	case "/dropbox/sync":
-		if method != "POST" {
-			return nil, fmt.Errorf("method %s not allowed for %s", method, endpoint)
-		}
-		force, ok := requestDataMap["force"].(bool)
-		if !ok {
-			force = false
-		}
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: http.Header{}, status: http.StatusOK}
+}
 
-		err := h.foodService.SyncToDropbox(force)
-		if err != nil {
-			return nil, err
-		}
-		return nil, nil
-
-	case "/dropbox/autosync":
-		switch method {
-		case "GET":
-			return map[string]interface{}{"enabled": h.foodService.GetAutoSync()}, nil
-		case "POST":
-			enabled, ok := requestDataMap["enabled"].(bool)
-			if !ok {
-				return nil, errors.New("invalid request: enabled must be a boolean")
-			}
-			err := h.foodService.SetAutoSync(enabled)
-			if err != nil {
-				return nil, err
-			}
-			return map[string]interface{}{"message": "Auto-sync setting updated"}, nil
-		default:
-			return nil, fmt.Errorf("method %s not allowed for endpoint %s", method, endpoint)
-		}
+func (w *responseRecorder) Header() http.Header {
+	return w.header
+}
 
-	case "/settings/weighttracking":
-		switch method {
-		case "GET":
-			return map[string]interface{}{"enabled": h.foodService.GetWeightTracking()}, nil
-		case "POST":
-			enabled, ok := requestDataMap["enabled"].(bool)
-			if !ok {
-				return nil, errors.New("invalid request: enabled must be a boolean")
-			}
-			err := h.foodService.SetWeightTracking(enabled)
-			if err != nil {
-				return nil, err
-			}
-			return map[string]interface{}{"message": "Weight tracking setting updated"}, nil
-		default:
-			return nil, fmt.Errorf("method %s not allowed for endpoint %s", method, endpoint)
-		}
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
 
-	case "/scanners":
-		if method != "GET" {
-			return nil, fmt.Errorf("method %s not allowed for endpoint %s", method, endpoint)
-		}
-		scanners, err := h.foodService.ListScanners()
-		if err != nil {
-			return nil, err
-		}
-		return scanners, nil
+func (w *responseRecorder) WriteHeader(status int) {
+	w.status = status
+}
 
-	case "/scanners/active":
-		if method != "POST" {
-			return nil, fmt.Errorf("method %s not allowed for endpoint %s", method, endpoint)
+// recordedResponse returns the response of a route. Error statuses are returned as error
+// response with the message of the response body.
+func recordedResponse(recorder *responseRecorder, requestId string) Response {
+	var data interface{}
+	if recorder.body.Len() > 0 {
+		if err := json.Unmarshal(recorder.body.Bytes(), &data); err != nil {
+			data = strings.TrimSpace(recorder.body.String())
 		}
-		devicePath, ok := requestDataMap["path"].(string)
-		if !ok {
-			return nil, errors.New("invalid device path")
-		}
-		err := h.foodService.SetActiveScanner(devicePath)
-		if err != nil {
-			return nil, err
-		}
-		return map[string]interface{}{"message": "Active scanner set"}, nil
+	}
 
-	default:
-		return nil, fmt.Errorf("unknown endpoint: %s", endpoint)
+	if recorder.status >= http.StatusBadRequest {
+		return errorResponse(recordedError(recorder), requestId)
 	}
-}
 
-func (h *StandardIOHandler) sendResponse(data interface{}, requestId string) {
 	response := Response{
 		Type:      "response",
 		Data:      data,
 		RequestId: requestId,
 	}
-	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
-		log.Printf("Error encoding response for request %s: %v\n", requestId, err)
+	if total, err := strconv.Atoi(recorder.header.Get(totalCountHeader)); err == nil {
+		response.Total = &total
 	}
+	return response
 }
 
-func (h *StandardIOHandler) sendErrorResponse(message string, requestId string) {
-	response := Response{
+// recordedError returns the error of an error response of a route, see writeError
+func recordedError(recorder *responseRecorder) *types.Error {
+	var response types.ApiResponse
+	if err := json.Unmarshal(recorder.body.Bytes(), &response); err != nil || response.Error == "" {
+		response.Error = http.StatusText(recorder.status)
+	}
+	if response.Code == "" {
+		response.Code = types.ErrorCodeInternal
+		for code, status := range errorStatus {
+			if status == recorder.status && code != types.ErrorCodeValidation && code != types.ErrorCodeSyncConflict {
+				response.Code = code
+				break
+			}
+		}
+	}
+	return &types.Error{Code: response.Code, Message: response.Error, Field: response.Field}
+}
+
+// errorResponse returns an error with the same code as the error responses of the REST API
+func errorResponse(err error, requestId string) Response {
+	code := types.ErrorCodeOf(err)
+	errorData := map[string]interface{}{
+		"message": err.Error(),
+		"code":    code,
+		"status":  errorStatus[code],
+	}
+	if field := types.ErrorField(err); field != "" {
+		errorData["field"] = field
+	}
+
+	return Response{
 		Type: "response",
 		Data: map[string]interface{}{
-			"error": map[string]string{
-				"message": message,
-				"code":    "BACKEND_ERROR",
-			},
+			"error": errorData,
 		},
 		RequestId: requestId,
 	}
-
-	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
-		log.Printf("Error encoding error response for request %s: %v\n", requestId, err)
-		fallbackResponse := fmt.Sprintf(`{"type":"response","data":{"error":{"message":"Internal server error","code":"INTERNAL_ERROR"}},"requestId":"%s"}`, requestId)
-		fmt.Println(fallbackResponse)
-	}
 }
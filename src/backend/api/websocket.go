@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	webSocketWriteTimeout = 10 * time.Second
+	webSocketPingInterval = 30 * time.Second
+	webSocketPongTimeout  = 2 * webSocketPingInterval // Read deadline, extended by every pong
+	webSocketMaxMessage   = 1 << 20                   // Maximum size of a request in bytes
+	webSocketMaxRequests  = 8                         // Maximum number of concurrent requests of a connection
+)
+
+var webSocketUpgrader = websocket.Upgrader{
+	// Apps and devices don't send an Origin, browsers need an allowed one like for CORS
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || isAllowedOrigin(origin)
+	},
+}
+
+// webSocketEvent is the message of a pushed event, like the events on stdout
+type webSocketEvent struct {
+	Type string          `json:"type"`
+	Data messaging.Event `json:"data"`
+}
+
+// webSocketConnection serializes the writes of the responses and events of a connection
+type webSocketConnection struct {
+	conn       *websocket.Conn
+	writeMutex sync.Mutex
+}
+
+func (c *webSocketConnection) write(message interface{}) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	return c.conn.WriteJSON(message)
+}
+
+func (c *webSocketConnection) ping() error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	return c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(webSocketWriteTimeout))
+}
+
+// serveWebSocket carries requests and change events on one connection. Requests use the
+// envelope of the standard IO transport and are answered with a response of the same
+// requestId, possibly out of order. At most webSocketMaxRequests requests run at a time,
+// further ones are rejected as RATE_LIMITED. Events are pushed like on stdout as
+// {"type": "sse-message", "data": <event>}, selected by the same query parameters as on
+// /api/sse.
+func (r *Router) serveWebSocket(c *gin.Context) {
+	subscription, ok := subscribeToEvents(c)
+	if !ok {
+		return
+	}
+	defer subscription.Close()
+
+	conn, err := webSocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has written the error response
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+	log.Printf("WebSocket client connected from %s", c.Request.RemoteAddr)
+
+	// Requests still running when the client disconnects are cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	connection := &webSocketConnection{conn: conn}
+	running := make(chan struct{}, webSocketMaxRequests)
+	go r.pushWebSocketEvents(ctx, connection, subscription)
+
+	conn.SetReadLimit(webSocketMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(webSocketPongTimeout))
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("WebSocket read failed: %v", err)
+			}
+			log.Printf("WebSocket client disconnected from %s", c.Request.RemoteAddr)
+			return
+		}
+
+		var request Request
+		if err := json.Unmarshal(message, &request); err != nil {
+			connection.write(errorResponse(types.NewInvalidRequestError("Invalid JSON request format"), ""))
+			continue
+		}
+
+		// Rejecting instead of waiting keeps the loop reading pongs and close messages
+		select {
+		case running <- struct{}{}:
+		default:
+			connection.write(errorResponse(types.NewRateLimitedError("Too many concurrent requests, at most %d are allowed", webSocketMaxRequests), request.RequestId))
+			continue
+		}
+
+		// Slow requests like product lookups don't hold up the following ones
+		go func() {
+			defer func() { <-running }()
+			if err := connection.write(r.dispatch(ctx, request)); err != nil {
+				log.Printf("Failed to send WebSocket response for request %s: %v", request.RequestId, err)
+			}
+		}()
+	}
+}
+
+// pushWebSocketEvents writes the events of the subscription and pings the client until ctx
+// is cancelled
+func (r *Router) pushWebSocketEvents(ctx context.Context, connection *webSocketConnection, subscription *messaging.Subscription) {
+	ping := time.NewTicker(webSocketPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-subscription.Notify():
+			for _, event := range subscription.Next() {
+				if err := connection.write(webSocketEvent{Type: "sse-message", Data: event}); err != nil {
+					// Ends the read loop of serveWebSocket
+					log.Printf("Failed to send WebSocket event: %v", err)
+					connection.conn.Close()
+					return
+				}
+			}
+		case <-ping.C:
+			if err := connection.ping(); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
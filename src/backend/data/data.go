@@ -1,12 +1,15 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"nutrack/backend/messaging"
 	"nutrack/backend/settings"
+	"nutrack/backend/types"
 	"os"
+	"strings"
 	"time"
 
 	_ "github.com/glebarez/go-sqlite"
@@ -36,7 +39,7 @@ func TestISO8601DateFormat() (string, error) {
 	}
 
 	// Insert the test item
-	err := InsertFoodItem(testItem)
+	err := NewRepository().InsertFoodItem(context.Background(), testItem)
 	if err != nil {
 		return "", fmt.Errorf("failed to insert test item: %v", err)
 	}
@@ -62,16 +65,27 @@ func TestISO8601DateFormat() (string, error) {
 }
 
 type PersistentFoodItem struct {
-	Barcode             string    `json:"barcode"`
-	Name                string    `json:"name"`
-	CaloriesPer100g     float64   `json:"energy-kcal_100g"`
-	ProteinPer100g      float64   `json:"proteins_100g"`
-	CarbsPer100g        float64   `json:"carbohydrates_100g"`
-	FatPer100g          float64   `json:"fat_100g"`
-	ServingQuantity     float64   `json:"serving_quantity"`
-	ServingQuantityUnit string    `json:"serving_quantity_unit"`
-	CreatedAt           time.Time `json:"created_at"`
-	LastUpdated         time.Time `json:"last_updated"`
+	Barcode             string        `json:"barcode"`
+	Name                string        `json:"name"`
+	CaloriesPer100g     float64       `json:"energy-kcal_100g"`
+	ProteinPer100g      float64       `json:"proteins_100g"`
+	CarbsPer100g        float64       `json:"carbohydrates_100g"`
+	FatPer100g          float64       `json:"fat_100g"`
+	ServingQuantity     float64       `json:"serving_quantity"`
+	ServingQuantityUnit string        `json:"serving_quantity_unit"`
+	SourceDishID        string        `json:"source_dish_id,omitempty"` // Set if the food item was converted from a dish
+	Density             *float64      `json:"density,omitempty"`        // Optional density in g/ml for converting between g and ml
+	Portions            []FoodPortion `json:"portions,omitempty"`       // Named portions like "slice" or "cup"
+	Tags                []string      `json:"tags,omitempty"`           // User-defined tags like "dairy" or "snacks"
+	CreatedAt           time.Time     `json:"created_at"`
+	LastUpdated         time.Time     `json:"last_updated"`
+}
+
+// FoodPortion is a named portion of a food item, e.g. 1 slice = 28 g
+type FoodPortion struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"` // Amount of one portion in Unit
+	Unit     string  `json:"unit"`     // "g" or "ml"
 }
 
 type ConsumedFoodItem struct {
@@ -84,18 +98,31 @@ type ConsumedFoodItem struct {
 }
 
 type ConsumedFoodItemWithDetails struct {
-	ID                  string  `json:"id"`
-	Barcode             string  `json:"barcode"`
-	Name                string  `json:"name"`
-	ConsumedQuantity    float64 `json:"consumed_quantity"`
-	Date                string  `json:"date"`
-	InsertDate          string  `json:"insert_date"`
-	CaloriesPer100g     float64 `json:"calories_per_100g"`
-	ProteinPer100g      float64 `json:"protein_per_100g"`
-	CarbsPer100g        float64 `json:"carbs_per_100g"`
-	FatPer100g          float64 `json:"fat_per_100g"`
-	ServingQuantity     float64 `json:"serving_quantity"`
-	ServingQuantityUnit string  `json:"serving_quantity_unit"`
+	ID                  string                   `json:"id"`
+	Barcode             string                   `json:"barcode"`
+	Name                string                   `json:"name"`
+	ConsumedQuantity    float64                  `json:"consumed_quantity"`
+	Date                string                   `json:"date"`
+	InsertDate          string                   `json:"insert_date"`
+	CaloriesPer100g     float64                  `json:"calories_per_100g"`
+	ProteinPer100g      float64                  `json:"protein_per_100g"`
+	CarbsPer100g        float64                  `json:"carbs_per_100g"`
+	FatPer100g          float64                  `json:"fat_per_100g"`
+	ServingQuantity     float64                  `json:"serving_quantity"`
+	ServingQuantityUnit string                   `json:"serving_quantity_unit"`
+	DishID              string                   `json:"dish_id,omitempty"`     // Set if the entry was logged from a dish
+	Ingredients         []ConsumedDishIngredient `json:"ingredients,omitempty"` // Ingredient breakdown of a logged dish
+}
+
+// ConsumedDishIngredient is the snapshot of an ingredient of a dish at the time the dish was logged
+type ConsumedDishIngredient struct {
+	Barcode         string  `json:"barcode"`
+	Name            string  `json:"name"`
+	Quantity        float64 `json:"quantity"` // in the serving quantity unit of the food item
+	CaloriesPer100g float64 `json:"calories_per_100g"`
+	ProteinPer100g  float64 `json:"protein_per_100g"`
+	CarbsPer100g    float64 `json:"carbs_per_100g"`
+	FatPer100g      float64 `json:"fat_per_100g"`
 }
 
 type UserSettings struct {
@@ -111,32 +138,100 @@ type UserSettings struct {
 	WeeklyWeightChange float64 `json:"weekly_weight_change"`
 }
 
+// GoalStrategy defines how the daily targets of a profile are calculated
+type GoalStrategy struct {
+	Preset            string      `json:"preset"`                        // "balanced", "high-protein", "keto" or "custom"
+	BMRFormula        string      `json:"bmr_formula"`                   // "mifflin-st-jeor", "harris-benedict" or "katch-mcardle"
+	BodyFatPercentage *float64    `json:"body_fat_percentage,omitempty"` // Required for Katch-McArdle
+	Protein           MacroTarget `json:"protein"`                       // Only used for the custom preset
+	Fat               MacroTarget `json:"fat"`                           // Only used for the custom preset
+	Carbs             MacroTarget `json:"carbs"`                         // Only used for the custom preset
+	MinCarbs          float64     `json:"min_carbs"`                     // Minimum carbs in g per day, 0 for no minimum
+}
+
+// MacroTarget defines the target of one macronutrient
+type MacroTarget struct {
+	Mode  string  `json:"mode"`  // "percent" of calories, "g_per_kg" body weight, "grams" or "rest" of the calories
+	Value float64 `json:"value"` // Unused for "rest"
+}
+
+// TargetHistoryEntry contains the daily targets a profile had from the effective date on
+type TargetHistoryEntry struct {
+	EffectiveDate string  `json:"effective_date"`
+	Calories      float64 `json:"calories"`
+	Proteins      float64 `json:"proteins"`
+	Carbs         float64 `json:"carbs"`
+	Fat           float64 `json:"fat"`
+}
+
+// TargetOverride replaces the daily targets on certain weekdays, in a date range or both.
+// Targets which are not set are taken from the user settings.
+type TargetOverride struct {
+	ID        string    `json:"id"`
+	ProfileID string    `json:"profile_id"`
+	Name      string    `json:"name"`
+	Weekdays  []int     `json:"weekdays,omitempty"`   // 0 = Sunday to 6 = Saturday, empty for every day
+	StartDate string    `json:"start_date,omitempty"` // Optional first day (YYYY-MM-DD)
+	EndDate   string    `json:"end_date,omitempty"`   // Optional last day (YYYY-MM-DD)
+	Calories  *float64  `json:"calories,omitempty"`
+	Proteins  *float64  `json:"proteins,omitempty"`
+	Carbs     *float64  `json:"carbs,omitempty"`
+	Fat       *float64  `json:"fat,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 type Dish struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Barcode     *string   `json:"barcode,omitempty"` // Optional Barcode
-	CreatedAt   time.Time `json:"created_at"`
-	LastUpdated time.Time `json:"last_updated"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Barcode      *string   `json:"barcode,omitempty"`       // Optional Barcode
+	CookedWeight *float64  `json:"cooked_weight,omitempty"` // Optional measured weight of the cooked dish in g
+	Portions     *int      `json:"portions,omitempty"`      // Optional number of portions the dish yields
+	Tags         []string  `json:"tags,omitempty"`          // User-defined tags like "homemade"
+	CreatedAt    time.Time `json:"created_at"`
+	LastUpdated  time.Time `json:"last_updated"`
 }
 
 type DishItem struct {
 	DishID   string  `json:"dish_id"`
 	Barcode  string  `json:"barcode"`
 	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"` // Unit of the quantity, defaults to the serving quantity unit of the food item
 }
 
 type DetailedDishItem struct {
 	Items    PersistentFoodItem `json:"food_item"`
 	Quantity float64            `json:"quantity"`
+	Unit     string             `json:"unit,omitempty"`
 }
 
 type DishWithDetailedItems struct {
-	ID          string             `json:"id"`
-	Name        string             `json:"name"`
-	Barcode     *string            `json:"barcode,omitempty"`
-	CreatedAt   time.Time          `json:"created_at"`
-	LastUpdated time.Time          `json:"last_updated"`
-	Items       []DetailedDishItem `json:"dish_items"`
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	Barcode      *string            `json:"barcode,omitempty"`
+	CookedWeight *float64           `json:"cooked_weight,omitempty"`
+	Portions     *int               `json:"portions,omitempty"`
+	Tags         []string           `json:"tags,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	LastUpdated  time.Time          `json:"last_updated"`
+	Items        []DetailedDishItem `json:"dish_items"`
+}
+
+// DishNutrition contains the nutrition values of a dish based on its yield
+type DishNutrition struct {
+	DishID             string  `json:"dish_id"`
+	Name               string  `json:"name"`
+	RawWeight          float64 `json:"raw_weight"`    // Sum of all ingredient weights in g
+	CookedWeight       float64 `json:"cooked_weight"` // Measured cooked weight, falls back to the raw weight
+	Portions           int     `json:"portions"`
+	PortionWeight      float64 `json:"portion_weight"`
+	CaloriesPer100g    float64 `json:"calories_per_100g"`
+	ProteinPer100g     float64 `json:"protein_per_100g"`
+	CarbsPer100g       float64 `json:"carbs_per_100g"`
+	FatPer100g         float64 `json:"fat_per_100g"`
+	CaloriesPerPortion float64 `json:"calories_per_portion"`
+	ProteinPerPortion  float64 `json:"protein_per_portion"`
+	CarbsPerPortion    float64 `json:"carbs_per_portion"`
+	FatPerPortion      float64 `json:"fat_per_portion"`
 }
 
 type Profile struct {
@@ -149,6 +244,13 @@ func InitDatabase() {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
+	if err := setupDatabase(db); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// setupDatabase creates the tables of a new database and migrates the tables of an older one
+func setupDatabase(db *sql.DB) error {
 	_, err := db.Exec(`
 	CREATE TABLE IF NOT EXISTS profiles (
 		id VARCHAR(36) PRIMARY KEY,
@@ -157,7 +259,7 @@ func InitDatabase() {
 	)
 	`)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	_, err = db.Exec(`
@@ -175,7 +277,32 @@ func InitDatabase() {
 		)
 	`)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS food_portions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		barcode TEXT NOT NULL,
+		name TEXT NOT NULL,
+		quantity REAL NOT NULL,
+		unit TEXT NOT NULL,
+		UNIQUE (barcode, name)
+	)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS food_tags (
+		barcode TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (barcode, tag)
+	)
+	`)
+	if err != nil {
+		return err
 	}
 
 	_, err = db.Exec(`
@@ -191,7 +318,7 @@ func InitDatabase() {
     )
     `)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	// Create index on date column
@@ -199,7 +326,31 @@ func InitDatabase() {
     CREATE INDEX IF NOT EXISTS idx_consumed_food_items_date ON consumedFoodItems(date)
     `)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS consumedDishIngredients (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		consumed_id TEXT NOT NULL,
+		barcode TEXT NOT NULL,
+		name TEXT,
+		quantity REAL NOT NULL,
+		kcalPer100g REAL,
+		proteinPer100g REAL,
+		carbsPer100g REAL,
+		fatPer100g REAL,
+		FOREIGN KEY (consumed_id) REFERENCES consumedFoodItems(id) ON DELETE CASCADE
+	)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE INDEX IF NOT EXISTS idx_consumed_dish_ingredients_consumed_id ON consumedDishIngredients(consumed_id)`)
+	if err != nil {
+		return err
 	}
 
 	_, err = db.Exec(`
@@ -219,7 +370,101 @@ func InitDatabase() {
 	)
     `)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS goalStrategies (
+		profile_id VARCHAR(36) PRIMARY KEY,
+		preset TEXT NOT NULL,
+		bmr_formula TEXT NOT NULL,
+		body_fat_percentage REAL,
+		protein_mode TEXT NOT NULL DEFAULT '',
+		protein_value REAL NOT NULL DEFAULT 0,
+		fat_mode TEXT NOT NULL DEFAULT '',
+		fat_value REAL NOT NULL DEFAULT 0,
+		carbs_mode TEXT NOT NULL DEFAULT '',
+		carbs_value REAL NOT NULL DEFAULT 0,
+		min_carbs REAL NOT NULL DEFAULT 0,
+		FOREIGN KEY (profile_id) REFERENCES profiles(id)
+	)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS mealPlanEntries (
+		id TEXT PRIMARY KEY,
+		profile_id VARCHAR(36) NOT NULL,
+		date TEXT NOT NULL,
+		meal TEXT NOT NULL,
+		barcode TEXT,
+		dish_id TEXT,
+		quantity REAL NOT NULL,
+		consumed_id TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (profile_id) REFERENCES profiles(id)
+	)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE INDEX IF NOT EXISTS idx_meal_plan_entries_profile_date ON mealPlanEntries(profile_id, date)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS favoriteFoods (
+		profile_id VARCHAR(36) NOT NULL,
+		barcode TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (profile_id, barcode),
+		FOREIGN KEY (profile_id) REFERENCES profiles(id)
+	)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS targetHistory (
+		profile_id VARCHAR(36) NOT NULL,
+		effective_date TEXT NOT NULL,
+		calories REAL NOT NULL,
+		proteins REAL NOT NULL,
+		carbs REAL NOT NULL,
+		fat REAL NOT NULL,
+		PRIMARY KEY (profile_id, effective_date),
+		FOREIGN KEY (profile_id) REFERENCES profiles(id)
+	)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS targetOverrides (
+		id TEXT PRIMARY KEY,
+		profile_id VARCHAR(36) NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		weekdays TEXT NOT NULL DEFAULT '',
+		start_date TEXT,
+		end_date TEXT,
+		calories REAL,
+		proteins REAL,
+		carbs REAL,
+		fat REAL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (profile_id) REFERENCES profiles(id)
+	)
+	`)
+	if err != nil {
+		return err
 	}
 
 	_, err = db.Exec(`
@@ -232,7 +477,7 @@ func InitDatabase() {
     )
     `)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	_, err = db.Exec(`
@@ -246,7 +491,19 @@ func InitDatabase() {
 	)
 	`)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS dish_tags (
+		dish_id TEXT NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (dish_id, tag),
+		FOREIGN KEY (dish_id) REFERENCES dishes(id)
+	)
+	`)
+	if err != nil {
+		return err
 	}
 
 	_, err = db.Exec(`
@@ -259,31 +516,62 @@ func InitDatabase() {
         )
     `)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	_, err = db.Exec(`
 	CREATE INDEX IF NOT EXISTS idx_weight_tracking_created_at ON weight_tracking(created_at)`)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	_, err = db.Exec(`
 	CREATE INDEX IF NOT EXISTS idx_weight_tracking_profile_id ON weight_tracking(profile_id)`)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE TABLE IF NOT EXISTS webhookDeliveries (
+		id TEXT PRIMARY KEY,
+		webhook_id TEXT NOT NULL,
+		event TEXT NOT NULL,
+		event_seq INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT '',
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhookDeliveries(created_at)`)
+	if err != nil {
+		return err
 	}
 
-	err = MigrateDatabase()
+	err = migrateDatabase(db)
 	if err != nil {
-		log.Fatalf("Failed to migrate database: %v", err)
+		return fmt.Errorf("failed to migrate database: %v", err)
 	}
+
+	checkForeignKeys(db)
+	return nil
 }
 
 func MigrateDatabase() error {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
+	return migrateDatabase(db)
+}
+
+func migrateDatabase(db *sql.DB) error {
 	// Start a transaction
 	tx, err := db.Begin()
 	if err != nil {
@@ -327,7 +615,7 @@ func MigrateDatabase() error {
 	}
 
 	// Run all pending migrations
-	err = RunMigrations()
+	err = runMigrations(db)
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %v", err)
 	}
@@ -335,15 +623,6 @@ func MigrateDatabase() error {
 	return nil
 }
 
-func OpenDataBase() *sql.DB {
-	dbPath := GetDBPath()
-	db, err := sql.Open("sqlite", dbPath)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return db
-}
-
 func GetDBPath() string {
 	// Check if running in container
 	if os.Getenv("CONTAINER") == "true" {
@@ -358,26 +637,40 @@ func GetDBPath() string {
 
 }
 
-func CloseDataBase(db *sql.DB) {
-	db.Close()
+// dbExecutor is implemented by *sql.DB and *sql.Tx, so helpers can run inside or outside of a transaction
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
-// BroadcastMessage sends a message to all connected clients,
-// using either SSE or StandardIO depending on the configuration
+// profileIDOf returns the profile of an entry of a per-profile table like consumedFoodItems,
+// so change events can name it. It returns "" if there is no entry with the ID.
+func profileIDOf(db dbExecutor, table string, id string) (string, error) {
+	var profileID string
+	err := db.QueryRow("SELECT profile_id FROM "+table+" WHERE id = ?", id).Scan(&profileID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to get profile of %s entry: %v", table, err)
+	}
+	return profileID, nil
+}
 
-func InsertFoodItem(item PersistentFoodItem) error {
+// InsertFoodItem inserts a food item with its portions and tags
+func (r *Repository) InsertFoodItem(ctx context.Context, item PersistentFoodItem) error {
 	// check if barcode is empty
 	if item.Barcode == "" {
 		println("Barcode is required")
-		return fmt.Errorf("barcode is required")
+		return types.NewValidationError("barcode", "barcode is required")
 	}
 
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+	db, release := r.executor(ctx)
+	defer release()
 
 	query := `
-    INSERT INTO foodItems (barcode, name, kcalPer100g, fatPer100g, carbsPer100g, proteinPer100g, servingQuantity, servingQuantityUnit, created_at, last_updated)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    INSERT INTO foodItems (barcode, name, kcalPer100g, fatPer100g, carbsPer100g, proteinPer100g, servingQuantity, servingQuantityUnit, density, created_at, last_updated)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
 	now := time.Now()
@@ -395,6 +688,7 @@ func InsertFoodItem(item PersistentFoodItem) error {
 		item.ProteinPer100g,
 		item.ServingQuantity,
 		item.ServingQuantityUnit,
+		item.Density,
 		createdAtISO,
 		lastUpdatedISO,
 	)
@@ -403,21 +697,27 @@ func InsertFoodItem(item PersistentFoodItem) error {
 		return fmt.Errorf("failed to insert food item: %v", err)
 	}
 
-	println("Inserted food item: "+item.Name, item.Barcode, item.CaloriesPer100g, item.FatPer100g, item.CarbsPer100g, item.ProteinPer100g, item.ServingQuantity, item.ServingQuantityUnit)
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
+	if err := insertFoodPortions(db, item.Barcode, item.Portions); err != nil {
+		return err
 	}
-	messaging.BroadcastMessage("food_items_updated")
+
+	if err := insertFoodTags(db, item.Barcode, item.Tags); err != nil {
+		return err
+	}
+
+	println("Inserted food item: "+item.Name, item.Barcode, item.CaloriesPer100g, item.FatPer100g, item.CarbsPer100g, item.ProteinPer100g, item.ServingQuantity, item.ServingQuantityUnit)
+	r.changed(messaging.EntityFoodItem, messaging.ActionCreated, "", item.Barcode)
 	return nil
 }
 
-func CheckFoodItemExists(item PersistentFoodItem) (bool, error) {
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+// FoodItemExists checks if a food item with the barcode exists
+func (r *Repository) FoodItemExists(ctx context.Context, barcode string) (bool, error) {
+	db, release := r.executor(ctx)
+	defer release()
 
 	// Prüfen, ob der Barcode bereits existiert
 	var exists bool
-	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", item.Barcode).Scan(&exists)
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", barcode).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("error checking if barcode exists: %v", err)
 	}
@@ -425,7 +725,7 @@ func CheckFoodItemExists(item PersistentFoodItem) (bool, error) {
 	return exists, nil
 }
 
-func GetFoodItemByBarcode(db *sql.DB, barcode string) (PersistentFoodItem, error) {
+func GetFoodItemByBarcode(db dbExecutor, barcode string) (PersistentFoodItem, error) {
 	query := `
     SELECT 
         barcode, 
@@ -435,7 +735,9 @@ func GetFoodItemByBarcode(db *sql.DB, barcode string) (PersistentFoodItem, error
         COALESCE(carbsPer100g, 0) as carbsPer100g, 
         COALESCE(proteinPer100g, 0) as proteinPer100g, 
         COALESCE(servingQuantity, 0) as servingQuantity, 
-        COALESCE(servingQuantityUnit, '') as servingQuantityUnit
+        COALESCE(servingQuantityUnit, '') as servingQuantityUnit,
+        COALESCE(source_dish_id, '') as source_dish_id,
+        density
     FROM foodItems
     WHERE barcode = ?
     `
@@ -450,22 +752,55 @@ func GetFoodItemByBarcode(db *sql.DB, barcode string) (PersistentFoodItem, error
 		&item.ProteinPer100g,
 		&item.ServingQuantity,
 		&item.ServingQuantityUnit,
+		&item.SourceDishID,
+		&item.Density,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return PersistentFoodItem{}, fmt.Errorf("no food item found with barcode %s", barcode)
+			return PersistentFoodItem{}, types.NewNotFoundError("no food item found with barcode %s", barcode)
 		}
 		return PersistentFoodItem{}, fmt.Errorf("failed to query food item: %v", err)
 	}
 
+	portions, err := getFoodPortions(db, barcode)
+	if err != nil {
+		return PersistentFoodItem{}, err
+	}
+	item.Portions = portions[barcode]
+
+	tags, err := getFoodTags(db, barcode)
+	if err != nil {
+		return PersistentFoodItem{}, err
+	}
+	item.Tags = tags[barcode]
+
 	return item, nil
 }
 
-func GetAllFoodItems() ([]PersistentFoodItem, error) {
+// GetAllFoodItems returns a page of the food items matching the filters of the options and the
+// total number of matching food items. By default the newest food items come first.
+func GetAllFoodItems(options ListOptions) ([]PersistentFoodItem, int, error) {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
+	var filter listFilter
+	if options.Unit != "" {
+		filter.add("servingQuantityUnit = ?", options.Unit)
+	}
+	filter.addIncomplete(options.Incomplete, incompleteFoodItemCondition)
+	filter.addCreatedRange(options, "created_at")
+	if options.Tag != "" {
+		filter.add("barcode IN (SELECT barcode FROM food_tags WHERE tag = ?)", options.Tag)
+	}
+
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM foodItems"+filter.where(), filter.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count food items: %v", err)
+	}
+
+	pageClause, pageArgs := page(options)
 	query := `
     SELECT 
         barcode, 
@@ -476,19 +811,19 @@ func GetAllFoodItems() ([]PersistentFoodItem, error) {
         COALESCE(proteinPer100g, 0) as proteinPer100g, 
         COALESCE(servingQuantity, 0) as servingQuantity, 
         COALESCE(servingQuantityUnit, '') as servingQuantityUnit,
+        COALESCE(source_dish_id, '') as source_dish_id,
+        density,
         created_at,
         last_updated
-    FROM foodItems
-    ORDER BY created_at DESC
-    `
+    FROM foodItems` + filter.where() + orderBy(options, FoodItemSortKeys, "created_at", "barcode") + pageClause
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, append(filter.args, pageArgs...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query food items: %v", err)
+		return nil, 0, fmt.Errorf("failed to query food items: %v", err)
 	}
 	defer rows.Close()
 
-	var foodItems []PersistentFoodItem
+	foodItems := []PersistentFoodItem{}
 
 	for rows.Next() {
 		var item PersistentFoodItem
@@ -501,20 +836,44 @@ func GetAllFoodItems() ([]PersistentFoodItem, error) {
 			&item.ProteinPer100g,
 			&item.ServingQuantity,
 			&item.ServingQuantityUnit,
+			&item.SourceDishID,
+			&item.Density,
 			&item.CreatedAt,
 			&item.LastUpdated,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan food item: %v", err)
+			return nil, 0, fmt.Errorf("failed to scan food item: %v", err)
 		}
 		foodItems = append(foodItems, item)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating food items: %v", err)
+		return nil, 0, fmt.Errorf("error iterating food items: %v", err)
+	}
+	rows.Close()
+
+	if len(foodItems) == 0 {
+		return foodItems, total, nil
+	}
+
+	barcodes := make([]string, len(foodItems))
+	for i, item := range foodItems {
+		barcodes[i] = item.Barcode
+	}
+	portions, err := getFoodPortions(db, barcodes...)
+	if err != nil {
+		return nil, 0, err
+	}
+	tags, err := getFoodTags(db, barcodes...)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range foodItems {
+		foodItems[i].Portions = portions[foodItems[i].Barcode]
+		foodItems[i].Tags = tags[foodItems[i].Barcode]
 	}
 
-	return foodItems, nil
+	return foodItems, total, nil
 }
 
 func UpdateFoodItem(barcode string, updateData map[string]interface{}) error {
@@ -541,6 +900,8 @@ func UpdateFoodItem(barcode string, updateData map[string]interface{}) error {
 			query += "servingQuantity = ?, "
 		case "serving_quantity_unit":
 			query += "servingQuantityUnit = ?, "
+		case "density":
+			query += "density = ?, "
 		default:
 			fmt.Println("Unknown field:", field)
 			continue // Skip unknown fields
@@ -549,7 +910,7 @@ func UpdateFoodItem(barcode string, updateData map[string]interface{}) error {
 	}
 
 	if len(args) == 0 {
-		return fmt.Errorf("no valid fields to update")
+		return types.NewValidationError("", "no valid fields to update")
 	}
 
 	// Add last_updated field
@@ -564,16 +925,34 @@ func UpdateFoodItem(barcode string, updateData map[string]interface{}) error {
 	query += "WHERE barcode = ?"
 	args = append(args, barcode)
 
-	_, err := db.Exec(query, args...)
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	_, err = tx.Exec(query, args...)
 	if err != nil {
+		tx.Rollback()
 		fmt.Printf("Error updating food item: %v", err)
 		return fmt.Errorf("failed to update food item: %v", err)
 	}
 
+	// Recalculate food items converted from dishes using this food item
+	_, err = syncDerivedFoodItemsForIngredient(tx, barcode)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
 	if err := markDatabaseAsUnsynced(); err != nil {
 		log.Printf("Failed to mark database as unsynced: %v", err)
 	}
-	messaging.BroadcastMessage("food_items_updated")
+	messaging.Publish(messaging.EntityFoodItem, messaging.ActionUpdated, "", barcode)
 	return nil
 }
 
@@ -582,124 +961,152 @@ func DeleteFoodItem(barcode string) error {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
-	query := "DELETE FROM foodItems WHERE barcode = ?"
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	// Recipes are never changed implicitly, the food item has to be removed from its dishes first
+	dishNames, err := getDishNamesByIngredient(tx, barcode)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(dishNames) > 0 {
+		tx.Rollback()
+		return types.NewConflictError("food item %s is an ingredient of these dishes: %s", barcode, strings.Join(dishNames, ", "))
+	}
+	plannedMeals, err := getPlannedMealsByBarcode(tx, barcode)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(plannedMeals) > 0 {
+		tx.Rollback()
+		return types.NewConflictError("food item %s is planned in these meals: %s", barcode, strings.Join(plannedMeals, ", "))
+	}
+
+	_, err = tx.Exec("DELETE FROM food_portions WHERE barcode = ?", barcode)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete food portions: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM food_tags WHERE barcode = ?", barcode)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete food tags: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM favoriteFoods WHERE barcode = ?", barcode)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete favorite food items: %v", err)
+	}
 
-	result, err := db.Exec(query, barcode)
+	// The referencing rows are deleted first, foreign keys are enforced
+	result, err := tx.Exec("DELETE FROM foodItems WHERE barcode = ?", barcode)
 	if err != nil {
+		tx.Rollback()
 		return fmt.Errorf("failed to delete food item: %v", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		return fmt.Errorf("error checking rows affected: %v", err)
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no food item found with barcode %s", barcode)
+		tx.Rollback()
+		return types.NewNotFoundError("no food item found with barcode %s", barcode)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
 	if err := markDatabaseAsUnsynced(); err != nil {
 		log.Printf("Failed to mark database as unsynced: %v", err)
 	}
-	messaging.BroadcastMessage("food_items_updated")
+	messaging.Publish(messaging.EntityFoodItem, messaging.ActionDeleted, "", barcode)
 	return nil
 }
 
-func SearchFoodItems(query string) ([]PersistentFoodItem, error) {
-	if len(query) < 2 {
-		return nil, fmt.Errorf("search query must be at least 2 characters long")
-	}
-
-	db := OpenDataBase()
-	defer CloseDataBase(db)
-
-	// Create a temporary trigger to create an index if it doesn't exist
-	_, err := db.Exec(`
-		CREATE INDEX IF NOT EXISTS idx_fooditems_name ON foodItems(name COLLATE NOCASE);
-		CREATE INDEX IF NOT EXISTS idx_fooditems_barcode ON foodItems(barcode);
-	`)
+// getDishNamesByIngredient returns the names of all dishes containing the given food item
+func getDishNamesByIngredient(db dbExecutor, barcode string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT d.name FROM dishes d
+		JOIN dish_items di ON di.dish_id = d.id
+		WHERE di.barcode = ?
+		ORDER BY d.name`, barcode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create index: %v", err)
+		return nil, fmt.Errorf("failed to query dishes by ingredient: %v", err)
 	}
+	defer rows.Close()
 
-	sqlQuery := `
-    SELECT 
-        barcode, 
-        name, 
-        COALESCE(kcalPer100g, 0) as kcalPer100g, 
-        COALESCE(fatPer100g, 0) as fatPer100g, 
-        COALESCE(carbsPer100g, 0) as carbsPer100g, 
-        COALESCE(proteinPer100g, 0) as proteinPer100g, 
-        COALESCE(servingQuantity, 0) as servingQuantity, 
-        COALESCE(servingQuantityUnit, '') as servingQuantityUnit
-    FROM foodItems
-    WHERE 
-        name LIKE ? COLLATE NOCASE 
-        OR barcode LIKE ? 
-    ORDER BY 
-        CASE 
-            WHEN name LIKE ? THEN 1  -- Exact match at start
-            WHEN name LIKE ? THEN 2  -- Match at start
-            WHEN name LIKE ? THEN 3  -- Match anywhere
-            ELSE 4                   -- Barcode match
-        END,
-        length(name),               -- Prefer shorter names
-        name COLLATE NOCASE
-    LIMIT 15
-    `
-
-	// Prepare search patterns
-	term := "%" + query + "%"
-	exactStart := query + "%"
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan dish name: %v", err)
+		}
+		names = append(names, name)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dish names: %v", err)
+	}
+	return names, nil
+}
 
-	rows, err := db.Query(sqlQuery, term, query+"%", exactStart, exactStart, term)
+// getPlannedMealsByBarcode returns the meal plan entries of a food item as "<date> <meal>"
+func getPlannedMealsByBarcode(db dbExecutor, barcode string) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT date, meal FROM mealPlanEntries
+		WHERE barcode = ?
+		ORDER BY date, meal`, barcode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query food items: %v", err)
+		return nil, fmt.Errorf("failed to query meal plan entries by barcode: %v", err)
 	}
 	defer rows.Close()
 
-	var foodItems []PersistentFoodItem
-
+	var meals []string
 	for rows.Next() {
-		var item PersistentFoodItem
-		err := rows.Scan(
-			&item.Barcode,
-			&item.Name,
-			&item.CaloriesPer100g,
-			&item.FatPer100g,
-			&item.CarbsPer100g,
-			&item.ProteinPer100g,
-			&item.ServingQuantity,
-			&item.ServingQuantityUnit,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan food item: %v", err)
+		var date, meal string
+		if err := rows.Scan(&date, &meal); err != nil {
+			return nil, fmt.Errorf("failed to scan meal plan entry: %v", err)
 		}
-		foodItems = append(foodItems, item)
+		meals = append(meals, date+" "+meal)
 	}
-
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating food items: %v", err)
+		return nil, fmt.Errorf("error iterating meal plan entries: %v", err)
 	}
-
-	return foodItems, nil
+	return meals, nil
 }
 
-func InsertConsumedFoodItem(item ConsumedFoodItem, profileID string) error {
-
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+// InsertConsumedFoodItem adds a food item to the diary of a profile
+func (r *Repository) InsertConsumedFoodItem(ctx context.Context, item ConsumedFoodItem, profileID string) error {
+	db, release := r.executor(ctx)
+	defer release()
 
 	// Format insertDate in ISO 8601 format
 	insertDateISO := FormatDateTimeISO8601(item.InsertDate)
 	fmt.Println("Inserting consumed food item: ", item, "with ISO date: ", insertDateISO)
 
+	// Snapshot the current name and nutrition values, so later changes to the food item don't change the history
+	foodItem, err := GetFoodItemByBarcode(db, item.Barcode)
+	if err != nil {
+		return err
+	}
+
 	query := `
-    INSERT INTO consumedFoodItems (id, barcode, consumed_quantity, serving_quantity, date, insertdate, profile_id)
-    VALUES (?, ?, ?, ?, ?, ?, ?)
+    INSERT INTO consumedFoodItems (id, barcode, consumed_quantity, serving_quantity, date, insertdate, profile_id,
+                                   name, kcalPer100g, proteinPer100g, carbsPer100g, fatPer100g, servingQuantityUnit)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	_, err := db.Exec(query,
+	_, err = db.Exec(query,
 		item.ID,
 		item.Barcode,
 		item.ConsumedQuantity,
@@ -707,74 +1114,183 @@ func InsertConsumedFoodItem(item ConsumedFoodItem, profileID string) error {
 		item.Date,
 		insertDateISO, // Use ISO 8601 formatted datetime
 		profileID,
+		foodItem.Name,
+		foodItem.CaloriesPer100g,
+		foodItem.ProteinPer100g,
+		foodItem.CarbsPer100g,
+		foodItem.FatPer100g,
+		foodItem.ServingQuantityUnit,
 	)
 
 	if err != nil {
-		return fmt.Errorf("failed to insert consumed food item: %v", err)
-	}
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
+		return foreignKeyError(err, "failed to insert consumed food item")
 	}
-	messaging.BroadcastMessage("consumed_food_items_updated")
+	r.changed(messaging.EntityConsumedFoodItem, messaging.ActionCreated, profileID, item.ID)
 	return nil
 }
 
+// InsertConsumedDish logs a dish as consumed food item. The nutrition values of the dish and
+// the ingredients of one portion are stored as snapshot, so later changes to the recipe
+// don't change the diary history. The consumed quantity is the number of portions.
+func (r *Repository) InsertConsumedDish(ctx context.Context, item ConsumedFoodItem, dishID string, profileID string) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
+
+		nutrition, err := calculateDishNutrition(db, dishID)
+		if err != nil {
+			return err
+		}
+		if nutrition.CookedWeight <= 0 {
+			return types.NewValidationError("items", "dish %s has no ingredients", dishID)
+		}
+
+		// Build the ingredient breakdown of one portion
+		dishIngredients, err := getDishIngredients(db, dishID)
+		if err != nil {
+			return err
+		}
+
+		var ingredients []ConsumedDishIngredient
+		for _, dishIngredient := range dishIngredients {
+			ingredients = append(ingredients, ConsumedDishIngredient{
+				Barcode:         dishIngredient.Item.Barcode,
+				Name:            dishIngredient.Item.Name,
+				Quantity:        dishIngredient.Quantity / float64(nutrition.Portions),
+				CaloriesPer100g: dishIngredient.Item.CaloriesPer100g,
+				ProteinPer100g:  dishIngredient.Item.ProteinPer100g,
+				CarbsPer100g:    dishIngredient.Item.CarbsPer100g,
+				FatPer100g:      dishIngredient.Item.FatPer100g,
+			})
+		}
+
+		insertDateISO := FormatDateTimeISO8601(item.InsertDate)
+
+		_, err = db.Exec(`
+		INSERT INTO consumedFoodItems (id, barcode, consumed_quantity, serving_quantity, date, insertdate, profile_id,
+		                               dish_id, name, kcalPer100g, proteinPer100g, carbsPer100g, fatPer100g, servingQuantityUnit)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 'g')
+		`,
+			item.ID,
+			item.Barcode,
+			item.ConsumedQuantity,
+			nutrition.PortionWeight,
+			item.Date,
+			insertDateISO,
+			profileID,
+			dishID,
+			nutrition.Name,
+			nutrition.CaloriesPer100g,
+			nutrition.ProteinPer100g,
+			nutrition.CarbsPer100g,
+			nutrition.FatPer100g,
+		)
+		if err != nil {
+			return foreignKeyError(err, "failed to insert consumed dish")
+		}
+
+		for _, ingredient := range ingredients {
+			_, err = db.Exec(`
+			INSERT INTO consumedDishIngredients (consumed_id, barcode, name, quantity, kcalPer100g, proteinPer100g, carbsPer100g, fatPer100g)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`, item.ID, ingredient.Barcode, ingredient.Name, ingredient.Quantity,
+				ingredient.CaloriesPer100g, ingredient.ProteinPer100g, ingredient.CarbsPer100g, ingredient.FatPer100g)
+			if err != nil {
+				return foreignKeyError(err, "failed to insert consumed dish ingredient")
+			}
+		}
+
+		repo.changed(messaging.EntityConsumedFoodItem, messaging.ActionCreated, profileID, item.ID)
+		return nil
+	})
+}
+
 // DeleteConsumedFoodItem löscht einen konsumierten Lebensmitteleintrag basierend auf der ID
-func DeleteConsumedFoodItem(id string) error {
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+func (r *Repository) DeleteConsumedFoodItem(ctx context.Context, id string) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
 
-	query := "DELETE FROM consumedFoodItems WHERE id = ?"
+		profileID, err := profileIDOf(db, "consumedFoodItems", id)
+		if err != nil {
+			return err
+		}
 
-	result, err := db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("failed to delete consumed food item: %v", err)
-	}
+		// Delete the ingredient snapshot of a logged dish first
+		_, err = db.Exec("DELETE FROM consumedDishIngredients WHERE consumed_id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete consumed dish ingredients: %v", err)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("error checking rows affected: %v", err)
-	}
+		result, err := db.Exec("DELETE FROM consumedFoodItems WHERE id = ?", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete consumed food item: %v", err)
+		}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("no consumed food item found with id %s", id)
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error checking rows affected: %v", err)
+		}
+		if rowsAffected == 0 {
+			return types.NewNotFoundError("no consumed food item found with id %s", id)
+		}
 
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
-	}
-	messaging.BroadcastMessage("consumed_food_items_updated")
-	return nil
+		repo.changed(messaging.EntityConsumedFoodItem, messaging.ActionDeleted, profileID, id)
+		return nil
+	})
 }
 
-func GetConsumedFoodItemsByDate(date string, profileID string) ([]ConsumedFoodItemWithDetails, error) {
+// GetConsumedFoodItemsByDate returns a page of the diary entries of a profile on a date matching
+// the filters of the options and the total number of matching entries. By default the latest
+// entries come first.
+func GetConsumedFoodItemsByDate(date string, profileID string, options ListOptions) ([]ConsumedFoodItemWithDetails, int, error) {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
+	var filter listFilter
+	filter.add("c.date = ?", date)
+	filter.add("c.profile_id = ?", profileID)
+	filter.add("(f.barcode IS NOT NULL OR c.kcalPer100g IS NOT NULL)")
+	if options.Unit != "" {
+		filter.add("COALESCE(c.servingQuantityUnit, f.servingQuantityUnit, '') = ?", options.Unit)
+	}
+	switch options.Kind {
+	case "food":
+		filter.add("c.dish_id IS NULL")
+	case "dish":
+		filter.add("c.dish_id IS NOT NULL")
+	}
+
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM consumedFoodItems c LEFT JOIN foodItems f ON c.barcode = f.barcode"+filter.where(),
+		filter.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count consumed food items: %v", err)
+	}
+
+	pageClause, pageArgs := page(options)
 	query := `
     SELECT 
         c.id, 
         c.barcode, 
-        f.name, 
+        COALESCE(c.name, f.name, '') as name, 
         c.consumed_quantity, 
         c.serving_quantity, 
         c.date, 
         c.insertdate,
-        COALESCE(f.kcalPer100g, 0) as kcalPer100g, 
-        COALESCE(f.proteinPer100g, 0) as proteinPer100g, 
-        COALESCE(f.carbsPer100g, 0) as carbsPer100g, 
-        COALESCE(f.fatPer100g, 0) as fatPer100g, 
-        COALESCE(f.servingQuantityUnit, '') as servingQuantityUnit
+        COALESCE(c.kcalPer100g, f.kcalPer100g, 0) as kcalPer100g, 
+        COALESCE(c.proteinPer100g, f.proteinPer100g, 0) as proteinPer100g, 
+        COALESCE(c.carbsPer100g, f.carbsPer100g, 0) as carbsPer100g, 
+        COALESCE(c.fatPer100g, f.fatPer100g, 0) as fatPer100g, 
+        COALESCE(c.servingQuantityUnit, f.servingQuantityUnit, '') as servingQuantityUnit,
+        COALESCE(c.dish_id, '') as dish_id
     FROM consumedFoodItems c
-    JOIN foodItems f ON c.barcode = f.barcode
-    WHERE c.date = ? AND c.profile_id = ?
-    ORDER BY c.insertdate DESC
-    `
+    LEFT JOIN foodItems f ON c.barcode = f.barcode` + filter.where() + orderBy(options, ConsumedFoodItemSortKeys, "insert_date", "c.id") + pageClause
 
-	rows, err := db.Query(query, date, profileID)
+	rows, err := db.Query(query, append(filter.args, pageArgs...)...)
 	if err != nil {
 		fmt.Println("Error querying consumed food items:", err)
-		return nil, fmt.Errorf("failed to query consumed food items: %v", err)
+		return nil, 0, fmt.Errorf("failed to query consumed food items: %v", err)
 	}
 	defer rows.Close()
 
@@ -795,35 +1311,97 @@ func GetConsumedFoodItemsByDate(date string, profileID string) ([]ConsumedFoodIt
 			&item.CarbsPer100g,
 			&item.FatPer100g,
 			&item.ServingQuantityUnit,
+			&item.DishID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan consumed food item: %v", err)
+			return nil, 0, fmt.Errorf("failed to scan consumed food item: %v", err)
 		}
 		consumedFoodItems = append(consumedFoodItems, item)
 	}
 
-	return consumedFoodItems, nil
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating consumed food items: %v", err)
+	}
+
+	// Attach the ingredient breakdown to logged dishes
+	for i := range consumedFoodItems {
+		if consumedFoodItems[i].DishID == "" {
+			continue
+		}
+		ingredients, err := getConsumedDishIngredients(db, consumedFoodItems[i].ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		// The snapshot holds the ingredients of one portion, scale it to the consumed amount
+		for j := range ingredients {
+			ingredients[j].Quantity *= consumedFoodItems[i].ConsumedQuantity
+		}
+		consumedFoodItems[i].Ingredients = ingredients
+	}
+
+	return consumedFoodItems, total, nil
 }
 
-func GetConsumedFoodItemByBarcodeAndDate(barcode, date string, profileID string) (*ConsumedFoodItem, error) {
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+// getConsumedDishIngredients returns the ingredient snapshot of a logged dish
+func getConsumedDishIngredients(db *sql.DB, consumedID string) ([]ConsumedDishIngredient, error) {
+	rows, err := db.Query(`
+	SELECT barcode, COALESCE(name, ''), quantity, 
+	       COALESCE(kcalPer100g, 0), COALESCE(proteinPer100g, 0), COALESCE(carbsPer100g, 0), COALESCE(fatPer100g, 0)
+	FROM consumedDishIngredients
+	WHERE consumed_id = ?
+	ORDER BY id
+	`, consumedID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consumed dish ingredients: %v", err)
+	}
+	defer rows.Close()
+
+	var ingredients []ConsumedDishIngredient
+	for rows.Next() {
+		var ingredient ConsumedDishIngredient
+		err := rows.Scan(
+			&ingredient.Barcode,
+			&ingredient.Name,
+			&ingredient.Quantity,
+			&ingredient.CaloriesPer100g,
+			&ingredient.ProteinPer100g,
+			&ingredient.CarbsPer100g,
+			&ingredient.FatPer100g,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consumed dish ingredient: %v", err)
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating consumed dish ingredients: %v", err)
+	}
+
+	return ingredients, nil
+}
+
+// GetConsumedFoodItemByBarcodeAndDate returns the diary entry of a food item on a date, or nil
+func (r *Repository) GetConsumedFoodItemByBarcodeAndDate(ctx context.Context, barcode, date string, profileID string) (*ConsumedFoodItem, error) {
+	db, release := r.executor(ctx)
+	defer release()
 
 	query := `
     SELECT id, barcode, consumed_quantity, serving_quantity, date, insertdate
     FROM consumedFoodItems
-    WHERE barcode = ? AND date = ? AND profile_id = ?
+    WHERE barcode = ? AND date = ? AND profile_id = ? AND dish_id IS NULL
     LIMIT 1
     `
 
 	var item ConsumedFoodItem
+	var insertDate string
 	err := db.QueryRow(query, barcode, date, profileID).Scan(
 		&item.ID,
 		&item.Barcode,
 		&item.ConsumedQuantity,
 		&item.ServingQuantity,
 		&item.Date,
-		&item.InsertDate,
+		&insertDate, // Stored as ISO 8601 text
 	)
 
 	if err == sql.ErrNoRows {
@@ -832,13 +1410,93 @@ func GetConsumedFoodItemByBarcodeAndDate(barcode, date string, profileID string)
 		return nil, fmt.Errorf("failed to get consumed food item: %v", err)
 	}
 
+	item.InsertDate, err = time.Parse(time.RFC3339, insertDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse insert date: %v", err)
+	}
+
 	return &item, nil
 }
 
-func UpdateConsumedFoodItem(id string, updateData map[string]interface{}) error {
+// GetConsumedEntries returns the diary entries of a profile on a date in the order they were
+// logged, without their nutrition values and ingredients. If IDs are given, only these entries
+// are returned and all of them have to exist.
+func (r *Repository) GetConsumedEntries(ctx context.Context, profileID string, date string, ids []string) ([]ConsumedFoodItemWithDetails, error) {
+	db, release := r.executor(ctx)
+	defer release()
 
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+	query := `
+	SELECT id, barcode, consumed_quantity, COALESCE(serving_quantity, 0), date, COALESCE(dish_id, '')
+	FROM consumedFoodItems
+	WHERE profile_id = ? AND date = ?
+	`
+	args := []interface{}{profileID, date}
+	if len(ids) > 0 {
+		query += " AND id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")"
+		for _, id := range ids {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY insertdate"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consumed food items: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []ConsumedFoodItemWithDetails
+	for rows.Next() {
+		var entry ConsumedFoodItemWithDetails
+		err := rows.Scan(&entry.ID, &entry.Barcode, &entry.ConsumedQuantity, &entry.ServingQuantity, &entry.Date, &entry.DishID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan consumed food item: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating consumed food items: %v", err)
+	}
+
+	if len(ids) > 0 && len(entries) != len(ids) {
+		return nil, types.NewNotFoundError("consumed food items not found for the given date and profile")
+	}
+
+	return entries, nil
+}
+
+// AddConsumedQuantity adds servings to a diary entry. The quantity is added by the update
+// itself, so concurrent additions are not lost.
+func (r *Repository) AddConsumedQuantity(ctx context.Context, id string, quantity float64) error {
+	db, release := r.executor(ctx)
+	defer release()
+
+	profileID, err := profileIDOf(db, "consumedFoodItems", id)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec("UPDATE consumedFoodItems SET consumed_quantity = consumed_quantity + ? WHERE id = ?", quantity, id)
+	if err != nil {
+		return fmt.Errorf("failed to update consumed food item: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return types.NewNotFoundError("no consumed food item found with id %s", id)
+	}
+
+	r.changed(messaging.EntityConsumedFoodItem, messaging.ActionUpdated, profileID, id)
+	return nil
+}
+
+func (r *Repository) UpdateConsumedFoodItem(ctx context.Context, id string, updateData map[string]interface{}) error {
+	db, release := r.executor(ctx)
+	defer release()
 
 	println("updateData", updateData)
 
@@ -846,7 +1504,7 @@ func UpdateConsumedFoodItem(id string, updateData map[string]interface{}) error
 	err := db.QueryRow("SELECT profile_id FROM consumedFoodItems WHERE id = ?", id).Scan(&existingProfileID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("no consumed food item found with id %s", id)
+			return types.NewNotFoundError("no consumed food item found with id %s", id)
 		}
 		return fmt.Errorf("failed to verify item ownership: %v", err)
 	}
@@ -873,7 +1531,7 @@ func UpdateConsumedFoodItem(id string, updateData map[string]interface{}) error
 	}
 
 	if len(args) == 0 {
-		return fmt.Errorf("no valid fields to update")
+		return types.NewValidationError("", "no valid fields to update")
 	}
 
 	// Remove the trailing comma and space
@@ -885,15 +1543,114 @@ func UpdateConsumedFoodItem(id string, updateData map[string]interface{}) error
 
 	_, err = db.Exec(query, args...)
 	if err != nil {
-		fmt.Printf("Error updating consumed food item: %v", err)
-		return fmt.Errorf("failed to update consumed food item: %v", err)
+		fmt.Printf("Error updating consumed food item: %v", err)
+		return fmt.Errorf("failed to update consumed food item: %v", err)
+	}
+
+	r.changed(messaging.EntityConsumedFoodItem, messaging.ActionUpdated, existingProfileID, id)
+	return nil
+}
+
+// RepriceConsumedFoodItems replaces the nutrition snapshot of consumed entries of a food item with
+// its current values. Logged dishes containing the food item are recalculated as well. The optional
+// start and end date and profile ID limit the entries to update. Returns the number of updated entries.
+func RepriceConsumedFoodItems(barcode string, startDate string, endDate string, profileID string) (int64, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	if _, err := GetFoodItemByBarcode(db, barcode); err != nil {
+		return 0, err
+	}
+
+	// Build the filter for the affected consumed entries
+	filter := ""
+	var filterArgs []interface{}
+	if startDate != "" {
+		filter += " AND date >= ?"
+		filterArgs = append(filterArgs, startDate)
+	}
+	if endDate != "" {
+		filter += " AND date <= ?"
+		filterArgs = append(filterArgs, endDate)
+	}
+	if profileID != "" {
+		filter += " AND profile_id = ?"
+		filterArgs = append(filterArgs, profileID)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	// Consumed food items
+	result, err := tx.Exec(`
+		UPDATE consumedFoodItems
+		SET name = f.name, kcalPer100g = f.kcalPer100g, proteinPer100g = f.proteinPer100g,
+		    carbsPer100g = f.carbsPer100g, fatPer100g = f.fatPer100g, servingQuantityUnit = f.servingQuantityUnit
+		FROM foodItems f
+		WHERE consumedFoodItems.barcode = f.barcode AND consumedFoodItems.barcode = ?
+		  AND consumedFoodItems.id IN (SELECT id FROM consumedFoodItems WHERE dish_id IS NULL`+filter+`)
+	`, append([]interface{}{barcode}, filterArgs...)...)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to reprice consumed food items: %v", err)
+	}
+
+	updatedItems, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("error checking rows affected: %v", err)
+	}
+
+	// Ingredients of consumed dishes
+	_, err = tx.Exec(`
+		UPDATE consumedDishIngredients
+		SET name = f.name, kcalPer100g = f.kcalPer100g, proteinPer100g = f.proteinPer100g,
+		    carbsPer100g = f.carbsPer100g, fatPer100g = f.fatPer100g
+		FROM foodItems f
+		WHERE consumedDishIngredients.barcode = f.barcode AND consumedDishIngredients.barcode = ?
+		  AND consumedDishIngredients.consumed_id IN (SELECT id FROM consumedFoodItems WHERE dish_id IS NOT NULL`+filter+`)
+	`, append([]interface{}{barcode}, filterArgs...)...)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to reprice consumed dish ingredients: %v", err)
+	}
+
+	// Recalculate the per 100g values of the consumed dishes from their ingredients of one portion
+	result, err = tx.Exec(`
+		UPDATE consumedFoodItems
+		SET kcalPer100g = (SELECT SUM(i.quantity * i.kcalPer100g) FROM consumedDishIngredients i WHERE i.consumed_id = consumedFoodItems.id) / serving_quantity,
+		    proteinPer100g = (SELECT SUM(i.quantity * i.proteinPer100g) FROM consumedDishIngredients i WHERE i.consumed_id = consumedFoodItems.id) / serving_quantity,
+		    carbsPer100g = (SELECT SUM(i.quantity * i.carbsPer100g) FROM consumedDishIngredients i WHERE i.consumed_id = consumedFoodItems.id) / serving_quantity,
+		    fatPer100g = (SELECT SUM(i.quantity * i.fatPer100g) FROM consumedDishIngredients i WHERE i.consumed_id = consumedFoodItems.id) / serving_quantity
+		WHERE serving_quantity > 0
+		  AND id IN (SELECT consumed_id FROM consumedDishIngredients WHERE barcode = ?)
+		  AND id IN (SELECT id FROM consumedFoodItems WHERE dish_id IS NOT NULL`+filter+`)
+	`, append([]interface{}{barcode}, filterArgs...)...)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to recalculate consumed dishes: %v", err)
+	}
+
+	updatedDishes, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("error checking rows affected: %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
+	if updatedItems+updatedDishes > 0 {
+		if err := markDatabaseAsUnsynced(); err != nil {
+			log.Printf("Failed to mark database as unsynced: %v", err)
+		}
+		messaging.Publish(messaging.EntityConsumedFoodItem, messaging.ActionUpdated, profileID)
 	}
-	messaging.BroadcastMessage("consumed_food_items_updated")
-	return nil
+	return updatedItems + updatedDishes, nil
 }
 
 func GetConsumedFoodItemById(id string) (*ConsumedFoodItem, error) {
@@ -916,7 +1673,7 @@ func GetConsumedFoodItemById(id string) (*ConsumedFoodItem, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("no consumed food item found with id %s", id)
+		return nil, types.NewNotFoundError("no consumed food item found with id %s", id)
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to get consumed food item: %v", err)
 	}
@@ -940,7 +1697,7 @@ func GetServingQuantityByBarcode(barcode string) (float64, error) {
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return 0, fmt.Errorf("no food item found with barcode %s", barcode)
+			return 0, types.NewNotFoundError("no food item found with barcode %s", barcode)
 		}
 		return 0, fmt.Errorf("failed to query serving quantity: %v", err)
 	}
@@ -959,17 +1716,22 @@ func SaveUserSettings(settings UserSettings, profileID string) error {
 		return fmt.Errorf("error checking if profile exists: %v", err)
 	}
 	if !exists {
-		return fmt.Errorf("profile with ID %s does not exist", profileID)
+		return types.NewNotFoundError("profile with ID %s does not exist", profileID)
 	}
 
 	fmt.Println("settings", profileID, settings)
 
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
 	query := `
     INSERT OR REPLACE INTO userSettings (profile_id, weight, height, calories, proteins, carbs, fat, birthdate, gender, activity_level, weekly_weight_change)
     VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
     `
 
-	_, err = db.Exec(query,
+	_, err = tx.Exec(query,
 		profileID,
 		settings.Weight,
 		settings.Height,
@@ -983,8 +1745,20 @@ func SaveUserSettings(settings UserSettings, profileID string) error {
 		settings.WeeklyWeightChange,
 	)
 	if err != nil {
+		tx.Rollback()
 		fmt.Println("error", err.Error())
-		return fmt.Errorf("failed to save user settings: %v", err)
+		return foreignKeyError(err, "failed to save user settings")
+	}
+
+	// Keep the history of the targets, so past days are compared with the targets of that time
+	if err := recordTargetHistory(tx, profileID, settings); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
 	if err := markDatabaseAsUnsynced(); err != nil {
@@ -992,7 +1766,7 @@ func SaveUserSettings(settings UserSettings, profileID string) error {
 	}
 
 	// Broadcast message for UI updates
-	messaging.BroadcastMessage("user_settings_updated")
+	messaging.Publish(messaging.EntityUserSettings, messaging.ActionUpdated, profileID)
 	return nil
 }
 
@@ -1029,198 +1803,169 @@ func GetUserSettings(profileID string) (UserSettings, error) {
 	return settings, nil
 }
 
-func CreateDish(dish Dish, items []DishItem) error {
-
-	db := OpenDataBase()
-	defer CloseDataBase(db)
-
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
-
-	// Insert the dish
-	_, err = tx.Exec(`
-		INSERT INTO dishes (id, name, barcode, created_at, last_updated)
-		VALUES (?, ?, ?, ?, ?)
-	`, dish.ID, dish.Name, dish.Barcode, time.Now(), time.Now())
-	if err != nil {
-		return fmt.Errorf("failed to insert dish: %v", err)
-	}
+func (r *Repository) CreateDish(ctx context.Context, dish Dish, items []DishItem) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
 
-	// Insert the dish items
-	for _, item := range items {
-		_, err = tx.Exec(`
-            INSERT INTO dish_items (dish_id, barcode, quantity)
-            VALUES (?, ?, ?)
-        `, dish.ID, item.Barcode, item.Quantity)
+		// Insert the dish
+		_, err := db.Exec(`
+			INSERT INTO dishes (id, name, barcode, cooked_weight, portions, created_at, last_updated)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, dish.ID, dish.Name, dish.Barcode, dish.CookedWeight, dish.Portions, time.Now(), time.Now())
 		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to insert dish item: %v", err)
+			return fmt.Errorf("failed to insert dish: %v", err)
 		}
-	}
 
-	// Commit the transaction
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
-	}
+		// Insert the dish items
+		for _, item := range items {
+			_, err = db.Exec(`
+				INSERT INTO dish_items (dish_id, barcode, quantity, unit)
+				VALUES (?, ?, ?, NULLIF(?, ''))
+			`, dish.ID, item.Barcode, item.Quantity, item.Unit)
+			if err != nil {
+				return foreignKeyError(err, "failed to insert dish item")
+			}
+		}
 
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
-	}
-	return nil
-}
+		if err := insertDishTags(db, dish.ID, dish.Tags); err != nil {
+			return err
+		}
 
-func DeleteDish(dishID string) error {
+		// Make sure the units of all ingredients can be converted
+		if _, err := getDishIngredients(db, dish.ID); err != nil {
+			return err
+		}
 
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+		repo.changed(messaging.EntityDish, messaging.ActionCreated, "", dish.ID)
+		return nil
+	})
+}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
+func (r *Repository) DeleteDish(ctx context.Context, dishID string) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
 
-	// Delete dish items first
-	_, err = tx.Exec("DELETE FROM dish_items WHERE dish_id = ?", dishID)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete dish items: %v", err)
-	}
+		// Delete dish items first
+		_, err := db.Exec("DELETE FROM dish_items WHERE dish_id = ?", dishID)
+		if err != nil {
+			return fmt.Errorf("failed to delete dish items: %v", err)
+		}
 
-	// Then delete the dish itself
-	_, err = tx.Exec("DELETE FROM dishes WHERE id = ?", dishID)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete dish: %v", err)
-	}
+		_, err = db.Exec("DELETE FROM dish_tags WHERE dish_id = ?", dishID)
+		if err != nil {
+			return fmt.Errorf("failed to delete dish tags: %v", err)
+		}
 
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
-	}
+		_, err = db.Exec("DELETE FROM mealPlanEntries WHERE dish_id = ?", dishID)
+		if err != nil {
+			return fmt.Errorf("failed to delete meal plan entries: %v", err)
+		}
 
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
-	}
-	return nil
-}
+		// Then delete the dish itself
+		_, err = db.Exec("DELETE FROM dishes WHERE id = ?", dishID)
+		if err != nil {
+			return fmt.Errorf("failed to delete dish: %v", err)
+		}
 
-func UpdateDish(dishID string, name string, barcode *string, items []DishItem) error {
+		// Food items converted from the dish are kept with their last values
+		result, err := db.Exec("UPDATE foodItems SET source_dish_id = NULL WHERE source_dish_id = ?", dishID)
+		if err != nil {
+			return fmt.Errorf("failed to unlink converted food items: %v", err)
+		}
 
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+		unlinked, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("error checking rows affected: %v", err)
+		}
 
-	tx, err := db.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
-	}
+		repo.changed(messaging.EntityDish, messaging.ActionDeleted, "", dishID)
+		if unlinked > 0 {
+			repo.changed(messaging.EntityFoodItem, messaging.ActionUpdated, "")
+		}
+		return nil
+	})
+}
 
-	// Update the dish name and barcode
-	_, err = tx.Exec(`
-		UPDATE dishes SET name = ?, barcode = ?, last_updated = ?
-		WHERE id = ?
-	`, name, barcode, time.Now(), dishID)
-	if err != nil {
-		return fmt.Errorf("failed to update dish: %v", err)
-	}
+func (r *Repository) UpdateDish(ctx context.Context, dish Dish, items []DishItem) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
 
-	// Delete existing dish items
-	_, err = tx.Exec("DELETE FROM dish_items WHERE dish_id = ?", dishID)
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to delete existing dish items: %v", err)
-	}
+		// Update the dish name, barcode and yield
+		_, err := db.Exec(`
+			UPDATE dishes SET name = ?, barcode = ?, cooked_weight = ?, portions = ?, last_updated = ?
+			WHERE id = ?
+		`, dish.Name, dish.Barcode, dish.CookedWeight, dish.Portions, time.Now(), dish.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update dish: %v", err)
+		}
 
-	// Insert the new dish items
-	for _, item := range items {
-		_, err = tx.Exec(`
-            INSERT INTO dish_items (dish_id, barcode, quantity)
-            VALUES (?, ?, ?)
-        `, dishID, item.Barcode, item.Quantity)
+		// Delete existing dish items
+		_, err = db.Exec("DELETE FROM dish_items WHERE dish_id = ?", dish.ID)
 		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to insert dish item: %v", err)
+			return fmt.Errorf("failed to delete existing dish items: %v", err)
 		}
-	}
 
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
-	}
+		// Insert the new dish items
+		for _, item := range items {
+			_, err = db.Exec(`
+				INSERT INTO dish_items (dish_id, barcode, quantity, unit)
+				VALUES (?, ?, ?, NULLIF(?, ''))
+			`, dish.ID, item.Barcode, item.Quantity, item.Unit)
+			if err != nil {
+				return foreignKeyError(err, "failed to insert dish item")
+			}
+		}
 
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
-	}
-	return nil
-}
+		// The tags are only replaced if given
+		if dish.Tags != nil {
+			if err := replaceDishTags(db, dish.ID, dish.Tags); err != nil {
+				return err
+			}
+		}
 
-func InsertDishAsFoodItem(dishID string) error {
-	db := OpenDataBase()
-	defer CloseDataBase(db)
+		// Make sure the units of all ingredients can be converted
+		if _, err := getDishIngredients(db, dish.ID); err != nil {
+			return err
+		}
 
-	// Calculate dish nutrition
-	dishNutrition, err := CalculateDishNutrition(dishID)
-	if err != nil {
-		return fmt.Errorf("failed to calculate dish nutrition: %v", err)
-	}
-	// Get the dish name and barcode
-	var dishName string
-	var dishBarcode *string
-	err = db.QueryRow("SELECT name, barcode FROM dishes WHERE id = ?", dishID).Scan(&dishName, &dishBarcode)
-	if err != nil {
-		return fmt.Errorf("failed to get dish details: %v", err)
-	}
+		// Keep the food item converted from this dish in sync
+		derivedUpdated, err := syncDerivedFoodItems(db, []string{dish.ID})
+		if err != nil {
+			return err
+		}
 
-	// Use the dish's barcode if it exists, otherwise use the dish ID
-	barcode := dishID
-	if dishBarcode != nil {
-		barcode = *dishBarcode
-	}
+		repo.changed(messaging.EntityDish, messaging.ActionUpdated, "", dish.ID)
+		if derivedUpdated {
+			repo.changed(messaging.EntityFoodItem, messaging.ActionUpdated, "")
+		}
+		return nil
+	})
+}
 
-	fmt.Println("Barcode:", barcode)
-	// Check if the barcode already exists in the foodItems table
-	var exists bool
-	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", barcode).Scan(&exists)
-	if err != nil {
-		return fmt.Errorf("error checking if barcode exists: %v", err)
-	}
+// InsertDishAsFoodItem converts a dish into a food item. The food item keeps a link to the dish
+// and is recalculated whenever the dish or one of its ingredients changes.
+func (r *Repository) InsertDishAsFoodItem(ctx context.Context, dishID string) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
 
-	if exists {
-		// Update the existing food item
-		_, err = db.Exec(`
-		UPDATE foodItems 
-		SET name = ?, kcalPer100g = ?, fatPer100g = ?, carbsPer100g = ?, proteinPer100g = ?, 
-		    servingQuantity = ?, servingQuantityUnit = ?, last_updated = ?
-		WHERE barcode = ?
-		`, dishName, dishNutrition.CaloriesPer100g, dishNutrition.FatPer100g,
-			dishNutrition.CarbsPer100g, dishNutrition.ProteinPer100g,
-			dishNutrition.ServingQuantity, dishNutrition.ServingQuantityUnit,
-			time.Now(), barcode)
+		barcode, err := upsertDishFoodItem(db, dishID)
 		if err != nil {
-			return fmt.Errorf("failed to update existing food item: %v", err)
+			return err
 		}
-	} else {
-		// Insert the dish as a new food item
-		_, err = db.Exec(`
-		INSERT INTO foodItems (barcode, name, kcalPer100g, fatPer100g, carbsPer100g, proteinPer100g, 
-		                       servingQuantity, servingQuantityUnit, created_at, last_updated)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		`, barcode, dishName, dishNutrition.CaloriesPer100g, dishNutrition.FatPer100g,
-			dishNutrition.CarbsPer100g, dishNutrition.ProteinPer100g,
-			dishNutrition.ServingQuantity, dishNutrition.ServingQuantityUnit,
-			time.Now(), time.Now())
+
+		// The converted dish may itself be an ingredient of other converted dishes
+		_, err = syncDerivedFoodItemsForIngredient(db, barcode)
 		if err != nil {
-			return fmt.Errorf("failed to insert dish as food item: %v", err)
+			return err
 		}
-	}
 
-	if err := markDatabaseAsUnsynced(); err != nil {
-		log.Printf("Failed to mark database as unsynced: %v", err)
-	}
-	messaging.BroadcastMessage("food_items_updated")
-	return nil
+		repo.changed(messaging.EntityFoodItem, messaging.ActionUpdated, "", barcode)
+		return nil
+	})
 }
 
 func GetDishWithItems(dishID string) (Dish, []DishItem, error) {
@@ -1230,20 +1975,20 @@ func GetDishWithItems(dishID string) (Dish, []DishItem, error) {
 	// Get dish details
 	var dish Dish
 	err := db.QueryRow(`
-        SELECT id, name, barcode, created_at, last_updated
+        SELECT id, name, barcode, cooked_weight, portions, created_at, last_updated
         FROM dishes
         WHERE id = ?
-    `, dishID).Scan(&dish.ID, &dish.Name, &dish.Barcode, &dish.CreatedAt, &dish.LastUpdated)
+    `, dishID).Scan(&dish.ID, &dish.Name, &dish.Barcode, &dish.CookedWeight, &dish.Portions, &dish.CreatedAt, &dish.LastUpdated)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return Dish{}, nil, fmt.Errorf("dish not found with ID: %s", dishID)
+			return Dish{}, nil, types.NewNotFoundError("dish not found with ID: %s", dishID)
 		}
 		return Dish{}, nil, fmt.Errorf("failed to get dish: %v", err)
 	}
 
 	// Get dish items
 	rows, err := db.Query(`
-        SELECT di.barcode, di.quantity, 
+        SELECT di.barcode, di.quantity, COALESCE(di.unit, '') as unit,
                f.name
         FROM dish_items di
         JOIN foodItems f ON di.barcode = f.barcode
@@ -1258,7 +2003,7 @@ func GetDishWithItems(dishID string) (Dish, []DishItem, error) {
 	for rows.Next() {
 		var item DishItem
 		var foodName string
-		err := rows.Scan(&item.Barcode, &item.Quantity, &foodName)
+		err := rows.Scan(&item.Barcode, &item.Quantity, &item.Unit, &foodName)
 		if err != nil {
 			return Dish{}, nil, fmt.Errorf("failed to scan dish item: %v", err)
 		}
@@ -1269,6 +2014,13 @@ func GetDishWithItems(dishID string) (Dish, []DishItem, error) {
 	if err = rows.Err(); err != nil {
 		return Dish{}, nil, fmt.Errorf("error iterating dish items: %v", err)
 	}
+	rows.Close()
+
+	tags, err := getDishTags(db, dishID)
+	if err != nil {
+		return Dish{}, nil, err
+	}
+	dish.Tags = tags[dishID]
 
 	return dish, items, nil
 }
@@ -1310,146 +2062,228 @@ func GetDishItemsAsPersistentFoodItems(dishID string) ([]PersistentFoodItem, err
 	return items, nil
 }
 
-func GetAllDishes() ([]DishWithDetailedItems, error) {
+// GetAllDishes returns a page of the dishes matching the filters of the options with their
+// ingredients and the total number of matching dishes. By default the newest dishes come first.
+func GetAllDishes(options ListOptions) ([]DishWithDetailedItems, int, error) {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
+	var filter listFilter
+	filter.addIncomplete(options.Incomplete, "NOT EXISTS (SELECT 1 FROM dish_items WHERE dish_id = d.id)")
+	filter.addCreatedRange(options, "d.created_at")
+	if options.Tag != "" {
+		filter.add("d.id IN (SELECT dish_id FROM dish_tags WHERE tag = ?)", options.Tag)
+	}
+
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM dishes d"+filter.where(), filter.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count dishes: %v", err)
+	}
+
+	pageClause, pageArgs := page(options)
 	query := `
-    SELECT d.id, d.name, d.barcode, d.created_at, d.last_updated
-    FROM dishes d
-    ORDER BY d.created_at DESC
-    `
+    SELECT d.id, d.name, d.barcode, d.cooked_weight, d.portions, d.created_at, d.last_updated
+    FROM dishes d` + filter.where() + orderBy(options, DishSortKeys, "created_at", "d.id") + pageClause
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, append(filter.args, pageArgs...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query dishes: %v", err)
+		return nil, 0, fmt.Errorf("failed to query dishes: %v", err)
 	}
 	defer rows.Close()
 
-	var dishes []DishWithDetailedItems
-
+	dishes := []DishWithDetailedItems{}
 	for rows.Next() {
 		var dish DishWithDetailedItems
 		err := rows.Scan(
 			&dish.ID,
 			&dish.Name,
 			&dish.Barcode,
+			&dish.CookedWeight,
+			&dish.Portions,
 			&dish.CreatedAt,
 			&dish.LastUpdated,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan dish: %v", err)
+			return nil, 0, fmt.Errorf("failed to scan dish: %v", err)
 		}
+		dishes = append(dishes, dish)
+	}
 
-		// Fetch dish items with full food item details for each dish
-		itemsQuery := `
-        SELECT di.barcode, di.quantity, 
-               f.name, f.kcalPer100g, f.fatPer100g, f.carbsPer100g, f.proteinPer100g, 
-               f.servingQuantity, f.servingQuantityUnit
-        FROM dish_items di
-        JOIN foodItems f ON di.barcode = f.barcode
-        WHERE di.dish_id = ?
-        `
-		itemRows, err := db.Query(itemsQuery, dish.ID)
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating dishes: %v", err)
+	}
+	rows.Close()
+
+	if len(dishes) == 0 {
+		return dishes, total, nil
+	}
+
+	dishIndex := make(map[string]int, len(dishes))
+	dishIDs := make([]string, len(dishes))
+	for i, dish := range dishes {
+		dishIndex[dish.ID] = i
+		dishIDs[i] = dish.ID
+	}
+
+	// Fetch dish items with full food item details for the dishes of the page
+	var itemFilter listFilter
+	itemFilter.addIn("di.dish_id", dishIDs)
+	itemRows, err := db.Query(`
+    SELECT di.dish_id, di.barcode, di.quantity, COALESCE(di.unit, '') as unit,
+           f.name, f.kcalPer100g, f.fatPer100g, f.carbsPer100g, f.proteinPer100g, 
+           f.servingQuantity, f.servingQuantityUnit, f.density
+    FROM dish_items di
+    JOIN foodItems f ON di.barcode = f.barcode`+itemFilter.where()+" ORDER BY di.id", itemFilter.args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query dish items: %v", err)
+	}
+	defer itemRows.Close()
+
+	var barcodes []string
+	seen := make(map[string]bool)
+	for itemRows.Next() {
+		var dishID string
+		var item DetailedDishItem
+		var foodItem PersistentFoodItem
+		err := itemRows.Scan(
+			&dishID, &foodItem.Barcode, &item.Quantity, &item.Unit,
+			&foodItem.Name, &foodItem.CaloriesPer100g, &foodItem.FatPer100g,
+			&foodItem.CarbsPer100g, &foodItem.ProteinPer100g,
+			&foodItem.ServingQuantity, &foodItem.ServingQuantityUnit, &foodItem.Density,
+		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to query dish items: %v", err)
+			return nil, 0, fmt.Errorf("failed to scan dish item: %v", err)
 		}
-		defer itemRows.Close()
-
-		for itemRows.Next() {
-			var item DetailedDishItem
-			var foodItem PersistentFoodItem
-			err := itemRows.Scan(
-				&foodItem.Barcode, &item.Quantity,
-				&foodItem.Name, &foodItem.CaloriesPer100g, &foodItem.FatPer100g,
-				&foodItem.CarbsPer100g, &foodItem.ProteinPer100g,
-				&foodItem.ServingQuantity, &foodItem.ServingQuantityUnit,
-			)
-			if err != nil {
-				return nil, fmt.Errorf("failed to scan dish item: %v", err)
-			}
-			item.Items = foodItem
-			dish.Items = append(dish.Items, item)
+		item.Items = foodItem
+		dishes[dishIndex[dishID]].Items = append(dishes[dishIndex[dishID]].Items, item)
+		if !seen[foodItem.Barcode] {
+			seen[foodItem.Barcode] = true
+			barcodes = append(barcodes, foodItem.Barcode)
 		}
+	}
 
-		dishes = append(dishes, dish)
+	if err = itemRows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating dish items: %v", err)
 	}
+	itemRows.Close()
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating dishes: %v", err)
+	portions := map[string][]FoodPortion{}
+	if len(barcodes) > 0 {
+		portions, err = getFoodPortions(db, barcodes...)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	tags, err := getDishTags(db, dishIDs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range dishes {
+		dishes[i].Tags = tags[dishes[i].ID]
+		for j := range dishes[i].Items {
+			dishes[i].Items[j].Items.Portions = portions[dishes[i].Items[j].Items.Barcode]
+		}
 	}
 
-	return dishes, nil
+	return dishes, total, nil
 }
 
+// CalculateDishNutrition calculates the dish as a food item with nutrition values per 100g
+// of the cooked dish and one portion as serving quantity
 func CalculateDishNutrition(dishID string) (PersistentFoodItem, error) {
-	db := OpenDataBase()
-	defer CloseDataBase(db)
-
 	var dish PersistentFoodItem
 	dish.Barcode = dishID // Use the dish ID as the "barcode"
 
-	var totalWeight float64
-	var totalCalories, totalProtein, totalCarbs, totalFat float64
+	nutrition, err := GetDishNutrition(dishID)
+	if err != nil {
+		return dish, err
+	}
 
-	rows, err := db.Query(`
-	SELECT 
-		di.quantity, 
-		COALESCE(f.kcalPer100g, 0) as kcalPer100g, 
-		COALESCE(f.proteinPer100g, 0) as proteinPer100g, 
-		COALESCE(f.carbsPer100g, 0) as carbsPer100g, 
-		COALESCE(f.fatPer100g, 0) as fatPer100g, 
-		COALESCE(f.servingQuantity, 0) as servingQuantity, 
-		COALESCE(f.servingQuantityUnit, '') as servingQuantityUnit
-	FROM dish_items di
-	JOIN foodItems f ON di.barcode = f.barcode
-	WHERE di.dish_id = ?
-	`, dishID)
-
-	if err != nil {
-		return dish, fmt.Errorf("failed to query dish items: %v", err)
+	return dishNutritionAsFoodItem(nutrition), nil
+}
+
+// dishNutritionAsFoodItem maps the nutrition of a dish to a food item with one portion as serving quantity
+func dishNutritionAsFoodItem(nutrition DishNutrition) PersistentFoodItem {
+	var dish PersistentFoodItem
+	dish.Barcode = nutrition.DishID // Use the dish ID as the "barcode"
+
+	if nutrition.CookedWeight > 0 {
+		dish.Name = nutrition.Name
+		dish.CaloriesPer100g = nutrition.CaloriesPer100g
+		dish.ProteinPer100g = nutrition.ProteinPer100g
+		dish.CarbsPer100g = nutrition.CarbsPer100g
+		dish.FatPer100g = nutrition.FatPer100g
+		dish.ServingQuantity = nutrition.PortionWeight
+		dish.ServingQuantityUnit = "g"
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var quantity, calories, protein, carbs, fat, servingQuantity float64
-		var servingQuantityUnit string
+	return dish
+}
 
-		err := rows.Scan(&quantity, &calories, &protein, &carbs, &fat, &servingQuantity, &servingQuantityUnit)
-		if err != nil {
-			return dish, fmt.Errorf("failed to scan dish item: %v", err)
-		}
+// GetDishNutrition calculates the nutrition values of a dish per 100g of cooked food and per portion.
+// If no cooked weight is set, the dish is assumed to weigh the sum of its raw ingredients.
+// If no portion count is set, the whole dish counts as one portion.
+func GetDishNutrition(dishID string) (DishNutrition, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
 
-		weight := quantity
-		if servingQuantityUnit != "g" && servingQuantity > 0 {
-			weight = quantity * servingQuantity
-		}
+	return calculateDishNutrition(db, dishID)
+}
+
+// calculateDishNutrition calculates the nutrition values of a dish using the given database or transaction
+func calculateDishNutrition(db dbExecutor, dishID string) (DishNutrition, error) {
+	nutrition := DishNutrition{DishID: dishID}
 
-		totalWeight += weight
-		totalCalories += (calories * weight) / 100
-		totalProtein += (protein * weight) / 100
-		totalCarbs += (carbs * weight) / 100
-		totalFat += (fat * weight) / 100
+	// Get dish name and yield
+	var cookedWeight *float64
+	var portions *int
+	err := db.QueryRow("SELECT name, cooked_weight, portions FROM dishes WHERE id = ?", dishID).Scan(&nutrition.Name, &cookedWeight, &portions)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nutrition, types.NewNotFoundError("dish not found with ID: %s", dishID)
+		}
+		return nutrition, fmt.Errorf("failed to get dish name: %v", err)
 	}
 
-	// Get dish name
-	var dishName string
-	err = db.QueryRow("SELECT name FROM dishes WHERE id = ?", dishID).Scan(&dishName)
+	var totalCalories, totalProtein, totalCarbs, totalFat float64
+
+	ingredients, err := getDishIngredients(db, dishID)
 	if err != nil {
-		return dish, fmt.Errorf("failed to get dish name: %v", err)
+		return nutrition, err
 	}
 
-	if totalWeight > 0 {
-		dish.Name = dishName
-		dish.CaloriesPer100g = (totalCalories / totalWeight) * 100
-		dish.ProteinPer100g = (totalProtein / totalWeight) * 100
-		dish.CarbsPer100g = (totalCarbs / totalWeight) * 100
-		dish.FatPer100g = (totalFat / totalWeight) * 100
-		dish.ServingQuantity = totalWeight
-		dish.ServingQuantityUnit = "g"
+	for _, ingredient := range ingredients {
+		nutrition.RawWeight += ingredient.Weight
+		totalCalories += (ingredient.Item.CaloriesPer100g * ingredient.Quantity) / 100
+		totalProtein += (ingredient.Item.ProteinPer100g * ingredient.Quantity) / 100
+		totalCarbs += (ingredient.Item.CarbsPer100g * ingredient.Quantity) / 100
+		totalFat += (ingredient.Item.FatPer100g * ingredient.Quantity) / 100
+	}
+
+	nutrition.CookedWeight = nutrition.RawWeight
+	if cookedWeight != nil && *cookedWeight > 0 {
+		nutrition.CookedWeight = *cookedWeight
 	}
 
-	return dish, nil
+	nutrition.Portions = 1
+	if portions != nil && *portions > 0 {
+		nutrition.Portions = *portions
+	}
+
+	if nutrition.CookedWeight > 0 {
+		nutrition.PortionWeight = nutrition.CookedWeight / float64(nutrition.Portions)
+		nutrition.CaloriesPer100g = (totalCalories / nutrition.CookedWeight) * 100
+		nutrition.ProteinPer100g = (totalProtein / nutrition.CookedWeight) * 100
+		nutrition.CarbsPer100g = (totalCarbs / nutrition.CookedWeight) * 100
+		nutrition.FatPer100g = (totalFat / nutrition.CookedWeight) * 100
+		nutrition.CaloriesPerPortion = totalCalories / float64(nutrition.Portions)
+		nutrition.ProteinPerPortion = totalProtein / float64(nutrition.Portions)
+		nutrition.CarbsPerPortion = totalCarbs / float64(nutrition.Portions)
+		nutrition.FatPerPortion = totalFat / float64(nutrition.Portions)
+	}
+
+	return nutrition, nil
 }
 
 func AddProfile(profile Profile) error {
@@ -1478,7 +2312,7 @@ func AddProfile(profile Profile) error {
 	if err := markDatabaseAsUnsynced(); err != nil {
 		log.Printf("Failed to mark database as unsynced: %v", err)
 	}
-	messaging.BroadcastMessage("profiles_updated")
+	messaging.Publish(messaging.EntityProfile, messaging.ActionCreated, profile.ID, profile.ID)
 	return nil
 }
 
@@ -1493,12 +2327,48 @@ func DeleteProfile(profileID string) error {
 	}
 
 	// Lösche abhängige Daten
+	_, err = tx.Exec("DELETE FROM consumedDishIngredients WHERE consumed_id IN (SELECT id FROM consumedFoodItems WHERE profile_id = ?)", profileID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete consumed dish ingredients: %v", err)
+	}
+
 	_, err = tx.Exec("DELETE FROM consumedFoodItems WHERE profile_id = ?", profileID)
 	if err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete consumed food items: %v", err)
 	}
 
+	_, err = tx.Exec("DELETE FROM goalStrategies WHERE profile_id = ?", profileID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete goal strategy: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM favoriteFoods WHERE profile_id = ?", profileID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete favorite food items: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM mealPlanEntries WHERE profile_id = ?", profileID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete meal plan entries: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM targetHistory WHERE profile_id = ?", profileID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete target history: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM targetOverrides WHERE profile_id = ?", profileID)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete target overrides: %v", err)
+	}
+
 	_, err = tx.Exec("DELETE FROM userSettings WHERE profile_id = ?", profileID)
 	if err != nil {
 		tx.Rollback()
@@ -1520,7 +2390,7 @@ func DeleteProfile(profileID string) error {
 
 	if rowsAffected == 0 {
 		tx.Rollback()
-		return fmt.Errorf("no profile found with id %s", profileID)
+		return types.NewNotFoundError("no profile found with id %s", profileID)
 	}
 
 	err = tx.Commit()
@@ -1531,7 +2401,7 @@ func DeleteProfile(profileID string) error {
 	if err := markDatabaseAsUnsynced(); err != nil {
 		log.Printf("Failed to mark database as unsynced: %v", err)
 	}
-	messaging.BroadcastMessage("profiles_updated")
+	messaging.Publish(messaging.EntityProfile, messaging.ActionDeleted, profileID, profileID)
 	return nil
 }
 
@@ -1556,13 +2426,13 @@ func UpdateProfile(profileID string, name string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("no profile found with id %s", profileID)
+		return types.NewNotFoundError("no profile found with id %s", profileID)
 	}
 
 	if err := markDatabaseAsUnsynced(); err != nil {
 		log.Printf("Failed to mark database as unsynced: %v", err)
 	}
-	messaging.BroadcastMessage("profiles_updated")
+	messaging.Publish(messaging.EntityProfile, messaging.ActionUpdated, profileID, profileID)
 	return nil
 }
 
@@ -1584,7 +2454,7 @@ func GetProfile(profileID string) (Profile, error) {
 	)
 
 	if err == sql.ErrNoRows {
-		return Profile{}, fmt.Errorf("no profile found with id %s", profileID)
+		return Profile{}, types.NewNotFoundError("no profile found with id %s", profileID)
 	}
 	if err != nil {
 		return Profile{}, fmt.Errorf("failed to get profile: %v", err)
@@ -1593,19 +2463,29 @@ func GetProfile(profileID string) (Profile, error) {
 	return profile, nil
 }
 
-func GetAllProfiles() ([]Profile, error) {
+// GetAllProfiles returns a page of the profiles matching the filters of the options and the
+// total number of matching profiles. By default the newest profiles come first.
+func GetAllProfiles(options ListOptions) ([]Profile, int, error) {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
+	var filter listFilter
+	filter.addCreatedRange(options, "created_at")
+
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM profiles"+filter.where(), filter.args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count profiles: %v", err)
+	}
+
+	pageClause, pageArgs := page(options)
 	query := `
     SELECT id, name, created_at
-    FROM profiles
-    ORDER BY created_at DESC
-    `
+    FROM profiles` + filter.where() + orderBy(options, ProfileSortKeys, "created_at", "id") + pageClause
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, append(filter.args, pageArgs...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query profiles: %v", err)
+		return nil, 0, fmt.Errorf("failed to query profiles: %v", err)
 	}
 	defer rows.Close()
 
@@ -1619,16 +2499,16 @@ func GetAllProfiles() ([]Profile, error) {
 			&profile.CreatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan profile: %v", err)
+			return nil, 0, fmt.Errorf("failed to scan profile: %v", err)
 		}
 		profiles = append(profiles, profile)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating profiles: %v", err)
+		return nil, 0, fmt.Errorf("error iterating profiles: %v", err)
 	}
 
-	return profiles, nil
+	return profiles, total, nil
 }
 
 func markDatabaseAsUnsynced() error {
@@ -1655,9 +2535,10 @@ func InsertWeightTracking(profileID string, weight float64) error {
 
 	_, err := db.Exec(query, id, profileID, weight)
 	if err != nil {
-		return fmt.Errorf("failed to insert weight tracking record: %v", err)
+		return foreignKeyError(err, "failed to insert weight tracking record")
 	}
 
+	messaging.PublishDetails(messaging.EntityWeight, messaging.ActionCreated, profileID, map[string]interface{}{"weight": weight}, id)
 	return nil
 }
 
@@ -1666,23 +2547,41 @@ func DeleteConsumedFoodItemsOlderThan(date string) (int64, error) {
 	db := OpenDataBase()
 	defer CloseDataBase(db)
 
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM consumedDishIngredients WHERE consumed_id IN (SELECT id FROM consumedFoodItems WHERE date < ?)", date)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("failed to delete old consumed dish ingredients: %v", err)
+	}
+
 	query := "DELETE FROM consumedFoodItems WHERE date < ?"
 
-	result, err := db.Exec(query, date)
+	result, err := tx.Exec(query, date)
 	if err != nil {
+		tx.Rollback()
 		return 0, fmt.Errorf("failed to delete old consumed food items: %v", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		tx.Rollback()
 		return 0, fmt.Errorf("error checking rows affected: %v", err)
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
 	if rowsAffected > 0 {
 		if err := markDatabaseAsUnsynced(); err != nil {
 			log.Printf("Failed to mark database as unsynced: %v", err)
 		}
-		messaging.BroadcastMessage("consumed_food_items_updated")
+		messaging.Publish(messaging.EntityConsumedFoodItem, messaging.ActionDeleted, "")
 	}
 
 	return rowsAffected, nil
@@ -0,0 +1,87 @@
+package data
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestGetDishNutritionPerPortion(t *testing.T) {
+	repo := NewRepository()
+	ctx := context.Background()
+	for _, item := range []PersistentFoodItem{
+		{Barcode: "portion-oats", Name: "Haferflocken", CaloriesPer100g: 370, ProteinPer100g: 13, CarbsPer100g: 59, FatPer100g: 7, ServingQuantity: 40, ServingQuantityUnit: "g"},
+		{Barcode: "portion-water", Name: "Wasser", ServingQuantity: 250, ServingQuantityUnit: "ml"},
+	} {
+		if err := repo.InsertFoodItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cookedWeight := 450.0
+	portions := 2
+	tests := []struct {
+		name                string
+		dish                Dish
+		wantCookedWeight    float64
+		wantPortions        int
+		wantPortionWeight   float64
+		wantCaloriesPer100g float64
+		wantCaloriesPortion float64
+		wantProteinPortion  float64
+	}{
+		{
+			name:                "cooked weight and portions",
+			dish:                Dish{ID: "portion-porridge", Name: "Porridge", CookedWeight: &cookedWeight, Portions: &portions},
+			wantCookedWeight:    450,
+			wantPortions:        2,
+			wantPortionWeight:   225,
+			wantCaloriesPer100g: 370.0 / 450 * 100,
+			wantCaloriesPortion: 185,
+			wantProteinPortion:  6.5,
+		},
+		{
+			name:                "raw weight as one portion",
+			dish:                Dish{ID: "portion-raw", Name: "Porridge roh"},
+			wantCookedWeight:    500,
+			wantPortions:        1,
+			wantPortionWeight:   500,
+			wantCaloriesPer100g: 74,
+			wantCaloriesPortion: 370,
+			wantProteinPortion:  13,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items := []DishItem{
+				{DishID: test.dish.ID, Barcode: "portion-oats", Quantity: 2.5, Unit: ServingUnit},
+				{DishID: test.dish.ID, Barcode: "portion-water", Quantity: 400},
+			}
+			if err := repo.CreateDish(ctx, test.dish, items); err != nil {
+				t.Fatal(err)
+			}
+
+			nutrition, err := GetDishNutrition(test.dish.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, value := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"raw weight", nutrition.RawWeight, 500},
+				{"cooked weight", nutrition.CookedWeight, test.wantCookedWeight},
+				{"portions", float64(nutrition.Portions), float64(test.wantPortions)},
+				{"portion weight", nutrition.PortionWeight, test.wantPortionWeight},
+				{"calories per 100g", nutrition.CaloriesPer100g, test.wantCaloriesPer100g},
+				{"calories per portion", nutrition.CaloriesPerPortion, test.wantCaloriesPortion},
+				{"protein per portion", nutrition.ProteinPerPortion, test.wantProteinPortion},
+			} {
+				if math.Abs(value.got-value.want) > 1e-9 {
+					t.Errorf("got %s %v, want %v", value.name, value.got, value.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"nutrack/backend/types"
+	"os"
+	"strings"
+	"sync"
+)
+
+// databasePragmas configure every connection of the shared database handle. WAL lets the UI
+// read while a scanner batch writes, the busy timeout makes concurrent writers wait for each
+// other instead of failing with "database is locked", and immediate transactions take the
+// write lock on begin, so a transaction never fails when it upgrades from reading to writing.
+const databasePragmas = "_pragma=journal_mode(WAL)&_pragma=busy_timeout(10000)&_pragma=foreign_keys(1)&_pragma=synchronous(NORMAL)&_txlock=immediate"
+
+// migrationPragmas configure the handle migrating a replacement database file. The file keeps
+// its journal mode, foreign keys are not enforced as in older versions.
+const migrationPragmas = "_pragma=busy_timeout(10000)&_txlock=immediate"
+
+// The database is opened once and shared by all data functions, database/sql pools its
+// connections. OpenDataBase and CloseDataBase count the functions using the handle, so
+// QuiesceDatabase can wait for them before the database file is read or replaced.
+var (
+	databaseMutex    sync.Mutex
+	databaseDrained  = sync.NewCond(&databaseMutex)
+	database         *sql.DB
+	databaseUsers    int
+	databaseReleased chan struct{} // Set while the database is quiesced, closed on release
+)
+
+// OpenDataBase returns the shared database handle, opening it on first use. Every call must
+// be followed by CloseDataBase. While the database is quiesced, OpenDataBase blocks until
+// it is released again, so data functions must not call other functions opening the handle
+// while they use it. Helpers take the caller's handle or transaction as dbExecutor instead.
+func OpenDataBase() *sql.DB {
+	databaseMutex.Lock()
+	defer databaseMutex.Unlock()
+
+	for databaseReleased != nil {
+		released := databaseReleased
+		databaseMutex.Unlock()
+		<-released
+		databaseMutex.Lock()
+	}
+
+	if database == nil {
+		db, err := sql.Open("sqlite", GetDBPath()+"?"+databasePragmas)
+		if err != nil {
+			log.Fatal(err)
+		}
+		database = db
+	}
+
+	databaseUsers++
+	return database
+}
+
+// CloseDataBase releases the handle returned by OpenDataBase. The shared handle itself stays
+// open.
+func CloseDataBase(db *sql.DB) {
+	databaseMutex.Lock()
+	defer databaseMutex.Unlock()
+
+	databaseUsers--
+	if databaseUsers == 0 {
+		databaseDrained.Broadcast()
+	}
+}
+
+// QuiesceDatabase waits for all running data functions, checkpoints the write-ahead log into
+// the database file and closes the shared handle. Then fn may read or replace the database
+// file at dbPath. New data functions are blocked until fn returns, afterwards the handle is
+// reopened on demand. Must not be called while using the handle.
+func QuiesceDatabase(fn func(dbPath string) error) error {
+	databaseMutex.Lock()
+	for databaseReleased != nil {
+		released := databaseReleased
+		databaseMutex.Unlock()
+		<-released
+		databaseMutex.Lock()
+	}
+	released := make(chan struct{})
+	databaseReleased = released
+	for databaseUsers > 0 {
+		databaseDrained.Wait()
+	}
+
+	var err error
+	if database != nil {
+		if _, checkpointErr := database.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); checkpointErr != nil {
+			err = fmt.Errorf("failed to checkpoint database: %v", checkpointErr)
+		}
+		if closeErr := database.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close database: %v", closeErr)
+		}
+		database = nil
+	}
+	databaseMutex.Unlock()
+
+	if err == nil {
+		err = fn(GetDBPath())
+	}
+
+	databaseMutex.Lock()
+	databaseReleased = nil
+	close(released)
+	databaseMutex.Unlock()
+	return err
+}
+
+// SnapshotDatabase copies the database with all committed changes to a temporary file, e.g. for
+// uploads and backups. The caller has to remove the file.
+func SnapshotDatabase() (string, error) {
+	snapshot, err := os.CreateTemp("", "nutrack-snapshot-*.db")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot file: %v", err)
+	}
+	defer snapshot.Close()
+
+	err = QuiesceDatabase(func(dbPath string) error {
+		file, err := os.Open(dbPath)
+		if err != nil {
+			return fmt.Errorf("failed to open database file: %v", err)
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(snapshot, file); err != nil {
+			return fmt.Errorf("failed to copy database file: %v", err)
+		}
+		return snapshot.Sync()
+	})
+	if err != nil {
+		snapshot.Close()
+		os.Remove(snapshot.Name())
+		return "", err
+	}
+
+	return snapshot.Name(), nil
+}
+
+// ReplaceDatabase replaces the database file with the file at path while the database is
+// quiesced, e.g. with a database downloaded from Dropbox. The file may have been written by
+// an older version, so it is migrated before it replaces the database. If the migration
+// fails, the database is kept. The write-ahead log of the old database is removed, it must
+// not be applied to the new file.
+func ReplaceDatabase(path string) error {
+	return QuiesceDatabase(func(dbPath string) error {
+		if err := migrateDatabaseFile(path); err != nil {
+			return err
+		}
+
+		source, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open database file: %v", err)
+		}
+		defer source.Close()
+
+		target, err := os.Create(dbPath)
+		if err != nil {
+			return fmt.Errorf("failed to create database file: %v", err)
+		}
+		defer target.Close()
+
+		if _, err := io.Copy(target, source); err != nil {
+			return fmt.Errorf("failed to copy database file: %v", err)
+		}
+		if err := target.Sync(); err != nil {
+			return fmt.Errorf("failed to sync database file: %v", err)
+		}
+
+		for _, suffix := range []string{"-wal", "-shm"} {
+			if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s file: %v", suffix, err)
+			}
+		}
+		return nil
+	})
+}
+
+// migrateDatabaseFile creates the missing tables, columns, search index and triggers of the
+// database file at path. The file is opened without write-ahead log, so it is complete after
+// the handle is closed.
+func migrateDatabaseFile(path string) error {
+	db, err := sql.Open("sqlite", path+"?"+migrationPragmas)
+	if err != nil {
+		return fmt.Errorf("failed to open database file: %v", err)
+	}
+	defer db.Close()
+
+	if err := setupDatabase(db); err != nil {
+		return fmt.Errorf("failed to migrate database file: %v", err)
+	}
+	return nil
+}
+
+// foreignKeyError returns a not found error for a write violating a foreign key, e.g. a diary
+// entry of a profile which does not exist (anymore), and an internal error with the message
+// otherwise
+func foreignKeyError(err error, message string) error {
+	if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+		return &types.Error{
+			Code:    types.ErrorCodeNotFound,
+			Message: message + ": a referenced profile, food item or dish does not exist",
+			Err:     err,
+		}
+	}
+	return fmt.Errorf("%s: %v", message, err)
+}
+
+// checkForeignKeys logs rows violating foreign keys. Foreign keys were not enforced by older
+// versions, such rows only fail when they are changed.
+func checkForeignKeys(db *sql.DB) {
+	rows, err := db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		log.Printf("Failed to check foreign keys: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	violations := map[string]int{}
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var index int
+		if err := rows.Scan(&table, &rowID, &parent, &index); err != nil {
+			log.Printf("Failed to scan foreign key violation: %v", err)
+			return
+		}
+		violations[table+" -> "+parent]++
+	}
+	for reference, count := range violations {
+		log.Printf("Warning: %d rows of %s violate a foreign key", count, reference)
+	}
+}
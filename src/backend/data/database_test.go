@@ -0,0 +1,126 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nutrack/backend/types"
+)
+
+// TestMain runs the tests of the package on a new database in a temporary directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "nutrack-data-test-*")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("DATA_DIR", dir)
+	InitDatabase()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// olderSchema is the schema of the first released version, before dishes had a yield and
+// consumed entries a snapshot of their nutrition values
+var olderSchema = []string{
+	`CREATE TABLE profiles (id VARCHAR(36) PRIMARY KEY, name VARCHAR(255) NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+	`CREATE TABLE foodItems (barcode TEXT PRIMARY KEY, name TEXT, kcalPer100g REAL, fatPer100g REAL, carbsPer100g REAL,
+		proteinPer100g REAL, servingQuantity REAL, servingQuantityUnit TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP, last_updated DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+	`CREATE TABLE consumedFoodItems (id TEXT PRIMARY KEY, barcode TEXT NOT NULL, consumed_quantity REAL NOT NULL,
+		serving_quantity REAL, date TEXT NOT NULL, insertdate TEXT NOT NULL, profile_id VARCHAR(36),
+		FOREIGN KEY (profile_id) REFERENCES profiles(id))`,
+	`CREATE TABLE userSettings (profile_id VARCHAR(36) PRIMARY KEY, weight REAL, height REAL, calories REAL,
+		proteins REAL, carbs REAL, fat REAL, birthdate TEXT, gender TEXT, activity_level INTEGER,
+		weekly_weight_change REAL, FOREIGN KEY (profile_id) REFERENCES profiles(id))`,
+	`CREATE TABLE dishes (id TEXT PRIMARY KEY, name TEXT NOT NULL, barcode TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP, last_updated DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+	`CREATE TABLE dish_items (id INTEGER PRIMARY KEY AUTOINCREMENT, dish_id TEXT, barcode TEXT, quantity REAL NOT NULL,
+		FOREIGN KEY (dish_id) REFERENCES dishes(id), FOREIGN KEY (barcode) REFERENCES foodItems(barcode))`,
+	`INSERT INTO profiles (id, name) VALUES ('p1', 'Older')`,
+	`INSERT INTO foodItems (barcode, name, kcalPer100g, fatPer100g, carbsPer100g, proteinPer100g, servingQuantity, servingQuantityUnit)
+		VALUES ('4000417025005', 'Haferflocken', 372, 7, 59, 13.5, 40, 'g')`,
+	`INSERT INTO consumedFoodItems (id, barcode, consumed_quantity, serving_quantity, date, insertdate, profile_id)
+		VALUES ('c1', '4000417025005', 2, 40, '2024-05-01', '2024-05-01T08:00:00.000Z', 'p1')`,
+}
+
+func TestReplaceDatabaseMigratesOlderFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "older.db")
+	older, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range olderSchema {
+		if _, err := older.Exec(statement); err != nil {
+			older.Close()
+			t.Fatalf("failed to create older database: %v", err)
+		}
+	}
+	older.Close()
+
+	if err := ReplaceDatabase(path); err != nil {
+		t.Fatalf("ReplaceDatabase failed: %v", err)
+	}
+
+	entries, _, err := GetConsumedFoodItemsByDate("2024-05-01", "p1", ListOptions{})
+	if err != nil {
+		t.Fatalf("reading the migrated diary failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "Haferflocken" {
+		t.Fatalf("got entries %+v, want the snapshotted entry c1", entries)
+	}
+
+	results, err := Search("hafer", "", "", "", 10, 0)
+	if err != nil {
+		t.Fatalf("searching the migrated database failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d search results, want the migrated food item", len(results))
+	}
+}
+
+func TestForeignKeyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want types.ErrorCode
+	}{
+		{"foreign key", errors.New("constraint failed: FOREIGN KEY constraint failed (787)"), types.ErrorCodeNotFound},
+		{"unique", errors.New("constraint failed: UNIQUE constraint failed: profiles.id (1555)"), types.ErrorCodeInternal},
+		{"other", errors.New("database is locked"), types.ErrorCodeInternal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := foreignKeyError(test.err, "failed to insert consumed food item")
+			if got := types.ErrorCodeOf(err); got != test.want {
+				t.Errorf("got code %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInsertConsumedFoodItemOfMissingProfile(t *testing.T) {
+	if err := AddProfile(Profile{ID: "fk-profile", Name: "Foreign keys"}); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewRepository()
+	if err := repo.InsertFoodItem(context.Background(), PersistentFoodItem{Barcode: "fk-food", Name: "Apfel", ServingQuantity: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := repo.InsertConsumedFoodItem(context.Background(), ConsumedFoodItem{
+		ID:               "fk-entry",
+		Barcode:          "fk-food",
+		ConsumedQuantity: 1,
+		Date:             "2024-05-01",
+	}, "missing-profile")
+	if !types.HasErrorCode(err, types.ErrorCodeNotFound) {
+		t.Fatalf("got error %v, want a not found error", err)
+	}
+}
@@ -0,0 +1,145 @@
+package data
+
+import (
+	"fmt"
+	"time"
+)
+
+// upsertDishFoodItem writes the calculated nutrition of a dish into the food item linked to the dish.
+// If the dish has not been converted yet, a food item is created using the dish's barcode or,
+// if the dish has none, the dish ID. Returns the barcode of the food item.
+func upsertDishFoodItem(db dbExecutor, dishID string) (string, error) {
+	nutrition, err := calculateDishNutrition(db, dishID)
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate dish nutrition: %v", err)
+	}
+	dishNutrition := dishNutritionAsFoodItem(nutrition)
+
+	// Use the food item already linked to the dish, otherwise the dish's barcode or the dish ID
+	var barcode string
+	err = db.QueryRow("SELECT barcode FROM foodItems WHERE source_dish_id = ? LIMIT 1", dishID).Scan(&barcode)
+	if err != nil {
+		var dishBarcode *string
+		err = db.QueryRow("SELECT barcode FROM dishes WHERE id = ?", dishID).Scan(&dishBarcode)
+		if err != nil {
+			return "", fmt.Errorf("failed to get dish details: %v", err)
+		}
+
+		barcode = dishID
+		if dishBarcode != nil {
+			barcode = *dishBarcode
+		}
+	}
+
+	// Check if the barcode already exists in the foodItems table
+	var exists bool
+	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", barcode).Scan(&exists)
+	if err != nil {
+		return "", fmt.Errorf("error checking if barcode exists: %v", err)
+	}
+
+	if exists {
+		// Update the existing food item
+		_, err = db.Exec(`
+		UPDATE foodItems 
+		SET name = ?, kcalPer100g = ?, fatPer100g = ?, carbsPer100g = ?, proteinPer100g = ?, 
+		    servingQuantity = ?, servingQuantityUnit = ?, source_dish_id = ?, last_updated = ?
+		WHERE barcode = ?
+		`, nutrition.Name, dishNutrition.CaloriesPer100g, dishNutrition.FatPer100g,
+			dishNutrition.CarbsPer100g, dishNutrition.ProteinPer100g,
+			dishNutrition.ServingQuantity, dishNutrition.ServingQuantityUnit,
+			dishID, time.Now(), barcode)
+		if err != nil {
+			return "", fmt.Errorf("failed to update existing food item: %v", err)
+		}
+	} else {
+		// Insert the dish as a new food item
+		_, err = db.Exec(`
+		INSERT INTO foodItems (barcode, name, kcalPer100g, fatPer100g, carbsPer100g, proteinPer100g, 
+		                       servingQuantity, servingQuantityUnit, source_dish_id, created_at, last_updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, barcode, nutrition.Name, dishNutrition.CaloriesPer100g, dishNutrition.FatPer100g,
+			dishNutrition.CarbsPer100g, dishNutrition.ProteinPer100g,
+			dishNutrition.ServingQuantity, dishNutrition.ServingQuantityUnit,
+			dishID, time.Now(), time.Now())
+		if err != nil {
+			return "", fmt.Errorf("failed to insert dish as food item: %v", err)
+		}
+	}
+
+	return barcode, nil
+}
+
+// syncDerivedFoodItems recalculates the food items converted from the given dishes.
+// Converted dishes can be ingredients of other dishes, so changes are propagated
+// until no further converted food item is affected. Returns true if any food item was updated.
+func syncDerivedFoodItems(db dbExecutor, dishIDs []string) (bool, error) {
+	updated := false
+	visited := make(map[string]bool)
+	queue := append([]string{}, dishIDs...)
+
+	for len(queue) > 0 {
+		dishID := queue[0]
+		queue = queue[1:]
+		if visited[dishID] {
+			continue
+		}
+		visited[dishID] = true
+
+		var linked bool
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE source_dish_id = ?)", dishID).Scan(&linked)
+		if err != nil {
+			return updated, fmt.Errorf("error checking for converted food item: %v", err)
+		}
+		if !linked {
+			continue
+		}
+
+		barcode, err := upsertDishFoodItem(db, dishID)
+		if err != nil {
+			return updated, err
+		}
+		updated = true
+
+		dependentDishIDs, err := getDishIDsByIngredient(db, barcode)
+		if err != nil {
+			return updated, err
+		}
+		queue = append(queue, dependentDishIDs...)
+	}
+
+	return updated, nil
+}
+
+// syncDerivedFoodItemsForIngredient recalculates all converted dishes that use the given food item
+func syncDerivedFoodItemsForIngredient(db dbExecutor, barcode string) (bool, error) {
+	dishIDs, err := getDishIDsByIngredient(db, barcode)
+	if err != nil {
+		return false, err
+	}
+	return syncDerivedFoodItems(db, dishIDs)
+}
+
+// getDishIDsByIngredient returns the IDs of all dishes containing the given food item
+func getDishIDsByIngredient(db dbExecutor, barcode string) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT dish_id FROM dish_items WHERE barcode = ?", barcode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dishes by ingredient: %v", err)
+	}
+	defer rows.Close()
+
+	var dishIDs []string
+	for rows.Next() {
+		var dishID string
+		if err := rows.Scan(&dishID); err != nil {
+			return nil, fmt.Errorf("failed to scan dish ID: %v", err)
+		}
+		dishIDs = append(dishIDs, dishID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dish IDs: %v", err)
+	}
+
+	return dishIDs, nil
+}
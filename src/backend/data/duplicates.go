@@ -0,0 +1,426 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"nutrack/backend/messaging"
+)
+
+// DuplicateFoodItemGroup represents food items that are likely the same product
+type DuplicateFoodItemGroup struct {
+	Items           []PersistentFoodItem `json:"items"`
+	Similarity      float64              `json:"similarity"`       // Lowest name similarity between linked items, between 0 and 1
+	SuggestedTarget string               `json:"suggested_target"` // Barcode of the item to keep, the one logged most often
+}
+
+// FoodItemMergeResult reports which references a merge rewrites
+type FoodItemMergeResult struct {
+	Target                PersistentFoodItem   `json:"target"`
+	Sources               []PersistentFoodItem `json:"sources"`
+	ConsumedEntries       int64                `json:"consumed_entries"`        // Diary entries of the source food items
+	DishIngredients       int64                `json:"dish_ingredients"`        // Ingredients of dishes
+	LoggedDishIngredients int64                `json:"logged_dish_ingredients"` // Ingredients of logged dishes
+	MealPlanEntries       int64                `json:"meal_plan_entries"`
+	Favorites             int64                `json:"favorites"`
+	Portions              int64                `json:"portions"` // Portions moved to the target, portions with an existing name are dropped
+	Tags                  int64                `json:"tags"`     // Tags added to the target
+	Applied               bool                 `json:"applied"`  // False for a preview
+}
+
+const (
+	// Food items with a name similarity of at least this value and near-identical
+	// nutrition values are suggested as duplicates
+	minDuplicateNameSimilarity = 0.75
+
+	// Calories may differ by 5%, at least 10 kcal, macros by 10%, at least 1 g
+	duplicateCaloriesTolerance     = 0.05
+	duplicateMinCaloriesDifference = 10.0
+	duplicateMacroTolerance        = 0.1
+	duplicateMinMacroDifference    = 1.0
+)
+
+// FindDuplicateFoodItems groups food items with similar names and near-identical nutrition values
+func FindDuplicateFoodItems() ([]DuplicateFoodItemGroup, error) {
+	foodItems, _, err := GetAllFoodItems(ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	timesLogged, err := getTimesLogged()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only food items with similar calories can be duplicates, so sorting by calories
+	// limits the name comparisons to neighbours
+	sort.SliceStable(foodItems, func(i, j int) bool {
+		return foodItems[i].CaloriesPer100g < foodItems[j].CaloriesPer100g
+	})
+	names := make([][]string, len(foodItems))
+	for i, item := range foodItems {
+		names[i] = duplicateNameTerms(item.Name)
+	}
+
+	parents := make([]int, len(foodItems))
+	for i := range parents {
+		parents[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+		return parents[i]
+	}
+
+	similarities := make(map[int]float64)
+	for i := range foodItems {
+		for j := i + 1; j < len(foodItems); j++ {
+			if !withinTolerance(foodItems[i].CaloriesPer100g, foodItems[j].CaloriesPer100g, duplicateCaloriesTolerance, duplicateMinCaloriesDifference) {
+				break
+			}
+			if !similarMacros(foodItems[i], foodItems[j]) {
+				continue
+			}
+			similarity := nameSimilarity(names[i], names[j])
+			if similarity < minDuplicateNameSimilarity {
+				continue
+			}
+
+			root := find(i)
+			other := find(j)
+			groupSimilarity := similarity
+			for _, r := range []int{root, other} {
+				if existing, ok := similarities[r]; ok {
+					groupSimilarity = math.Min(groupSimilarity, existing)
+				}
+			}
+			delete(similarities, other)
+			parents[other] = root
+			similarities[root] = groupSimilarity
+		}
+	}
+
+	members := make(map[int][]PersistentFoodItem)
+	for i, item := range foodItems {
+		root := find(i)
+		if _, ok := similarities[root]; ok {
+			members[root] = append(members[root], item)
+		}
+	}
+
+	groups := []DuplicateFoodItemGroup{}
+	for root, items := range members {
+		sort.Slice(items, func(i, j int) bool {
+			if timesLogged[items[i].Barcode] != timesLogged[items[j].Barcode] {
+				return timesLogged[items[i].Barcode] > timesLogged[items[j].Barcode]
+			}
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		})
+		groups = append(groups, DuplicateFoodItemGroup{
+			Items:           items,
+			Similarity:      math.Round(similarities[root]*100) / 100,
+			SuggestedTarget: items[0].Barcode,
+		})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Similarity != groups[j].Similarity {
+			return groups[i].Similarity > groups[j].Similarity
+		}
+		return strings.ToLower(groups[i].Items[0].Name) < strings.ToLower(groups[j].Items[0].Name)
+	})
+
+	return groups, nil
+}
+
+// getTimesLogged returns the number of diary entries per barcode
+func getTimesLogged() (map[string]int, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	rows, err := db.Query("SELECT barcode, COUNT(*) FROM consumedFoodItems WHERE dish_id IS NULL GROUP BY barcode")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query consumed food items: %v", err)
+	}
+	defer rows.Close()
+
+	timesLogged := make(map[string]int)
+	for rows.Next() {
+		var barcode string
+		var count int
+		if err := rows.Scan(&barcode, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan consumed food items: %v", err)
+		}
+		timesLogged[barcode] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating consumed food items: %v", err)
+	}
+
+	return timesLogged, nil
+}
+
+func withinTolerance(a float64, b float64, tolerance float64, minDifference float64) bool {
+	return math.Abs(a-b) <= math.Max(minDifference, tolerance*math.Max(a, b))
+}
+
+func similarMacros(a PersistentFoodItem, b PersistentFoodItem) bool {
+	return withinTolerance(a.ProteinPer100g, b.ProteinPer100g, duplicateMacroTolerance, duplicateMinMacroDifference) &&
+		withinTolerance(a.CarbsPer100g, b.CarbsPer100g, duplicateMacroTolerance, duplicateMinMacroDifference) &&
+		withinTolerance(a.FatPer100g, b.FatPer100g, duplicateMacroTolerance, duplicateMinMacroDifference)
+}
+
+// duplicateNameTerms splits a name into words without umlauts and diacritics
+func duplicateNameTerms(name string) []string {
+	terms := searchTerms(name)
+	for i, term := range terms {
+		terms[i] = removeDiacritics(spellOutUmlauts(term))
+	}
+	return terms
+}
+
+// nameSimilarity compares two names word by word, words with one typo count as equal, as a
+// whole to catch differently split compounds like "Hafer Flocken", and by their leading words
+// to catch varieties of a product like "Haferflocken zart" and "Haferflocken kernig". Returns
+// the highest of these similarities between 0 and 1.
+func nameSimilarity(a []string, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	matched := 0
+	used := make([]bool, len(b))
+	for _, termA := range a {
+		for j, termB := range b {
+			if !used[j] && similarTerms(termA, termB) {
+				used[j] = true
+				matched++
+				break
+			}
+		}
+	}
+	wordSimilarity := 2 * float64(matched) / float64(len(a)+len(b))
+
+	joinedA := strings.Join(a, "")
+	joinedB := strings.Join(b, "")
+	length := max(utf8.RuneCountInString(joinedA), utf8.RuneCountInString(joinedB))
+	textSimilarity := 1 - float64(editDistance(joinedA, joinedB))/float64(length)
+
+	return math.Max(math.Max(wordSimilarity, textSimilarity), prefixSimilarity(a, b))
+}
+
+// prefixSimilarity returns the share of the letters of the shorter name in the leading words
+// both names have in common. German product names start with the product, so the following
+// words are mostly the variety.
+func prefixSimilarity(a []string, b []string) float64 {
+	shared := 0
+	for i := 0; i < len(a) && i < len(b) && similarTerms(a[i], b[i]); i++ {
+		shared += utf8.RuneCountInString(a[i])
+	}
+	length := min(utf8.RuneCountInString(strings.Join(a, "")), utf8.RuneCountInString(strings.Join(b, "")))
+	if length == 0 {
+		return 0
+	}
+	return math.Min(1, float64(shared)/float64(length))
+}
+
+// similarTerms reports if two words are equal or, if both have at least 5 letters, differ by one typo
+func similarTerms(a string, b string) bool {
+	return a == b || (utf8.RuneCountInString(a) >= 5 && utf8.RuneCountInString(b) >= 5 && editDistance(a, b) <= 1)
+}
+
+// errMergePreview rolls back the transaction of a merge which is only previewed
+var errMergePreview = errors.New("merge preview")
+
+// MergeFoodItems replaces the source food items by the target food item in one transaction.
+// Diary entries keep their nutrition values and quantities, ingredients of dishes are converted
+// to grams and planned servings to servings of the target, so no nutrition values change except
+// of planned entries. Favorites, portions and tags are moved to the target, then the source food items
+// are deleted. If apply is false, the changes are rolled back and only reported as preview.
+func (r *Repository) MergeFoodItems(ctx context.Context, targetBarcode string, sourceBarcodes []string, apply bool) (FoodItemMergeResult, error) {
+	var result FoodItemMergeResult
+
+	err := r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
+
+		target, err := GetFoodItemByBarcode(db, targetBarcode)
+		if err != nil {
+			return err
+		}
+		result.Target = target
+
+		for _, barcode := range sourceBarcodes {
+			source, err := GetFoodItemByBarcode(db, barcode)
+			if err != nil {
+				return err
+			}
+			result.Sources = append(result.Sources, source)
+		}
+
+		for _, source := range result.Sources {
+			if err := mergeFoodItem(db, source, target, &result); err != nil {
+				return err
+			}
+		}
+
+		if !apply {
+			return errMergePreview
+		}
+
+		// Converted dishes containing the target change if a source had other nutrition values
+		if _, err := syncDerivedFoodItemsForIngredient(db, targetBarcode); err != nil {
+			return err
+		}
+
+		repo.changed(messaging.EntityFoodItem, messaging.ActionDeleted, "", sourceBarcodes...)
+		repo.changed(messaging.EntityFoodItem, messaging.ActionUpdated, "", targetBarcode)
+		repo.changed(messaging.EntityConsumedFoodItem, messaging.ActionUpdated, "")
+		repo.changed(messaging.EntityDish, messaging.ActionUpdated, "")
+		return nil
+	})
+	if errors.Is(err, errMergePreview) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+func mergeFoodItem(db dbExecutor, source PersistentFoodItem, target PersistentFoodItem, result *FoodItemMergeResult) error {
+	// Freeze the nutrition values of entries logged before they were snapshotted
+	_, err := db.Exec(`
+	UPDATE consumedFoodItems
+	SET name = ?, kcalPer100g = ?, proteinPer100g = ?, carbsPer100g = ?, fatPer100g = ?, servingQuantityUnit = ?
+	WHERE barcode = ? AND dish_id IS NULL AND kcalPer100g IS NULL
+	`, source.Name, source.CaloriesPer100g, source.ProteinPer100g, source.CarbsPer100g, source.FatPer100g,
+		source.ServingQuantityUnit, source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot consumed food items: %v", err)
+	}
+
+	affected, err := execRowsAffected(db, "UPDATE consumedFoodItems SET barcode = ? WHERE barcode = ? AND dish_id IS NULL", target.Barcode, source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to update consumed food items: %v", err)
+	}
+	result.ConsumedEntries += affected
+
+	affected, err = execRowsAffected(db, "UPDATE consumedDishIngredients SET barcode = ? WHERE barcode = ?", target.Barcode, source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to update consumed dish ingredients: %v", err)
+	}
+	result.LoggedDishIngredients += affected
+
+	// Ingredients may use units of the source like portions, so they are stored in grams
+	rows, err := db.Query("SELECT id, quantity, COALESCE(unit, '') FROM dish_items WHERE barcode = ?", source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to query dish items: %v", err)
+	}
+	type dishItem struct {
+		id       int64
+		quantity float64
+		unit     string
+	}
+	var dishItems []dishItem
+	for rows.Next() {
+		var item dishItem
+		if err := rows.Scan(&item.id, &item.quantity, &item.unit); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan dish item: %v", err)
+		}
+		dishItems = append(dishItems, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating dish items: %v", err)
+	}
+
+	for _, item := range dishItems {
+		quantity, err := ConvertToBaseUnit(source, item.quantity, item.unit)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec("UPDATE dish_items SET barcode = ?, quantity = ?, unit = 'g' WHERE id = ?",
+			target.Barcode, WeightInGrams(source, quantity), item.id)
+		if err != nil {
+			return fmt.Errorf("failed to update dish item: %v", err)
+		}
+		result.DishIngredients++
+	}
+
+	// Planned servings of the source become the servings of the target with the same weight
+	factor := 1.0
+	if source.ServingQuantity > 0 && target.ServingQuantity > 0 {
+		grams := WeightInGrams(source, source.ServingQuantity)
+		targetUnit := target.ServingQuantityUnit
+		if targetUnit == "" {
+			targetUnit = "g"
+		}
+		factor = convertBetweenGramsAndMilliliters(target, grams, "g", targetUnit) / target.ServingQuantity
+	}
+	affected, err = execRowsAffected(db, "UPDATE mealPlanEntries SET barcode = ?, quantity = quantity * ? WHERE barcode = ?",
+		target.Barcode, factor, source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to update meal plan entries: %v", err)
+	}
+	result.MealPlanEntries += affected
+
+	affected, err = execRowsAffected(db, `
+	INSERT INTO favoriteFoods (profile_id, barcode, created_at)
+	SELECT profile_id, ?, created_at FROM favoriteFoods WHERE barcode = ?
+	ON CONFLICT(profile_id, barcode) DO NOTHING
+	`, target.Barcode, source.Barcode)
+	if err != nil {
+		return foreignKeyError(err, "failed to move favorite food items")
+	}
+	result.Favorites += affected
+	_, err = db.Exec("DELETE FROM favoriteFoods WHERE barcode = ?", source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to delete favorite food items: %v", err)
+	}
+
+	affected, err = execRowsAffected(db, "UPDATE OR IGNORE food_portions SET barcode = ? WHERE barcode = ?", target.Barcode, source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to move food portions: %v", err)
+	}
+	result.Portions += affected
+	_, err = db.Exec("DELETE FROM food_portions WHERE barcode = ?", source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to delete food portions: %v", err)
+	}
+
+	affected, err = execRowsAffected(db, "UPDATE OR IGNORE food_tags SET barcode = ? WHERE barcode = ?", target.Barcode, source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to move food tags: %v", err)
+	}
+	result.Tags += affected
+	_, err = db.Exec("DELETE FROM food_tags WHERE barcode = ?", source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to delete food tags: %v", err)
+	}
+
+	_, err = db.Exec("DELETE FROM foodItems WHERE barcode = ?", source.Barcode)
+	if err != nil {
+		return fmt.Errorf("failed to delete food item: %v", err)
+	}
+
+	return nil
+}
+
+func execRowsAffected(db dbExecutor, query string, args ...interface{}) (int64, error) {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,243 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"nutrack/backend/types"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		duplicate bool
+	}{
+		{"Haferflocken", "Haferflocken", true},
+		{"Haferflocken", "haferflocken", true},
+		{"Haferflocken zart", "Haferflocken kernig", true},
+		{"Bio Haferflocken zart", "Bio Haferflocken kernig", true},
+		{"Haferflocken", "Haferflocken zart", true},
+		{"Hafer Flocken", "Haferflocken", true},
+		{"Haferflocken", "Haferflockn", true},
+		{"Müsli", "Muesli", true},
+		{"Haferflocken", "Dinkelflocken", false},
+		{"Milchreis", "Milchbrötchen", false},
+		{"Gouda jung", "Emmentaler", false},
+		{"", "Haferflocken", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.a+"/"+test.b, func(t *testing.T) {
+			similarity := nameSimilarity(duplicateNameTerms(test.a), duplicateNameTerms(test.b))
+			if duplicate := similarity >= minDuplicateNameSimilarity; duplicate != test.duplicate {
+				t.Errorf("got similarity %.2f, want duplicate %v", similarity, test.duplicate)
+			}
+			if reverse := nameSimilarity(duplicateNameTerms(test.b), duplicateNameTerms(test.a)); reverse != similarity {
+				t.Errorf("got similarity %.2f in reverse, want %.2f", reverse, similarity)
+			}
+		})
+	}
+}
+
+func TestFindDuplicateFoodItems(t *testing.T) {
+	repo := NewRepository()
+	items := []PersistentFoodItem{
+		{Barcode: "dup-manual", Name: "Haferflocken zart", CaloriesPer100g: 370, ProteinPer100g: 13, CarbsPer100g: 59, FatPer100g: 7, ServingQuantity: 40},
+		{Barcode: "dup-off", Name: "Haferflocken kernig", CaloriesPer100g: 368, ProteinPer100g: 13.5, CarbsPer100g: 58.7, FatPer100g: 7, ServingQuantity: 40},
+		{Barcode: "dup-dish", Name: "Hafer Flocken", CaloriesPer100g: 372, ProteinPer100g: 12.8, CarbsPer100g: 59.5, FatPer100g: 6.9, ServingQuantity: 40},
+		// Similar name, other nutrition values
+		{Barcode: "dup-porridge", Name: "Haferflocken Porridge", CaloriesPer100g: 95, ProteinPer100g: 3.5, CarbsPer100g: 15, FatPer100g: 2, ServingQuantity: 250},
+		// Same nutrition values, other name
+		{Barcode: "dup-dinkel", Name: "Dinkelflocken", CaloriesPer100g: 369, ProteinPer100g: 13.2, CarbsPer100g: 59, FatPer100g: 7, ServingQuantity: 40},
+	}
+	for _, item := range items {
+		if err := repo.InsertFoodItem(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	groups, err := FindDuplicateFoodItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupOf := make(map[string]int)
+	for i, group := range groups {
+		for _, item := range group.Items {
+			groupOf[item.Barcode] = i + 1
+		}
+	}
+	group := groupOf["dup-manual"]
+	if group == 0 || groupOf["dup-off"] != group || groupOf["dup-dish"] != group {
+		t.Fatalf("got groups %v, want the Haferflocken in one group", groupOf)
+	}
+	for _, barcode := range []string{"dup-porridge", "dup-dinkel"} {
+		if groupOf[barcode] == group {
+			t.Errorf("got %s in the Haferflocken group", barcode)
+		}
+	}
+}
+
+func TestMergeFoodItems(t *testing.T) {
+	repo := NewRepository()
+	ctx := context.Background()
+	if err := AddProfile(Profile{ID: "merge-profile", Name: "Merge"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []PersistentFoodItem{
+		{Barcode: "merge-source", Name: "Haferflocken alt", CaloriesPer100g: 380, ProteinPer100g: 12, CarbsPer100g: 60, FatPer100g: 7,
+			ServingQuantity: 50, ServingQuantityUnit: "g",
+			Portions: []FoodPortion{{Name: "Tasse", Quantity: 80, Unit: "g"}, {Name: "Löffel", Quantity: 10, Unit: "g"}},
+			Tags:     []string{"frühstück", "getreide"}},
+		{Barcode: "merge-target", Name: "Haferflocken", CaloriesPer100g: 370, ProteinPer100g: 13, CarbsPer100g: 59, FatPer100g: 7,
+			ServingQuantity: 40, ServingQuantityUnit: "g",
+			Portions: []FoodPortion{{Name: "Löffel", Quantity: 12, Unit: "g"}},
+			Tags:     []string{"frühstück"}},
+	} {
+		if err := repo.InsertFoodItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.InsertConsumedFoodItem(ctx, ConsumedFoodItem{ID: "merge-consumed", Barcode: "merge-source", ConsumedQuantity: 2, ServingQuantity: 50, Date: "2024-06-01", InsertDate: time.Now()}, "merge-profile"); err != nil {
+		t.Fatal(err)
+	}
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+	// Entries logged before nutrition values were snapshotted have none
+	_, err := db.Exec(`
+	INSERT INTO consumedFoodItems (id, barcode, consumed_quantity, serving_quantity, date, insertdate, profile_id)
+	VALUES ('merge-legacy', 'merge-source', 1, 50, '2024-05-01', ?, 'merge-profile')
+	`, FormatDateTimeISO8601(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateDish(ctx, Dish{ID: "merge-dish", Name: "Porridge"}, []DishItem{{DishID: "merge-dish", Barcode: "merge-source", Quantity: 2, Unit: "Tasse"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.InsertConsumedDish(ctx, ConsumedFoodItem{ID: "merge-consumed-dish", Barcode: "merge-dish", ConsumedQuantity: 1, Date: "2024-06-01", InsertDate: time.Now()}, "merge-dish", "merge-profile"); err != nil {
+		t.Fatal(err)
+	}
+	planID, err := repo.InsertMealPlanEntry(ctx, MealPlanEntry{ProfileID: "merge-profile", Date: "2024-06-02", Meal: "breakfast", Barcode: "merge-source", Quantity: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AddFavoriteFoodItem(ctx, "merge-profile", "merge-source"); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(query string, args ...interface{}) int {
+		var n int
+		if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	references := func(barcode string) []int {
+		return []int{
+			count("SELECT COUNT(*) FROM consumedFoodItems WHERE barcode = ?", barcode),
+			count("SELECT COUNT(*) FROM consumedDishIngredients WHERE barcode = ?", barcode),
+			count("SELECT COUNT(*) FROM dish_items WHERE barcode = ?", barcode),
+			count("SELECT COUNT(*) FROM mealPlanEntries WHERE barcode = ?", barcode),
+			count("SELECT COUNT(*) FROM favoriteFoods WHERE barcode = ?", barcode),
+			count("SELECT COUNT(*) FROM food_portions WHERE barcode = ?", barcode),
+			count("SELECT COUNT(*) FROM food_tags WHERE barcode = ?", barcode),
+		}
+	}
+	checkResult := func(t *testing.T, result FoodItemMergeResult, applied bool) {
+		t.Helper()
+		if result.Target.Barcode != "merge-target" || len(result.Sources) != 1 || result.Sources[0].Barcode != "merge-source" || result.Applied != applied {
+			t.Errorf("got result %+v, want merge-source merged into merge-target with applied %v", result, applied)
+		}
+		got := []int64{result.ConsumedEntries, result.DishIngredients, result.LoggedDishIngredients, result.MealPlanEntries, result.Favorites, result.Portions, result.Tags}
+		if want := []int64{2, 1, 1, 1, 1, 1, 1}; fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("got rewritten references %v, want %v", got, want)
+		}
+	}
+
+	t.Run("preview", func(t *testing.T) {
+		sourceBefore, targetBefore := references("merge-source"), references("merge-target")
+
+		result, err := repo.MergeFoodItems(ctx, "merge-target", []string{"merge-source"}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResult(t, result, false)
+
+		if got := references("merge-source"); fmt.Sprint(got) != fmt.Sprint(sourceBefore) {
+			t.Errorf("got references %v of the source, want them unchanged %v", got, sourceBefore)
+		}
+		if got := references("merge-target"); fmt.Sprint(got) != fmt.Sprint(targetBefore) {
+			t.Errorf("got references %v of the target, want them unchanged %v", got, targetBefore)
+		}
+		if _, err := GetFoodItemByBarcode(db, "merge-source"); err != nil {
+			t.Errorf("got error %v, want the source kept", err)
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		result, err := repo.MergeFoodItems(ctx, "merge-target", []string{"merge-source"}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResult(t, result, true)
+
+		if got := references("merge-source"); fmt.Sprint(got) != fmt.Sprint([]int{0, 0, 0, 0, 0, 0, 0}) {
+			t.Errorf("got references %v of the source, want none", got)
+		}
+		if _, err := GetFoodItemByBarcode(db, "merge-source"); !types.HasErrorCode(err, types.ErrorCodeNotFound) {
+			t.Errorf("got error %v, want the source deleted", err)
+		}
+
+		// Diary entries keep the nutrition values of the source
+		var name string
+		var calories float64
+		err = db.QueryRow("SELECT barcode, kcalPer100g FROM consumedFoodItems WHERE id = 'merge-legacy'").Scan(&name, &calories)
+		if err != nil || name != "merge-target" || calories != 380 {
+			t.Errorf("got legacy entry of %s with %v kcal and error %v, want it on the target with 380 kcal", name, calories, err)
+		}
+		if got := count("SELECT COUNT(*) FROM consumedFoodItems WHERE id IN ('merge-consumed', 'merge-legacy') AND barcode = 'merge-target'"); got != 2 {
+			t.Errorf("got %d diary entries on the target, want 2", got)
+		}
+		if got := count("SELECT COUNT(*) FROM consumedDishIngredients WHERE consumed_id = 'merge-consumed-dish' AND barcode = 'merge-target' AND kcalPer100g = 380"); got != 1 {
+			t.Errorf("got %d ingredients of the logged dish on the target, want 1", got)
+		}
+
+		// 2 cups of 80 g are stored in grams
+		var quantity float64
+		var unit string
+		err = db.QueryRow("SELECT barcode, quantity, unit FROM dish_items WHERE dish_id = 'merge-dish'").Scan(&name, &quantity, &unit)
+		if err != nil || name != "merge-target" || quantity != 160 || unit != "g" {
+			t.Errorf("got dish item %s %v %s and error %v, want merge-target 160 g", name, quantity, unit, err)
+		}
+
+		// 2 servings of 50 g are 2.5 servings of 40 g
+		entry, err := repo.GetMealPlanEntry(ctx, planID)
+		if err != nil || entry.Barcode != "merge-target" || entry.Quantity != 2.5 {
+			t.Errorf("got meal plan entry %+v and error %v, want 2.5 servings of merge-target", entry, err)
+		}
+
+		if got := count("SELECT COUNT(*) FROM favoriteFoods WHERE profile_id = 'merge-profile' AND barcode = 'merge-target'"); got != 1 {
+			t.Errorf("got %d favorites of the target, want 1", got)
+		}
+
+		// Portions with the name of a target portion are dropped
+		target, err := GetFoodItemByBarcode(db, "merge-target")
+		if err != nil {
+			t.Fatal(err)
+		}
+		portions := map[string]float64{}
+		for _, portion := range target.Portions {
+			portions[portion.Name] = portion.Quantity
+		}
+		if len(portions) != 2 || portions["Löffel"] != 12 || portions["Tasse"] != 80 {
+			t.Errorf("got portions %v, want the Löffel of the target and the Tasse of the source", portions)
+		}
+		if tags := fmt.Sprint(target.Tags); tags != "[frühstück getreide]" {
+			t.Errorf("got tags %s, want [frühstück getreide]", tags)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+)
+
+// FoodItemUsage represents a food item with statistics on how a profile logs it
+type FoodItemUsage struct {
+	PersistentFoodItem
+	TimesLogged  int     `json:"times_logged"`  // Number of diary entries in the considered period
+	LastLogged   string  `json:"last_logged"`   // Insert date of the latest diary entry, empty if never logged
+	LastQuantity float64 `json:"last_quantity"` // Consumed quantity of the latest diary entry
+	Favorite     bool    `json:"favorite"`
+}
+
+// foodItemUsageQuery selects food items with their usage by a profile. The usage counts diary
+// entries on or after the since date. Its arguments are the profile ID three times and the
+// since date, followed by the arguments of the condition and the limit.
+const foodItemUsageQuery = `
+	SELECT
+		f.barcode,
+		f.name,
+		COALESCE(f.kcalPer100g, 0) as kcalPer100g,
+		COALESCE(f.fatPer100g, 0) as fatPer100g,
+		COALESCE(f.carbsPer100g, 0) as carbsPer100g,
+		COALESCE(f.proteinPer100g, 0) as proteinPer100g,
+		COALESCE(f.servingQuantity, 0) as servingQuantity,
+		COALESCE(f.servingQuantityUnit, '') as servingQuantityUnit,
+		COALESCE(f.source_dish_id, '') as source_dish_id,
+		f.density,
+		f.created_at,
+		f.last_updated,
+		COALESCE(u.times_logged, 0) as times_logged,
+		COALESCE(u.last_logged, '') as last_logged,
+		COALESCE((
+			SELECT c.consumed_quantity FROM consumedFoodItems c
+			WHERE c.profile_id = ? AND c.barcode = f.barcode AND c.dish_id IS NULL
+			ORDER BY c.insertdate DESC LIMIT 1
+		), 0) as last_quantity,
+		EXISTS(SELECT 1 FROM favoriteFoods fav WHERE fav.profile_id = ? AND fav.barcode = f.barcode) as favorite
+	FROM foodItems f
+	LEFT JOIN (
+		SELECT barcode, COUNT(*) as times_logged, MAX(insertdate) as last_logged
+		FROM consumedFoodItems
+		WHERE profile_id = ? AND dish_id IS NULL AND date >= ?
+		GROUP BY barcode
+	) u ON u.barcode = f.barcode
+	WHERE %s
+	ORDER BY %s
+	LIMIT ?
+	`
+
+func queryFoodItemUsage(profileID string, since string, condition string, order string, limit int, args ...interface{}) ([]FoodItemUsage, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	queryArgs := append([]interface{}{profileID, profileID, profileID, since}, args...)
+	queryArgs = append(queryArgs, limit)
+
+	rows, err := db.Query(fmt.Sprintf(foodItemUsageQuery, condition, order), queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query food item usage: %v", err)
+	}
+	defer rows.Close()
+
+	items := []FoodItemUsage{}
+	for rows.Next() {
+		var item FoodItemUsage
+		err := rows.Scan(
+			&item.Barcode,
+			&item.Name,
+			&item.CaloriesPer100g,
+			&item.FatPer100g,
+			&item.CarbsPer100g,
+			&item.ProteinPer100g,
+			&item.ServingQuantity,
+			&item.ServingQuantityUnit,
+			&item.SourceDishID,
+			&item.Density,
+			&item.CreatedAt,
+			&item.LastUpdated,
+			&item.TimesLogged,
+			&item.LastLogged,
+			&item.LastQuantity,
+			&item.Favorite,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan food item usage: %v", err)
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating food item usage: %v", err)
+	}
+	rows.Close()
+
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	barcodes := make([]string, len(items))
+	for i, item := range items {
+		barcodes[i] = item.Barcode
+	}
+	portions, err := getFoodPortions(db, barcodes...)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := getFoodTags(db, barcodes...)
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Portions = portions[items[i].Barcode]
+		items[i].Tags = tags[items[i].Barcode]
+	}
+
+	return items, nil
+}
+
+// GetRecentFoodItems returns the food items a profile logged most recently
+func GetRecentFoodItems(profileID string, limit int) ([]FoodItemUsage, error) {
+	return queryFoodItemUsage(profileID, "", "u.barcode IS NOT NULL", "u.last_logged DESC", limit)
+}
+
+// GetFrequentFoodItems returns the food items a profile logged most often since a date
+func GetFrequentFoodItems(profileID string, since string, limit int) ([]FoodItemUsage, error) {
+	return queryFoodItemUsage(profileID, since, "u.barcode IS NOT NULL", "u.times_logged DESC, u.last_logged DESC", limit)
+}
+
+// GetFavoriteFoodItems returns the food items pinned by a profile, with their usage since a date
+func GetFavoriteFoodItems(profileID string, since string, limit int) ([]FoodItemUsage, error) {
+	return queryFoodItemUsage(profileID, since,
+		"f.barcode IN (SELECT barcode FROM favoriteFoods WHERE profile_id = ?)",
+		"f.name COLLATE NOCASE", limit, profileID)
+}
+
+// AddFavoriteFoodItem pins a food item for a profile
+func (r *Repository) AddFavoriteFoodItem(ctx context.Context, profileID string, barcode string) error {
+	db, release := r.executor(ctx)
+	defer release()
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM profiles WHERE id = ?)", profileID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking if profile exists: %v", err)
+	}
+	if !exists {
+		return types.NewNotFoundError("profile with ID %s does not exist", profileID)
+	}
+
+	err = db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", barcode).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking if barcode exists: %v", err)
+	}
+	if !exists {
+		return types.NewNotFoundError("no food item found with barcode %s", barcode)
+	}
+
+	_, err = db.Exec(`
+	INSERT INTO favoriteFoods (profile_id, barcode, created_at)
+	VALUES (?, ?, ?)
+	ON CONFLICT(profile_id, barcode) DO NOTHING
+	`, profileID, barcode, time.Now())
+	if err != nil {
+		return foreignKeyError(err, "failed to insert favorite food item")
+	}
+
+	r.changed(messaging.EntityFoodItem, messaging.ActionUpdated, profileID, barcode)
+	return nil
+}
+
+// RemoveFavoriteFoodItem unpins a food item for a profile
+func (r *Repository) RemoveFavoriteFoodItem(ctx context.Context, profileID string, barcode string) error {
+	db, release := r.executor(ctx)
+	defer release()
+
+	result, err := db.Exec("DELETE FROM favoriteFoods WHERE profile_id = ? AND barcode = ?", profileID, barcode)
+	if err != nil {
+		return fmt.Errorf("failed to delete favorite food item: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return types.NewNotFoundError("no favorite food item found with barcode %s", barcode)
+	}
+
+	r.changed(messaging.EntityFoodItem, messaging.ActionUpdated, profileID, barcode)
+	return nil
+}
@@ -0,0 +1,274 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+)
+
+// ServingUnit logs a quantity as number of servings of a food item
+const ServingUnit = "serving"
+
+// ConvertToBaseUnit converts a quantity in the given unit to the serving quantity unit of the
+// food item ("g" or "ml"), which the nutrition values per 100 refer to. Supported units are
+// "g", "ml", "serving" and the names of the food item's portions. An empty unit means the
+// serving quantity unit of the food item.
+func ConvertToBaseUnit(item PersistentFoodItem, quantity float64, unit string) (float64, error) {
+	baseUnit := item.ServingQuantityUnit
+	if baseUnit == "" {
+		baseUnit = "g"
+	}
+
+	switch {
+	case unit == "" || unit == baseUnit:
+		return quantity, nil
+	case unit == ServingUnit:
+		return quantity * item.ServingQuantity, nil
+	case unit == "g" || unit == "ml":
+		return convertBetweenGramsAndMilliliters(item, quantity, unit, baseUnit), nil
+	}
+
+	for _, portion := range item.Portions {
+		if strings.EqualFold(portion.Name, unit) {
+			return convertBetweenGramsAndMilliliters(item, quantity*portion.Quantity, portion.Unit, baseUnit), nil
+		}
+	}
+
+	return 0, types.NewValidationError("unit", "unknown unit %s for food item %s", unit, item.Barcode)
+}
+
+// WeightInGrams converts a quantity in the serving quantity unit of the food item to grams
+func WeightInGrams(item PersistentFoodItem, quantity float64) float64 {
+	if item.ServingQuantityUnit != "ml" {
+		return quantity
+	}
+	return convertBetweenGramsAndMilliliters(item, quantity, "ml", "g")
+}
+
+// convertBetweenGramsAndMilliliters uses the density of the food item. Without a known density
+// 1 g/ml is assumed, which is close enough for most drinks.
+func convertBetweenGramsAndMilliliters(item PersistentFoodItem, quantity float64, from string, to string) float64 {
+	if from == to {
+		return quantity
+	}
+
+	density := 1.0
+	if item.Density != nil && *item.Density > 0 {
+		density = *item.Density
+	}
+
+	if from == "ml" {
+		return quantity * density
+	}
+	return quantity / density
+}
+
+// GetFoodItem returns a food item including its portions
+func GetFoodItem(barcode string) (PersistentFoodItem, error) {
+	return NewRepository().GetFoodItem(context.Background(), barcode)
+}
+
+// GetFoodItem returns a food item including its portions
+func (r *Repository) GetFoodItem(ctx context.Context, barcode string) (PersistentFoodItem, error) {
+	db, release := r.executor(ctx)
+	defer release()
+
+	return GetFoodItemByBarcode(db, barcode)
+}
+
+// SetFoodPortions replaces the named portions of a food item
+func SetFoodPortions(barcode string, portions []FoodPortion) error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", barcode).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking if barcode exists: %v", err)
+	}
+	if !exists {
+		return types.NewNotFoundError("no food item found with barcode %s", barcode)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	_, err = tx.Exec("DELETE FROM food_portions WHERE barcode = ?", barcode)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete food portions: %v", err)
+	}
+
+	if err := insertFoodPortions(tx, barcode, portions); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE foodItems SET last_updated = ? WHERE barcode = ?", time.Now(), barcode)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update food item: %v", err)
+	}
+
+	// Dish ingredients may be measured in the changed portions
+	_, err = syncDerivedFoodItemsForIngredient(tx, barcode)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	if err := markDatabaseAsUnsynced(); err != nil {
+		log.Printf("Failed to mark database as unsynced: %v", err)
+	}
+	messaging.Publish(messaging.EntityFoodItem, messaging.ActionUpdated, "", barcode)
+	return nil
+}
+
+func insertFoodPortions(db dbExecutor, barcode string, portions []FoodPortion) error {
+	for _, portion := range portions {
+		_, err := db.Exec(`
+		INSERT INTO food_portions (barcode, name, quantity, unit)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(barcode, name) DO UPDATE SET quantity = excluded.quantity, unit = excluded.unit
+		`, barcode, portion.Name, portion.Quantity, portion.Unit)
+		if err != nil {
+			return fmt.Errorf("failed to insert food portion: %v", err)
+		}
+	}
+	return nil
+}
+
+// getFoodPortions returns the portions of the food items with the barcodes, or of all food
+// items without barcodes
+func getFoodPortions(db dbExecutor, barcodes ...string) (map[string][]FoodPortion, error) {
+	var filter listFilter
+	if len(barcodes) > 0 {
+		filter.addIn("barcode", barcodes)
+	}
+
+	rows, err := db.Query("SELECT barcode, name, quantity, unit FROM food_portions"+filter.where()+" ORDER BY id", filter.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query food portions: %v", err)
+	}
+	defer rows.Close()
+
+	portions := make(map[string][]FoodPortion)
+	for rows.Next() {
+		var portionBarcode string
+		var portion FoodPortion
+		if err := rows.Scan(&portionBarcode, &portion.Name, &portion.Quantity, &portion.Unit); err != nil {
+			return nil, fmt.Errorf("failed to scan food portion: %v", err)
+		}
+		portions[portionBarcode] = append(portions[portionBarcode], portion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating food portions: %v", err)
+	}
+
+	return portions, nil
+}
+
+// dishIngredient is an ingredient of a dish with its quantity converted to the
+// serving quantity unit of the food item and to grams
+type dishIngredient struct {
+	Item     PersistentFoodItem
+	Quantity float64 // In the serving quantity unit of the food item
+	Weight   float64 // In g
+}
+
+// getDishIngredients returns the ingredients of a dish with converted quantities
+func getDishIngredients(db dbExecutor, dishID string) ([]dishIngredient, error) {
+	rows, err := db.Query(`
+	SELECT
+		di.barcode,
+		COALESCE(f.name, '') as name,
+		di.quantity,
+		COALESCE(di.unit, '') as unit,
+		COALESCE(f.kcalPer100g, 0) as kcalPer100g,
+		COALESCE(f.proteinPer100g, 0) as proteinPer100g,
+		COALESCE(f.carbsPer100g, 0) as carbsPer100g,
+		COALESCE(f.fatPer100g, 0) as fatPer100g,
+		COALESCE(f.servingQuantity, 0) as servingQuantity,
+		COALESCE(f.servingQuantityUnit, '') as servingQuantityUnit,
+		f.density
+	FROM dish_items di
+	JOIN foodItems f ON di.barcode = f.barcode
+	WHERE di.dish_id = ?
+	ORDER BY di.id
+	`, dishID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dish items: %v", err)
+	}
+
+	type dishItemRow struct {
+		item     PersistentFoodItem
+		quantity float64
+		unit     string
+	}
+
+	// Read all rows first, the portions are queried afterwards on the same connection
+	var itemRows []dishItemRow
+	for rows.Next() {
+		var row dishItemRow
+		err := rows.Scan(
+			&row.item.Barcode,
+			&row.item.Name,
+			&row.quantity,
+			&row.unit,
+			&row.item.CaloriesPer100g,
+			&row.item.ProteinPer100g,
+			&row.item.CarbsPer100g,
+			&row.item.FatPer100g,
+			&row.item.ServingQuantity,
+			&row.item.ServingQuantityUnit,
+			&row.item.Density,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan dish item: %v", err)
+		}
+		itemRows = append(itemRows, row)
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating dish items: %v", err)
+	}
+	rows.Close()
+
+	var ingredients []dishIngredient
+	for _, row := range itemRows {
+		if row.unit != "" && row.unit != "g" && row.unit != "ml" && row.unit != ServingUnit {
+			portions, err := getFoodPortions(db, row.item.Barcode)
+			if err != nil {
+				return nil, err
+			}
+			row.item.Portions = portions[row.item.Barcode]
+		}
+
+		quantity, err := ConvertToBaseUnit(row.item, row.quantity, row.unit)
+		if err != nil {
+			return nil, err
+		}
+
+		ingredients = append(ingredients, dishIngredient{
+			Item:     row.item,
+			Quantity: quantity,
+			Weight:   WeightInGrams(row.item, quantity),
+		})
+	}
+
+	return ingredients, nil
+}
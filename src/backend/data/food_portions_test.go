@@ -0,0 +1,89 @@
+package data
+
+import (
+	"math"
+	"testing"
+
+	"nutrack/backend/types"
+)
+
+func TestConvertToBaseUnit(t *testing.T) {
+	milkDensity := 1.03
+	bread := PersistentFoodItem{
+		Barcode:             "bread",
+		ServingQuantity:     50,
+		ServingQuantityUnit: "g",
+		Portions:            []FoodPortion{{Name: "slice", Quantity: 28, Unit: "g"}},
+	}
+	milk := PersistentFoodItem{
+		Barcode:             "milk",
+		ServingQuantity:     200,
+		ServingQuantityUnit: "ml",
+		Density:             &milkDensity,
+		Portions:            []FoodPortion{{Name: "cup", Quantity: 250, Unit: "ml"}, {Name: "scoop", Quantity: 10.3, Unit: "g"}},
+	}
+	withoutDensity := PersistentFoodItem{Barcode: "juice", ServingQuantity: 250, ServingQuantityUnit: "ml"}
+	withoutUnit := PersistentFoodItem{Barcode: "oats", ServingQuantity: 40}
+
+	tests := []struct {
+		name     string
+		item     PersistentFoodItem
+		quantity float64
+		unit     string
+		want     float64
+	}{
+		{"base unit", bread, 120, "g", 120},
+		{"empty unit", bread, 120, "", 120},
+		{"servings", bread, 2, ServingUnit, 100},
+		{"portion", bread, 3, "slice", 84},
+		{"portion ignores case", bread, 1, "Slice", 28},
+		{"grams of a drink", milk, 103, "g", 100},
+		{"servings of a drink", milk, 1.5, ServingUnit, 300},
+		{"portion in ml", milk, 2, "cup", 500},
+		{"portion in g of a drink", milk, 1, "scoop", 10},
+		{"grams without density", withoutDensity, 330, "g", 330},
+		{"default base unit", withoutUnit, 2, ServingUnit, 80},
+		{"ml of default base unit", withoutUnit, 50, "ml", 50},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ConvertToBaseUnit(test.item, test.quantity, test.unit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestConvertToBaseUnitOfUnknownUnit(t *testing.T) {
+	_, err := ConvertToBaseUnit(PersistentFoodItem{Barcode: "bread", ServingQuantityUnit: "g"}, 1, "cup")
+	if !types.HasErrorCode(err, types.ErrorCodeValidation) || types.ErrorField(err) != "unit" {
+		t.Fatalf("got error %v, want a validation error of the unit", err)
+	}
+}
+
+func TestWeightInGrams(t *testing.T) {
+	density := 0.92
+	tests := []struct {
+		name     string
+		item     PersistentFoodItem
+		quantity float64
+		want     float64
+	}{
+		{"grams", PersistentFoodItem{ServingQuantityUnit: "g"}, 100, 100},
+		{"ml with density", PersistentFoodItem{ServingQuantityUnit: "ml", Density: &density}, 100, 92},
+		{"ml without density", PersistentFoodItem{ServingQuantityUnit: "ml"}, 100, 100},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := WeightInGrams(test.item, test.quantity); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
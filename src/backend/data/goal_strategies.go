@@ -0,0 +1,104 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+)
+
+// GetGoalStrategy returns the goal strategy of a profile or nil if none is configured
+func GetGoalStrategy(profileID string) (*GoalStrategy, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	query := `
+    SELECT preset, bmr_formula, body_fat_percentage,
+           protein_mode, protein_value, fat_mode, fat_value, carbs_mode, carbs_value, min_carbs
+    FROM goalStrategies
+    WHERE profile_id = ?
+    `
+
+	var strategy GoalStrategy
+	err := db.QueryRow(query, profileID).Scan(
+		&strategy.Preset,
+		&strategy.BMRFormula,
+		&strategy.BodyFatPercentage,
+		&strategy.Protein.Mode,
+		&strategy.Protein.Value,
+		&strategy.Fat.Mode,
+		&strategy.Fat.Value,
+		&strategy.Carbs.Mode,
+		&strategy.Carbs.Value,
+		&strategy.MinCarbs,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get goal strategy: %v", err)
+	}
+
+	return &strategy, nil
+}
+
+// SaveGoalStrategy creates or replaces the goal strategy of a profile
+func SaveGoalStrategy(profileID string, strategy GoalStrategy) error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM profiles WHERE id = ?)", profileID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("error checking if profile exists: %v", err)
+	}
+	if !exists {
+		return types.NewNotFoundError("profile with ID %s does not exist", profileID)
+	}
+
+	_, err = db.Exec(`
+    INSERT OR REPLACE INTO goalStrategies (profile_id, preset, bmr_formula, body_fat_percentage,
+                                           protein_mode, protein_value, fat_mode, fat_value, carbs_mode, carbs_value, min_carbs)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `,
+		profileID,
+		strategy.Preset,
+		strategy.BMRFormula,
+		strategy.BodyFatPercentage,
+		strategy.Protein.Mode,
+		strategy.Protein.Value,
+		strategy.Fat.Mode,
+		strategy.Fat.Value,
+		strategy.Carbs.Mode,
+		strategy.Carbs.Value,
+		strategy.MinCarbs,
+	)
+	if err != nil {
+		return foreignKeyError(err, "failed to save goal strategy")
+	}
+
+	if err := markDatabaseAsUnsynced(); err != nil {
+		log.Printf("Failed to mark database as unsynced: %v", err)
+	}
+	messaging.Publish(messaging.EntityUserSettings, messaging.ActionUpdated, profileID)
+	return nil
+}
+
+// DeleteGoalStrategy removes the goal strategy of a profile, the default calculation is used again
+func DeleteGoalStrategy(profileID string) error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	_, err := db.Exec("DELETE FROM goalStrategies WHERE profile_id = ?", profileID)
+	if err != nil {
+		return fmt.Errorf("failed to delete goal strategy: %v", err)
+	}
+
+	if err := markDatabaseAsUnsynced(); err != nil {
+		log.Printf("Failed to mark database as unsynced: %v", err)
+	}
+	messaging.Publish(messaging.EntityUserSettings, messaging.ActionUpdated, profileID)
+	return nil
+}
@@ -0,0 +1,144 @@
+package data
+
+import (
+	"strings"
+)
+
+// ListOptions selects a page of a list with its sort order and filters. Filters which do not
+// apply to a list are ignored.
+type ListOptions struct {
+	Limit       int    // Maximum number of entries, 0 for all
+	Offset      int    // Number of entries to skip
+	Sort        string // Sort key of the list, empty for the default order
+	Descending  bool
+	Unit        string // Only entries with this serving quantity unit ("g" or "ml")
+	Incomplete  *bool  // Only entries with (true) or without (false) missing data
+	CreatedFrom string // First creation day (YYYY-MM-DD)
+	CreatedTo   string // Last creation day (YYYY-MM-DD)
+	Tag         string // Only entries with this tag
+	Kind        string // Only diary entries of food items ("food") or dishes ("dish")
+}
+
+// Sort keys of the lists with their SQL expressions
+var (
+	FoodItemSortKeys = map[string]string{
+		"name":         "name COLLATE NOCASE",
+		"created_at":   "created_at",
+		"last_updated": "last_updated",
+		"kcal":         "COALESCE(kcalPer100g, 0)",
+	}
+	DishSortKeys = map[string]string{
+		"name":         "d.name COLLATE NOCASE",
+		"created_at":   "d.created_at",
+		"last_updated": "d.last_updated",
+		"kcal":         dishCaloriesPerPortion,
+	}
+	ProfileSortKeys = map[string]string{
+		"name":       "name COLLATE NOCASE",
+		"created_at": "created_at",
+	}
+	ConsumedFoodItemSortKeys = map[string]string{
+		"insert_date": "c.insertdate",
+		"name":        "COALESCE(c.name, f.name, '') COLLATE NOCASE",
+		"kcal":        "c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.kcalPer100g, f.kcalPer100g, 0)",
+	}
+)
+
+// incompleteFoodItemCondition matches food items without a real name, without any nutrition
+// values or without a serving quantity, e.g. placeholders for products unknown to OpenFoodFacts
+const incompleteFoodItemCondition = `(COALESCE(name, '') = '' OR name = barcode
+	OR (COALESCE(kcalPer100g, 0) = 0 AND COALESCE(proteinPer100g, 0) = 0 AND COALESCE(carbsPer100g, 0) = 0 AND COALESCE(fatPer100g, 0) = 0)
+	OR COALESCE(servingQuantity, 0) <= 0)`
+
+// dishCaloriesPerPortion calculates the calories per portion of the dish d like
+// calculateDishNutrition. The quantities of the ingredients are converted to the serving
+// quantity unit of the food item like by ConvertToBaseUnit, ingredients of unknown portions
+// are left out.
+const dishCaloriesPerPortion = `(SELECT COALESCE(SUM(COALESCE(f.kcalPer100g, 0) * CASE
+		WHEN COALESCE(di.unit, '') IN ('', COALESCE(NULLIF(f.servingQuantityUnit, ''), 'g')) THEN di.quantity
+		WHEN di.unit = 'serving' THEN di.quantity * COALESCE(f.servingQuantity, 0)
+		WHEN di.unit = 'ml' THEN di.quantity * (CASE WHEN f.density > 0 THEN f.density ELSE 1 END)
+		WHEN di.unit = 'g' THEN di.quantity / (CASE WHEN f.density > 0 THEN f.density ELSE 1 END)
+		ELSE (SELECT di.quantity * p.quantity * CASE
+				WHEN p.unit = COALESCE(NULLIF(f.servingQuantityUnit, ''), 'g') THEN 1
+				WHEN p.unit = 'ml' THEN (CASE WHEN f.density > 0 THEN f.density ELSE 1 END)
+				ELSE 1 / (CASE WHEN f.density > 0 THEN f.density ELSE 1 END)
+			END
+			FROM food_portions p WHERE p.barcode = di.barcode AND p.name = di.unit COLLATE NOCASE ORDER BY p.id LIMIT 1)
+	END / 100), 0)
+	FROM dish_items di JOIN foodItems f ON di.barcode = f.barcode WHERE di.dish_id = d.id)
+	/ (CASE WHEN d.portions > 0 THEN d.portions ELSE 1 END)`
+
+// listFilter collects the conditions of a list query
+type listFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (f *listFilter) add(condition string, args ...interface{}) {
+	f.conditions = append(f.conditions, condition)
+	f.args = append(f.args, args...)
+}
+
+// addIn limits a column to a list of values
+func (f *listFilter) addIn(column string, values []string) {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	f.add(column+" IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")+")", args...)
+}
+
+// addIncomplete adds a condition and its negation depending on the incomplete filter
+func (f *listFilter) addIncomplete(incomplete *bool, condition string) {
+	if incomplete == nil {
+		return
+	}
+	if *incomplete {
+		f.add(condition)
+	} else {
+		f.add("NOT " + condition)
+	}
+}
+
+// addCreatedRange limits a timestamp column to the creation days of the options. The
+// timestamps are stored in different formats, all of them start with the date.
+func (f *listFilter) addCreatedRange(options ListOptions, column string) {
+	if options.CreatedFrom != "" {
+		f.add("substr("+column+", 1, 10) >= ?", options.CreatedFrom)
+	}
+	if options.CreatedTo != "" {
+		f.add("substr("+column+", 1, 10) <= ?", options.CreatedTo)
+	}
+}
+
+// where returns the WHERE clause of all conditions, or an empty string without conditions
+func (f *listFilter) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// orderBy returns the ORDER BY clause of the sort key of the options, falling back to the
+// default key. The tie breaker keeps the order of pages stable.
+func orderBy(options ListOptions, sortKeys map[string]string, defaultKey string, tieBreaker string) string {
+	expression, ok := sortKeys[options.Sort]
+	if !ok || expression == "" {
+		expression = sortKeys[defaultKey]
+	}
+	direction := " ASC"
+	if options.Descending {
+		direction = " DESC"
+	}
+	return " ORDER BY " + expression + direction + ", " + tieBreaker + direction
+}
+
+// page returns the LIMIT clause of the options with its arguments
+func page(options ListOptions) (string, []interface{}) {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+	return " LIMIT ? OFFSET ?", []interface{}{limit, options.Offset}
+}
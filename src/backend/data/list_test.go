@@ -0,0 +1,169 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestGetAllDishesByCalories(t *testing.T) {
+	repo := NewRepository()
+	ctx := context.Background()
+	milkDensity, oilDensity := 1.03, 0.92
+	for _, item := range []PersistentFoodItem{
+		{Barcode: "kcal-oats", Name: "Haferflocken", CaloriesPer100g: 370, ServingQuantity: 40, ServingQuantityUnit: "g",
+			Portions: []FoodPortion{{Name: "Tasse", Quantity: 80, Unit: "g"}}},
+		{Barcode: "kcal-milk", Name: "Milch", CaloriesPer100g: 64, ServingQuantity: 200, ServingQuantityUnit: "ml", Density: &milkDensity},
+		{Barcode: "kcal-oil", Name: "Rapsöl", CaloriesPer100g: 900, ServingQuantity: 10, ServingQuantityUnit: "g", Density: &oilDensity},
+	} {
+		if err := repo.InsertFoodItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	portions := 2
+	for _, dish := range []struct {
+		dish  Dish
+		items []DishItem
+	}{
+		// 296 kcal of oats and 124.3 kcal of 194.2 ml milk in 2 portions: 210.1 kcal
+		{Dish{ID: "kcal-porridge", Name: "Porridge", Portions: &portions, Tags: []string{"kcal-test"}},
+			[]DishItem{{Barcode: "kcal-oats", Quantity: 1, Unit: "tasse"}, {Barcode: "kcal-milk", Quantity: 200, Unit: "g"}}},
+		// 296 kcal of oats and 82.8 kcal of 9.2 g oil: 378.8 kcal
+		{Dish{ID: "kcal-muesli", Name: "Müsli", Tags: []string{"kcal-test"}},
+			[]DishItem{{Barcode: "kcal-oats", Quantity: 2, Unit: ServingUnit}, {Barcode: "kcal-oil", Quantity: 10, Unit: "ml"}}},
+		// 192 kcal
+		{Dish{ID: "kcal-milk-dish", Name: "Warme Milch", Tags: []string{"kcal-test"}},
+			[]DishItem{{Barcode: "kcal-milk", Quantity: 300}}},
+	} {
+		for i := range dish.items {
+			dish.items[i].DishID = dish.dish.ID
+		}
+		if err := repo.CreateDish(ctx, dish.dish, dish.items); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The SQL sort key has to agree with the calculated nutrition
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+	for _, id := range []string{"kcal-porridge", "kcal-muesli", "kcal-milk-dish"} {
+		nutrition, err := GetDishNutrition(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var calories float64
+		if err := db.QueryRow("SELECT "+dishCaloriesPerPortion+" FROM dishes d WHERE d.id = ?", id).Scan(&calories); err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(calories-nutrition.CaloriesPerPortion) > 1e-9 {
+			t.Errorf("got %v kcal per portion of %s in SQL, want %v", calories, id, nutrition.CaloriesPerPortion)
+		}
+	}
+
+	ascending := []string{"kcal-milk-dish", "kcal-porridge", "kcal-muesli"}
+	tests := []struct {
+		name       string
+		descending bool
+		limit      int
+		offset     int
+		wantIDs    []string
+	}{
+		{"ascending", false, 0, 0, ascending},
+		{"descending", true, 0, 0, []string{"kcal-muesli", "kcal-porridge", "kcal-milk-dish"}},
+		{"second page", false, 2, 2, []string{"kcal-muesli"}},
+		{"first page descending", true, 2, 0, []string{"kcal-muesli", "kcal-porridge"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dishes, total, err := GetAllDishes(ListOptions{
+				Limit:      test.limit,
+				Offset:     test.offset,
+				Sort:       "kcal",
+				Descending: test.descending,
+				Tag:        "kcal-test",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 3 {
+				t.Errorf("got total %d, want 3", total)
+			}
+
+			var ids []string
+			for _, dish := range dishes {
+				ids = append(ids, dish.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(test.wantIDs) {
+				t.Errorf("got %v, want %v", ids, test.wantIDs)
+			}
+		})
+	}
+
+	// The ingredients, their portions and the tags are loaded for the dishes of the page
+	dishes, _, err := GetAllDishes(ListOptions{Limit: 1, Offset: 1, Sort: "kcal", Tag: "kcal-test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dishes) != 1 || len(dishes[0].Items) != 2 || fmt.Sprint(dishes[0].Tags) != "[kcal-test]" {
+		t.Fatalf("got dishes %+v, want the porridge with 2 ingredients and its tag", dishes)
+	}
+	if oats := dishes[0].Items[0]; oats.Items.Barcode != "kcal-oats" || len(oats.Items.Portions) != 1 || oats.Unit != "tasse" {
+		t.Errorf("got ingredient %+v, want 1 tasse of oats with its portion", oats)
+	}
+}
+
+func TestGetAllProfilesPages(t *testing.T) {
+	for day := 1; day <= 5; day++ {
+		profile := Profile{
+			ID:        fmt.Sprintf("page-%d", day),
+			Name:      fmt.Sprintf("Seite %d", day),
+			CreatedAt: time.Date(2001, 2, day, 12, 0, 0, 0, time.UTC),
+		}
+		if err := AddProfile(profile); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		limit   int
+		offset  int
+		wantIDs []string
+	}{
+		{"all", 0, 0, []string{"page-1", "page-2", "page-3", "page-4", "page-5"}},
+		{"first page", 2, 0, []string{"page-1", "page-2"}},
+		{"last partial page", 2, 4, []string{"page-5"}},
+		{"offset after the end", 2, 9, nil},
+		{"offset without limit", 0, 3, []string{"page-4", "page-5"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profiles, total, err := GetAllProfiles(ListOptions{
+				Limit:       test.limit,
+				Offset:      test.offset,
+				Sort:        "created_at",
+				CreatedFrom: "2001-02-01",
+				CreatedTo:   "2001-02-28",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 5 {
+				t.Errorf("got total %d, want 5", total)
+			}
+
+			var ids []string
+			for _, profile := range profiles {
+				ids = append(ids, profile.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(test.wantIDs) {
+				t.Errorf("got %v, want %v", ids, test.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+
+	"github.com/google/uuid"
+)
+
+// MealPlanEntry represents a planned food item or dish for a meal of a profile
+type MealPlanEntry struct {
+	ID         string    `json:"id"`
+	ProfileID  string    `json:"profile_id"`
+	Date       string    `json:"date"`
+	Meal       string    `json:"meal"`
+	Barcode    string    `json:"barcode,omitempty"`     // Set for planned food items
+	DishID     string    `json:"dish_id,omitempty"`     // Set for planned dishes
+	Quantity   float64   `json:"quantity"`              // Servings of the food item or portions of the dish
+	ConsumedID string    `json:"consumed_id,omitempty"` // Diary entry of the eaten plan entry
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+const mealPlanEntryColumns = `id, profile_id, date, meal, COALESCE(barcode, ''), COALESCE(dish_id, ''), quantity, COALESCE(consumed_id, ''), created_at`
+
+func scanMealPlanEntry(scanner interface{ Scan(...interface{}) error }) (MealPlanEntry, error) {
+	var entry MealPlanEntry
+	err := scanner.Scan(
+		&entry.ID,
+		&entry.ProfileID,
+		&entry.Date,
+		&entry.Meal,
+		&entry.Barcode,
+		&entry.DishID,
+		&entry.Quantity,
+		&entry.ConsumedID,
+		&entry.CreatedAt,
+	)
+	return entry, err
+}
+
+// GetMealPlanEntries returns the plan entries of a profile between two dates (inclusive)
+func (r *Repository) GetMealPlanEntries(ctx context.Context, profileID string, startDate string, endDate string) ([]MealPlanEntry, error) {
+	db, release := r.executor(ctx)
+	defer release()
+
+	rows, err := db.Query(`
+	SELECT `+mealPlanEntryColumns+`
+	FROM mealPlanEntries
+	WHERE profile_id = ? AND date BETWEEN ? AND ?
+	ORDER BY date, CASE meal WHEN 'breakfast' THEN 1 WHEN 'lunch' THEN 2 WHEN 'dinner' THEN 3 ELSE 4 END, created_at
+	`, profileID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query meal plan entries: %v", err)
+	}
+	defer rows.Close()
+
+	entries := []MealPlanEntry{}
+	for rows.Next() {
+		entry, err := scanMealPlanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan meal plan entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating meal plan entries: %v", err)
+	}
+
+	return entries, nil
+}
+
+// GetMealPlanEntry returns a plan entry by its ID
+func (r *Repository) GetMealPlanEntry(ctx context.Context, id string) (MealPlanEntry, error) {
+	db, release := r.executor(ctx)
+	defer release()
+
+	entry, err := scanMealPlanEntry(db.QueryRow(`SELECT `+mealPlanEntryColumns+` FROM mealPlanEntries WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return MealPlanEntry{}, types.NewNotFoundError("no meal plan entry found with id %s", id)
+	} else if err != nil {
+		return MealPlanEntry{}, fmt.Errorf("failed to get meal plan entry: %v", err)
+	}
+	return entry, nil
+}
+
+// InsertMealPlanEntry inserts a plan entry and returns its ID
+func (r *Repository) InsertMealPlanEntry(ctx context.Context, entry MealPlanEntry) (string, error) {
+	db, release := r.executor(ctx)
+	defer release()
+
+	if err := checkMealPlanReferences(db, entry); err != nil {
+		return "", err
+	}
+
+	id := uuid.New().String()
+	_, err := db.Exec(`
+	INSERT INTO mealPlanEntries (id, profile_id, date, meal, barcode, dish_id, quantity, created_at)
+	VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?)
+	`, id, entry.ProfileID, entry.Date, entry.Meal, entry.Barcode, entry.DishID, entry.Quantity, time.Now())
+	if err != nil {
+		return "", foreignKeyError(err, "failed to insert meal plan entry")
+	}
+
+	r.changed(messaging.EntityMealPlanEntry, messaging.ActionCreated, entry.ProfileID, id)
+	return id, nil
+}
+
+// UpdateMealPlanEntry replaces the date, meal, food item or dish and quantity of a plan entry
+func (r *Repository) UpdateMealPlanEntry(ctx context.Context, entry MealPlanEntry) error {
+	db, release := r.executor(ctx)
+	defer release()
+
+	if err := checkMealPlanReferences(db, entry); err != nil {
+		return err
+	}
+
+	profileID, err := profileIDOf(db, "mealPlanEntries", entry.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(`
+	UPDATE mealPlanEntries
+	SET date = ?, meal = ?, barcode = NULLIF(?, ''), dish_id = NULLIF(?, ''), quantity = ?
+	WHERE id = ?
+	`, entry.Date, entry.Meal, entry.Barcode, entry.DishID, entry.Quantity, entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update meal plan entry: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return types.NewNotFoundError("no meal plan entry found with id %s", entry.ID)
+	}
+
+	r.changed(messaging.EntityMealPlanEntry, messaging.ActionUpdated, profileID, entry.ID)
+	return nil
+}
+
+// SetMealPlanEntryConsumed links a plan entry to the diary entry it was eaten as
+func (r *Repository) SetMealPlanEntryConsumed(ctx context.Context, id string, consumedID string) error {
+	db, release := r.executor(ctx)
+	defer release()
+
+	profileID, err := profileIDOf(db, "mealPlanEntries", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE mealPlanEntries SET consumed_id = ? WHERE id = ?", consumedID, id)
+	if err != nil {
+		return fmt.Errorf("failed to update meal plan entry: %v", err)
+	}
+
+	r.changed(messaging.EntityMealPlanEntry, messaging.ActionUpdated, profileID, id)
+	return nil
+}
+
+// DeleteMealPlanEntry deletes a plan entry, its diary entry is kept
+func (r *Repository) DeleteMealPlanEntry(ctx context.Context, id string) error {
+	db, release := r.executor(ctx)
+	defer release()
+
+	profileID, err := profileIDOf(db, "mealPlanEntries", id)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec("DELETE FROM mealPlanEntries WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete meal plan entry: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return types.NewNotFoundError("no meal plan entry found with id %s", id)
+	}
+
+	r.changed(messaging.EntityMealPlanEntry, messaging.ActionDeleted, profileID, id)
+	return nil
+}
+
+// checkMealPlanReferences checks that the profile and the planned food item or dish exist
+func checkMealPlanReferences(db dbExecutor, entry MealPlanEntry) error {
+	var exists bool
+	if entry.ProfileID != "" {
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM profiles WHERE id = ?)", entry.ProfileID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("error checking if profile exists: %v", err)
+		}
+		if !exists {
+			return types.NewNotFoundError("profile with ID %s does not exist", entry.ProfileID)
+		}
+	}
+
+	if entry.Barcode != "" {
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", entry.Barcode).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("error checking if barcode exists: %v", err)
+		}
+		if !exists {
+			return types.NewNotFoundError("no food item found with barcode %s", entry.Barcode)
+		}
+	}
+
+	if entry.DishID != "" {
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM dishes WHERE id = ?)", entry.DishID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("error checking if dish exists: %v", err)
+		}
+		if !exists {
+			return types.NewNotFoundError("dish not found")
+		}
+	}
+
+	return nil
+}
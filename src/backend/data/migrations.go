@@ -1,5 +1,130 @@
 package data
 
+import (
+	"database/sql"
+	"fmt"
+)
+
 func RunMigrations() error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	return runMigrations(db)
+}
+
+func runMigrations(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	// Recipe yield of dishes
+	if err := addColumnIfNotExists(tx, "dishes", "cooked_weight", "REAL"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := addColumnIfNotExists(tx, "dishes", "portions", "INTEGER"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Logging dishes directly with a snapshot of their nutrition values
+	consumedFoodItemsColumns := []struct {
+		name       string
+		definition string
+	}{
+		{"dish_id", "TEXT"},
+		{"name", "TEXT"},
+		{"kcalPer100g", "REAL"},
+		{"proteinPer100g", "REAL"},
+		{"carbsPer100g", "REAL"},
+		{"fatPer100g", "REAL"},
+		{"servingQuantityUnit", "TEXT"},
+	}
+	for _, column := range consumedFoodItemsColumns {
+		if err := addColumnIfNotExists(tx, "consumedFoodItems", column.name, column.definition); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Freeze the history of entries logged before nutrition values were snapshotted
+	_, err = tx.Exec(`
+		UPDATE consumedFoodItems
+		SET name = f.name, kcalPer100g = f.kcalPer100g, proteinPer100g = f.proteinPer100g,
+		    carbsPer100g = f.carbsPer100g, fatPer100g = f.fatPer100g, servingQuantityUnit = f.servingQuantityUnit
+		FROM foodItems f
+		WHERE consumedFoodItems.barcode = f.barcode
+		  AND consumedFoodItems.dish_id IS NULL AND consumedFoodItems.kcalPer100g IS NULL
+	`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to snapshot nutrition values of consumed food items: %v", err)
+	}
+	_, err = tx.Exec("UPDATE consumedFoodItems SET servingQuantityUnit = 'g' WHERE dish_id IS NOT NULL AND servingQuantityUnit IS NULL")
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to set serving unit of consumed dishes: %v", err)
+	}
+
+	// Link food items converted from dishes to their dish
+	if err := addColumnIfNotExists(tx, "foodItems", "source_dish_id", "TEXT"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Custom serving units
+	if err := addColumnIfNotExists(tx, "foodItems", "density", "REAL"); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := addColumnIfNotExists(tx, "dish_items", "unit", "TEXT"); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Start the target history with the current targets of profiles saved before it existed
+	_, err = tx.Exec(`
+		INSERT INTO targetHistory (profile_id, effective_date, calories, proteins, carbs, fat)
+		SELECT profile_id, date('now', 'localtime'), COALESCE(calories, 0), COALESCE(proteins, 0), COALESCE(carbs, 0), COALESCE(fat, 0)
+		FROM userSettings u
+		WHERE NOT EXISTS (SELECT 1 FROM targetHistory h WHERE h.profile_id = u.profile_id)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to initialize target history: %v", err)
+	}
+
+	// Full-text search of food items and dishes
+	if err := initSearchIndex(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// addColumnIfNotExists adds a column to a table unless it is already present
+func addColumnIfNotExists(tx *sql.Tx, table string, column string, definition string) error {
+	var columnExists bool
+	err := tx.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&columnExists)
+	if err != nil {
+		return fmt.Errorf("failed to check if %s column exists: %v", column, err)
+	}
+
+	if columnExists {
+		return nil
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to add %s column: %v", column, err)
+	}
+
 	return nil
 }
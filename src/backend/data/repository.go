@@ -0,0 +1,122 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"nutrack/backend/messaging"
+)
+
+// Repository runs data operations with a context, so they are cancelled when the request is.
+// Outside of a transaction every operation uses the shared database handle on its own. Inside
+// of InTransaction all operations are committed together and their change events are only
+// published after the commit.
+type Repository struct {
+	tx      *sql.Tx  // Set inside of InTransaction
+	changes []change // Events to publish after the commit
+}
+
+// change is a pending event of a transaction, see messaging.Publish
+type change struct {
+	entity    string
+	action    string
+	profileID string
+	ids       []string
+}
+
+func NewRepository() *Repository {
+	return &Repository{}
+}
+
+// InTransaction runs fn in a transaction, which is committed if fn returns nil and rolled back
+// otherwise. The transaction is rolled back as well if ctx is cancelled before the commit.
+// Calls inside of a transaction join it.
+func (r *Repository) InTransaction(ctx context.Context, fn func(repo *Repository) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	repo := &Repository{tx: tx}
+	if err := fn(repo); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if len(repo.changes) > 0 {
+		if err := markDatabaseAsUnsynced(); err != nil {
+			log.Printf("Failed to mark database as unsynced: %v", err)
+		}
+	}
+	for _, change := range repo.changes {
+		messaging.Publish(change.entity, change.action, change.profileID, change.ids...)
+	}
+	return nil
+}
+
+// executor returns the transaction or the shared database handle bound to ctx. The returned
+// function releases the handle.
+func (r *Repository) executor(ctx context.Context) (dbExecutor, func()) {
+	if r.tx != nil {
+		return contextExecutor{ctx: ctx, db: r.tx}, func() {}
+	}
+
+	db := OpenDataBase()
+	return contextExecutor{ctx: ctx, db: db}, func() { CloseDataBase(db) }
+}
+
+// changed marks the database as unsynced and publishes the change event, inside of a
+// transaction after the commit. Changes of a transaction with the same entity, action and
+// profile are published as one event.
+func (r *Repository) changed(entity string, action string, profileID string, ids ...string) {
+	if r.tx != nil {
+		for i, pending := range r.changes {
+			if pending.entity == entity && pending.action == action && pending.profileID == profileID {
+				r.changes[i].ids = append(r.changes[i].ids, ids...)
+				return
+			}
+		}
+		r.changes = append(r.changes, change{entity: entity, action: action, profileID: profileID, ids: ids})
+		return
+	}
+
+	if err := markDatabaseAsUnsynced(); err != nil {
+		log.Printf("Failed to mark database as unsynced: %v", err)
+	}
+	messaging.Publish(entity, action, profileID, ids...)
+}
+
+// contextExecutor binds a context to *sql.DB or *sql.Tx, so the helpers taking a dbExecutor
+// are cancelled with it
+type contextExecutor struct {
+	ctx context.Context
+	db  interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	}
+}
+
+func (e contextExecutor) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return e.db.ExecContext(e.ctx, query, args...)
+}
+
+func (e contextExecutor) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return e.db.QueryContext(e.ctx, query, args...)
+}
+
+func (e contextExecutor) QueryRow(query string, args ...interface{}) *sql.Row {
+	return e.db.QueryRowContext(e.ctx, query, args...)
+}
@@ -0,0 +1,448 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"nutrack/backend/types"
+)
+
+const (
+	SearchTypeFoodItem = "food_item"
+	SearchTypeDish     = "dish"
+)
+
+// SearchResult is a food item or a dish matching a local search
+type SearchResult struct {
+	Type     string              `json:"type"` // "food_item" or "dish"
+	ID       string              `json:"id"`   // Barcode of the food item or ID of the dish
+	Name     string              `json:"name"`
+	FoodItem *PersistentFoodItem `json:"food_item,omitempty"`
+	Dish     *DishNutrition      `json:"dish,omitempty"`
+}
+
+// searchAltName spells out German umlauts in a name, so "Müsli" is also found as "Muesli".
+// The tokenizer of the search index removes the diacritics of the original name.
+const searchAltName = `replace(replace(replace(replace(replace(replace(replace(%s,
+	'ä', 'ae'), 'Ä', 'Ae'), 'ö', 'oe'), 'Ö', 'Oe'), 'ü', 'ue'), 'Ü', 'Ue'), 'ß', 'ss')`
+
+// initSearchIndex creates the full-text index of food items and dishes. The index is kept up
+// to date by triggers and filled with the existing food items and dishes when it is created.
+func initSearchIndex(tx *sql.Tx) error {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'searchIndex')").Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check if search index exists: %v", err)
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS searchIndex USING fts5(
+			kind UNINDEXED,
+			ref_id UNINDEXED,
+			name,
+			name_alt,
+			barcode,
+			tokenize = 'unicode61 remove_diacritics 2'
+		)`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS searchVocabulary USING fts5vocab(searchIndex, 'row')`,
+
+		`CREATE TRIGGER IF NOT EXISTS foodItems_search_insert AFTER INSERT ON foodItems BEGIN
+			INSERT INTO searchIndex (kind, ref_id, name, name_alt, barcode)
+			VALUES ('food', new.barcode, new.name, ` + fmt.Sprintf(searchAltName, "new.name") + `, new.barcode);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS foodItems_search_update AFTER UPDATE OF barcode, name ON foodItems BEGIN
+			DELETE FROM searchIndex WHERE kind = 'food' AND ref_id = old.barcode;
+			INSERT INTO searchIndex (kind, ref_id, name, name_alt, barcode)
+			VALUES ('food', new.barcode, new.name, ` + fmt.Sprintf(searchAltName, "new.name") + `, new.barcode);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS foodItems_search_delete AFTER DELETE ON foodItems BEGIN
+			DELETE FROM searchIndex WHERE kind = 'food' AND ref_id = old.barcode;
+		END`,
+
+		`CREATE TRIGGER IF NOT EXISTS dishes_search_insert AFTER INSERT ON dishes BEGIN
+			INSERT INTO searchIndex (kind, ref_id, name, name_alt, barcode)
+			VALUES ('dish', new.id, new.name, ` + fmt.Sprintf(searchAltName, "new.name") + `, COALESCE(new.barcode, ''));
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS dishes_search_update AFTER UPDATE OF name, barcode ON dishes BEGIN
+			DELETE FROM searchIndex WHERE kind = 'dish' AND ref_id = old.id;
+			INSERT INTO searchIndex (kind, ref_id, name, name_alt, barcode)
+			VALUES ('dish', new.id, new.name, ` + fmt.Sprintf(searchAltName, "new.name") + `, COALESCE(new.barcode, ''));
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS dishes_search_delete AFTER DELETE ON dishes BEGIN
+			DELETE FROM searchIndex WHERE kind = 'dish' AND ref_id = old.id;
+		END`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return fmt.Errorf("failed to create search index: %v", err)
+		}
+	}
+
+	if exists {
+		return nil
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO searchIndex (kind, ref_id, name, name_alt, barcode)
+	SELECT 'food', barcode, name, ` + fmt.Sprintf(searchAltName, "name") + `, barcode FROM foodItems
+	UNION ALL
+	SELECT 'dish', id, name, ` + fmt.Sprintf(searchAltName, "name") + `, COALESCE(barcode, '') FROM dishes
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to fill search index: %v", err)
+	}
+
+	return nil
+}
+
+// searchHitsQuery ranks the matches of the search index. Favorites and food items the profile
+// logged often since a date are ranked higher, as are names starting with the first search term.
+// Its arguments are the profile ID, the since date, the profile ID, the match expression, the
+// arguments of the filter, the first search term as name, as first word and as prefix, the
+// limit and the offset.
+const searchHitsQuery = `
+	SELECT searchIndex.kind, searchIndex.ref_id, searchIndex.name
+	FROM searchIndex
+	LEFT JOIN (
+		SELECT barcode as usage_barcode, COUNT(*) as times_logged
+		FROM consumedFoodItems
+		WHERE profile_id = ? AND dish_id IS NULL AND date >= ?
+		GROUP BY barcode
+	) u ON searchIndex.kind = 'food' AND u.usage_barcode = searchIndex.ref_id
+	LEFT JOIN (
+		SELECT barcode as favorite_barcode FROM favoriteFoods WHERE profile_id = ?
+	) fav ON searchIndex.kind = 'food' AND fav.favorite_barcode = searchIndex.ref_id
+	WHERE searchIndex MATCH ?%s
+	ORDER BY
+		bm25(searchIndex, 0, 0, 10.0, 10.0, 5.0)
+		- CASE
+			WHEN searchIndex.name LIKE ? OR searchIndex.name LIKE ? THEN 2 -- Word match at start
+			WHEN searchIndex.name LIKE ? THEN 1                            -- Match at start
+			ELSE 0
+		END
+		- CASE WHEN fav.favorite_barcode IS NOT NULL THEN 1.5 ELSE 0 END -- Favorites of the profile
+		- MIN(COALESCE(u.times_logged, 0), 20) / 10.0,                   -- Frequently logged by the profile
+		length(searchIndex.name),                                        -- Prefer shorter names
+		searchIndex.name COLLATE NOCASE
+	LIMIT ? OFFSET ?
+	`
+
+type searchHit struct {
+	kind string
+	id   string
+	name string
+}
+
+// searchIndexHits returns the ranked matches of a search query. All terms of the query have to
+// match in any order, each term as prefix. If no entry matches, terms without any match are
+// replaced by similar terms of the index to tolerate typos. Only food items and dishes with
+// the tag are returned if one is given.
+func searchIndexHits(db dbExecutor, query string, kind string, tag string, boostProfileID string, since string, limit int, offset int) ([]searchHit, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return []searchHit{}, nil
+	}
+
+	filter := ""
+	var filterArgs []interface{}
+	if kind != "" {
+		filter += " AND searchIndex.kind = ?"
+		filterArgs = append(filterArgs, kind)
+	}
+	if tag != "" {
+		filter += ` AND (
+			(searchIndex.kind = 'food' AND searchIndex.ref_id IN (SELECT barcode FROM food_tags WHERE tag = ?))
+			OR (searchIndex.kind = 'dish' AND searchIndex.ref_id IN (SELECT dish_id FROM dish_tags WHERE tag = ?)))`
+		filterArgs = append(filterArgs, tag, tag)
+	}
+
+	match := searchMatchExpression(terms)
+	var matches bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM searchIndex WHERE searchIndex MATCH ?"+filter+")",
+		append([]interface{}{match}, filterArgs...)...).Scan(&matches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search index: %v", err)
+	}
+	if !matches {
+		match, err = fuzzySearchMatchExpression(db, terms)
+		if err != nil {
+			return nil, err
+		}
+		if match == "" {
+			return []searchHit{}, nil
+		}
+	}
+
+	// Without a profile the joins match nothing and the ranking is unchanged
+	args := append([]interface{}{boostProfileID, since, boostProfileID, match}, filterArgs...)
+	args = append(args, terms[0], terms[0]+" %", terms[0]+"%", limit, offset)
+	rows, err := db.Query(fmt.Sprintf(searchHitsQuery, filter), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query search index: %v", err)
+	}
+	defer rows.Close()
+
+	hits := []searchHit{}
+	for rows.Next() {
+		var hit searchHit
+		if err := rows.Scan(&hit.kind, &hit.id, &hit.name); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %v", err)
+		}
+		hits = append(hits, hit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating search results: %v", err)
+	}
+
+	return hits, nil
+}
+
+// SearchFoodItems searches food items by name or barcode, see searchIndexHits. With a
+// profile ID its favorites and frequently logged food items are ranked higher.
+func SearchFoodItems(query string, tag string, boostProfileID string, since string, limit int, offset int) ([]PersistentFoodItem, error) {
+	if len(query) < 2 {
+		return nil, types.NewValidationError("q", "search query must be at least 2 characters long")
+	}
+
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	hits, err := searchIndexHits(db, query, "food", tag, boostProfileID, since, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	foodItems := []PersistentFoodItem{}
+	for _, hit := range hits {
+		item, err := GetFoodItemByBarcode(db, hit.id)
+		if err != nil {
+			return nil, err
+		}
+		foodItems = append(foodItems, item)
+	}
+
+	return foodItems, nil
+}
+
+// Search searches food items and dishes by name or barcode, see searchIndexHits
+func Search(query string, tag string, boostProfileID string, since string, limit int, offset int) ([]SearchResult, error) {
+	if len(query) < 2 {
+		return nil, types.NewValidationError("q", "search query must be at least 2 characters long")
+	}
+
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	hits, err := searchIndexHits(db, query, "", tag, boostProfileID, since, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+	for _, hit := range hits {
+		result := SearchResult{ID: hit.id, Name: hit.name}
+		if hit.kind == "dish" {
+			nutrition, err := calculateDishNutrition(db, hit.id)
+			if err != nil {
+				return nil, err
+			}
+			result.Type = SearchTypeDish
+			result.Dish = &nutrition
+		} else {
+			item, err := GetFoodItemByBarcode(db, hit.id)
+			if err != nil {
+				return nil, err
+			}
+			result.Type = SearchTypeFoodItem
+			result.FoodItem = &item
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// searchTerms splits a query into lower case words
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchMatchExpression matches all terms as prefix. Terms with umlauts also match their
+// spelled out form.
+func searchMatchExpression(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = quoteSearchTerm(term)
+		if alt := spellOutUmlauts(term); alt != term {
+			parts[i] = "(" + parts[i] + " OR " + quoteSearchTerm(alt) + ")"
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// fuzzySearchMatchExpression replaces the terms without any match in the index by the most
+// similar terms of the index. Returns an empty expression if a term has no similar terms.
+func fuzzySearchMatchExpression(db dbExecutor, terms []string) (string, error) {
+	rows, err := db.Query("SELECT term FROM searchVocabulary")
+	if err != nil {
+		return "", fmt.Errorf("failed to query search vocabulary: %v", err)
+	}
+	defer rows.Close()
+
+	var vocabulary []string
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			return "", fmt.Errorf("failed to scan search vocabulary: %v", err)
+		}
+		vocabulary = append(vocabulary, term)
+	}
+	if err = rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating search vocabulary: %v", err)
+	}
+
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		folded := removeDiacritics(term)
+		alt := spellOutUmlauts(term)
+
+		prefixMatch := false
+		for _, candidate := range vocabulary {
+			if strings.HasPrefix(candidate, folded) || strings.HasPrefix(candidate, alt) {
+				prefixMatch = true
+				break
+			}
+		}
+		if prefixMatch {
+			parts[i] = searchMatchExpression([]string{term})
+			continue
+		}
+
+		similar := similarSearchTerms(folded, vocabulary)
+		if len(similar) == 0 {
+			return "", nil
+		}
+		for j, candidate := range similar {
+			similar[j] = quoteSearchTerm(candidate)
+		}
+		parts[i] = "(" + strings.Join(similar, " OR ") + ")"
+	}
+
+	return strings.Join(parts, " AND "), nil
+}
+
+// maxSimilarSearchTerms limits the number of alternatives for a misspelled term
+const maxSimilarSearchTerms = 5
+
+// similarSearchTerms returns the terms of the vocabulary within the allowed edit distance of
+// a term. Short terms allow no typo, terms with 4 to 7 letters one and longer terms two.
+// Each term is also compared with the beginning of the vocabulary terms, so prefixes with
+// typos still match.
+func similarSearchTerms(term string, vocabulary []string) []string {
+	length := utf8.RuneCountInString(term)
+	maxDistance := 0
+	switch {
+	case length >= 8:
+		maxDistance = 2
+	case length >= 4:
+		maxDistance = 1
+	}
+	if maxDistance == 0 {
+		return nil
+	}
+
+	type candidate struct {
+		term     string
+		distance int
+	}
+	var candidates []candidate
+	for _, vocabularyTerm := range vocabulary {
+		distance := editDistance(term, vocabularyTerm)
+		if runes := []rune(vocabularyTerm); len(runes) > length {
+			distance = min(distance, editDistance(term, string(runes[:length])))
+		}
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{vocabularyTerm, distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].term < candidates[j].term
+	})
+
+	var similar []string
+	for _, c := range candidates {
+		if len(similar) == maxSimilarSearchTerms {
+			break
+		}
+		similar = append(similar, c.term)
+	}
+	return similar
+}
+
+// quoteSearchTerm quotes a term as FTS5 string with prefix matching
+func quoteSearchTerm(term string) string {
+	return `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+}
+
+var umlautReplacer = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+
+func spellOutUmlauts(term string) string {
+	return umlautReplacer.Replace(term)
+}
+
+// diacriticsReplacer removes the diacritics of common lower case letters like the
+// tokenizer of the search index does
+var diacriticsReplacer = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a",
+	"ç", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u",
+	"ý", "y", "ÿ", "y",
+)
+
+func removeDiacritics(term string) string {
+	return diacriticsReplacer.Replace(term)
+}
+
+// editDistance returns the number of inserted, deleted, replaced or swapped adjacent letters
+// needed to turn one string into the other (optimal string alignment distance)
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	distances := make([][]int, len(ra)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(rb)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			distances[i][j] = min(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return distances[len(ra)][len(rb)]
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchFoodItemsWithUmlauts(t *testing.T) {
+	repo := NewRepository()
+	for _, item := range []PersistentFoodItem{
+		{Barcode: "search-muesli", Name: "Knusper Müsli", ServingQuantity: 50},
+		{Barcode: "search-bread", Name: "Weißbrot", ServingQuantity: 50},
+		{Barcode: "search-creme", Name: "Crème fraîche", ServingQuantity: 30},
+	} {
+		if err := repo.InsertFoodItem(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"Müsli", "search-muesli"},
+		{"müsli", "search-muesli"},
+		{"MÜSLI", "search-muesli"},
+		{"Muesli", "search-muesli"},
+		{"Musli", "search-muesli"},
+		{"Müs", "search-muesli"},
+		{"knusper mues", "search-muesli"},
+		{"Weißbrot", "search-bread"},
+		{"Weissbrot", "search-bread"},
+		{"creme fraiche", "search-creme"},
+		{"Crème", "search-creme"},
+		{"search-bread", "search-bread"},
+		// Typos are found by the most similar words of the index
+		{"Weisbrot", "search-bread"},
+		{"Müslii", "search-muesli"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			items, err := SearchFoodItems(test.query, "", "", "", 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, item := range items {
+				if item.Barcode == test.want {
+					return
+				}
+			}
+			t.Errorf("got %d results without %s", len(items), test.want)
+		})
+	}
+}
+
+func TestSearchMatchExpression(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"Müsli", `("müsli"* OR "muesli"*)`},
+		{"Haferflocken zart", `"haferflocken"* AND "zart"*`},
+		{`Say "cheese"`, `"say"* AND "cheese"*`},
+		{"Weiß-Brot", `("weiß"* OR "weiss"*) AND "brot"*`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			if got := searchMatchExpression(searchTerms(test.query)); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+)
+
+// TagUsage contains a tag with the number of food items and dishes using it
+type TagUsage struct {
+	Tag       string `json:"tag"`
+	FoodItems int    `json:"food_items"`
+	Dishes    int    `json:"dishes"`
+}
+
+// TagIntake contains the intake logged from food items and dishes with a tag. Entries
+// without any tag are summed up with an empty tag.
+type TagIntake struct {
+	Tag      string  `json:"tag"`
+	Entries  int     `json:"entries"`
+	Calories float64 `json:"calories"`
+	Proteins float64 `json:"proteins"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+}
+
+// NormalizeTag trims a tag, converts it to lower case and collapses whitespace,
+// so "Snacks" and " snacks " are the same tag
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// NormalizeTags normalizes tags and removes empty and duplicate tags
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// GetTags returns all tags in use, sorted by name
+func GetTags() ([]TagUsage, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	rows, err := db.Query(`
+	SELECT tag, SUM(food_items) as food_items, SUM(dishes) as dishes
+	FROM (
+		SELECT tag, 1 as food_items, 0 as dishes FROM food_tags
+		UNION ALL
+		SELECT tag, 0 as food_items, 1 as dishes FROM dish_tags
+	)
+	GROUP BY tag
+	ORDER BY tag
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tags: %v", err)
+	}
+	defer rows.Close()
+
+	tags := []TagUsage{}
+	for rows.Next() {
+		var tag TagUsage
+		if err := rows.Scan(&tag.Tag, &tag.FoodItems, &tag.Dishes); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %v", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %v", err)
+	}
+
+	return tags, nil
+}
+
+// SetFoodItemTags replaces the tags of a food item
+func (r *Repository) SetFoodItemTags(ctx context.Context, barcode string, tags []string) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
+
+		var exists bool
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM foodItems WHERE barcode = ?)", barcode).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("error checking if barcode exists: %v", err)
+		}
+		if !exists {
+			return types.NewNotFoundError("no food item found with barcode %s", barcode)
+		}
+
+		_, err = db.Exec("DELETE FROM food_tags WHERE barcode = ?", barcode)
+		if err != nil {
+			return fmt.Errorf("failed to delete food tags: %v", err)
+		}
+
+		if err := insertFoodTags(db, barcode, tags); err != nil {
+			return err
+		}
+
+		_, err = db.Exec("UPDATE foodItems SET last_updated = ? WHERE barcode = ?", time.Now(), barcode)
+		if err != nil {
+			return fmt.Errorf("failed to update food item: %v", err)
+		}
+
+		repo.changed(messaging.EntityFoodItem, messaging.ActionUpdated, "", barcode)
+		return nil
+	})
+}
+
+// SetDishTags replaces the tags of a dish
+func (r *Repository) SetDishTags(ctx context.Context, dishID string, tags []string) error {
+	return r.InTransaction(ctx, func(repo *Repository) error {
+		db, release := repo.executor(ctx)
+		defer release()
+
+		var exists bool
+		err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM dishes WHERE id = ?)", dishID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("error checking if dish exists: %v", err)
+		}
+		if !exists {
+			return types.NewNotFoundError("dish not found with ID: %s", dishID)
+		}
+
+		if err := replaceDishTags(db, dishID, tags); err != nil {
+			return err
+		}
+
+		_, err = db.Exec("UPDATE dishes SET last_updated = ? WHERE id = ?", time.Now(), dishID)
+		if err != nil {
+			return fmt.Errorf("failed to update dish: %v", err)
+		}
+
+		repo.changed(messaging.EntityDish, messaging.ActionUpdated, "", dishID)
+		return nil
+	})
+}
+
+// GetIntakeByTag returns the intake of a profile between two dates (inclusive) per tag of the
+// logged food items and dishes. An entry with several tags counts for each of its tags.
+func GetIntakeByTag(profileID string, startDate string, endDate string) ([]TagIntake, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	rows, err := db.Query(`
+	SELECT
+		c.barcode,
+		COALESCE(c.dish_id, '') as dish_id,
+		c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.kcalPer100g, f.kcalPer100g, 0) / 100 as calories,
+		c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.proteinPer100g, f.proteinPer100g, 0) / 100 as proteins,
+		c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.carbsPer100g, f.carbsPer100g, 0) / 100 as carbs,
+		c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.fatPer100g, f.fatPer100g, 0) / 100 as fat
+	FROM consumedFoodItems c
+	LEFT JOIN foodItems f ON c.barcode = f.barcode
+	WHERE c.profile_id = ? AND c.date BETWEEN ? AND ? AND (f.barcode IS NOT NULL OR c.kcalPer100g IS NOT NULL)
+	`, profileID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query intake: %v", err)
+	}
+
+	type intakeRow struct {
+		barcode string
+		dishID  string
+		intake  TagIntake
+	}
+
+	// Read all rows first, the tags are queried afterwards on the same connection
+	var intakeRows []intakeRow
+	for rows.Next() {
+		var row intakeRow
+		err := rows.Scan(&row.barcode, &row.dishID, &row.intake.Calories, &row.intake.Proteins, &row.intake.Carbs, &row.intake.Fat)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan intake: %v", err)
+		}
+		intakeRows = append(intakeRows, row)
+	}
+	if err = rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating intake: %v", err)
+	}
+	rows.Close()
+
+	foodTags, err := getFoodTags(db)
+	if err != nil {
+		return nil, err
+	}
+	dishTags, err := getDishTags(db)
+	if err != nil {
+		return nil, err
+	}
+
+	byTag := make(map[string]*TagIntake)
+	for _, row := range intakeRows {
+		tags := foodTags[row.barcode]
+		if row.dishID != "" {
+			tags = dishTags[row.dishID]
+		}
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+
+		for _, tag := range tags {
+			total, ok := byTag[tag]
+			if !ok {
+				total = &TagIntake{Tag: tag}
+				byTag[tag] = total
+			}
+			total.Entries++
+			total.Calories += row.intake.Calories
+			total.Proteins += row.intake.Proteins
+			total.Carbs += row.intake.Carbs
+			total.Fat += row.intake.Fat
+		}
+	}
+
+	intake := make([]TagIntake, 0, len(byTag))
+	for _, total := range byTag {
+		intake = append(intake, *total)
+	}
+	sort.Slice(intake, func(i, j int) bool {
+		if intake[i].Calories != intake[j].Calories {
+			return intake[i].Calories > intake[j].Calories
+		}
+		return intake[i].Tag < intake[j].Tag
+	})
+
+	return intake, nil
+}
+
+func insertFoodTags(db dbExecutor, barcode string, tags []string) error {
+	for _, tag := range NormalizeTags(tags) {
+		_, err := db.Exec("INSERT OR IGNORE INTO food_tags (barcode, tag) VALUES (?, ?)", barcode, tag)
+		if err != nil {
+			return foreignKeyError(err, "failed to insert food tag")
+		}
+	}
+	return nil
+}
+
+func insertDishTags(db dbExecutor, dishID string, tags []string) error {
+	for _, tag := range NormalizeTags(tags) {
+		_, err := db.Exec("INSERT OR IGNORE INTO dish_tags (dish_id, tag) VALUES (?, ?)", dishID, tag)
+		if err != nil {
+			return foreignKeyError(err, "failed to insert dish tag")
+		}
+	}
+	return nil
+}
+
+func replaceDishTags(db dbExecutor, dishID string, tags []string) error {
+	_, err := db.Exec("DELETE FROM dish_tags WHERE dish_id = ?", dishID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dish tags: %v", err)
+	}
+	return insertDishTags(db, dishID, tags)
+}
+
+// getFoodTags returns the tags of the food items with the barcodes, or of all food items
+// without barcodes
+func getFoodTags(db dbExecutor, barcodes ...string) (map[string][]string, error) {
+	var filter listFilter
+	if len(barcodes) > 0 {
+		filter.addIn("barcode", barcodes)
+	}
+
+	rows, err := db.Query("SELECT barcode, tag FROM food_tags"+filter.where()+" ORDER BY tag", filter.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query food tags: %v", err)
+	}
+	return scanTags(rows, "food")
+}
+
+// getDishTags returns the tags of the dishes with the IDs, or of all dishes without IDs
+func getDishTags(db dbExecutor, dishIDs ...string) (map[string][]string, error) {
+	var filter listFilter
+	if len(dishIDs) > 0 {
+		filter.addIn("dish_id", dishIDs)
+	}
+
+	rows, err := db.Query("SELECT dish_id, tag FROM dish_tags"+filter.where()+" ORDER BY tag", filter.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dish tags: %v", err)
+	}
+	return scanTags(rows, "dish")
+}
+
+func scanTags(rows *sql.Rows, kind string) (map[string][]string, error) {
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var id, tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, fmt.Errorf("failed to scan %s tag: %v", kind, err)
+		}
+		tags[id] = append(tags[id], tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s tags: %v", kind, err)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,250 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+
+	"github.com/google/uuid"
+)
+
+// recordTargetHistory stores the targets of the settings as the targets from today on.
+// Nothing is stored if the targets didn't change.
+func recordTargetHistory(db dbExecutor, profileID string, settings UserSettings) error {
+	today := time.Now().Format("2006-01-02")
+
+	var latest TargetHistoryEntry
+	err := db.QueryRow(`
+	SELECT effective_date, calories, proteins, carbs, fat
+	FROM targetHistory
+	WHERE profile_id = ? AND effective_date <= ?
+	ORDER BY effective_date DESC
+	LIMIT 1
+	`, profileID, today).Scan(&latest.EffectiveDate, &latest.Calories, &latest.Proteins, &latest.Carbs, &latest.Fat)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query target history: %v", err)
+	}
+
+	if err == nil && latest.Calories == settings.Calories && latest.Proteins == settings.Proteins &&
+		latest.Carbs == settings.Carbs && latest.Fat == settings.Fat {
+		return nil
+	}
+
+	_, err = db.Exec(`
+	INSERT INTO targetHistory (profile_id, effective_date, calories, proteins, carbs, fat)
+	VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(profile_id, effective_date) DO UPDATE SET
+		calories = excluded.calories, proteins = excluded.proteins, carbs = excluded.carbs, fat = excluded.fat
+	`, profileID, today, settings.Calories, settings.Proteins, settings.Carbs, settings.Fat)
+	if err != nil {
+		return foreignKeyError(err, "failed to insert target history")
+	}
+
+	return nil
+}
+
+// GetTargetHistory returns the target changes of a profile ordered by effective date
+func GetTargetHistory(profileID string) ([]TargetHistoryEntry, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	rows, err := db.Query(`
+	SELECT effective_date, calories, proteins, carbs, fat
+	FROM targetHistory
+	WHERE profile_id = ?
+	ORDER BY effective_date
+	`, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query target history: %v", err)
+	}
+	defer rows.Close()
+
+	history := []TargetHistoryEntry{}
+	for rows.Next() {
+		var entry TargetHistoryEntry
+		if err := rows.Scan(&entry.EffectiveDate, &entry.Calories, &entry.Proteins, &entry.Carbs, &entry.Fat); err != nil {
+			return nil, fmt.Errorf("failed to scan target history: %v", err)
+		}
+		history = append(history, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating target history: %v", err)
+	}
+
+	return history, nil
+}
+
+// GetTargetOverrides returns the target overrides of a profile, the newest first
+func GetTargetOverrides(profileID string) ([]TargetOverride, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	rows, err := db.Query(`
+	SELECT id, profile_id, name, weekdays, COALESCE(start_date, ''), COALESCE(end_date, ''), calories, proteins, carbs, fat, created_at
+	FROM targetOverrides
+	WHERE profile_id = ?
+	ORDER BY created_at DESC, rowid DESC
+	`, profileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query target overrides: %v", err)
+	}
+	defer rows.Close()
+
+	overrides := []TargetOverride{}
+	for rows.Next() {
+		var override TargetOverride
+		var weekdays string
+		err := rows.Scan(
+			&override.ID,
+			&override.ProfileID,
+			&override.Name,
+			&weekdays,
+			&override.StartDate,
+			&override.EndDate,
+			&override.Calories,
+			&override.Proteins,
+			&override.Carbs,
+			&override.Fat,
+			&override.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan target override: %v", err)
+		}
+		override.Weekdays = parseWeekdays(weekdays)
+		overrides = append(overrides, override)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating target overrides: %v", err)
+	}
+
+	return overrides, nil
+}
+
+// CreateTargetOverride inserts a target override and returns its ID
+func CreateTargetOverride(override TargetOverride) (string, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM profiles WHERE id = ?)", override.ProfileID).Scan(&exists)
+	if err != nil {
+		return "", fmt.Errorf("error checking if profile exists: %v", err)
+	}
+	if !exists {
+		return "", types.NewNotFoundError("profile with ID %s does not exist", override.ProfileID)
+	}
+
+	id := uuid.New().String()
+	_, err = db.Exec(`
+	INSERT INTO targetOverrides (id, profile_id, name, weekdays, start_date, end_date, calories, proteins, carbs, fat, created_at)
+	VALUES (?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?)
+	`, id, override.ProfileID, override.Name, formatWeekdays(override.Weekdays), override.StartDate, override.EndDate,
+		override.Calories, override.Proteins, override.Carbs, override.Fat, time.Now())
+	if err != nil {
+		return "", foreignKeyError(err, "failed to insert target override")
+	}
+
+	if err := markDatabaseAsUnsynced(); err != nil {
+		log.Printf("Failed to mark database as unsynced: %v", err)
+	}
+	messaging.Publish(messaging.EntityUserSettings, messaging.ActionUpdated, override.ProfileID)
+	return id, nil
+}
+
+// UpdateTargetOverride replaces the rules and targets of a target override
+func UpdateTargetOverride(override TargetOverride) error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	profileID, err := profileIDOf(db, "targetOverrides", override.ID)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(`
+	UPDATE targetOverrides
+	SET name = ?, weekdays = ?, start_date = NULLIF(?, ''), end_date = NULLIF(?, ''), calories = ?, proteins = ?, carbs = ?, fat = ?
+	WHERE id = ?
+	`, override.Name, formatWeekdays(override.Weekdays), override.StartDate, override.EndDate,
+		override.Calories, override.Proteins, override.Carbs, override.Fat, override.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update target override: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return types.NewNotFoundError("no target override found with id %s", override.ID)
+	}
+
+	if err := markDatabaseAsUnsynced(); err != nil {
+		log.Printf("Failed to mark database as unsynced: %v", err)
+	}
+	messaging.Publish(messaging.EntityUserSettings, messaging.ActionUpdated, profileID)
+	return nil
+}
+
+// DeleteTargetOverride deletes a target override
+func DeleteTargetOverride(id string) error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	profileID, err := profileIDOf(db, "targetOverrides", id)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec("DELETE FROM targetOverrides WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete target override: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error checking rows affected: %v", err)
+	}
+	if rowsAffected == 0 {
+		return types.NewNotFoundError("no target override found with id %s", id)
+	}
+
+	if err := markDatabaseAsUnsynced(); err != nil {
+		log.Printf("Failed to mark database as unsynced: %v", err)
+	}
+	messaging.Publish(messaging.EntityUserSettings, messaging.ActionUpdated, profileID)
+	return nil
+}
+
+// formatWeekdays stores weekdays as comma separated list, e.g. "1,3,5"
+func formatWeekdays(weekdays []int) string {
+	parts := make([]string, len(weekdays))
+	for i, weekday := range weekdays {
+		parts[i] = strconv.Itoa(weekday)
+	}
+	return strings.Join(parts, ",")
+}
+
+func parseWeekdays(value string) []int {
+	if value == "" {
+		return nil
+	}
+
+	var weekdays []int
+	for _, part := range strings.Split(value, ",") {
+		weekday, err := strconv.Atoi(part)
+		if err != nil {
+			continue
+		}
+		weekdays = append(weekdays, weekday)
+	}
+	return weekdays
+}
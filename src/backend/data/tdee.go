@@ -0,0 +1,96 @@
+package data
+
+import (
+	"fmt"
+)
+
+// DailyIntake represents the logged calories and macros of one day
+type DailyIntake struct {
+	Date     string  `json:"date"`
+	Entries  int     `json:"entries"`
+	Calories float64 `json:"calories"`
+	Proteins float64 `json:"proteins"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+}
+
+// WeightEntry represents the average tracked weight of one day
+type WeightEntry struct {
+	Date   string  `json:"date"`
+	Weight float64 `json:"weight"`
+}
+
+// GetDailyIntake returns the logged calories and macros per day of a profile between two dates (inclusive).
+// Days without any logged entries are not included.
+func GetDailyIntake(profileID string, startDate string, endDate string) ([]DailyIntake, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	rows, err := db.Query(`
+	SELECT
+		c.date,
+		COUNT(*) as entries,
+		SUM(c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.kcalPer100g, f.kcalPer100g, 0) / 100) as calories,
+		SUM(c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.proteinPer100g, f.proteinPer100g, 0) / 100) as proteins,
+		SUM(c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.carbsPer100g, f.carbsPer100g, 0) / 100) as carbs,
+		SUM(c.consumed_quantity * COALESCE(c.serving_quantity, 0) * COALESCE(c.fatPer100g, f.fatPer100g, 0) / 100) as fat
+	FROM consumedFoodItems c
+	LEFT JOIN foodItems f ON c.barcode = f.barcode
+	WHERE c.profile_id = ? AND c.date BETWEEN ? AND ? AND (f.barcode IS NOT NULL OR c.kcalPer100g IS NOT NULL)
+	GROUP BY c.date
+	ORDER BY c.date
+	`, profileID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily intake: %v", err)
+	}
+	defer rows.Close()
+
+	var intake []DailyIntake
+	for rows.Next() {
+		var day DailyIntake
+		if err := rows.Scan(&day.Date, &day.Entries, &day.Calories, &day.Proteins, &day.Carbs, &day.Fat); err != nil {
+			return nil, fmt.Errorf("failed to scan daily intake: %v", err)
+		}
+		intake = append(intake, day)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily intake: %v", err)
+	}
+
+	return intake, nil
+}
+
+// GetWeightEntries returns the tracked weight of a profile between two dates (inclusive),
+// averaged per day
+func GetWeightEntries(profileID string, startDate string, endDate string) ([]WeightEntry, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	rows, err := db.Query(`
+	SELECT date(created_at) as day, AVG(weight) as weight
+	FROM weight_tracking
+	WHERE profile_id = ? AND weight > 0 AND date(created_at) BETWEEN ? AND ?
+	GROUP BY day
+	ORDER BY day
+	`, profileID, startDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weight entries: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []WeightEntry
+	for rows.Next() {
+		var entry WeightEntry
+		if err := rows.Scan(&entry.Date, &entry.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan weight entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating weight entries: %v", err)
+	}
+
+	return entries, nil
+}
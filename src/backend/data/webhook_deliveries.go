@@ -0,0 +1,102 @@
+package data
+
+import (
+	"fmt"
+	"time"
+
+	"nutrack/backend/types"
+)
+
+// WebhookDeliveryRetention is how long deliveries are kept in the delivery log
+const WebhookDeliveryRetention = 30 * 24 * time.Hour
+
+// The delivery log is not part of the nutrition data, so changes to it neither mark the
+// database as unsynced nor publish events, which would fire the webhooks again.
+
+// InsertWebhookDelivery adds a delivery to the log and drops the deliveries older than
+// the retention
+func InsertWebhookDelivery(delivery types.WebhookDelivery) error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	_, err := db.Exec(`
+	INSERT INTO webhookDeliveries (id, webhook_id, event, event_seq, status, attempts, response_status, error, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, delivery.ID, delivery.WebhookID, delivery.Event, delivery.EventSeq, delivery.Status, delivery.Attempts,
+		delivery.ResponseStatus, delivery.Error, FormatDateTimeISO8601(delivery.CreatedAt), FormatDateTimeISO8601(delivery.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook delivery: %v", err)
+	}
+
+	_, err = db.Exec("DELETE FROM webhookDeliveries WHERE created_at < ?", FormatDateTimeISO8601(time.Now().Add(-WebhookDeliveryRetention)))
+	if err != nil {
+		return fmt.Errorf("failed to delete old webhook deliveries: %v", err)
+	}
+	return nil
+}
+
+// UpdateWebhookDelivery stores the state of the last attempt of a delivery
+func UpdateWebhookDelivery(delivery types.WebhookDelivery) error {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	_, err := db.Exec(`
+	UPDATE webhookDeliveries
+	SET status = ?, attempts = ?, response_status = ?, error = ?, updated_at = ?
+	WHERE id = ?
+	`, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.Error, FormatDateTimeISO8601(delivery.UpdatedAt), delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %v", err)
+	}
+	return nil
+}
+
+// FailPendingWebhookDeliveries fails the deliveries which were pending when the backend
+// stopped and returns their number
+func FailPendingWebhookDeliveries(message string) (int64, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	result, err := db.Exec("UPDATE webhookDeliveries SET status = ?, error = ?, updated_at = ? WHERE status = ?",
+		types.WebhookDeliveryFailed, message, FormatDateTimeISO8601(time.Now()), types.WebhookDeliveryPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update webhook deliveries: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook or, without an ID, of all
+// webhooks, the latest first
+func GetWebhookDeliveries(webhookID string, limit int) ([]types.WebhookDelivery, error) {
+	db := OpenDataBase()
+	defer CloseDataBase(db)
+
+	var filter listFilter
+	if webhookID != "" {
+		filter.add("webhook_id = ?", webhookID)
+	}
+	rows, err := db.Query(`
+	SELECT id, webhook_id, event, event_seq, status, attempts, response_status, error, created_at, updated_at
+	FROM webhookDeliveries`+filter.where()+" ORDER BY created_at DESC, rowid DESC LIMIT ?", append(filter.args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		var delivery types.WebhookDelivery
+		err := rows.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.EventSeq, &delivery.Status,
+			&delivery.Attempts, &delivery.ResponseStatus, &delivery.Error, &delivery.CreatedAt, &delivery.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %v", err)
+	}
+
+	return deliveries, nil
+}
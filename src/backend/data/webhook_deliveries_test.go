@@ -0,0 +1,67 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"nutrack/backend/types"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	deliveries := []types.WebhookDelivery{
+		{ID: "delivery-old", WebhookID: "log-hook", Event: "dish.created", Status: types.WebhookDeliveryDelivered,
+			CreatedAt: now.Add(-WebhookDeliveryRetention - time.Hour), UpdatedAt: now.Add(-WebhookDeliveryRetention - time.Hour)},
+		{ID: "delivery-kept", WebhookID: "log-hook", Event: "dish.updated", Status: types.WebhookDeliveryPending,
+			CreatedAt: now.Add(-time.Hour), UpdatedAt: now.Add(-time.Hour)},
+		{ID: "delivery-new", WebhookID: "log-hook", Event: "dish.deleted", EventSeq: 3, Status: types.WebhookDeliveryPending,
+			CreatedAt: now, UpdatedAt: now},
+		{ID: "delivery-other", WebhookID: "log-other-hook", Event: "dish.deleted", Status: types.WebhookDeliveryPending,
+			CreatedAt: now, UpdatedAt: now},
+	}
+	for _, delivery := range deliveries {
+		if err := InsertWebhookDelivery(delivery); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	newest := deliveries[2]
+	newest.Status, newest.Attempts, newest.ResponseStatus, newest.Error = types.WebhookDeliveryFailed, 2, 500, "boom"
+	if err := UpdateWebhookDelivery(newest); err != nil {
+		t.Fatal(err)
+	}
+
+	// The delivery older than the retention is gone, the others are listed latest first
+	got, err := GetWebhookDeliveries("log-hook", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "delivery-new" || got[1].ID != "delivery-kept" {
+		t.Fatalf("got deliveries %+v, want the new and the kept one", got)
+	}
+	if got[0].Status != types.WebhookDeliveryFailed || got[0].Attempts != 2 || got[0].ResponseStatus != 500 ||
+		got[0].Error != "boom" || got[0].EventSeq != 3 || !got[0].CreatedAt.Equal(now) {
+		t.Errorf("got delivery %+v, want the updated attempt", got[0])
+	}
+	if limited, err := GetWebhookDeliveries("log-hook", 1); err != nil || len(limited) != 1 {
+		t.Errorf("got %d deliveries with a limit of 1, err %v", len(limited), err)
+	}
+
+	// Deliveries pending at a restart fail
+	failed, err := FailPendingWebhookDeliveries("backend restarted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 2 {
+		t.Errorf("got %d failed deliveries, want the kept and the other one", failed)
+	}
+	got, err = GetWebhookDeliveries("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, delivery := range got {
+		if delivery.Status != types.WebhookDeliveryFailed {
+			t.Errorf("got delivery %+v still pending", delivery)
+		}
+	}
+}
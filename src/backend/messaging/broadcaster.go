@@ -4,99 +4,47 @@ import (
 	"encoding/json"
 	"log"
 	"os"
-	"sync"
 )
 
-type MessageBroadcaster interface {
-	Broadcast(message string)
-}
-
-type SSEBroadcaster struct{}
-
-func (b *SSEBroadcaster) Broadcast(message string) {
-	BroadcastSSEMessage(message)
-}
-
-type StandardIOBroadcaster struct{}
-
-func (b *StandardIOBroadcaster) Broadcast(message string) {
-	BroadcastStandardIOMessage(message)
-}
-
-var broadcaster MessageBroadcaster
+// standardIO is set if the events are sent on stdout to the Electron app instead of SSE
+var standardIO bool
 
 func InitBroadcaster(useElectronIPC bool) {
 	println("Initializing broadcaster with useElectronIPC:", useElectronIPC)
-	if useElectronIPC {
-		broadcaster = &StandardIOBroadcaster{}
-	} else {
-		broadcaster = &SSEBroadcaster{}
-	}
+	standardIO = useElectronIPC
 }
 
-func BroadcastMessage(message string) {
-	if broadcaster != nil {
-		println("Broadcasting message:", message)
-		broadcaster.Broadcast(message)
-	} else {
-		println("No broadcaster initialized")
-	}
-}
-
-// SSE
-
-var sseClients = make(map[chan string]bool)
-var sseClientsMutex sync.Mutex
-
-func AddSSEClient(client chan string) {
-	sseClientsMutex.Lock()
-	sseClients[client] = true
-	sseClientsMutex.Unlock()
+// Publish records a change of entities in the event log, from which SSE clients read it,
+// and sends it to the Electron app in standard IO mode. Without IDs all entities of the kind
+// may have changed.
+func Publish(entity string, action string, profileID string, ids ...string) Event {
+	return PublishDetails(entity, action, profileID, nil, ids...)
 }
 
-func RemoveSSEClient(client chan string) {
-	sseClientsMutex.Lock()
-	delete(sseClients, client)
-	close(client)
-	sseClientsMutex.Unlock()
-}
+// PublishDetails publishes an event with values for notifications, e.g. by webhooks
+func PublishDetails(entity string, action string, profileID string, details map[string]interface{}, ids ...string) Event {
+	event := events.append(Event{
+		Entity:    entity,
+		Action:    action,
+		IDs:       ids,
+		ProfileID: profileID,
+		Details:   details,
+	})
+	println("Publishing event:", event.Seq, event.Message, event.Action)
 
-func BroadcastSSEMessage(message string) {
-	sseClientsMutex.Lock()
-	clientCount := len(sseClients)
-	messagesSent := 0
-	
-	// Log client count for debugging
-	if clientCount > 0 {
-		println("Broadcasting to", clientCount, "clients, message:", message)
-	}
-	
-	for client := range sseClients {
-		select {
-		case client <- message:
-			messagesSent++
-		default:
-			// If we can't send immediately, the client might be slow or blocked
-			println("Client channel full or blocked, removing client")
-			RemoveSSEClient(client)
-		}
-	}
-	sseClientsMutex.Unlock()
-	
-	if messagesSent > 0 {
-		println("Message sent:", message, "to", messagesSent, "clients")
-	} else if clientCount > 0 {
-		println("Warning: Message not delivered to any clients:", message)
+	if standardIO {
+		BroadcastStandardIOMessage(event)
 	}
+	return event
 }
 
-func BroadcastStandardIOMessage(message string) {
+func BroadcastStandardIOMessage(event Event) {
 	response := struct {
 		Type string      `json:"type"`
 		Data interface{} `json:"data"`
 	}{
 		Type: "sse-message",
-		Data: message,
+		Data: event,
 	}
 
 	if err := json.NewEncoder(os.Stdout).Encode(response); err != nil {
@@ -0,0 +1,268 @@
+package messaging
+
+import (
+	"sync"
+	"time"
+)
+
+// Entities of events
+const (
+	EntityFoodItem         = "food_item"
+	EntityConsumedFoodItem = "consumed_food_item"
+	EntityDish             = "dish"
+	EntityMealPlanEntry    = "meal_plan_entry"
+	EntityProfile          = "profile"
+	EntityUserSettings     = "user_settings" // Settings, goal strategies and target overrides
+	EntityDatabase         = "database"      // The whole database, e.g. after a Dropbox download
+	EntityWeight           = "weight"        // Tracked weight of a profile
+	EntityDailyTarget      = "daily_target"  // Calorie target of a profile on a day, the ID is the date
+	EntityScanner          = "scanner"       // Barcode scanner device, the ID is the device path
+)
+
+// Actions of events
+const (
+	ActionCreated  = "created"
+	ActionUpdated  = "updated"
+	ActionDeleted  = "deleted"
+	ActionReset    = "reset"    // All entities of the kind may have changed, clients reload them
+	ActionConflict = "conflict" // The local and the Dropbox database were both changed
+	ActionReached  = "reached"  // The intake of a day reached the daily target
+	ActionOffline  = "offline"  // The scanner process stopped, e.g. because the device was unplugged
+)
+
+var actions = map[string]bool{
+	ActionCreated:  true,
+	ActionUpdated:  true,
+	ActionDeleted:  true,
+	ActionReset:    true,
+	ActionConflict: true,
+	ActionReached:  true,
+	ActionOffline:  true,
+}
+
+// entityMessages are the plain messages of the entities, which were sent before events were
+// typed. Clients still match them, so every event carries the message of its entity.
+var entityMessages = map[string]string{
+	EntityFoodItem:         "food_items_updated",
+	EntityConsumedFoodItem: "consumed_food_items_updated",
+	EntityDish:             "dishes_updated",
+	EntityMealPlanEntry:    "meal_plan_updated",
+	EntityProfile:          "profiles_updated",
+	EntityUserSettings:     "user_settings_updated",
+	EntityDatabase:         "REMOTE_FILE_UPDATED",
+	EntityWeight:           "weight_updated",
+	EntityDailyTarget:      "daily_target_reached",
+	EntityScanner:          "scanner_offline",
+}
+
+// Event is a change of entities. The sequence numbers increase by one per event and are sent
+// as SSE event IDs, so reconnecting clients can replay the events they missed.
+type Event struct {
+	Seq       uint64                 `json:"seq"`
+	Message   string                 `json:"message"` // Plain message like "food_items_updated"
+	Entity    string                 `json:"entity"`
+	Action    string                 `json:"action"`
+	IDs       []string               `json:"ids,omitempty"`        // Barcodes or IDs of the changed entities, empty if unknown
+	ProfileID string                 `json:"profile_id,omitempty"` // Profile of the changed entities, empty for shared ones
+	Details   map[string]interface{} `json:"details,omitempty"`    // Values for notifications, e.g. the calories of a reached target
+	Time      time.Time              `json:"time"`
+}
+
+// IsEntity reports whether name is the entity of events, e.g. "food_item"
+func IsEntity(name string) bool {
+	_, ok := entityMessages[name]
+	return ok
+}
+
+// IsAction reports whether name is the action of events, e.g. "created"
+func IsAction(name string) bool {
+	return actions[name]
+}
+
+func eventMessage(entity string, action string) string {
+	if entity == EntityDatabase && action == ActionConflict {
+		return "SHOW_SYNC_CONFLICT"
+	}
+	return entityMessages[entity]
+}
+
+// eventLogSize is the number of events kept for replay
+const eventLogSize = 1000
+
+// eventLog keeps the latest events in a ring buffer and wakes the subscriptions on new ones
+type eventLog struct {
+	mutex         sync.Mutex
+	events        []Event
+	next          int    // Position of the next event in the ring buffer
+	lastSeq       uint64 // Sequence number of the latest event
+	subscriptions map[*Subscription]struct{}
+}
+
+// The sequence numbers start at the start time in milliseconds, so the event IDs of a
+// restarted server don't overlap with the ones a client has seen before
+var events = &eventLog{
+	events:        make([]Event, 0, eventLogSize),
+	lastSeq:       uint64(time.Now().UnixMilli()),
+	subscriptions: map[*Subscription]struct{}{},
+}
+
+func (l *eventLog) append(event Event) Event {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.lastSeq++
+	event.Seq = l.lastSeq
+	event.Message = eventMessage(event.Entity, event.Action)
+	event.Time = time.Now().UTC()
+
+	if len(l.events) < eventLogSize {
+		l.events = append(l.events, event)
+	} else {
+		l.events[l.next] = event
+	}
+	l.next = (l.next + 1) % eventLogSize
+
+	for subscription := range l.subscriptions {
+		if subscription.filter.Matches(event) {
+			subscription.wake()
+		}
+	}
+	return event
+}
+
+// since returns the events after seq. It returns false if events after seq were already
+// dropped from the log or seq is unknown, e.g. from before a restart.
+func (l *eventLog) since(seq uint64) ([]Event, bool) {
+	if seq > l.lastSeq {
+		return nil, false
+	}
+	missed := int(l.lastSeq - seq)
+	if missed > len(l.events) {
+		return nil, false
+	}
+
+	result := make([]Event, 0, missed)
+	for i := len(l.events) - missed; i < len(l.events); i++ {
+		// The oldest event is at the next write position once the ring buffer is full
+		index := i
+		if len(l.events) == eventLogSize {
+			index = (l.next + i) % eventLogSize
+		}
+		result = append(result, l.events[index])
+	}
+	return result, true
+}
+
+// resetEvents tell a client which missed events to reload everything
+func resetEvents(seq uint64) []Event {
+	now := time.Now().UTC()
+	var result []Event
+	for _, entity := range []string{EntityDatabase, EntityFoodItem, EntityConsumedFoodItem, EntityDish, EntityMealPlanEntry, EntityProfile, EntityUserSettings} {
+		result = append(result, Event{
+			Seq:     seq,
+			Message: eventMessage(entity, ActionReset),
+			Entity:  entity,
+			Action:  ActionReset,
+			Time:    now,
+		})
+	}
+	return result
+}
+
+// EventFilter selects the events of a subscription. Empty lists match everything.
+type EventFilter struct {
+	ProfileIDs []string
+	Entities   []string
+}
+
+// Matches reports whether the filter selects the event. Events without a profile concern
+// all profiles, e.g. changes to food items, and database events concern all entities, so
+// they are always selected.
+func (f EventFilter) Matches(event Event) bool {
+	if event.Entity == EntityDatabase {
+		return true
+	}
+	if len(f.Entities) > 0 && !contains(f.Entities, event.Entity) {
+		return false
+	}
+	return len(f.ProfileIDs) == 0 || event.ProfileID == "" || contains(f.ProfileIDs, event.ProfileID)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription reads the events of the event log in order. A subscriber is never dropped for
+// being slow: if it falls behind the log, it gets reset events instead of the missed ones.
+type Subscription struct {
+	filter  EventFilter
+	lastSeq uint64        // Sequence number of the last event read
+	notify  chan struct{} // Signaled when events after lastSeq were appended
+}
+
+// Subscribe returns a subscription to the events after lastSeq selected by the filter. With
+// lastSeq 0 only new events are read.
+func Subscribe(lastSeq uint64, filter EventFilter) *Subscription {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	subscription := &Subscription{
+		filter:  filter,
+		lastSeq: lastSeq,
+		notify:  make(chan struct{}, 1),
+	}
+	if lastSeq == 0 {
+		subscription.lastSeq = events.lastSeq
+	}
+	if subscription.lastSeq != events.lastSeq {
+		subscription.wake()
+	}
+	events.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+func (s *Subscription) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+		// Already signaled
+	}
+}
+
+// Notify returns a channel which is signaled when new events can be read
+func (s *Subscription) Notify() <-chan struct{} {
+	return s.notify
+}
+
+// Next returns the events selected by the filter which were appended since the last call
+func (s *Subscription) Next() []Event {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	appended, ok := events.since(s.lastSeq)
+	if !ok {
+		appended = resetEvents(events.lastSeq)
+	}
+	s.lastSeq = events.lastSeq
+
+	var result []Event
+	for _, event := range appended {
+		if s.filter.Matches(event) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	events.mutex.Lock()
+	defer events.mutex.Unlock()
+
+	delete(events.subscriptions, s)
+}
@@ -0,0 +1,152 @@
+package messaging
+
+import (
+	"strconv"
+	"testing"
+)
+
+// publishEvents publishes n food item events with their index as ID and returns them
+func publishEvents(n int, profileID string) []Event {
+	published := make([]Event, n)
+	for i := range published {
+		published[i] = Publish(EntityFoodItem, ActionUpdated, profileID, strconv.Itoa(i))
+	}
+	return published
+}
+
+func seqs(events []Event) []uint64 {
+	result := make([]uint64, len(events))
+	for i, event := range events {
+		result[i] = event.Seq
+	}
+	return result
+}
+
+func isReset(events []Event) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for _, event := range events {
+		if event.Action != ActionReset {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubscribeReplaysMissedEvents(t *testing.T) {
+	published := publishEvents(4, "")
+
+	// A client which saw the second event reconnects with its ID as Last-Event-ID
+	subscription := Subscribe(published[1].Seq, EventFilter{})
+	defer subscription.Close()
+
+	select {
+	case <-subscription.Notify():
+	default:
+		t.Fatal("got no notification of the missed events")
+	}
+	got := subscription.Next()
+	if want := seqs(published[2:]); len(got) != len(want) || got[0].Seq != want[0] || got[1].Seq != want[1] {
+		t.Fatalf("got events %v, want %v", seqs(got), want)
+	}
+	if got[0].Message != "food_items_updated" || got[0].Entity != EntityFoodItem || got[0].Action != ActionUpdated {
+		t.Errorf("got event %+v, want a typed food item event", got[0])
+	}
+
+	if got := subscription.Next(); len(got) != 0 {
+		t.Errorf("got events %v again", seqs(got))
+	}
+}
+
+func TestSubscribeWithoutLastEventID(t *testing.T) {
+	publishEvents(2, "")
+
+	subscription := Subscribe(0, EventFilter{})
+	defer subscription.Close()
+
+	select {
+	case <-subscription.Notify():
+		t.Fatal("got a notification without new events")
+	default:
+	}
+
+	published := publishEvents(1, "")
+	<-subscription.Notify()
+	if got := subscription.Next(); len(got) != 1 || got[0].Seq != published[0].Seq {
+		t.Errorf("got events %v, want only the new event %d", seqs(got), published[0].Seq)
+	}
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	subscription := Subscribe(0, EventFilter{ProfileIDs: []string{"p1"}, Entities: []string{EntityConsumedFoodItem}})
+	defer subscription.Close()
+
+	Publish(EntityConsumedFoodItem, ActionCreated, "p1", "c1")
+	Publish(EntityConsumedFoodItem, ActionCreated, "p2", "c2")
+	Publish(EntityConsumedFoodItem, ActionUpdated, "")
+	Publish(EntityDish, ActionUpdated, "p1")
+	Publish(EntityDatabase, ActionReset, "")
+
+	var got []string
+	for _, event := range subscription.Next() {
+		got = append(got, event.Entity+"."+event.Action+":"+event.ProfileID)
+	}
+	want := []string{"consumed_food_item.created:p1", "consumed_food_item.updated:", "database.reset:"}
+	if len(got) != len(want) {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got events %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestSubscribeResetsUnknownEvents(t *testing.T) {
+	published := publishEvents(1, "")
+
+	tests := []struct {
+		name    string
+		lastSeq uint64
+	}{
+		{"from before a restart", published[0].Seq + 1000},
+		{"dropped from the log", published[0].Seq - eventLogSize - 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subscription := Subscribe(test.lastSeq, EventFilter{})
+			defer subscription.Close()
+
+			if got := subscription.Next(); !isReset(got) {
+				t.Errorf("got events %+v, want reset events", got)
+			}
+		})
+	}
+}
+
+func TestSubscribeAfterTheLogWrapped(t *testing.T) {
+	published := publishEvents(eventLogSize+10, "")
+
+	subscription := Subscribe(published[len(published)-4].Seq, EventFilter{})
+	defer subscription.Close()
+
+	got := subscription.Next()
+	want := seqs(published[len(published)-3:])
+	if len(got) != len(want) {
+		t.Fatalf("got events %v, want %v", seqs(got), want)
+	}
+	for i := range want {
+		if got[i].Seq != want[i] {
+			t.Fatalf("got events %v, want %v", seqs(got), want)
+		}
+	}
+
+	oldest := Subscribe(published[len(published)-eventLogSize].Seq-1, EventFilter{})
+	defer oldest.Close()
+	if got := oldest.Next(); len(got) != eventLogSize || isReset(got) {
+		t.Errorf("got %d events, want the whole log of %d events", len(got), eventLogSize)
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+// MaxCopyDays limits the number of days entries can be copied to at once
+const MaxCopyDays = 366
+
+// CopyConsumedFoodItems copies the entries of a date, or a subset of them, to another date or
+// profile. With an end date the entries are copied to every day of the range, optionally limited
+// to some weekdays. Every copy is logged like a new entry with the current food item or dish, so
+// food items are added to an existing entry of the same food item on the target date. All copies
+// are written in one transaction with one upload.
+func (s *FoodService) CopyConsumedFoodItems(ctx context.Context, request types.CopyConsumedFoodItemsRequest) (types.ConsumedCopyResult, error) {
+	if err := s.SyncToDropbox(request.ForceSync); err != nil {
+		return types.ConsumedCopyResult{}, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	sourceProfileID, err := s.resolveProfileID(request.SourceProfileID)
+	if err != nil {
+		return types.ConsumedCopyResult{}, err
+	}
+	targetProfileID := request.TargetProfileID
+	if targetProfileID == "" {
+		targetProfileID = sourceProfileID
+	}
+
+	if err := ValidateDate(request.SourceDate); err != nil {
+		return types.ConsumedCopyResult{}, withField(err, "source_date")
+	}
+	if err := ValidateDate(request.TargetDate); err != nil {
+		return types.ConsumedCopyResult{}, withField(err, "target_date")
+	}
+	for _, weekday := range request.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return types.ConsumedCopyResult{}, types.NewValidationError("weekdays", "weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+
+	start, _ := time.Parse("2006-01-02", request.TargetDate)
+	end := start
+	if request.TargetEndDate != "" {
+		if err := ValidateDate(request.TargetEndDate); err != nil {
+			return types.ConsumedCopyResult{}, withField(err, "target_end_date")
+		}
+		end, _ = time.Parse("2006-01-02", request.TargetEndDate)
+		if end.Before(start) {
+			return types.ConsumedCopyResult{}, types.NewValidationError("target_end_date", "start date must not be after end date")
+		}
+		if end.Sub(start).Hours()/24 >= MaxCopyDays {
+			return types.ConsumedCopyResult{}, types.NewValidationError("target_end_date", "date range must not exceed %d days", MaxCopyDays)
+		}
+	}
+
+	var dates []string
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if len(request.Weekdays) > 0 && !containsWeekday(request.Weekdays, day.Weekday()) {
+			continue
+		}
+
+		date := day.Format("2006-01-02")
+		// Copying onto the source would double the entries
+		if date == request.SourceDate && targetProfileID == sourceProfileID {
+			if request.TargetEndDate == "" {
+				return types.ConsumedCopyResult{}, types.NewValidationError("target_date", "target date must differ from the source date")
+			}
+			continue
+		}
+		dates = append(dates, date)
+	}
+	if len(dates) == 0 {
+		return types.ConsumedCopyResult{}, types.NewValidationError("target_end_date", "no target dates in the date range")
+	}
+
+	if _, err := data.GetProfile(targetProfileID); err != nil {
+		return types.ConsumedCopyResult{}, err
+	}
+
+	result := types.ConsumedCopyResult{Dates: dates}
+	err = s.repository.InTransaction(ctx, func(repo *data.Repository) error {
+		entries, err := repo.GetConsumedEntries(ctx, sourceProfileID, request.SourceDate, request.EntryIDs)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return types.NewNotFoundError("no consumed food items found to copy")
+		}
+
+		for _, date := range dates {
+			for _, entry := range entries {
+				merged := false
+				if entry.DishID != "" {
+					_, err = s.logConsumedDish(ctx, repo, types.ConsumedDishRequest{
+						DishID:    entry.DishID,
+						Portions:  entry.ConsumedQuantity,
+						Date:      date,
+						ProfileID: targetProfileID,
+					})
+				} else {
+					_, merged, err = s.logConsumedFoodItem(ctx, repo, types.ConsumedFoodItemRequest{
+						Barcode:          entry.Barcode,
+						ConsumedQuantity: entry.ConsumedQuantity,
+						Date:             date,
+						ProfileID:        targetProfileID,
+					})
+				}
+				if err != nil {
+					return err
+				}
+
+				if merged {
+					result.Merged++
+				} else {
+					result.Inserted++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return types.ConsumedCopyResult{}, err
+	}
+
+	s.ScheduleDelayedUpload()
+	return result, nil
+}
+
+func containsWeekday(weekdays []int, weekday time.Weekday) bool {
+	for _, candidate := range weekdays {
+		if time.Weekday(candidate) == weekday {
+			return true
+		}
+	}
+	return false
+}
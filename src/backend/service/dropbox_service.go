@@ -12,13 +12,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
 	"nutrack/backend/data"
 	"nutrack/backend/messaging"
 	"nutrack/backend/settings"
+	"nutrack/backend/types"
 )
 
 const (
@@ -67,7 +67,7 @@ func (s *FoodService) ExchangeToken(code, codeVerifier string) (*TokenResponse,
 
 	resp, err := http.PostForm("https://api.dropboxapi.com/oauth2/token", data)
 	if err != nil {
-		return nil, fmt.Errorf("error making token request: %w", err)
+		return nil, types.NewUpstreamUnavailableError(err, "error making token request: %v", err)
 	}
 	defer resp.Body.Close()
 
@@ -77,7 +77,7 @@ func (s *FoodService) ExchangeToken(code, codeVerifier string) (*TokenResponse,
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, dropboxStatusError("token request", resp.StatusCode, body)
 	}
 
 	var tokenResp TokenResponse
@@ -104,7 +104,7 @@ func (s *FoodService) ExchangeToken(code, codeVerifier string) (*TokenResponse,
 		RefreshToken: tokenResp.RefreshToken,
 		ExpiresAt:    expiresAt,
 	}); err != nil {
-		return nil, fmt.Errorf("failed to save tokens: %v", err)
+		return nil, fmt.Errorf("failed to save tokens: %w", err)
 	}
 
 	return &tokenResp, nil
@@ -134,7 +134,7 @@ func (s *FoodService) GetAuthenticationStatus() (bool, error) {
 // Logout removes the stored tokens and disables auto-sync
 func (s *FoodService) Logout() error {
 	if err := s.SetAutoSync(false); err != nil {
-		return fmt.Errorf("failed to disable auto-sync: %v", err)
+		return fmt.Errorf("failed to disable auto-sync: %w", err)
 	}
 	return s.tokenStore.DeleteTokens()
 }
@@ -258,7 +258,7 @@ func (s *FoodService) getRemoteFileMetadata(tokens *DropboxTokens, path string)
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, types.NewUpstreamUnavailableError(err, "error making request: %v", err)
 	}
 	defer resp.Body.Close()
 
@@ -289,7 +289,7 @@ func (s *FoodService) getRemoteFileMetadata(tokens *DropboxTokens, path string)
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("metadata request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, dropboxStatusError("metadata request", resp.StatusCode, body)
 	}
 
 	var metadata DropboxMetadata
@@ -325,9 +325,15 @@ func (s *FoodService) UploadDatabase() (*OperationResult, error) {
 
 	tokens, err := s.GetValidTokens()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get valid tokens: %v", err)
+		return nil, fmt.Errorf("failed to get valid tokens: %w", err)
 	}
-	dbPath := data.GetDBPath()
+	// Upload a snapshot, the database file itself misses the changes in the write-ahead log
+	// and may change during the upload
+	dbPath, err := data.SnapshotDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("error creating database snapshot: %w", err)
+	}
+	defer os.Remove(dbPath)
 
 	// Check if we need to upload
 	needsUpload, localHash, _, err := s.hashComparison(tokens, dbPath, "/"+dbFileName)
@@ -373,13 +379,13 @@ func (s *FoodService) UploadDatabase() (*OperationResult, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, types.NewUpstreamUnavailableError(err, "error making request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, dropboxStatusError("upload", resp.StatusCode, body)
 	}
 
 	// Store the local hash after successful upload
@@ -397,9 +403,13 @@ func (s *FoodService) DownloadDatabase() (*OperationResult, error) {
 
 	tokens, err := s.GetValidTokens()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get valid tokens: %v", err)
+		return nil, fmt.Errorf("failed to get valid tokens: %w", err)
+	}
+	dbPath, err := data.SnapshotDatabase()
+	if err != nil {
+		return nil, fmt.Errorf("error creating database snapshot: %w", err)
 	}
-	dbPath := data.GetDBPath()
+	defer os.Remove(dbPath)
 
 	// Check if we need to download
 	needsDownload, _, remoteHash, err := s.hashComparison(tokens, dbPath, "/"+dbFileName)
@@ -440,13 +450,13 @@ func (s *FoodService) DownloadDatabase() (*OperationResult, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error making request: %w", err)
+		return nil, types.NewUpstreamUnavailableError(err, "error making request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("download failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, dropboxStatusError("download", resp.StatusCode, body)
 	}
 
 	// Create a temporary file
@@ -463,42 +473,14 @@ func (s *FoodService) DownloadDatabase() (*OperationResult, error) {
 		return nil, fmt.Errorf("error copying response to temp file: %w", err)
 	}
 
-	// Close the temp file before moving it
+	// Close the temp file before replacing the database with it
 	tempFile.Close()
 
-	// Instead of using os.Rename, copy the file contents
-	srcFile, err := os.Open(tempFile.Name())
-	if err != nil {
-		return nil, fmt.Errorf("error opening temp file: %w", err)
-	}
-	defer srcFile.Close()
-
-	// Create the target file
-	dstFile, err := os.Create(dbPath)
-	if err != nil {
-		return nil, fmt.Errorf("error creating target file: %w", err)
-	}
-	defer dstFile.Close()
-
-	// Copy the contents
-	_, err = io.Copy(dstFile, srcFile)
-	if err != nil {
-		return nil, fmt.Errorf("error copying file contents: %w", err)
+	// Replace the database while no data function uses it
+	if err := data.ReplaceDatabase(tempFile.Name()); err != nil {
+		return nil, fmt.Errorf("error replacing database: %w", err)
 	}
 
-	// Ensure all data is written to disk
-	err = dstFile.Sync()
-	if err != nil {
-		return nil, fmt.Errorf("error syncing file to disk: %w", err)
-	}
-
-	// Close both files before removing the temp file
-	srcFile.Close()
-	dstFile.Close()
-
-	// Remove the temporary file
-	os.Remove(tempFile.Name())
-
 	// Store the remote hash after successful download
 	log.Printf("Download successful, marking as synced with hash: %s", remoteHash)
 	s.settingsStore.MarkAsSynced(remoteHash)
@@ -507,12 +489,7 @@ func (s *FoodService) DownloadDatabase() (*OperationResult, error) {
 	}
 
 	// Notify UI components that data has been updated
-	messaging.BroadcastMessage("REMOTE_FILE_UPDATED")
-	// Small delay to ensure messages are properly processed
-	time.Sleep(50 * time.Millisecond)
-	messaging.BroadcastMessage("consumed_food_items_updated")
-	time.Sleep(50 * time.Millisecond)
-	messaging.BroadcastMessage("food_items_updated")
+	publishDatabaseReplaced()
 
 	return &OperationResult{
 		Success: true,
@@ -552,13 +529,13 @@ func (s *FoodService) refreshAccessToken(refreshToken string) (*TokenResponse, e
 
 	resp, err := http.PostForm("https://api.dropboxapi.com/oauth2/token", data)
 	if err != nil {
-		return nil, fmt.Errorf("error making refresh token request: %w", err)
+		return nil, types.NewUpstreamUnavailableError(err, "error making refresh token request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("refresh token request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, dropboxStatusError("refresh token request", resp.StatusCode, body)
 	}
 
 	var tokenResp TokenResponse
@@ -573,12 +550,12 @@ func (s *FoodService) refreshAccessToken(refreshToken string) (*TokenResponse, e
 func (s *FoodService) SetAutoSync(enabled bool) error {
 	settings, err := s.settingsStore.Load()
 	if err != nil {
-		return fmt.Errorf("failed to load settings: %v", err)
+		return fmt.Errorf("failed to load settings: %w", err)
 	}
 
 	settings.AutoSyncDropbox = enabled
 	if err := s.settingsStore.Save(settings); err != nil {
-		return fmt.Errorf("failed to save settings: %v", err)
+		return fmt.Errorf("failed to save settings: %w", err)
 	}
 
 	return nil
@@ -626,6 +603,28 @@ func (s *FoodService) updateLastCheckTime() error {
 	return nil
 }
 
+// publishDatabaseReplaced tells the clients to reload everything after a download. The
+// reset events of the diary and the food items are kept for clients which only reload
+// them on their own events.
+func publishDatabaseReplaced() {
+	messaging.Publish(messaging.EntityDatabase, messaging.ActionReset, "")
+	messaging.Publish(messaging.EntityConsumedFoodItem, messaging.ActionReset, "")
+	messaging.Publish(messaging.EntityFoodItem, messaging.ActionReset, "")
+}
+
+// dropboxStatusError returns the error of a failed Dropbox request. Rate limits and server
+// errors are typed, so callers can back off.
+func dropboxStatusError(request string, status int, body []byte) error {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return types.NewRateLimitedError("%s rate limited by Dropbox: %s", request, string(body))
+	case status >= http.StatusInternalServerError:
+		return types.NewUpstreamUnavailableError(nil, "%s failed with status %d: %s", request, status, string(body))
+	default:
+		return fmt.Errorf("%s failed with status %d: %s", request, status, string(body))
+	}
+}
+
 // SyncToDropbox checks the remote file hash and resolves any conflicts
 func (s *FoodService) SyncToDropbox(force bool) error {
 	log.Println("Checking if sync is needed")
@@ -639,7 +638,7 @@ func (s *FoodService) SyncToDropbox(force bool) error {
 		shouldCheck, err := s.shouldSync()
 		if err != nil {
 			log.Printf("Error checking if sync is needed: %v", err)
-			return fmt.Errorf("error checking if sync is needed: %v", err)
+			return fmt.Errorf("error checking if sync is needed: %w", err)
 		}
 
 		if !shouldCheck {
@@ -652,13 +651,13 @@ func (s *FoodService) SyncToDropbox(force bool) error {
 	tokens, err := s.GetValidTokens()
 	if err != nil {
 		log.Println("No valid tokens available:", err)
-		return fmt.Errorf("no valid tokens available: %v", err)
+		return fmt.Errorf("no valid tokens available: %w", err)
 	}
 
 	metadata, err := s.getRemoteFileMetadata(tokens, "/"+dbFileName)
 	if err != nil {
 		log.Println("Error getting remote file metadata:", err)
-		return fmt.Errorf("failed to get remote file metadata: %v", err)
+		return fmt.Errorf("failed to get remote file metadata: %w", err)
 	}
 
 	if metadata == nil || metadata.ContentHash == "" {
@@ -696,8 +695,8 @@ func (s *FoodService) SyncToDropbox(force bool) error {
 	} else if settingsData.StoredHash != remoteHash {
 		if !settingsData.Synced {
 			log.Println("Remote file changed but local is not synced, showing conflict dialog")
-			messaging.BroadcastMessage("SHOW_SYNC_CONFLICT")
-			return fmt.Errorf("remote file changed but local is not synced")
+			messaging.Publish(messaging.EntityDatabase, messaging.ActionConflict, "")
+			return types.NewSyncConflictError("remote file changed but local is not synced")
 		} else {
 			log.Println("Remote file changed and local is synced, downloading...")
 			result, err := s.DownloadDatabase()
@@ -749,7 +748,7 @@ func (s *FoodService) checkRemoteChanged(tokens *DropboxTokens) (bool, error) {
 	remotePath := "/" + dbFileName
 	metadata, err := s.getRemoteFileMetadata(tokens, remotePath)
 	if err != nil {
-		return false, fmt.Errorf("error checking remote file: %v", err)
+		return false, fmt.Errorf("error checking remote file: %w", err)
 	}
 	if metadata == nil {
 		return false, nil
@@ -837,7 +836,7 @@ func (s *FoodService) StartAutoSyncMonitor() {
 		tokens, err := s.GetValidTokens()
 		if err != nil {
 			log.Printf("Error getting valid tokens: %v", err)
-			if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "too many requests") {
+			if types.HasErrorCode(err, types.ErrorCodeRateLimited) {
 				apiLimitHit = true
 			}
 			// Use backoff for next check
@@ -853,7 +852,7 @@ func (s *FoodService) StartAutoSyncMonitor() {
 		hasChanged, err := s.checkRemoteChanged(tokens)
 		if err != nil {
 			log.Printf("Error checking if remote file has changed: %v", err)
-			if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "too many requests") {
+			if types.HasErrorCode(err, types.ErrorCodeRateLimited) {
 				apiLimitHit = true
 			}
 			// Use backoff for next check
@@ -870,7 +869,7 @@ func (s *FoodService) StartAutoSyncMonitor() {
 			result, err := s.DownloadDatabase()
 			if err != nil {
 				log.Printf("Error downloading database: %v", err)
-				if strings.Contains(err.Error(), "429") || strings.Contains(err.Error(), "too many requests") {
+				if types.HasErrorCode(err, types.ErrorCodeRateLimited) {
 					apiLimitHit = true
 					// Use backoff for next check
 					nextInterval = s.calculateNextBackoff(apiLimitHit)
@@ -879,14 +878,8 @@ func (s *FoodService) StartAutoSyncMonitor() {
 					nextInterval = s.calculateNextBackoff(false)
 				}
 			} else {
+				// DownloadDatabase notified the clients about the new database
 				log.Printf("Download result: %+v", result)
-				// Notify the user about the download
-				messaging.BroadcastMessage("REMOTE_FILE_UPDATED")
-				// Small delay to ensure messages are properly processed
-				time.Sleep(50 * time.Millisecond)
-				messaging.BroadcastMessage("consumed_food_items_updated")
-				time.Sleep(50 * time.Millisecond)
-				messaging.BroadcastMessage("food_items_updated")
 				// Reset backoff on successful download
 				currentBackoff = initialBackoff
 			}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+// FindDuplicateFoodItems suggests groups of food items with similar names and near-identical nutrition values
+func (s *FoodService) FindDuplicateFoodItems() ([]data.DuplicateFoodItemGroup, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	return data.FindDuplicateFoodItems()
+}
+
+// MergeFoodItems replaces the source food items by the target food item. Without apply the
+// merge is only previewed.
+func (s *FoodService) MergeFoodItems(ctx context.Context, request types.MergeFoodItemsRequest) (data.FoodItemMergeResult, error) {
+	if err := s.SyncToDropbox(request.ForceSync); err != nil {
+		return data.FoodItemMergeResult{}, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	if err := ValidateMergeFoodItems(request); err != nil {
+		return data.FoodItemMergeResult{}, err
+	}
+
+	result, err := s.repository.MergeFoodItems(ctx, request.TargetBarcode, request.SourceBarcodes, request.Apply)
+	if err != nil {
+		return data.FoodItemMergeResult{}, err
+	}
+
+	if result.Applied {
+		s.ScheduleDelayedUpload()
+	}
+	return result, nil
+}
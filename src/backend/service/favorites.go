@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+const (
+	// DefaultFoodItemUsageLimit is the number of recent, frequent or favorite food items returned by default
+	DefaultFoodItemUsageLimit = 30
+	MaxFoodItemUsageLimit     = 200
+
+	// DefaultFrequentFoodItemsDays is the period in which logged food items count as frequent
+	DefaultFrequentFoodItemsDays = 90
+)
+
+// GetRecentFoodItems returns the food items a profile logged most recently
+func (s *FoodService) GetRecentFoodItems(profileID string, limit int) ([]data.FoodItemUsage, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = DefaultFoodItemUsageLimit
+	}
+	if err := ValidateLimit(limit, MaxFoodItemUsageLimit); err != nil {
+		return nil, err
+	}
+
+	return data.GetRecentFoodItems(profileID, limit)
+}
+
+// GetFrequentFoodItems returns the food items a profile logged most often in the last days
+func (s *FoodService) GetFrequentFoodItems(profileID string, days int, limit int) ([]data.FoodItemUsage, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if days == 0 {
+		days = DefaultFrequentFoodItemsDays
+	}
+	if days < 1 {
+		return nil, types.NewValidationError("days", "days must be positive")
+	}
+	if limit == 0 {
+		limit = DefaultFoodItemUsageLimit
+	}
+	if err := ValidateLimit(limit, MaxFoodItemUsageLimit); err != nil {
+		return nil, err
+	}
+
+	return data.GetFrequentFoodItems(profileID, frequentSince(days), limit)
+}
+
+// GetFavoriteFoodItems returns the food items pinned by a profile
+func (s *FoodService) GetFavoriteFoodItems(profileID string) ([]data.FoodItemUsage, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetFavoriteFoodItems(profileID, frequentSince(DefaultFrequentFoodItemsDays), MaxFoodItemUsageLimit)
+}
+
+// AddFavoriteFoodItem pins a food item for a profile
+func (s *FoodService) AddFavoriteFoodItem(ctx context.Context, profileID string, barcode string) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return err
+	}
+
+	if err := ValidateBarcode(barcode); err != nil {
+		return err
+	}
+
+	if err := s.repository.AddFavoriteFoodItem(ctx, profileID, barcode); err != nil {
+		return err
+	}
+
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// RemoveFavoriteFoodItem unpins a food item for a profile
+func (s *FoodService) RemoveFavoriteFoodItem(ctx context.Context, profileID string, barcode string) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repository.RemoveFavoriteFoodItem(ctx, profileID, barcode); err != nil {
+		return err
+	}
+
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// frequentSince returns the first date of a period of days ending today
+func frequentSince(days int) string {
+	return time.Now().AddDate(0, 0, -(days - 1)).Format("2006-01-02")
+}
@@ -0,0 +1,323 @@
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+const (
+	BMRFormulaMifflinStJeor  = "mifflin-st-jeor"
+	BMRFormulaHarrisBenedict = "harris-benedict"
+	BMRFormulaKatchMcArdle   = "katch-mcardle"
+
+	PresetBalanced    = "balanced"
+	PresetHighProtein = "high-protein"
+	PresetKeto        = "keto"
+	PresetCustom      = "custom"
+
+	MacroModePercent = "percent"
+	MacroModePerKg   = "g_per_kg"
+	MacroModeGrams   = "grams"
+	MacroModeRest    = "rest"
+)
+
+// Energy per gram of macronutrient in kcal
+const (
+	kcalPerGramProtein = 4
+	kcalPerGramCarbs   = 4
+	kcalPerGramFat     = 9
+)
+
+// macroPresets contains the macro split of the presets
+var macroPresets = map[string]struct {
+	Protein, Fat, Carbs data.MacroTarget
+}{
+	// 2 g protein per kg, 30% fat and the rest from carbs
+	PresetBalanced: {
+		Protein: data.MacroTarget{Mode: MacroModePerKg, Value: 2},
+		Fat:     data.MacroTarget{Mode: MacroModePercent, Value: 30},
+		Carbs:   data.MacroTarget{Mode: MacroModeRest},
+	},
+	PresetHighProtein: {
+		Protein: data.MacroTarget{Mode: MacroModePerKg, Value: 2.4},
+		Fat:     data.MacroTarget{Mode: MacroModePercent, Value: 25},
+		Carbs:   data.MacroTarget{Mode: MacroModeRest},
+	},
+	// Carbs are limited to 25 g, the rest comes from fat
+	PresetKeto: {
+		Protein: data.MacroTarget{Mode: MacroModePerKg, Value: 1.6},
+		Fat:     data.MacroTarget{Mode: MacroModeRest},
+		Carbs:   data.MacroTarget{Mode: MacroModeGrams, Value: 25},
+	},
+}
+
+// DefaultGoalStrategy is used for profiles without a configured goal strategy
+func DefaultGoalStrategy() data.GoalStrategy {
+	return data.GoalStrategy{
+		Preset:     PresetBalanced,
+		BMRFormula: BMRFormulaMifflinStJeor,
+	}
+}
+
+// GetGoalStrategyPresets returns the available presets with their macro split
+func GetGoalStrategyPresets() map[string]data.GoalStrategy {
+	presets := make(map[string]data.GoalStrategy)
+	for name, preset := range macroPresets {
+		presets[name] = data.GoalStrategy{
+			Preset:     name,
+			BMRFormula: BMRFormulaMifflinStJeor,
+			Protein:    preset.Protein,
+			Fat:        preset.Fat,
+			Carbs:      preset.Carbs,
+		}
+	}
+	return presets
+}
+
+// CalculateBMR calculates the basal metabolic rate with the formula of the strategy
+func CalculateBMR(strategy data.GoalStrategy, weight float64, height float64, age int, gender string) float64 {
+	switch strategy.BMRFormula {
+	case BMRFormulaKatchMcArdle:
+		if strategy.BodyFatPercentage != nil {
+			leanBodyMass := weight * (1 - *strategy.BodyFatPercentage/100)
+			return 370 + 21.6*leanBodyMass
+		}
+	case BMRFormulaHarrisBenedict:
+		// Revised Harris-Benedict equation (Roza and Shizgal, 1984)
+		male := 88.362 + 13.397*weight + 4.799*height - 5.677*float64(age)
+		female := 447.593 + 9.247*weight + 3.098*height - 4.330*float64(age)
+		switch gender {
+		case "male":
+			return male
+		case "female":
+			return female
+		default: // for 'other' use average of male and female formulas
+			return (male + female) / 2
+		}
+	}
+
+	// Mifflin-St Jeor
+	switch gender {
+	case "male":
+		return 10*weight + 6.25*height - 5*float64(age) + 5
+	case "female":
+		return 10*weight + 6.25*height - 5*float64(age) - 161
+	default: // for 'other' use average of male and female formulas
+		return 10*weight + 6.25*height - 5*float64(age) - 78
+	}
+}
+
+// CalculateMacros splits the calories into proteins, carbs and fat in g according to the strategy
+func CalculateMacros(strategy data.GoalStrategy, calories float64, weight float64) (proteins float64, carbs float64, fat float64) {
+	protein, fatTarget, carbsTarget := strategy.Protein, strategy.Fat, strategy.Carbs
+	if preset, ok := macroPresets[strategy.Preset]; ok {
+		protein, fatTarget, carbsTarget = preset.Protein, preset.Fat, preset.Carbs
+	}
+
+	targetGrams := func(target data.MacroTarget, kcalPerGram float64) float64 {
+		switch target.Mode {
+		case MacroModePercent:
+			return calories * target.Value / 100 / kcalPerGram
+		case MacroModePerKg:
+			return weight * target.Value
+		case MacroModeGrams:
+			return target.Value
+		}
+		return 0
+	}
+
+	proteins = targetGrams(protein, kcalPerGramProtein)
+	fat = targetGrams(fatTarget, kcalPerGramFat)
+	carbs = targetGrams(carbsTarget, kcalPerGramCarbs)
+
+	// The remaining calories go to the macro with mode "rest"
+	rest := math.Max(0, calories-(proteins*kcalPerGramProtein+fat*kcalPerGramFat+carbs*kcalPerGramCarbs))
+	switch {
+	case protein.Mode == MacroModeRest:
+		proteins = rest / kcalPerGramProtein
+	case fatTarget.Mode == MacroModeRest:
+		fat = rest / kcalPerGramFat
+	case carbsTarget.Mode == MacroModeRest:
+		carbs = rest / kcalPerGramCarbs
+	}
+
+	// Raise carbs to the minimum, the calories are taken from the rest macro or from fat
+	if carbs < strategy.MinCarbs {
+		missingCalories := (strategy.MinCarbs - carbs) * kcalPerGramCarbs
+		carbs = strategy.MinCarbs
+		if protein.Mode == MacroModeRest {
+			proteins = math.Max(0, proteins-missingCalories/kcalPerGramProtein)
+		} else {
+			fat = math.Max(0, fat-missingCalories/kcalPerGramFat)
+		}
+	}
+
+	return proteins, carbs, fat
+}
+
+// CalculateNutritionWithStrategy calculates the daily nutrition targets based on user metrics and a goal strategy
+func (s *FoodService) CalculateNutritionWithStrategy(
+	strategy data.GoalStrategy,
+	weight float64,
+	height float64,
+	age int,
+	gender string,
+	activityLevel int,
+	weeklyWeightChange float64,
+) *types.NutritionCalculationResponse {
+	bmr := CalculateBMR(strategy, weight, height, age, gender)
+
+	activityFactors := []float64{1.2, 1.375, 1.55, 1.725, 1.9}
+	maintenanceCalories := bmr * activityFactors[activityLevel]
+
+	// Convert weekly weight change to daily calorie adjustment
+	// 1 kg of body fat is approximately 7700 calories
+	// So to lose/gain 1 kg per week, we need a daily deficit/surplus of 1100 calories
+	dailyCalorieAdjustment := weeklyWeightChange * 1100
+	adjustedCalories := maintenanceCalories + dailyCalorieAdjustment
+
+	return s.CalculateNutritionFromCaloriesAndWeightWithStrategy(strategy, adjustedCalories, weight)
+}
+
+// CalculateNutritionFromCaloriesAndWeightWithStrategy calculates macronutrients based on target calories, weight and a goal strategy
+func (s *FoodService) CalculateNutritionFromCaloriesAndWeightWithStrategy(
+	strategy data.GoalStrategy,
+	calories float64,
+	weight float64,
+) *types.NutritionCalculationResponse {
+	proteins, carbs, fat := CalculateMacros(strategy, calories, weight)
+
+	fmt.Printf("Calories: %f, Proteins: %f, Carbs: %f, Fat: %f\n", calories, proteins, carbs, fat)
+
+	return &types.NutritionCalculationResponse{
+		Calories: int(math.Round(calories)),
+		Proteins: int(math.Round(proteins)),
+		Carbs:    int(math.Round(carbs)),
+		Fat:      int(math.Round(fat)),
+	}
+}
+
+// GetGoalStrategy returns the goal strategy of a profile, or the default strategy if none is configured
+func (s *FoodService) GetGoalStrategy(profileID string) (data.GoalStrategy, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return data.GoalStrategy{}, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	// If no profile ID is provided, use the active profile
+	if profileID == "" {
+		profileID = s.GetActiveProfile()
+
+		if profileID == "" {
+			return data.GoalStrategy{}, types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
+		}
+
+		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
+	}
+
+	return s.getGoalStrategy(profileID)
+}
+
+func (s *FoodService) getGoalStrategy(profileID string) (data.GoalStrategy, error) {
+	strategy, err := data.GetGoalStrategy(profileID)
+	if err != nil {
+		return data.GoalStrategy{}, err
+	}
+	if strategy == nil {
+		return DefaultGoalStrategy(), nil
+	}
+	return *strategy, nil
+}
+
+// SaveGoalStrategy saves the goal strategy of a profile and applies it to the current targets
+func (s *FoodService) SaveGoalStrategy(profileID string, strategy data.GoalStrategy) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	// If no profile ID is provided, use the active profile
+	if profileID == "" {
+		profileID = s.GetActiveProfile()
+
+		if profileID == "" {
+			return types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
+		}
+
+		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
+	}
+
+	if strategy.BMRFormula == "" {
+		strategy.BMRFormula = BMRFormulaMifflinStJeor
+	}
+
+	if err := ValidateGoalStrategy(strategy); err != nil {
+		return err
+	}
+
+	if err := data.SaveGoalStrategy(profileID, strategy); err != nil {
+		return err
+	}
+
+	// Apply the new split to the current targets
+	settings, err := data.GetUserSettings(profileID)
+	if err != nil {
+		return err
+	}
+	if settings.Calories > 0 {
+		calculation := s.CalculateNutritionFromCaloriesAndWeightWithStrategy(strategy, settings.Calories, settings.Weight)
+		settings.Proteins = float64(calculation.Proteins)
+		settings.Carbs = float64(calculation.Carbs)
+		settings.Fat = float64(calculation.Fat)
+		if err := data.SaveUserSettings(settings, profileID); err != nil {
+			return err
+		}
+	}
+
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// DeleteGoalStrategy removes the goal strategy of a profile, targets are no longer derived from it
+func (s *FoodService) DeleteGoalStrategy(profileID string) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	// If no profile ID is provided, use the active profile
+	if profileID == "" {
+		profileID = s.GetActiveProfile()
+
+		if profileID == "" {
+			return types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
+		}
+
+		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
+	}
+
+	if err := data.DeleteGoalStrategy(profileID); err != nil {
+		return err
+	}
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// ResolveGoalStrategy returns the given strategy, the strategy of the profile or the default strategy
+func (s *FoodService) ResolveGoalStrategy(strategy *data.GoalStrategy, profileID string) (data.GoalStrategy, error) {
+	if strategy != nil {
+		if strategy.BMRFormula == "" {
+			strategy.BMRFormula = BMRFormulaMifflinStJeor
+		}
+		if err := ValidateGoalStrategy(*strategy); err != nil {
+			return data.GoalStrategy{}, err
+		}
+		return *strategy, nil
+	}
+
+	if profileID != "" {
+		return s.getGoalStrategy(profileID)
+	}
+
+	return DefaultGoalStrategy(), nil
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+// MaxListLimit is the largest page of a list
+const MaxListLimit = 500
+
+// listOptions validates a list query against the sort keys of a list and converts it into
+// the options of the data layer
+func listOptions(query types.ListQuery, sortKeys map[string]string) (data.ListOptions, error) {
+	if query.Limit != 0 {
+		if err := ValidateLimit(query.Limit, MaxListLimit); err != nil {
+			return data.ListOptions{}, err
+		}
+	}
+	if err := ValidateOffset(query.Offset); err != nil {
+		return data.ListOptions{}, err
+	}
+
+	if _, ok := sortKeys[query.Sort]; query.Sort != "" && !ok {
+		keys := make([]string, 0, len(sortKeys))
+		for key := range sortKeys {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return data.ListOptions{}, types.NewValidationError("sort", "invalid sort key %s, must be one of %s", query.Sort, strings.Join(keys, ", "))
+	}
+	if query.Order != "" && query.Order != "asc" && query.Order != "desc" {
+		return data.ListOptions{}, types.NewValidationError("order", "invalid order %s, must be asc or desc", query.Order)
+	}
+	if query.Unit != "" {
+		if err := ValidateServingQuantityUnit(query.Unit); err != nil {
+			return data.ListOptions{}, err
+		}
+	}
+	if query.Kind != "" && query.Kind != "food" && query.Kind != "dish" {
+		return data.ListOptions{}, types.NewValidationError("kind", "invalid kind %s, must be food or dish", query.Kind)
+	}
+
+	for _, date := range []string{query.CreatedFrom, query.CreatedTo} {
+		if date == "" {
+			continue
+		}
+		if err := ValidateDate(date); err != nil {
+			return data.ListOptions{}, err
+		}
+	}
+	if query.CreatedFrom != "" && query.CreatedTo != "" && query.CreatedFrom > query.CreatedTo {
+		return data.ListOptions{}, types.NewValidationError("created_to", "created_from must not be after created_to")
+	}
+
+	return data.ListOptions{
+		Limit:       query.Limit,
+		Offset:      query.Offset,
+		Sort:        query.Sort,
+		Descending:  query.Order == "desc" || (query.Order == "" && query.Sort != "name"),
+		Unit:        query.Unit,
+		Incomplete:  query.Incomplete,
+		CreatedFrom: query.CreatedFrom,
+		CreatedTo:   query.CreatedTo,
+		Tag:         data.NormalizeTag(query.Tag),
+		Kind:        query.Kind,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+func TestListOptionsBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     types.ListQuery
+		wantField string // Field of the validation error, empty if the query is valid
+	}{
+		{"no limit", types.ListQuery{}, ""},
+		{"smallest limit", types.ListQuery{Limit: 1}, ""},
+		{"largest limit", types.ListQuery{Limit: MaxListLimit}, ""},
+		{"limit too large", types.ListQuery{Limit: MaxListLimit + 1}, "limit"},
+		{"negative limit", types.ListQuery{Limit: -1}, "limit"},
+		{"offset after the end", types.ListQuery{Limit: 10, Offset: 100000}, ""},
+		{"negative offset", types.ListQuery{Offset: -1}, "offset"},
+		{"unknown sort key", types.ListQuery{Sort: "barcode"}, "sort"},
+		{"unknown order", types.ListQuery{Order: "up"}, "order"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options, err := listOptions(test.query, data.ProfileSortKeys)
+			if test.wantField == "" {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				if options.Limit != test.query.Limit || options.Offset != test.query.Offset {
+					t.Errorf("got limit %d and offset %d, want %d and %d", options.Limit, options.Offset, test.query.Limit, test.query.Offset)
+				}
+				return
+			}
+			if !types.HasErrorCode(err, types.ErrorCodeValidation) || types.ErrorField(err) != test.wantField {
+				t.Errorf("got error %v, want a validation error of %s", err, test.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+// Meals a plan entry can be assigned to
+var MealPlanMeals = []string{"breakfast", "lunch", "dinner", "snack"}
+
+// mealPlanProjector calculates the projected nutrition of plan entries. Food items and
+// dishes are loaded once per projection.
+type mealPlanProjector struct {
+	foodItems map[string]data.PersistentFoodItem
+	dishes    map[string]data.DishNutrition
+}
+
+func newMealPlanProjector() *mealPlanProjector {
+	return &mealPlanProjector{
+		foodItems: make(map[string]data.PersistentFoodItem),
+		dishes:    make(map[string]data.DishNutrition),
+	}
+}
+
+// project returns the plan entry with the nutrition of its planned quantity. Food items use
+// their current nutrition values, dishes the values of one portion of the current recipe.
+func (p *mealPlanProjector) project(entry data.MealPlanEntry) (types.MealPlanEntry, error) {
+	projected := types.MealPlanEntry{
+		ID:         entry.ID,
+		ProfileID:  entry.ProfileID,
+		Date:       entry.Date,
+		Meal:       entry.Meal,
+		Barcode:    entry.Barcode,
+		DishID:     entry.DishID,
+		Quantity:   entry.Quantity,
+		Eaten:      entry.ConsumedID != "",
+		ConsumedID: entry.ConsumedID,
+	}
+
+	if entry.DishID != "" {
+		nutrition, ok := p.dishes[entry.DishID]
+		if !ok {
+			var err error
+			nutrition, err = data.GetDishNutrition(entry.DishID)
+			if err != nil {
+				return projected, err
+			}
+			p.dishes[entry.DishID] = nutrition
+		}
+
+		projected.Name = nutrition.Name
+		projected.Weight = entry.Quantity * nutrition.PortionWeight
+		projected.Nutrition = types.NutritionTotals{
+			Calories: entry.Quantity * nutrition.CaloriesPerPortion,
+			Proteins: entry.Quantity * nutrition.ProteinPerPortion,
+			Carbs:    entry.Quantity * nutrition.CarbsPerPortion,
+			Fat:      entry.Quantity * nutrition.FatPerPortion,
+		}
+		return projected, nil
+	}
+
+	foodItem, ok := p.foodItems[entry.Barcode]
+	if !ok {
+		var err error
+		foodItem, err = data.GetFoodItem(entry.Barcode)
+		if err != nil {
+			return projected, err
+		}
+		p.foodItems[entry.Barcode] = foodItem
+	}
+
+	projected.Name = foodItem.Name
+	projected.Weight = entry.Quantity * foodItem.ServingQuantity
+	projected.Nutrition = types.NutritionTotals{
+		Calories: projected.Weight * foodItem.CaloriesPer100g / 100,
+		Proteins: projected.Weight * foodItem.ProteinPer100g / 100,
+		Carbs:    projected.Weight * foodItem.CarbsPer100g / 100,
+		Fat:      projected.Weight * foodItem.FatPer100g / 100,
+	}
+	return projected, nil
+}
+
+// GetMealPlan returns the plan of a profile for every day between two dates (inclusive),
+// with the projected totals of each day compared with the targets in effect on that day
+func (s *FoodService) GetMealPlan(ctx context.Context, profileID string, startDate string, endDate string) ([]types.MealPlanDay, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.loadTargetSchedule(profileID)
+	if err != nil {
+		return nil, err
+	}
+	entriesByDate, err := s.projectMealPlan(ctx, profileID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []types.MealPlanDay
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		targets := schedule.targetsOn(day)
+		entries := entriesByDate[targets.Date]
+		if entries == nil {
+			entries = []types.MealPlanEntry{}
+		}
+
+		planned := sumMealPlanEntries(entries)
+		plan = append(plan, types.MealPlanDay{
+			Date:      targets.Date,
+			Entries:   entries,
+			Planned:   planned,
+			Targets:   targets,
+			Remaining: math.Round(targets.Calories - planned.Calories),
+		})
+	}
+
+	return plan, nil
+}
+
+// GetMealPlanReport compares the planned with the logged intake of every day between two dates (inclusive)
+func (s *FoodService) GetMealPlanReport(ctx context.Context, profileID string, startDate string, endDate string) ([]types.MealPlanReportDay, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.loadTargetSchedule(profileID)
+	if err != nil {
+		return nil, err
+	}
+	entriesByDate, err := s.projectMealPlan(ctx, profileID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	intake, err := data.GetDailyIntake(profileID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	intakeByDate := make(map[string]data.DailyIntake, len(intake))
+	for _, day := range intake {
+		intakeByDate[day.Date] = day
+	}
+
+	var report []types.MealPlanReportDay
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		targets := schedule.targetsOn(day)
+		entries := entriesByDate[targets.Date]
+		dayIntake := intakeByDate[targets.Date]
+
+		planned := sumMealPlanEntries(entries)
+		actual := roundNutritionTotals(types.NutritionTotals{
+			Calories: dayIntake.Calories,
+			Proteins: dayIntake.Proteins,
+			Carbs:    dayIntake.Carbs,
+			Fat:      dayIntake.Fat,
+		})
+
+		eaten := 0
+		for _, entry := range entries {
+			if entry.Eaten {
+				eaten++
+			}
+		}
+
+		report = append(report, types.MealPlanReportDay{
+			Date:           targets.Date,
+			PlannedEntries: len(entries),
+			EatenEntries:   eaten,
+			LoggedEntries:  dayIntake.Entries,
+			Planned:        planned,
+			Actual:         actual,
+			Difference: roundNutritionTotals(types.NutritionTotals{
+				Calories: actual.Calories - planned.Calories,
+				Proteins: actual.Proteins - planned.Proteins,
+				Carbs:    actual.Carbs - planned.Carbs,
+				Fat:      actual.Fat - planned.Fat,
+			}),
+			Targets: targets,
+		})
+	}
+
+	return report, nil
+}
+
+// projectMealPlan returns the projected plan entries of a profile grouped by date
+func (s *FoodService) projectMealPlan(ctx context.Context, profileID string, startDate string, endDate string) (map[string][]types.MealPlanEntry, error) {
+	entries, err := s.repository.GetMealPlanEntries(ctx, profileID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	projector := newMealPlanProjector()
+	entriesByDate := make(map[string][]types.MealPlanEntry)
+	for _, entry := range entries {
+		projected, err := projector.project(entry)
+		if err != nil {
+			return nil, err
+		}
+		projected.Weight = math.Round(projected.Weight*10) / 10
+		projected.Nutrition = roundNutritionTotals(projected.Nutrition)
+		entriesByDate[entry.Date] = append(entriesByDate[entry.Date], projected)
+	}
+
+	return entriesByDate, nil
+}
+
+// CreateMealPlanEntry plans a food item or a dish for a meal and returns the ID of the plan entry
+func (s *FoodService) CreateMealPlanEntry(ctx context.Context, request types.MealPlanEntryRequest) (string, error) {
+	if err := s.SyncToDropbox(request.ForceSync); err != nil {
+		return "", fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(request.ProfileID)
+	if err != nil {
+		return "", err
+	}
+
+	entry, err := s.toDataMealPlanEntry(request)
+	if err != nil {
+		return "", err
+	}
+	entry.ProfileID = profileID
+
+	id, err := s.repository.InsertMealPlanEntry(ctx, entry)
+	if err != nil {
+		return "", err
+	}
+
+	s.ScheduleDelayedUpload()
+	return id, nil
+}
+
+// UpdateMealPlanEntry replaces the date, meal, food item or dish and quantity of a plan entry
+func (s *FoodService) UpdateMealPlanEntry(ctx context.Context, id string, request types.MealPlanEntryRequest) error {
+	if err := s.SyncToDropbox(request.ForceSync); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	if err := ValidateUUID(id); err != nil {
+		return err
+	}
+
+	entry, err := s.toDataMealPlanEntry(request)
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+
+	if err := s.repository.UpdateMealPlanEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// DeleteMealPlanEntry deletes a plan entry. The diary entry of an eaten plan entry is kept.
+func (s *FoodService) DeleteMealPlanEntry(ctx context.Context, id string) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	if err := ValidateUUID(id); err != nil {
+		return err
+	}
+
+	if err := s.repository.DeleteMealPlanEntry(ctx, id); err != nil {
+		return err
+	}
+
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// MarkMealPlanEntryEaten logs a plan entry as consumed on its date and returns the ID of the diary entry
+func (s *FoodService) MarkMealPlanEntryEaten(ctx context.Context, id string, request types.MarkMealPlanEntryEatenRequest) (string, error) {
+	if err := s.SyncToDropbox(request.ForceSync); err != nil {
+		return "", fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	if err := ValidateUUID(id); err != nil {
+		return "", err
+	}
+
+	var consumedID string
+	// The diary entry is only kept if the plan entry is marked as eaten
+	err := s.repository.InTransaction(ctx, func(repo *data.Repository) error {
+		entry, err := repo.GetMealPlanEntry(ctx, id)
+		if err != nil {
+			return err
+		}
+		if entry.ConsumedID != "" {
+			return types.NewConflictError("meal plan entry %s is already marked as eaten", id)
+		}
+
+		quantity := entry.Quantity
+		if request.Quantity != 0 {
+			if err := ValidateConsumedQuantity(request.Quantity); err != nil {
+				return err
+			}
+			quantity = request.Quantity
+		}
+
+		if entry.DishID != "" {
+			consumedID, err = s.logConsumedDish(ctx, repo, types.ConsumedDishRequest{
+				DishID:    entry.DishID,
+				Portions:  quantity,
+				Date:      entry.Date,
+				ProfileID: entry.ProfileID,
+			})
+		} else {
+			consumedID, _, err = s.logConsumedFoodItem(ctx, repo, types.ConsumedFoodItemRequest{
+				Barcode:          entry.Barcode,
+				ConsumedQuantity: quantity,
+				Date:             entry.Date,
+				ProfileID:        entry.ProfileID,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		return repo.SetMealPlanEntryConsumed(ctx, id, consumedID)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.ScheduleDelayedUpload()
+	return consumedID, nil
+}
+
+// toDataMealPlanEntry validates a plan request and converts its quantity to servings or portions
+func (s *FoodService) toDataMealPlanEntry(request types.MealPlanEntryRequest) (data.MealPlanEntry, error) {
+	if err := ValidateMealPlanEntry(request); err != nil {
+		return data.MealPlanEntry{}, err
+	}
+
+	if request.Quantity == 0 {
+		request.Quantity = 1
+	}
+
+	// Convert quantities of food items in other units to the number of servings
+	if request.Barcode != "" && request.Unit != "" && request.Unit != data.ServingUnit {
+		foodItem, err := data.GetFoodItem(request.Barcode)
+		if err != nil {
+			return data.MealPlanEntry{}, err
+		}
+		if foodItem.ServingQuantity <= 0 {
+			return data.MealPlanEntry{}, types.NewValidationError("unit", "food item %s has no serving quantity to convert %s", request.Barcode, request.Unit)
+		}
+
+		quantity, err := data.ConvertToBaseUnit(foodItem, request.Quantity, request.Unit)
+		if err != nil {
+			return data.MealPlanEntry{}, err
+		}
+		request.Quantity = quantity / foodItem.ServingQuantity
+	}
+
+	return data.MealPlanEntry{
+		Date:     request.Date,
+		Meal:     request.Meal,
+		Barcode:  request.Barcode,
+		DishID:   request.DishID,
+		Quantity: request.Quantity,
+	}, nil
+}
+
+// parseDateRange validates a date range of at most MaxSummaryDays days
+func parseDateRange(startDate string, endDate string) (time.Time, time.Time, error) {
+	if err := ValidateDate(startDate); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if err := ValidateDate(endDate); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, _ := time.Parse("2006-01-02", startDate)
+	end, _ := time.Parse("2006-01-02", endDate)
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, types.NewValidationError("end_date", "start date must not be after end date")
+	}
+	if end.Sub(start).Hours()/24 >= MaxSummaryDays {
+		return time.Time{}, time.Time{}, types.NewValidationError("end_date", "date range must not exceed %d days", MaxSummaryDays)
+	}
+	return start, end, nil
+}
+
+func sumMealPlanEntries(entries []types.MealPlanEntry) types.NutritionTotals {
+	var totals types.NutritionTotals
+	for _, entry := range entries {
+		totals.Calories += entry.Nutrition.Calories
+		totals.Proteins += entry.Nutrition.Proteins
+		totals.Carbs += entry.Nutrition.Carbs
+		totals.Fat += entry.Nutrition.Fat
+	}
+	return roundNutritionTotals(totals)
+}
+
+// roundNutritionTotals rounds calories to whole kcal and macros to one decimal
+func roundNutritionTotals(totals types.NutritionTotals) types.NutritionTotals {
+	return types.NutritionTotals{
+		Calories: math.Round(totals.Calories),
+		Proteins: math.Round(totals.Proteins*10) / 10,
+		Carbs:    math.Round(totals.Carbs*10) / 10,
+		Fat:      math.Round(totals.Fat*10) / 10,
+	}
+}
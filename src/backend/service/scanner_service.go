@@ -3,7 +3,9 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"nutrack/backend/messaging"
 	local_settings "nutrack/backend/settings"
+	"nutrack/backend/types"
 	"os/exec"
 )
 
@@ -107,7 +109,7 @@ func (s *FoodService) SetActiveScanner(devicePath string) error {
 	}
 
 	if activeDevice == nil {
-		return fmt.Errorf("device not found: %s", devicePath)
+		return types.NewNotFoundError("device not found: %s", devicePath)
 	}
 
 	// Stop previous scanner, if one is running
@@ -188,9 +190,11 @@ def send_barcode_batch(barcode_list):
             "consumed_quantity": count,  # Use the number of scans as quantity
         })
     
+    # Log the valid scans even if others fail, e.g. an unknown barcode
     payload = {
         "items": items,
-        "force_sync": True
+        "force_sync": True,
+        "partial": True
     }
     
     log_message(f"Payload: {payload}")
@@ -202,8 +206,16 @@ def send_barcode_batch(barcode_list):
         log_message("Sending batch API request...")
         response = requests.post(url, json=payload, headers=headers)
         log_message(f"API response status: {response.status_code}")
+        if not response.ok:
+            log_message(f"API error: {response.text}")
         response.raise_for_status()
-        log_message(f"API response: {response.json()}")
+        result = response.json()
+        log_message(f"API response: {result.get('message')}")
+        for item in result.get("results") or []:
+            if item.get("error"):
+                log_message(f"Barcode {item.get('barcode')}: {item.get('status')} ({item.get('code')}): {item.get('error')}")
+            else:
+                log_message(f"Barcode {item.get('barcode')}: {item.get('status')}")
     except Exception as e:
         log_message(f"ERROR sending barcode batch: {e}")
         log_message(traceback.format_exc())
@@ -282,11 +294,35 @@ except Exception as e:
 	if err := cmd.Start(); err != nil {
 		return fmt.Errorf("failed to start scanner: %v", err)
 	}
+	go s.watchScanner(cmd, *activeDevice)
 
 	fmt.Printf("Scanner started successfully\n")
 	return nil
 }
 
+// watchScanner publishes a scanner offline event if the scanner process ends without being
+// stopped, e.g. because the device was unplugged
+func (s *FoodService) watchScanner(cmd *exec.Cmd, device ScannerDevice) {
+	err := cmd.Wait()
+
+	s.mutex.Lock()
+	stopped := s.activeCmd != cmd
+	if !stopped {
+		s.activeCmd = nil
+	}
+	s.mutex.Unlock()
+	if stopped {
+		return
+	}
+
+	fmt.Printf("Scanner %s stopped unexpectedly: %v\n", device.Name, err)
+	details := map[string]interface{}{"name": device.Name}
+	if err != nil {
+		details["error"] = err.Error()
+	}
+	messaging.PublishDetails(messaging.EntityScanner, messaging.ActionOffline, "", details, device.Path)
+}
+
 // StopListening stops the active scanner process
 func (s *FoodService) StopListening() error {
 	if s.activeCmd == nil || s.activeCmd.Process == nil {
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -21,6 +22,12 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	// DefaultSearchLimit is the page size of local searches
+	DefaultSearchLimit = 15
+	MaxSearchLimit     = 100
+)
+
 type FoodService struct {
 	lastCheckTime time.Time
 	tokenStore    *TokenStore
@@ -29,6 +36,9 @@ type FoodService struct {
 	mutex         sync.Mutex
 	activeProfile string
 	lastChecked   time.Time // For day change monitoring
+	repository    *data.Repository
+
+	webhookWorkers *webhookWorkers
 }
 
 func NewFoodService() (*FoodService, error) {
@@ -47,10 +57,20 @@ func NewFoodService() (*FoodService, error) {
 		settingsStore: settingsStore,
 		lastCheckTime: time.Now().Add(-checkInterval),
 		lastChecked:   time.Time{},
+		repository:    data.NewRepository(),
+
+		webhookWorkers: newWebhookWorkers(),
 	}
 
 	// Start the day change monitor in a separate goroutine
 	go service.onDayChangeMonitor()
+	go service.dailyTargetMonitor()
+
+	// The queues of the webhooks are gone with the previous process
+	if _, err := data.FailPendingWebhookDeliveries("backend restarted"); err != nil {
+		log.Printf("Failed to update pending webhook deliveries: %v", err)
+	}
+	go service.webhookDispatcher()
 
 	// Initialize scanner if one is set as active
 	settings, err := settingsStore.Load()
@@ -79,7 +99,7 @@ func NewFoodService() (*FoodService, error) {
 		// If no active profile is set in settings, try to set the first available profile
 		go func() {
 			// Get all profiles
-			profiles, err := data.GetAllProfiles()
+			profiles, _, err := data.GetAllProfiles(data.ListOptions{})
 			if err != nil {
 				log.Printf("Error fetching profiles on startup: %v", err)
 				return
@@ -125,26 +145,23 @@ func GetSecret(key string) string {
 	return ""
 }
 
-func (s *FoodService) GetProductData(barcode string) (*data.PersistentFoodItem, error) {
+func (s *FoodService) GetProductData(ctx context.Context, barcode string) (*data.PersistentFoodItem, error) {
 	if err := ValidateBarcode(barcode); err != nil {
 		return nil, err
 	}
 
 	// URL encode the barcode to handle special characters and spaces
 	encodedBarcode := url.QueryEscape(barcode)
-	resp, err := http.Get("https://world.openfoodfacts.org/api/v3/product/" + encodedBarcode + "?fields=code,product_name,nutriments,serving_quantity,serving_quantity_unit")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://world.openfoodfacts.org/api/v3/product/"+encodedBarcode+"?fields=code,product_name,nutriments,serving_quantity,serving_quantity_unit,serving_size,categories_tags", nil)
 	if err != nil {
-		// Instead of returning error, create default item for non-barcode items
-		return &data.PersistentFoodItem{
-			Barcode:             barcode,
-			Name:                barcode, // Use the barcode as name for now
-			CaloriesPer100g:     0,
-			ProteinPer100g:      0,
-			CarbsPer100g:        0,
-			FatPer100g:          0,
-			ServingQuantity:     100,
-			ServingQuantityUnit: "g",
-		}, nil
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, types.NewUpstreamUnavailableError(err, "failed to reach OpenFoodFacts: %v", err)
 	}
 	defer resp.Body.Close()
 
@@ -167,7 +184,7 @@ func (s *FoodService) GetProductData(barcode string) (*data.PersistentFoodItem,
 	}
 
 	if offResponse.Status != "success" && offResponse.Status != 1 {
-		return nil, fmt.Errorf("no product found in OpenFoodFacts")
+		return nil, types.NewNotFoundError("no product found in OpenFoodFacts")
 	}
 
 	calories := parseFloat(offResponse.Product.Nutriments.EnergyKcal100g)
@@ -178,6 +195,12 @@ func (s *FoodService) GetProductData(barcode string) (*data.PersistentFoodItem,
 		calories = math.Round(calories)
 	}
 
+	// Named portions like "1 slice (28 g)"
+	var portions []data.FoodPortion
+	if portion, ok := ParseServingSize(offResponse.Product.ServingSize); ok {
+		portions = append(portions, portion)
+	}
+
 	return &data.PersistentFoodItem{
 		Barcode:             barcode,
 		Name:                offResponse.Product.ProductName,
@@ -187,24 +210,33 @@ func (s *FoodService) GetProductData(barcode string) (*data.PersistentFoodItem,
 		FatPer100g:          parseFloat(offResponse.Product.Nutriments.Fat100g),
 		ServingQuantity:     parseFloat(offResponse.Product.ServingQuantity),
 		ServingQuantityUnit: offResponse.Product.ServingQuantityUnit,
+		Portions:            portions,
+		Tags:                categoryTags(offResponse.Product.CategoriesTags),
 	}, nil
 }
 
-func (s *FoodService) GetAllFoodItems() ([]data.PersistentFoodItem, error) {
+// GetAllFoodItems returns a page of the food items matching the filters of the query and the
+// total number of matching food items
+func (s *FoodService) GetAllFoodItems(query types.ListQuery) ([]data.PersistentFoodItem, int, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return nil, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return nil, 0, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
-	foodItems, err := data.GetAllFoodItems()
+	options, err := listOptions(query, data.FoodItemSortKeys)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	foodItems, total, err := data.GetAllFoodItems(options)
 	if err != nil {
 		fmt.Println("Error getting all food items:", err)
-		return nil, err
+		return nil, 0, err
 	}
-	return foodItems, nil
+	return foodItems, total, nil
 }
 
 func (s *FoodService) GetServingQuantityByBarcode(barcode string) (float64, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return 0, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return 0, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if err := ValidateBarcode(barcode); err != nil {
 		return 0, err
@@ -219,7 +251,7 @@ func (s *FoodService) GetServingQuantityByBarcode(barcode string) (float64, erro
 
 func (s *FoodService) UpdateFoodItem(barcode string, updateData map[string]interface{}) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if err := ValidateBarcode(barcode); err != nil {
 		return err
@@ -241,7 +273,7 @@ func (s *FoodService) UpdateFoodItem(barcode string, updateData map[string]inter
 
 func (s *FoodService) DeleteFoodItem(barcode string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if err := ValidateBarcode(barcode); err != nil {
 		return err
@@ -254,15 +286,34 @@ func (s *FoodService) DeleteFoodItem(barcode string) error {
 	return nil
 }
 
-func (s *FoodService) ResetFoodItem(barcode string) error {
+// SetFoodPortions replaces the named portions of a food item
+func (s *FoodService) SetFoodPortions(barcode string, portions []data.FoodPortion) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	if err := ValidateBarcode(barcode); err != nil {
+		return err
+	}
+	if err := ValidateFoodPortions(portions); err != nil {
+		return err
+	}
+
+	if err := data.SetFoodPortions(barcode, portions); err != nil {
+		return err
+	}
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+func (s *FoodService) ResetFoodItem(ctx context.Context, barcode string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if err := ValidateBarcode(barcode); err != nil {
 		return err
 	}
 
-	newFoodData, err := s.GetProductData(barcode)
+	newFoodData, err := s.GetProductData(ctx, barcode)
 	if err != nil {
 		return err
 	}
@@ -286,29 +337,60 @@ func (s *FoodService) ResetFoodItem(barcode string) error {
 	return nil
 }
 
-func (s *FoodService) CheckAndInsertFoodItem(barcode string) error {
+func (s *FoodService) CheckAndInsertFoodItem(ctx context.Context, barcode string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
-	if err := ValidateBarcode(barcode); err != nil {
+
+	fetched, err := s.fetchFoodItem(ctx, barcode)
+	if err != nil || fetched == nil {
 		return err
 	}
 
-	exists, err := data.CheckFoodItemExists(data.PersistentFoodItem{Barcode: barcode})
+	var inserted bool
+	err = s.repository.InTransaction(ctx, func(repo *data.Repository) error {
+		inserted, err = insertFetchedFoodItem(ctx, repo, fetched)
+		return err
+	})
 	if err != nil {
 		return err
 	}
+	if inserted {
+		s.ScheduleDelayedUpload()
+	}
+	return nil
+}
 
-	if exists {
-		return nil
+// fetchedFoodItem is the product data of a barcode without food item
+type fetchedFoodItem struct {
+	foodItem     data.PersistentFoodItem
+	lookupFailed bool // OpenFoodFacts had no data, the food item is empty
+}
+
+// fetchFoodItem returns the product data of a barcode without food item, or nil if the food
+// item exists. Barcodes unknown to OpenFoodFacts get an empty food item, which can be
+// completed later. The data is fetched outside of transactions, so slow requests don't block
+// the database.
+func (s *FoodService) fetchFoodItem(ctx context.Context, barcode string) (*fetchedFoodItem, error) {
+	if err := ValidateBarcode(barcode); err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repository.FoodItemExists(ctx, barcode)
+	if err != nil || exists {
+		return nil, err
 	}
 
-	newFoodData, err := s.GetProductData(barcode)
+	fetched := &fetchedFoodItem{}
+	newFoodData, err := s.GetProductData(ctx, barcode)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		fmt.Printf("Failed to get product data for barcode %s: %v\n", barcode, err)
 		newFoodData = &data.PersistentFoodItem{
 			Barcode:             barcode,
-			Name:                "",
+			Name:                barcode, // Use the barcode as name for now
 			CaloriesPer100g:     0,
 			ProteinPer100g:      0,
 			CarbsPer100g:        0,
@@ -316,25 +398,37 @@ func (s *FoodService) CheckAndInsertFoodItem(barcode string) error {
 			ServingQuantity:     100,
 			ServingQuantityUnit: "g",
 		}
+		fetched.lookupFailed = true
 	}
 
 	ModifyPersistentFoodItem(newFoodData)
 
 	if err := ValidatePersistentFoodItem(*newFoodData); err != nil {
-		return err
+		return nil, err
 	}
+	fetched.foodItem = *newFoodData
+	return fetched, nil
+}
 
-	err = data.InsertFoodItem(*newFoodData)
-	if err != nil {
-		return err
+// insertFetchedFoodItem inserts a fetched food item unless it was added in the meantime
+func insertFetchedFoodItem(ctx context.Context, repo *data.Repository, fetched *fetchedFoodItem) (bool, error) {
+	if fetched == nil {
+		return false, nil
 	}
-	s.ScheduleDelayedUpload()
-	return nil
+
+	exists, err := repo.FoodItemExists(ctx, fetched.foodItem.Barcode)
+	if err != nil || exists {
+		return false, err
+	}
+	if err := repo.InsertFoodItem(ctx, fetched.foodItem); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-func (s *FoodService) ManuallyAddFoodItem(newItem data.PersistentFoodItem) error {
+func (s *FoodService) ManuallyAddFoodItem(ctx context.Context, newItem data.PersistentFoodItem) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if err := ValidatePersistentFoodItem(newItem); err != nil {
 		return err
@@ -342,7 +436,7 @@ func (s *FoodService) ManuallyAddFoodItem(newItem data.PersistentFoodItem) error
 
 	ModifyPersistentFoodItem(&newItem)
 
-	err := data.InsertFoodItem(newItem)
+	err := s.repository.InsertFoodItem(ctx, newItem)
 	if err != nil {
 		return err
 	}
@@ -350,10 +444,26 @@ func (s *FoodService) ManuallyAddFoodItem(newItem data.PersistentFoodItem) error
 	return nil
 }
 
-func (s *FoodService) PostConsumedFoodItem(request types.ConsumedFoodItemRequest) error {
+func (s *FoodService) PostConsumedFoodItem(ctx context.Context, request types.ConsumedFoodItemRequest) error {
 	if err := s.SyncToDropbox(request.ForceSync); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	err := s.repository.InTransaction(ctx, func(repo *data.Repository) error {
+		_, _, err := s.logConsumedFoodItem(ctx, repo, request)
+		return err
+	})
+	if err != nil {
+		return err
 	}
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// logConsumedFoodItem adds a food item to the diary and returns the ID of the diary entry.
+// The quantity is added to an existing entry of the same food item on the same date, which
+// is reported as merged.
+func (s *FoodService) logConsumedFoodItem(ctx context.Context, repo *data.Repository, request types.ConsumedFoodItemRequest) (string, bool, error) {
 	if request.Date == "" {
 		request.Date = time.Now().Format("2006-01-02")
 	}
@@ -366,45 +476,53 @@ func (s *FoodService) PostConsumedFoodItem(request types.ConsumedFoodItemRequest
 		request.ProfileID = s.GetActiveProfile()
 
 		if request.ProfileID == "" {
-			return fmt.Errorf("no profile ID provided and no active profile set")
+			return "", false, types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
 		}
 
 		fmt.Printf("No profile ID provided, using active profile: %s\n", request.ProfileID)
 	}
 
 	if err := ValidateBarcode(request.Barcode); err != nil {
-		return err
+		return "", false, err
 	}
 
 	if err := ValidateDate(request.Date); err != nil {
-		return err
+		return "", false, err
 	}
 
 	if err := ValidateConsumedQuantity(request.ConsumedQuantity); err != nil {
-		return err
+		return "", false, err
 	}
 
-	servingQuantity, err := s.GetServingQuantityByBarcode(request.Barcode)
+	foodItem, err := repo.GetFoodItem(ctx, request.Barcode)
 	if err != nil {
-		return err
+		return "", false, err
+	}
+	servingQuantity := foodItem.ServingQuantity
+
+	// Convert quantities in other units to the number of servings
+	if request.Unit != "" && request.Unit != data.ServingUnit {
+		if servingQuantity <= 0 {
+			return "", false, types.NewValidationError("unit", "food item %s has no serving quantity to convert %s", request.Barcode, request.Unit)
+		}
+
+		quantity, err := data.ConvertToBaseUnit(foodItem, request.ConsumedQuantity, request.Unit)
+		if err != nil {
+			return "", false, err
+		}
+		request.ConsumedQuantity = quantity / servingQuantity
 	}
 
-	existingItem, err := data.GetConsumedFoodItemByBarcodeAndDate(request.Barcode, request.Date, request.ProfileID)
+	existingItem, err := repo.GetConsumedFoodItemByBarcodeAndDate(ctx, request.Barcode, request.Date, request.ProfileID)
 	if err != nil {
-		return err
+		return "", false, err
 	}
 
 	if existingItem != nil {
-		existingItem.ConsumedQuantity += request.ConsumedQuantity
-		err = data.UpdateConsumedFoodItem(existingItem.ID, map[string]interface{}{
-			"consumed_quantity": existingItem.ConsumedQuantity,
-			"profile_id":        request.ProfileID,
-		})
-		if err != nil {
-			return err
+		if err := repo.AddConsumedQuantity(ctx, existingItem.ID, request.ConsumedQuantity); err != nil {
+			return "", false, err
 		}
-		s.ScheduleDelayedUpload()
-		return nil
+		return existingItem.ID, true, nil
 	}
 
 	id := uuid.New().String()
@@ -419,19 +537,111 @@ func (s *FoodService) PostConsumedFoodItem(request types.ConsumedFoodItemRequest
 		InsertDate:       currentDate,
 	}
 
-	err = data.InsertConsumedFoodItem(newConsumedFoodItem, request.ProfileID)
+	err = repo.InsertConsumedFoodItem(ctx, newConsumedFoodItem, request.ProfileID)
 	if err != nil {
+		return "", false, err
+	}
+	return id, false, nil
+}
+
+// RepriceHistory applies the current nutrition values of a food item to already consumed entries.
+// Without a date range the whole history is updated, without a profile ID all profiles are updated.
+func (s *FoodService) RepriceHistory(request types.RepriceHistoryRequest) (int64, error) {
+	if err := s.SyncToDropbox(request.ForceSync); err != nil {
+		return 0, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	if err := ValidateBarcode(request.Barcode); err != nil {
+		return 0, err
+	}
+	if request.StartDate != "" {
+		if err := ValidateDate(request.StartDate); err != nil {
+			return 0, err
+		}
+	}
+	if request.EndDate != "" {
+		if err := ValidateDate(request.EndDate); err != nil {
+			return 0, err
+		}
+	}
+	if request.StartDate != "" && request.EndDate != "" && request.StartDate > request.EndDate {
+		return 0, types.NewValidationError("end_date", "start date must not be after end date")
+	}
+
+	updated, err := data.RepriceConsumedFoodItems(request.Barcode, request.StartDate, request.EndDate, request.ProfileID)
+	if err != nil {
+		return 0, err
+	}
+	if updated > 0 {
+		s.ScheduleDelayedUpload()
+	}
+	return updated, nil
+}
+
+// PostConsumedDish logs a dish or a fraction of it with a snapshot of its ingredients
+func (s *FoodService) PostConsumedDish(ctx context.Context, request types.ConsumedDishRequest) error {
+	if err := s.SyncToDropbox(request.ForceSync); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	if _, err := s.logConsumedDish(ctx, s.repository, request); err != nil {
 		return err
 	}
 	s.ScheduleDelayedUpload()
 	return nil
 }
 
-func (s *FoodService) DeleteConsumedFoodItem(id string) error {
+// logConsumedDish adds a dish to the diary and returns the ID of the diary entry
+func (s *FoodService) logConsumedDish(ctx context.Context, repo *data.Repository, request types.ConsumedDishRequest) (string, error) {
+	if request.Date == "" {
+		request.Date = time.Now().Format("2006-01-02")
+	}
+
+	if request.Portions == 0 {
+		request.Portions = 1
+	}
+
+	if request.ProfileID == "" {
+		request.ProfileID = s.GetActiveProfile()
+
+		if request.ProfileID == "" {
+			return "", types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
+		}
+
+		fmt.Printf("No profile ID provided, using active profile: %s\n", request.ProfileID)
+	}
+
+	if request.DishID == "" {
+		return "", types.NewValidationError("dish_id", "dish ID is required")
+	}
+
+	if err := ValidateDate(request.Date); err != nil {
+		return "", err
+	}
+
+	if err := ValidateConsumedQuantity(request.Portions); err != nil {
+		return "", err
+	}
+
+	newConsumedDish := data.ConsumedFoodItem{
+		ID:               uuid.New().String(),
+		Barcode:          request.DishID,
+		ConsumedQuantity: request.Portions,
+		Date:             request.Date,
+		InsertDate:       time.Now(),
+	}
+
+	err := repo.InsertConsumedDish(ctx, newConsumedDish, request.DishID, request.ProfileID)
+	if err != nil {
+		return "", err
+	}
+	return newConsumedDish.ID, nil
+}
+
+func (s *FoodService) DeleteConsumedFoodItem(ctx context.Context, id string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
-	err := data.DeleteConsumedFoodItem(id)
+	err := s.repository.DeleteConsumedFoodItem(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -439,12 +649,18 @@ func (s *FoodService) DeleteConsumedFoodItem(id string) error {
 	return nil
 }
 
-func (s *FoodService) GetConsumedFoodItemsByDate(date string, profileID string) ([]data.ConsumedFoodItemWithDetails, error) {
+// GetConsumedFoodItemsByDate returns a page of the diary entries of a date matching the filters
+// of the query and the total number of matching entries
+func (s *FoodService) GetConsumedFoodItemsByDate(date string, profileID string, query types.ListQuery) ([]data.ConsumedFoodItemWithDetails, int, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return nil, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return nil, 0, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if err := ValidateDate(date); err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	options, err := listOptions(query, data.ConsumedFoodItemSortKeys)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// if no profile ID is provided, use the active profile
@@ -452,30 +668,30 @@ func (s *FoodService) GetConsumedFoodItemsByDate(date string, profileID string)
 		profileID = s.GetActiveProfile()
 
 		if profileID == "" {
-			return nil, fmt.Errorf("no profile ID provided and no active profile set")
+			return nil, 0, types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
 		}
 
 		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
 	}
 
-	consumedItems, err := data.GetConsumedFoodItemsByDate(date, profileID)
+	consumedItems, total, err := data.GetConsumedFoodItemsByDate(date, profileID, options)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return consumedItems, nil
+	return consumedItems, total, nil
 }
 
-func (s *FoodService) UpdateConsumedFoodItem(id string, updateData map[string]interface{}) error {
+func (s *FoodService) UpdateConsumedFoodItem(ctx context.Context, id string, updateData map[string]interface{}) error {
 
 	fmt.Println("force_sync", updateData["force_sync"])
 	if updateData["force_sync"] != nil {
 		if err := s.SyncToDropbox(updateData["force_sync"].(bool)); err != nil {
-			return fmt.Errorf("failed to sync with Dropbox: %v", err)
+			return fmt.Errorf("failed to sync with Dropbox: %w", err)
 		}
 	} else {
 		if err := s.SyncToDropbox(false); err != nil {
-			return fmt.Errorf("failed to sync with Dropbox: %v", err)
+			return fmt.Errorf("failed to sync with Dropbox: %w", err)
 		}
 	}
 	for field, value := range updateData {
@@ -485,7 +701,7 @@ func (s *FoodService) UpdateConsumedFoodItem(id string, updateData map[string]in
 		}
 	}
 
-	err := data.UpdateConsumedFoodItem(id, updateData)
+	err := s.repository.UpdateConsumedFoodItem(ctx, id, updateData)
 	if err != nil {
 		return err
 	}
@@ -493,20 +709,69 @@ func (s *FoodService) UpdateConsumedFoodItem(id string, updateData map[string]in
 	return nil
 }
 
-func (s *FoodService) SearchFoodItems(query string) ([]data.PersistentFoodItem, error) {
+// SearchFoodItems searches food items by name or barcode, optionally only food items with a tag.
+// With boost, favorites and food items the profile logged often are ranked higher. Without a
+// profile ID the active profile is boosted.
+func (s *FoodService) SearchFoodItems(query string, tag string, boost bool, profileID string, limit int, offset int) ([]data.PersistentFoodItem, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return nil, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	boostProfileID, limit, err := s.prepareSearch(boost, profileID, limit, offset)
+	if err != nil {
+		return nil, err
 	}
-	items, err := data.SearchFoodItems(query)
+
+	items, err := data.SearchFoodItems(query, data.NormalizeTag(tag), boostProfileID, frequentSince(DefaultFrequentFoodItemsDays), limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	return items, nil
 }
 
+// Search searches food items and dishes by name or barcode, ranked like SearchFoodItems
+func (s *FoodService) Search(query string, tag string, boost bool, profileID string, limit int, offset int) ([]data.SearchResult, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	boostProfileID, limit, err := s.prepareSearch(boost, profileID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := data.Search(query, data.NormalizeTag(tag), boostProfileID, frequentSince(DefaultFrequentFoodItemsDays), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// prepareSearch validates the page of a search and resolves the profile to boost
+func (s *FoodService) prepareSearch(boost bool, profileID string, limit int, offset int) (string, int, error) {
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+	if err := ValidateLimit(limit, MaxSearchLimit); err != nil {
+		return "", 0, err
+	}
+	if err := ValidateOffset(offset); err != nil {
+		return "", 0, err
+	}
+
+	if !boost {
+		return "", limit, nil
+	}
+	boostProfileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return "", 0, err
+	}
+	return boostProfileID, limit, nil
+}
+
 func (s *FoodService) SearchOpenFoodFacts(query string) (map[string]interface{}, error) {
 	if len(query) < 3 {
-		return nil, fmt.Errorf("search query must be at least 3 characters long")
+		return nil, types.NewValidationError("q", "search query must be at least 3 characters long")
 	}
 
 	// Check if query is a barcode
@@ -528,13 +793,16 @@ func (s *FoodService) SearchOpenFoodFacts(query string) (map[string]interface{},
 
 	resp, err := http.Get(apiUrl)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch data from OpenFoodFacts: %v", err)
+		return nil, types.NewUpstreamUnavailableError(err, "failed to fetch data from OpenFoodFacts: %v", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, types.NewRateLimitedError("OpenFoodFacts rate limit reached, try again later")
+	}
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("OpenFoodFacts API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, types.NewUpstreamUnavailableError(nil, "OpenFoodFacts API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	var result map[string]interface{}
@@ -553,7 +821,7 @@ func (s *FoodService) SearchOpenFoodFacts(query string) (map[string]interface{},
 				return map[string]interface{}{"products": []interface{}{}}, nil
 			}
 		} else {
-			return nil, fmt.Errorf("invalid response format")
+			return nil, types.NewUpstreamUnavailableError(nil, "invalid response format")
 		}
 	}
 
@@ -562,7 +830,7 @@ func (s *FoodService) SearchOpenFoodFacts(query string) (map[string]interface{},
 
 func (s *FoodService) SaveUserSettings(settings data.UserSettings, profileID string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 
 	// If no profile ID is provided, use the active profile
@@ -570,7 +838,7 @@ func (s *FoodService) SaveUserSettings(settings data.UserSettings, profileID str
 		profileID = s.GetActiveProfile()
 
 		if profileID == "" {
-			return fmt.Errorf("no profile ID provided and no active profile set")
+			return types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
 		}
 
 		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
@@ -581,8 +849,20 @@ func (s *FoodService) SaveUserSettings(settings data.UserSettings, profileID str
 		return err
 	}
 
+	// A configured goal strategy defines the macro split of the calories
+	strategy, err := data.GetGoalStrategy(profileID)
+	if err != nil {
+		return err
+	}
+	if strategy != nil && settings.Calories > 0 {
+		calculation := s.CalculateNutritionFromCaloriesAndWeightWithStrategy(*strategy, settings.Calories, settings.Weight)
+		settings.Proteins = float64(calculation.Proteins)
+		settings.Carbs = float64(calculation.Carbs)
+		settings.Fat = float64(calculation.Fat)
+	}
+
 	// Save the user settings
-	err := data.SaveUserSettings(settings, profileID)
+	err = data.SaveUserSettings(settings, profileID)
 	if err != nil {
 		return err
 	}
@@ -603,7 +883,7 @@ func (s *FoodService) SaveUserSettings(settings data.UserSettings, profileID str
 
 func (s *FoodService) GetUserSettings(profileID string) (data.UserSettings, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return data.UserSettings{}, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return data.UserSettings{}, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 
 	// If no profile ID is provided, use the active profile
@@ -611,7 +891,7 @@ func (s *FoodService) GetUserSettings(profileID string) (data.UserSettings, erro
 		profileID = s.GetActiveProfile()
 
 		if profileID == "" {
-			return data.UserSettings{}, fmt.Errorf("no profile ID provided and no active profile set")
+			return data.UserSettings{}, types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
 		}
 
 		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
@@ -624,65 +904,169 @@ func (s *FoodService) GetUserSettings(profileID string) (data.UserSettings, erro
 	return settings, nil
 }
 
-func (s *FoodService) CheckInsertAndConsume(request types.ConsumedFoodItemRequest) error {
+func (s *FoodService) CheckInsertAndConsume(ctx context.Context, request types.ConsumedFoodItemRequest) error {
+	_, err := s.CheckInsertAndConsumeBatch(ctx, types.BatchConsumedFoodItemRequest{
+		Items:     []types.ConsumedFoodItemRequest{request},
+		ForceSync: request.ForceSync,
+	})
+	return err
+}
+
+// CheckInsertAndConsumeBatch adds missing food items and logs the items of a batch. By default
+// all items are logged in one transaction and a failing item fails the whole batch. In partial
+// mode every item is logged on its own and failures are only reported in its result.
+func (s *FoodService) CheckInsertAndConsumeBatch(ctx context.Context, request types.BatchConsumedFoodItemRequest) ([]types.BatchItemResult, error) {
+	// First sync with Dropbox if necessary
 	if err := s.SyncToDropbox(request.ForceSync); err != nil {
 		fmt.Printf("Dropbox sync check failed: %v\n", err)
-		return err
+		return nil, err
 	}
 
-	err := s.CheckAndInsertFoodItem(request.Barcode)
-	if err != nil {
-		fmt.Printf("CheckAndInsertFoodItem failed for barcode %s: %v\n", request.Barcode, err)
-		return err
+	var results []types.BatchItemResult
+	var err error
+	if request.Partial {
+		results, err = s.consumeBatchPartially(ctx, request.Items)
+	} else {
+		results, err = s.consumeBatch(ctx, request.Items)
 	}
-	err = s.PostConsumedFoodItem(request)
 	if err != nil {
-		fmt.Printf("PostConsumedFoodItem failed: %v\n", err)
-		return err
+		fmt.Printf("CheckInsertAndConsumeBatch failed: %v\n", err)
+		return nil, err
 	}
-	return nil
-}
 
-func (s *FoodService) CheckInsertAndConsumeBatch(request types.BatchConsumedFoodItemRequest) error {
-	// First sync with Dropbox if necessary
-	if err := s.SyncToDropbox(request.ForceSync); err != nil {
-		fmt.Printf("Dropbox sync check failed: %v\n", err)
-		return err
+	for _, result := range results {
+		if result.ConsumedID != "" {
+			s.ScheduleDelayedUpload()
+			break
+		}
 	}
+	return results, nil
+}
 
-	// Process each item individually
-	for _, item := range request.Items {
-		fmt.Printf("[CheckInsertAndConsumeBatch] Processing item: %v\n", item)
-		// Check and insert the food item if it doesn't exist
-		err := s.CheckAndInsertFoodItem(item.Barcode)
+// consumeBatch logs all items in one transaction
+func (s *FoodService) consumeBatch(ctx context.Context, items []types.ConsumedFoodItemRequest) ([]types.BatchItemResult, error) {
+	fetched := map[string]*fetchedFoodItem{}
+	for i, item := range items {
+		if _, ok := fetched[item.Barcode]; ok {
+			continue
+		}
+		foodItem, err := s.fetchFoodItem(ctx, item.Barcode)
 		if err != nil {
-			fmt.Printf("CheckAndInsertFoodItem failed for barcode %s: %v\n", item.Barcode, err)
-			return err
+			return nil, fmt.Errorf("item %d (%s): %w", i, item.Barcode, err)
 		}
+		fetched[item.Barcode] = foodItem
+	}
 
-		// Consume the food item
-		err = s.PostConsumedFoodItem(item)
+	results := make([]types.BatchItemResult, len(items))
+	err := s.repository.InTransaction(ctx, func(repo *data.Repository) error {
+		for i, item := range items {
+			fmt.Printf("[CheckInsertAndConsumeBatch] Processing item: %v\n", item)
+			result, err := s.consumeBatchItem(ctx, repo, i, item, fetched[item.Barcode])
+			if err != nil {
+				return fmt.Errorf("item %d (%s): %w", i, item.Barcode, err)
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// consumeBatchPartially logs every item in its own transaction. Failing items are reported in
+// their result, only a cancelled request stops the batch.
+func (s *FoodService) consumeBatchPartially(ctx context.Context, items []types.ConsumedFoodItemRequest) ([]types.BatchItemResult, error) {
+	results := make([]types.BatchItemResult, len(items))
+	for i, item := range items {
+		fmt.Printf("[CheckInsertAndConsumeBatch] Processing item: %v\n", item)
+		fetched, err := s.fetchFoodItem(ctx, item.Barcode)
+		if err == nil {
+			err = s.repository.InTransaction(ctx, func(repo *data.Repository) error {
+				var err error
+				results[i], err = s.consumeBatchItem(ctx, repo, i, item, fetched)
+				return err
+			})
+		}
 		if err != nil {
-			fmt.Printf("PostConsumedFoodItem failed: %v\n", err)
-			return err
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			results[i] = failedBatchItem(i, item.Barcode, err)
 		}
 	}
+	return results, nil
+}
 
-	s.ScheduleDelayedUpload()
+// consumeBatchItem inserts the fetched food item of a batch item, if any, and logs the item
+func (s *FoodService) consumeBatchItem(ctx context.Context, repo *data.Repository, index int, item types.ConsumedFoodItemRequest, fetched *fetchedFoodItem) (types.BatchItemResult, error) {
+	inserted, err := insertFetchedFoodItem(ctx, repo, fetched)
+	if err != nil {
+		return types.BatchItemResult{}, err
+	}
 
-	return nil
+	consumedID, merged, err := s.logConsumedFoodItem(ctx, repo, item)
+	if err != nil {
+		return types.BatchItemResult{}, err
+	}
+
+	result := types.BatchItemResult{
+		Index:      index,
+		Barcode:    item.Barcode,
+		Status:     types.BatchItemLogged,
+		ConsumedID: consumedID,
+	}
+	switch {
+	case inserted && fetched.lookupFailed:
+		result.Status = types.BatchItemLookupFailed
+	case inserted:
+		result.Status = types.BatchItemInserted
+	case merged:
+		result.Status = types.BatchItemMerged
+	}
+	return result, nil
+}
+
+// failedBatchItem returns the result of a batch item which was not logged
+func failedBatchItem(index int, barcode string, err error) types.BatchItemResult {
+	status := types.BatchItemFailed
+	if types.HasErrorCode(err, types.ErrorCodeValidation) {
+		status = types.BatchItemValidationError
+	}
+
+	return types.BatchItemResult{
+		Index:   index,
+		Barcode: barcode,
+		Status:  status,
+		Error:   err.Error(),
+		Code:    types.ErrorCodeOf(err),
+		Field:   types.ErrorField(err),
+	}
 }
 
-func (s *FoodService) CreateDish(request types.DishRequest) error {
+func (s *FoodService) CreateDish(ctx context.Context, request types.DishRequest) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	if err := ValidateCookedWeight(request.Dish.CookedWeight); err != nil {
+		return err
+	}
+	if err := ValidatePortions(request.Dish.Portions); err != nil {
+		return err
+	}
+	if err := ValidateTags(request.Dish.Tags); err != nil {
+		return err
 	}
 	dishID := uuid.New().String()
 
 	dish := data.Dish{
-		ID:      dishID,
-		Name:    request.Dish.Name,
-		Barcode: request.Dish.Barcode,
+		ID:           dishID,
+		Name:         request.Dish.Name,
+		Barcode:      request.Dish.Barcode,
+		CookedWeight: request.Dish.CookedWeight,
+		Portions:     request.Dish.Portions,
+		Tags:         request.Dish.Tags,
 	}
 
 	items := make([]data.DishItem, len(request.Items))
@@ -691,10 +1075,11 @@ func (s *FoodService) CreateDish(request types.DishRequest) error {
 			DishID:   dishID,
 			Barcode:  item.Barcode,
 			Quantity: item.Quantity,
+			Unit:     item.Unit,
 		}
 	}
 
-	err := data.CreateDish(dish, items)
+	err := s.repository.CreateDish(ctx, dish, items)
 	if err != nil {
 		return err
 	}
@@ -702,11 +1087,11 @@ func (s *FoodService) CreateDish(request types.DishRequest) error {
 	return nil
 }
 
-func (s *FoodService) DeleteDish(id string) error {
+func (s *FoodService) DeleteDish(ctx context.Context, id string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
-	err := data.DeleteDish(id)
+	err := s.repository.DeleteDish(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -714,9 +1099,27 @@ func (s *FoodService) DeleteDish(id string) error {
 	return nil
 }
 
-func (s *FoodService) UpdateDish(id string, request types.DishRequest) error {
+func (s *FoodService) UpdateDish(ctx context.Context, id string, request types.DishRequest) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	if err := ValidateCookedWeight(request.Dish.CookedWeight); err != nil {
+		return err
+	}
+	if err := ValidatePortions(request.Dish.Portions); err != nil {
+		return err
+	}
+	if err := ValidateTags(request.Dish.Tags); err != nil {
+		return err
+	}
+
+	dish := data.Dish{
+		ID:           id,
+		Name:         request.Dish.Name,
+		Barcode:      request.Dish.Barcode,
+		CookedWeight: request.Dish.CookedWeight,
+		Portions:     request.Dish.Portions,
+		Tags:         request.Dish.Tags,
 	}
 
 	items := make([]data.DishItem, len(request.Items))
@@ -725,10 +1128,11 @@ func (s *FoodService) UpdateDish(id string, request types.DishRequest) error {
 			DishID:   id,
 			Barcode:  item.Barcode,
 			Quantity: item.Quantity,
+			Unit:     item.Unit,
 		}
 	}
 
-	err := data.UpdateDish(id, request.Dish.Name, request.Dish.Barcode, items)
+	err := s.repository.UpdateDish(ctx, dish, items)
 	if err != nil {
 		return err
 	}
@@ -738,7 +1142,7 @@ func (s *FoodService) UpdateDish(id string, request types.DishRequest) error {
 
 func (s *FoodService) GetDish(id string) (data.Dish, []data.DishItem, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return data.Dish{}, nil, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return data.Dish{}, nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	dish, items, err := data.GetDishWithItems(id)
 	if err != nil {
@@ -747,23 +1151,42 @@ func (s *FoodService) GetDish(id string) (data.Dish, []data.DishItem, error) {
 	return dish, items, nil
 }
 
-func (s *FoodService) GetAllDishes() ([]data.DishWithDetailedItems, error) {
+// GetAllDishes returns a page of the dishes matching the filters of the query and the total
+// number of matching dishes
+func (s *FoodService) GetAllDishes(query types.ListQuery) ([]data.DishWithDetailedItems, int, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return nil, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return nil, 0, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
-	dishes, err := data.GetAllDishes()
+	options, err := listOptions(query, data.DishSortKeys)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return dishes, nil
+	dishes, total, err := data.GetAllDishes(options)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return dishes, total, nil
+}
+
+// GetDishNutrition returns the nutrition values of a dish per 100g and per portion
+func (s *FoodService) GetDishNutrition(id string) (data.DishNutrition, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return data.DishNutrition{}, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	nutrition, err := data.GetDishNutrition(id)
+	if err != nil {
+		return data.DishNutrition{}, err
+	}
+	return nutrition, nil
 }
 
-func (s *FoodService) ConvertDishToFoodItem(id string) error {
+func (s *FoodService) ConvertDishToFoodItem(ctx context.Context, id string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
-	err := data.InsertDishAsFoodItem(id)
+	err := s.repository.InsertDishAsFoodItem(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -773,10 +1196,10 @@ func (s *FoodService) ConvertDishToFoodItem(id string) error {
 
 func (s *FoodService) CreateProfile(name string) (string, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return "", fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return "", fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if name == "" {
-		return "", fmt.Errorf("profile name is required")
+		return "", types.NewValidationError("name", "profile name is required")
 	}
 
 	profile := data.Profile{
@@ -795,14 +1218,14 @@ func (s *FoodService) CreateProfile(name string) (string, error) {
 
 func (s *FoodService) GetProfile(profileID string) (data.Profile, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return data.Profile{}, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return data.Profile{}, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 
 	if profileID == "" {
 		profileID = s.GetActiveProfile()
 
 		if profileID == "" {
-			return data.Profile{}, fmt.Errorf("no profile ID provided and no active profile set")
+			return data.Profile{}, types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
 		}
 
 		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
@@ -810,27 +1233,34 @@ func (s *FoodService) GetProfile(profileID string) (data.Profile, error) {
 
 	profile, err := data.GetProfile(profileID)
 	if err != nil {
-		return data.Profile{}, fmt.Errorf("failed to get profile: %v", err)
+		return data.Profile{}, fmt.Errorf("failed to get profile: %w", err)
 	}
 
 	return profile, nil
 }
 
-func (s *FoodService) GetAllProfiles() ([]data.Profile, error) {
+// GetAllProfiles returns a page of the profiles matching the filters of the query and the
+// total number of matching profiles
+func (s *FoodService) GetAllProfiles(query types.ListQuery) ([]data.Profile, int, error) {
 	if err := s.SyncToDropbox(false); err != nil {
-		return nil, fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return nil, 0, fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
-	profiles, err := data.GetAllProfiles()
+	options, err := listOptions(query, data.ProfileSortKeys)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get profiles: %v", err)
+		return nil, 0, err
 	}
 
-	return profiles, nil
+	profiles, total, err := data.GetAllProfiles(options)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get profiles: %v", err)
+	}
+
+	return profiles, total, nil
 }
 
 func (s *FoodService) UpdateProfile(profileID string, name string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 
 	// If no profile ID is provided, use the active profile
@@ -838,19 +1268,19 @@ func (s *FoodService) UpdateProfile(profileID string, name string) error {
 		profileID = s.GetActiveProfile()
 
 		if profileID == "" {
-			return fmt.Errorf("no profile ID provided and no active profile set")
+			return types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
 		}
 
 		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
 	}
 
 	if name == "" {
-		return fmt.Errorf("profile name is required")
+		return types.NewValidationError("name", "profile name is required")
 	}
 
 	err := data.UpdateProfile(profileID, name)
 	if err != nil {
-		return fmt.Errorf("failed to update profile: %v", err)
+		return fmt.Errorf("failed to update profile: %w", err)
 	}
 	s.ScheduleDelayedUpload()
 	return nil
@@ -858,15 +1288,15 @@ func (s *FoodService) UpdateProfile(profileID string, name string) error {
 
 func (s *FoodService) DeleteProfile(profileID string) error {
 	if err := s.SyncToDropbox(false); err != nil {
-		return fmt.Errorf("failed to sync with Dropbox: %v", err)
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
 	}
 	if profileID == "" {
-		return fmt.Errorf("profile ID is required")
+		return types.NewValidationError("profile_id", "profile ID is required")
 	}
 
 	err := data.DeleteProfile(profileID)
 	if err != nil {
-		return fmt.Errorf("failed to delete profile: %v", err)
+		return fmt.Errorf("failed to delete profile: %w", err)
 	}
 	s.ScheduleDelayedUpload()
 	return nil
@@ -909,11 +1339,11 @@ func (s *FoodService) SetActiveProfile(profileID string) error {
 	// Check if the profile exists
 	profile, err := s.GetProfile(profileID)
 	if err != nil {
-		return fmt.Errorf("failed to get profile: %v", err)
+		return fmt.Errorf("failed to get profile: %w", err)
 	}
 
 	if profile.ID == "" {
-		return fmt.Errorf("profile with ID %s not found", profileID)
+		return types.NewNotFoundError("profile with ID %s not found", profileID)
 	}
 
 	s.activeProfile = profileID
@@ -1007,6 +1437,32 @@ func (s *FoodService) SetAutoRecalculateNutritionValues(enabled bool) error {
 	return nil
 }
 
+// GetAdaptiveTDEE returns whether the daily recalculation uses the estimated TDEE instead of the BMR formula
+func (s *FoodService) GetAdaptiveTDEE() bool {
+	settings, err := s.settingsStore.Load()
+	if err != nil {
+		log.Printf("Failed to load settings: %v, using default value false", err)
+		return false
+	}
+
+	return settings.AdaptiveTDEE
+}
+
+// SetAdaptiveTDEE sets whether the daily recalculation uses the estimated TDEE instead of the BMR formula
+func (s *FoodService) SetAdaptiveTDEE(enabled bool) error {
+	settings, err := s.settingsStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %v", err)
+	}
+
+	settings.AdaptiveTDEE = enabled
+	if err := s.settingsStore.Save(settings); err != nil {
+		return fmt.Errorf("failed to save settings: %v", err)
+	}
+
+	return nil
+}
+
 // CalculateAge calculates the age in years from a birthdate string in YYYY-MM-DD format
 func CalculateAge(birthDate string) int {
 	// Parse the birthdate
@@ -1036,7 +1492,7 @@ func CalculateAge(birthDate string) int {
 	return years
 }
 
-// CalculateNutrition calculates the daily nutrition targets based on user metrics
+// CalculateNutrition calculates the daily nutrition targets based on user metrics with the default goal strategy
 func (s *FoodService) CalculateNutrition(
 	weight float64,
 	height float64,
@@ -1045,54 +1501,15 @@ func (s *FoodService) CalculateNutrition(
 	activityLevel int,
 	weeklyWeightChange float64,
 ) *types.NutritionCalculationResponse {
-	var bmr float64
-	switch gender {
-	case "male":
-		bmr = 10*weight + 6.25*height - 5*float64(age) + 5
-	case "female":
-		bmr = 10*weight + 6.25*height - 5*float64(age) - 161
-	default: // for 'other' use average of male and female formulas
-		bmr = 10*weight + 6.25*height - 5*float64(age) - 78
-	}
-
-	activityFactors := []float64{1.2, 1.375, 1.55, 1.725, 1.9}
-	maintenanceCalories := bmr * activityFactors[activityLevel]
-
-	// Convert weekly weight change to daily calorie adjustment
-	// 1 kg of body fat is approximately 7700 calories
-	// So to lose/gain 1 kg per week, we need a daily deficit/surplus of 1100 calories
-	dailyCalorieAdjustment := weeklyWeightChange * 1100
-	adjustedCalories := maintenanceCalories + dailyCalorieAdjustment
-
-	proteins := weight * 2                                 // 2g protein per kg body weight
-	fat := (adjustedCalories * 0.3) / 9                    // 30% of calories from fat
-	carbs := (adjustedCalories - (proteins*4 + fat*9)) / 4 // Rest from carbs
-
-	fmt.Printf("Calories: %f, Proteins: %f, Carbs: %f, Fat: %f\n", adjustedCalories, proteins, carbs, fat)
-
-	return &types.NutritionCalculationResponse{
-		Calories: int(math.Round(adjustedCalories)),
-		Proteins: int(math.Round(proteins)),
-		Carbs:    int(math.Round(carbs)),
-		Fat:      int(math.Round(fat)),
-	}
+	return s.CalculateNutritionWithStrategy(DefaultGoalStrategy(), weight, height, age, gender, activityLevel, weeklyWeightChange)
 }
 
-// CalculateNutritionFromCaloriesAndWeight calculates macronutrients based on target calories and weight
+// CalculateNutritionFromCaloriesAndWeight calculates macronutrients based on target calories and weight with the default goal strategy
 func (s *FoodService) CalculateNutritionFromCaloriesAndWeight(
 	calories float64,
 	weight float64,
 ) *types.NutritionCalculationResponse {
-	proteins := weight * 2                         // 2g protein per kg body weight
-	fat := (calories * 0.3) / 9                    // 30% of calories from fat
-	carbs := (calories - (proteins*4 + fat*9)) / 4 // Rest from carbs
-
-	return &types.NutritionCalculationResponse{
-		Calories: int(math.Round(calories)),
-		Proteins: int(math.Round(proteins)),
-		Carbs:    int(math.Round(carbs)),
-		Fat:      int(math.Round(fat)),
-	}
+	return s.CalculateNutritionFromCaloriesAndWeightWithStrategy(DefaultGoalStrategy(), calories, weight)
 }
 
 // onDayChangeMonitor runs in a separate goroutine to check for day changes
@@ -1138,7 +1555,7 @@ func (s *FoodService) checkAndRecalculate() {
 	log.Println("Performing daily nutrition values check...")
 
 	// Get all profiles
-	profiles, err := s.GetAllProfiles()
+	profiles, _, err := s.GetAllProfiles(types.ListQuery{})
 	if err != nil {
 		log.Printf("Error getting profiles for nutrition recalculation: %v", err)
 		return
@@ -1166,14 +1583,30 @@ func (s *FoodService) recalculateForProfile(profileID string) {
 		// Calculate age from birthdate
 		age := CalculateAge(settings.BirthDate)
 
-		// Calculate new nutrition values
-		calculationResponse := s.CalculateNutrition(settings.Weight, settings.Height, age, settings.Gender, settings.ActivityLevel, settings.WeeklyWeightChange)
+		strategy, err := s.getGoalStrategy(profileID)
+		if err != nil {
+			log.Printf("Error getting goal strategy for profile %s: %v", profileID, err)
+			return
+		}
+
+		// Calculate new nutrition values, based on the estimated TDEE if enabled and reliable
+		var calculationResponse *types.NutritionCalculationResponse
+		if s.GetAdaptiveTDEE() {
+			calculationResponse = s.calculateNutritionFromEstimatedTDEE(profileID, strategy, settings)
+		}
+		if calculationResponse == nil {
+			calculationResponse = s.CalculateNutritionWithStrategy(strategy, settings.Weight, settings.Height, age, settings.Gender, settings.ActivityLevel, settings.WeeklyWeightChange)
+		}
 		if calculationResponse == nil {
 			log.Printf("Error calculating nutrition values for profile %s", profileID)
 			return
 		}
 
-		// Save settings to trigger recalculation (the existing SaveUserSettings handles the calculation)
+		settings.Calories = float64(calculationResponse.Calories)
+		settings.Proteins = float64(calculationResponse.Proteins)
+		settings.Carbs = float64(calculationResponse.Carbs)
+		settings.Fat = float64(calculationResponse.Fat)
+
 		err = s.SaveUserSettings(settings, profileID)
 		if err != nil {
 			log.Printf("Error saving recalculated nutrition values for profile %s: %v", profileID, err)
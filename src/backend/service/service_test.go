@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+// TestMain runs the tests of the package on a new database in a temporary directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "nutrack-service-test-*")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("DATA_DIR", dir)
+	data.InitDatabase()
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestConsumeBatch(t *testing.T) {
+	s := &FoodService{repository: data.NewRepository()}
+	ctx := context.Background()
+	if err := data.AddProfile(data.Profile{ID: "batch-profile", Name: "Batch"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []data.PersistentFoodItem{
+		{Barcode: "4000000000011", Name: "Apfel", CaloriesPer100g: 52, ServingQuantity: 150, ServingQuantityUnit: "g"},
+		{Barcode: "4000000000028", Name: "Banane", CaloriesPer100g: 89, ServingQuantity: 120, ServingQuantityUnit: "g"},
+	} {
+		if err := s.repository.InsertFoodItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second item fails after the first one was logged in the same transaction
+	batch := func(date string) []types.ConsumedFoodItemRequest {
+		return []types.ConsumedFoodItemRequest{
+			{Barcode: "4000000000011", ConsumedQuantity: 1, Date: date, ProfileID: "batch-profile"},
+			{Barcode: "4000000000028", ConsumedQuantity: -1, Date: date, ProfileID: "batch-profile"},
+			{Barcode: "4000000000028", ConsumedQuantity: 2, Date: date, ProfileID: "batch-profile"},
+		}
+	}
+	entriesOn := func(date string) int {
+		_, total, err := data.GetConsumedFoodItemsByDate(date, "batch-profile", data.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return total
+	}
+
+	t.Run("atomic", func(t *testing.T) {
+		results, err := s.consumeBatch(ctx, batch("2024-05-01"))
+		if !types.HasErrorCode(err, types.ErrorCodeValidation) {
+			t.Fatalf("got results %+v and error %v, want a validation error", results, err)
+		}
+		if entries := entriesOn("2024-05-01"); entries != 0 {
+			t.Errorf("got %d diary entries, want the batch rolled back", entries)
+		}
+	})
+
+	t.Run("partial", func(t *testing.T) {
+		results, err := s.consumeBatchPartially(ctx, batch("2024-05-02"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantStatuses := []types.BatchItemStatus{types.BatchItemLogged, types.BatchItemValidationError, types.BatchItemLogged}
+		if len(results) != len(wantStatuses) {
+			t.Fatalf("got %d results, want %d", len(results), len(wantStatuses))
+		}
+		for i, result := range results {
+			if result.Index != i || result.Status != wantStatuses[i] {
+				t.Errorf("got result %+v, want index %d with status %s", result, i, wantStatuses[i])
+			}
+		}
+		if results[1].Field != "consumed_quantity" || results[1].ConsumedID != "" {
+			t.Errorf("got failed result %+v, want an error of consumed_quantity without diary entry", results[1])
+		}
+		if entries := entriesOn("2024-05-02"); entries != 2 {
+			t.Errorf("got %d diary entries, want the 2 valid items", entries)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"nutrack/backend/data"
+)
+
+// servingSizePattern matches OpenFoodFacts serving sizes like "1 slice (28 g)", "2 biscuits (25g)" or "1 cup (240 ml)"
+var servingSizePattern = regexp.MustCompile(`^\s*(\d+(?:[.,]\d+)?)?\s*([^()\d]+?)\s*\(\s*(\d+(?:[.,]\d+)?)\s*(g|kg|ml|cl|dl|l)\s*\)\s*$`)
+
+// ParseServingSize parses an OpenFoodFacts serving size into a named portion.
+// Returns false if the serving size has no name or no amount in g or ml.
+func ParseServingSize(servingSize string) (data.FoodPortion, bool) {
+	match := servingSizePattern.FindStringSubmatch(strings.ToLower(servingSize))
+	if match == nil {
+		return data.FoodPortion{}, false
+	}
+
+	count := 1.0
+	if match[1] != "" {
+		count = parseDecimal(match[1])
+	}
+	amount := parseDecimal(match[3])
+	name := strings.TrimSpace(match[2])
+	if count <= 0 || amount <= 0 || name == "" || name == "g" || name == "ml" || name == data.ServingUnit {
+		return data.FoodPortion{}, false
+	}
+
+	// Normalize the amount to g or ml
+	unit := match[4]
+	switch unit {
+	case "kg":
+		amount, unit = amount*1000, "g"
+	case "cl":
+		amount, unit = amount*10, "ml"
+	case "dl":
+		amount, unit = amount*100, "ml"
+	case "l":
+		amount, unit = amount*1000, "ml"
+	}
+
+	return data.FoodPortion{
+		Name:     name,
+		Quantity: amount / count,
+		Unit:     unit,
+	}, true
+}
+
+// parseDecimal parses numbers with a decimal point or comma
+func parseDecimal(value string) float64 {
+	f, _ := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	return f
+}
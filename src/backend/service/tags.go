@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+const (
+	MaxTags      = 20
+	MaxTagLength = 50
+	// Number of the most specific OpenFoodFacts categories imported as tags
+	MaxImportedCategoryTags = 5
+)
+
+// GetTags returns all tags in use with the number of food items and dishes using them
+func (s *FoodService) GetTags() ([]data.TagUsage, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	return data.GetTags()
+}
+
+// SetFoodItemTags replaces the tags of a food item
+func (s *FoodService) SetFoodItemTags(ctx context.Context, barcode string, tags []string) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	if err := ValidateBarcode(barcode); err != nil {
+		return err
+	}
+	if err := ValidateTags(tags); err != nil {
+		return err
+	}
+
+	if err := s.repository.SetFoodItemTags(ctx, barcode, tags); err != nil {
+		return err
+	}
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// SetDishTags replaces the tags of a dish
+func (s *FoodService) SetDishTags(ctx context.Context, dishID string, tags []string) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+	if err := ValidateTags(tags); err != nil {
+		return err
+	}
+
+	if err := s.repository.SetDishTags(ctx, dishID, tags); err != nil {
+		return err
+	}
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// GetIntakeByTag breaks down the intake of a profile between two dates (inclusive) by the tags
+// of the logged food items and dishes, e.g. to see how much of the intake comes from snacks
+func (s *FoodService) GetIntakeByTag(profileID string, startDate string, endDate string) (types.TagIntakeSummary, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return types.TagIntakeSummary{}, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return types.TagIntakeSummary{}, err
+	}
+	if _, _, err := parseDateRange(startDate, endDate); err != nil {
+		return types.TagIntakeSummary{}, err
+	}
+
+	intake, err := data.GetIntakeByTag(profileID, startDate, endDate)
+	if err != nil {
+		return types.TagIntakeSummary{}, err
+	}
+	daily, err := data.GetDailyIntake(profileID, startDate, endDate)
+	if err != nil {
+		return types.TagIntakeSummary{}, err
+	}
+
+	summary := types.TagIntakeSummary{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Tags:      []types.TagIntake{},
+	}
+	for _, day := range daily {
+		summary.Entries += day.Entries
+		summary.Total.Calories += day.Calories
+		summary.Total.Proteins += day.Proteins
+		summary.Total.Carbs += day.Carbs
+		summary.Total.Fat += day.Fat
+	}
+
+	for _, tag := range intake {
+		tagIntake := types.TagIntake{
+			Tag:      tag.Tag,
+			Entries:  tag.Entries,
+			Calories: math.Round(tag.Calories),
+			Proteins: math.Round(tag.Proteins*10) / 10,
+			Carbs:    math.Round(tag.Carbs*10) / 10,
+			Fat:      math.Round(tag.Fat*10) / 10,
+		}
+		if summary.Total.Calories > 0 {
+			tagIntake.Share = math.Round(tag.Calories/summary.Total.Calories*1000) / 10
+		}
+
+		if tag.Tag == "" {
+			summary.Untagged = tagIntake
+		} else {
+			summary.Tags = append(summary.Tags, tagIntake)
+		}
+	}
+	summary.Total = roundNutritionTotals(summary.Total)
+
+	return summary, nil
+}
+
+// categoryTags converts the English OpenFoodFacts categories of a product into tags, e.g.
+// "en:breakfast-cereals" into "breakfast cereals". The categories are ordered from general to
+// specific, only the most specific ones are used.
+func categoryTags(categories []string) []string {
+	var tags []string
+	for i := len(categories) - 1; i >= 0 && len(tags) < MaxImportedCategoryTags; i-- {
+		category, ok := strings.CutPrefix(categories[i], "en:")
+		if !ok {
+			continue
+		}
+		tag := data.NormalizeTag(strings.ReplaceAll(category, "-", " "))
+		if tag != "" && len(tag) <= MaxTagLength {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
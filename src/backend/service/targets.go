@@ -0,0 +1,391 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
+)
+
+const (
+	TargetSourceSettings  = "settings"
+	TargetSourceHistory   = "history"
+	TargetSourceWeekday   = "weekday"
+	TargetSourceDateRange = "date_range"
+
+	// MaxSummaryDays limits the date range of a daily summary
+	MaxSummaryDays = 366
+)
+
+// targetSchedule contains everything needed to resolve the targets of a profile on any date
+type targetSchedule struct {
+	settings  data.UserSettings
+	history   []data.TargetHistoryEntry
+	overrides []data.TargetOverride
+}
+
+func (s *FoodService) loadTargetSchedule(profileID string) (*targetSchedule, error) {
+	settings, err := data.GetUserSettings(profileID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := data.GetTargetHistory(profileID)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := data.GetTargetOverrides(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &targetSchedule{settings: settings, history: history, overrides: overrides}, nil
+}
+
+// targetsOn resolves the targets in effect on a date. Overrides with a date range take
+// precedence over overrides for weekdays only, the newest override wins within each group.
+// Without an override the targets of the history are used. Dates before the first history
+// entry use the first entry, dates since the latest change the current settings.
+func (t *targetSchedule) targetsOn(date time.Time) types.DailyTargets {
+	day := date.Format("2006-01-02")
+	targets := types.DailyTargets{
+		Date:     day,
+		Calories: t.settings.Calories,
+		Proteins: t.settings.Proteins,
+		Carbs:    t.settings.Carbs,
+		Fat:      t.settings.Fat,
+		Source:   TargetSourceSettings,
+	}
+
+	// The latest history entry corresponds to the current settings
+	if len(t.history) > 1 && day < t.history[len(t.history)-1].EffectiveDate {
+		entry := t.history[0]
+		for _, candidate := range t.history {
+			if candidate.EffectiveDate > day {
+				break
+			}
+			entry = candidate
+		}
+		targets.Calories = entry.Calories
+		targets.Proteins = entry.Proteins
+		targets.Carbs = entry.Carbs
+		targets.Fat = entry.Fat
+		targets.Source = TargetSourceHistory
+	}
+
+	var weekdayOverride, rangeOverride *data.TargetOverride
+	for i := range t.overrides {
+		override := &t.overrides[i]
+		if !overrideAppliesOn(*override, day, date.Weekday()) {
+			continue
+		}
+		if override.StartDate != "" || override.EndDate != "" {
+			if rangeOverride == nil {
+				rangeOverride = override
+			}
+		} else if weekdayOverride == nil {
+			weekdayOverride = override
+		}
+	}
+
+	override, source := rangeOverride, TargetSourceDateRange
+	if override == nil {
+		override, source = weekdayOverride, TargetSourceWeekday
+	}
+	if override == nil {
+		return targets
+	}
+
+	if override.Calories != nil {
+		targets.Calories = *override.Calories
+	}
+	if override.Proteins != nil {
+		targets.Proteins = *override.Proteins
+	}
+	if override.Carbs != nil {
+		targets.Carbs = *override.Carbs
+	}
+	if override.Fat != nil {
+		targets.Fat = *override.Fat
+	}
+	targets.Source = source
+	targets.OverrideID = override.ID
+	targets.OverrideName = override.Name
+
+	return targets
+}
+
+func overrideAppliesOn(override data.TargetOverride, day string, weekday time.Weekday) bool {
+	if override.StartDate != "" && day < override.StartDate {
+		return false
+	}
+	if override.EndDate != "" && day > override.EndDate {
+		return false
+	}
+	return len(override.Weekdays) == 0 || containsWeekday(override.Weekdays, weekday)
+}
+
+// resolveProfileID returns the given profile ID or the active profile
+func (s *FoodService) resolveProfileID(profileID string) (string, error) {
+	if profileID != "" {
+		return profileID, nil
+	}
+
+	profileID = s.GetActiveProfile()
+	if profileID == "" {
+		return "", types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
+	}
+
+	fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
+	return profileID, nil
+}
+
+// GetTargetsForDate returns the targets of a profile in effect on a date
+func (s *FoodService) GetTargetsForDate(profileID string, date string) (*types.DailyTargets, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateDate(date); err != nil {
+		return nil, err
+	}
+	day, _ := time.Parse("2006-01-02", date)
+
+	schedule, err := s.loadTargetSchedule(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := schedule.targetsOn(day)
+	return &targets, nil
+}
+
+// GetTargetHistory returns the target changes of a profile
+func (s *FoodService) GetTargetHistory(profileID string) ([]data.TargetHistoryEntry, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetTargetHistory(profileID)
+}
+
+// GetTargetOverrides returns the target overrides of a profile
+func (s *FoodService) GetTargetOverrides(profileID string) ([]data.TargetOverride, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetTargetOverrides(profileID)
+}
+
+// CreateTargetOverride creates a target override and returns its ID
+func (s *FoodService) CreateTargetOverride(request types.TargetOverrideRequest) (string, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return "", fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(request.ProfileID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := ValidateTargetOverride(request); err != nil {
+		return "", err
+	}
+
+	override := toDataTargetOverride(request)
+	override.ProfileID = profileID
+	id, err := data.CreateTargetOverride(override)
+	if err != nil {
+		return "", err
+	}
+
+	s.ScheduleDelayedUpload()
+	return id, nil
+}
+
+// UpdateTargetOverride replaces the rules and targets of a target override
+func (s *FoodService) UpdateTargetOverride(id string, request types.TargetOverrideRequest) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	if err := ValidateUUID(id); err != nil {
+		return err
+	}
+	if err := ValidateTargetOverride(request); err != nil {
+		return err
+	}
+
+	override := toDataTargetOverride(request)
+	override.ID = id
+	if err := data.UpdateTargetOverride(override); err != nil {
+		return err
+	}
+
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+// DeleteTargetOverride deletes a target override
+func (s *FoodService) DeleteTargetOverride(id string) error {
+	if err := s.SyncToDropbox(false); err != nil {
+		return fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	if err := ValidateUUID(id); err != nil {
+		return err
+	}
+
+	if err := data.DeleteTargetOverride(id); err != nil {
+		return err
+	}
+
+	s.ScheduleDelayedUpload()
+	return nil
+}
+
+func toDataTargetOverride(request types.TargetOverrideRequest) data.TargetOverride {
+	return data.TargetOverride{
+		Name:      request.Name,
+		Weekdays:  request.Weekdays,
+		StartDate: request.StartDate,
+		EndDate:   request.EndDate,
+		Calories:  request.Calories,
+		Proteins:  request.Proteins,
+		Carbs:     request.Carbs,
+		Fat:       request.Fat,
+	}
+}
+
+// GetDailySummary returns the intake of every day between two dates (inclusive), each
+// compared with the targets in effect on that day
+func (s *FoodService) GetDailySummary(profileID string, startDate string, endDate string) ([]types.DailySummary, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	profileID, err := s.resolveProfileID(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end, err := parseDateRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.loadTargetSchedule(profileID)
+	if err != nil {
+		return nil, err
+	}
+	intake, err := data.GetDailyIntake(profileID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	intakeByDate := make(map[string]data.DailyIntake, len(intake))
+	for _, day := range intake {
+		intakeByDate[day.Date] = day
+	}
+
+	var summary []types.DailySummary
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		targets := schedule.targetsOn(day)
+		dayIntake := intakeByDate[targets.Date]
+		summary = append(summary, types.DailySummary{
+			Date:      targets.Date,
+			Entries:   dayIntake.Entries,
+			Calories:  math.Round(dayIntake.Calories),
+			Proteins:  math.Round(dayIntake.Proteins*10) / 10,
+			Carbs:     math.Round(dayIntake.Carbs*10) / 10,
+			Fat:       math.Round(dayIntake.Fat*10) / 10,
+			Targets:   targets,
+			Remaining: math.Round(targets.Calories - dayIntake.Calories),
+		})
+	}
+
+	return summary, nil
+}
+
+// dailyTargetMonitor publishes a daily target reached event when the calories logged by a
+// profile today reach its calorie target. The event is published again if the intake drops
+// below the target and reaches it again, e.g. after an entry was deleted. After a restart it
+// is published once more for profiles which already reached the target.
+func (s *FoodService) dailyTargetMonitor() {
+	subscription := messaging.Subscribe(0, messaging.EventFilter{Entities: []string{messaging.EntityConsumedFoodItem}})
+	defer subscription.Close()
+
+	reached := make(map[string]bool) // Profiles which reached the target of reachedDate
+	var reachedDate string
+	for range subscription.Notify() {
+		today := time.Now().Format("2006-01-02")
+		if today != reachedDate {
+			reached = make(map[string]bool)
+			reachedDate = today
+		}
+
+		for _, event := range subscription.Next() {
+			// Events without a profile are database events or concern older entries
+			if event.ProfileID == "" {
+				continue
+			}
+			if err := s.checkDailyTarget(event.ProfileID, today, reached); err != nil {
+				log.Printf("Failed to check daily target of profile %s: %v", event.ProfileID, err)
+			}
+		}
+	}
+}
+
+func (s *FoodService) checkDailyTarget(profileID string, date string, reached map[string]bool) error {
+	day, _ := time.Parse("2006-01-02", date)
+	schedule, err := s.loadTargetSchedule(profileID)
+	if err != nil {
+		return err
+	}
+	targets := schedule.targetsOn(day)
+	if targets.Calories <= 0 {
+		return nil
+	}
+
+	intake, err := data.GetDailyIntake(profileID, date, date)
+	if err != nil {
+		return err
+	}
+	var calories float64
+	for _, dayIntake := range intake {
+		calories += dayIntake.Calories
+	}
+
+	if calories < targets.Calories {
+		delete(reached, profileID)
+		return nil
+	}
+	if reached[profileID] {
+		return nil
+	}
+
+	reached[profileID] = true
+	messaging.PublishDetails(messaging.EntityDailyTarget, messaging.ActionReached, profileID, map[string]interface{}{
+		"calories": math.Round(calories),
+		"target":   targets.Calories,
+	}, date)
+	return nil
+}
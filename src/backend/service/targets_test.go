@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+func TestTargetsOn(t *testing.T) {
+	target := func(value float64) *float64 { return &value }
+	schedule := &targetSchedule{
+		settings: data.UserSettings{Calories: 2000, Proteins: 120, Carbs: 250, Fat: 70},
+		history: []data.TargetHistoryEntry{
+			{EffectiveDate: "2024-01-01", Calories: 1800, Proteins: 110, Carbs: 220, Fat: 60},
+			{EffectiveDate: "2024-03-01", Calories: 2000, Proteins: 120, Carbs: 250, Fat: 70},
+		},
+		// Newest first, like data.GetTargetOverrides
+		overrides: []data.TargetOverride{
+			{ID: "holiday-late", Name: "Urlaub Ende", StartDate: "2024-05-10", EndDate: "2024-05-12", Calories: target(3000)},
+			{ID: "holiday", Name: "Urlaub", StartDate: "2024-05-01", EndDate: "2024-05-31", Calories: target(2500), Proteins: target(100)},
+			{ID: "weekend", Name: "Wochenende", Weekdays: []int{0, 6}, Calories: target(2200)},
+			{ID: "sunday", Name: "Sonntag", Weekdays: []int{0}, Calories: target(1900)},
+		},
+	}
+
+	tests := []struct {
+		date         string
+		want         types.DailyTargets
+		wantOverride string
+	}{
+		{date: "2023-12-01", want: types.DailyTargets{Calories: 1800, Proteins: 110, Carbs: 220, Fat: 60, Source: TargetSourceHistory}},
+		{date: "2024-02-14", want: types.DailyTargets{Calories: 1800, Proteins: 110, Carbs: 220, Fat: 60, Source: TargetSourceHistory}},
+		{date: "2024-04-03", want: types.DailyTargets{Calories: 2000, Proteins: 120, Carbs: 250, Fat: 70, Source: TargetSourceSettings}},
+		// Weekday overrides replace only their targets, the others come from the history
+		{date: "2024-02-17", want: types.DailyTargets{Calories: 2200, Proteins: 110, Carbs: 220, Fat: 60, Source: TargetSourceWeekday}, wantOverride: "weekend"},
+		{date: "2024-04-06", want: types.DailyTargets{Calories: 2200, Proteins: 120, Carbs: 250, Fat: 70, Source: TargetSourceWeekday}, wantOverride: "weekend"},
+		// The newest weekday override wins
+		{date: "2024-04-07", want: types.DailyTargets{Calories: 2200, Proteins: 120, Carbs: 250, Fat: 70, Source: TargetSourceWeekday}, wantOverride: "weekend"},
+		// Date ranges take precedence over weekdays, the newest range wins
+		{date: "2024-05-01", want: types.DailyTargets{Calories: 2500, Proteins: 100, Carbs: 250, Fat: 70, Source: TargetSourceDateRange}, wantOverride: "holiday"},
+		{date: "2024-05-04", want: types.DailyTargets{Calories: 2500, Proteins: 100, Carbs: 250, Fat: 70, Source: TargetSourceDateRange}, wantOverride: "holiday"},
+		{date: "2024-05-11", want: types.DailyTargets{Calories: 3000, Proteins: 120, Carbs: 250, Fat: 70, Source: TargetSourceDateRange}, wantOverride: "holiday-late"},
+		{date: "2024-05-31", want: types.DailyTargets{Calories: 2500, Proteins: 100, Carbs: 250, Fat: 70, Source: TargetSourceDateRange}, wantOverride: "holiday"},
+		{date: "2024-06-01", want: types.DailyTargets{Calories: 2200, Proteins: 120, Carbs: 250, Fat: 70, Source: TargetSourceWeekday}, wantOverride: "weekend"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.date, func(t *testing.T) {
+			date, _ := time.Parse("2006-01-02", test.date)
+			got := schedule.targetsOn(date)
+
+			want := test.want
+			want.Date = test.date
+			want.OverrideID = test.wantOverride
+			for _, override := range schedule.overrides {
+				if override.ID == test.wantOverride {
+					want.OverrideName = override.Name
+				}
+			}
+			if got != want {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
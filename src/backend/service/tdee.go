@@ -0,0 +1,199 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/types"
+)
+
+const (
+	TDEEConfidenceNone   = "none"
+	TDEEConfidenceLow    = "low"
+	TDEEConfidenceMedium = "medium"
+	TDEEConfidenceHigh   = "high"
+
+	// DefaultTDEEWindowDays is the rolling window of the estimate
+	DefaultTDEEWindowDays = 28
+	MinTDEEWindowDays     = 14
+	MaxTDEEWindowDays     = 180
+
+	// 1 kg of body weight is approximately 7700 kcal
+	kcalPerKgBodyWeight = 7700
+
+	// An estimate needs at least a week of logged food and two weigh-ins a week apart
+	minTDEELoggedDays   = 7
+	minTDEEWeightSpread = 7
+)
+
+// EstimateTDEE estimates the total daily energy expenditure of a profile over the last days.
+// The estimate is the average logged intake minus the energy equivalent of the weight trend:
+// if the weight stays the same, the intake equals the expenditure. The current day is excluded
+// because it is usually not completely logged yet.
+func (s *FoodService) EstimateTDEE(profileID string, days int) (*types.TDEEEstimate, error) {
+	if err := s.SyncToDropbox(false); err != nil {
+		return nil, fmt.Errorf("failed to sync with Dropbox: %w", err)
+	}
+
+	// If no profile ID is provided, use the active profile
+	if profileID == "" {
+		profileID = s.GetActiveProfile()
+
+		if profileID == "" {
+			return nil, types.NewValidationError("profile_id", "no profile ID provided and no active profile set")
+		}
+
+		fmt.Printf("No profile ID provided, using active profile: %s\n", profileID)
+	}
+
+	if days == 0 {
+		days = DefaultTDEEWindowDays
+	}
+	if err := ValidateTDEEWindowDays(days); err != nil {
+		return nil, err
+	}
+
+	return s.estimateTDEE(profileID, days)
+}
+
+func (s *FoodService) estimateTDEE(profileID string, days int) (*types.TDEEEstimate, error) {
+	end := time.Now().AddDate(0, 0, -1)
+	start := end.AddDate(0, 0, -(days - 1))
+
+	estimate := &types.TDEEEstimate{
+		ProfileID:  profileID,
+		StartDate:  start.Format("2006-01-02"),
+		EndDate:    end.Format("2006-01-02"),
+		WindowDays: days,
+		Confidence: TDEEConfidenceNone,
+	}
+
+	intake, err := data.GetDailyIntake(profileID, estimate.StartDate, estimate.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	weights, err := data.GetWeightEntries(profileID, estimate.StartDate, estimate.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Days with a logged total of 0 kcal are treated as not logged
+	var totalIntake float64
+	for _, day := range intake {
+		if day.Calories > 0 {
+			totalIntake += day.Calories
+			estimate.LoggedDays++
+		}
+	}
+	if estimate.LoggedDays > 0 {
+		estimate.AverageIntake = math.Round(totalIntake / float64(estimate.LoggedDays))
+	}
+	estimate.WeightEntries = len(weights)
+
+	settings, err := data.GetUserSettings(profileID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Weight > 0 && settings.Height > 0 && settings.BirthDate != "" && settings.Gender != "" {
+		strategy, err := s.getGoalStrategy(profileID)
+		if err != nil {
+			return nil, err
+		}
+		activityFactors := []float64{1.2, 1.375, 1.55, 1.725, 1.9}
+		bmr := CalculateBMR(strategy, settings.Weight, settings.Height, CalculateAge(settings.BirthDate), settings.Gender)
+		estimate.FormulaTDEE = math.Round(bmr * activityFactors[settings.ActivityLevel])
+	}
+
+	slope, spread := weightTrend(weights, start)
+	switch {
+	case estimate.LoggedDays < minTDEELoggedDays:
+		estimate.Message = fmt.Sprintf("at least %d days with logged food are required, found %d", minTDEELoggedDays, estimate.LoggedDays)
+		return estimate, nil
+	case len(weights) < 2 || spread < minTDEEWeightSpread:
+		estimate.Message = fmt.Sprintf("at least two weight entries %d days apart are required", minTDEEWeightSpread)
+		return estimate, nil
+	}
+
+	estimate.SufficientData = true
+	estimate.WeightTrend = math.Round(slope*7*100) / 100
+	estimate.TDEE = math.Round(estimate.AverageIntake - slope*kcalPerKgBodyWeight)
+
+	// The estimate gets more reliable the more days are logged and the more
+	// weigh-ins spread over the window. A weigh-in every 4 days counts as complete.
+	intakeCoverage := float64(estimate.LoggedDays) / float64(days)
+	weightCoverage := math.Min(1, float64(len(weights))/(float64(days)/4))
+	spreadCoverage := math.Min(1, float64(spread)/float64(days-1))
+	score := intakeCoverage * math.Min(weightCoverage, spreadCoverage)
+	estimate.ConfidenceScore = math.Round(score*100) / 100
+
+	switch {
+	case score >= 0.75:
+		estimate.Confidence = TDEEConfidenceHigh
+	case score >= 0.4:
+		estimate.Confidence = TDEEConfidenceMedium
+	default:
+		estimate.Confidence = TDEEConfidenceLow
+	}
+
+	return estimate, nil
+}
+
+// weightTrend returns the slope of the linear regression of the weight entries in kg per day
+// and the number of days between the first and the last entry
+func weightTrend(weights []data.WeightEntry, start time.Time) (float64, int) {
+	if len(weights) < 2 {
+		return 0, 0
+	}
+
+	startDay, _ := time.Parse("2006-01-02", start.Format("2006-01-02"))
+	var sumX, sumY, sumXY, sumXX float64
+	var firstDay, lastDay float64
+	for i, entry := range weights {
+		day, err := time.Parse("2006-01-02", entry.Date)
+		if err != nil {
+			continue
+		}
+		x := day.Sub(startDay).Hours() / 24
+		if i == 0 {
+			firstDay = x
+		}
+		lastDay = x
+
+		sumX += x
+		sumY += entry.Weight
+		sumXY += x * entry.Weight
+		sumXX += x * x
+	}
+
+	n := float64(len(weights))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, 0
+	}
+
+	return (n*sumXY - sumX*sumY) / denominator, int(math.Round(lastDay - firstDay))
+}
+
+// calculateNutritionFromEstimatedTDEE calculates the targets from the estimated TDEE.
+// Returns nil if there is no estimate with at least medium confidence.
+func (s *FoodService) calculateNutritionFromEstimatedTDEE(profileID string, strategy data.GoalStrategy, settings data.UserSettings) *types.NutritionCalculationResponse {
+	estimate, err := s.estimateTDEE(profileID, DefaultTDEEWindowDays)
+	if err != nil {
+		log.Printf("Error estimating TDEE for profile %s: %v", profileID, err)
+		return nil
+	}
+
+	if !estimate.SufficientData || estimate.Confidence == TDEEConfidenceLow {
+		log.Printf("Estimated TDEE for profile %s is not reliable (%s), using BMR formula", profileID, estimate.Confidence)
+		return nil
+	}
+
+	log.Printf("Using estimated TDEE of %.0f kcal (%s confidence) for profile %s", estimate.TDEE, estimate.Confidence, profileID)
+
+	// Same daily adjustment as for the formula based maintenance calories
+	calories := estimate.TDEE + settings.WeeklyWeightChange*1100
+	return s.CalculateNutritionFromCaloriesAndWeightWithStrategy(strategy, calories, settings.Weight)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"nutrack/backend/data"
+)
+
+func TestWeightTrend(t *testing.T) {
+	start := time.Date(2024, 5, 1, 18, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		weights    []data.WeightEntry
+		wantSlope  float64
+		wantSpread int
+	}{
+		{
+			name:    "no entries",
+			weights: nil,
+		},
+		{
+			name:    "one entry",
+			weights: []data.WeightEntry{{Date: "2024-05-01", Weight: 80}},
+		},
+		{
+			name:    "entries of the same day",
+			weights: []data.WeightEntry{{Date: "2024-05-03", Weight: 80}, {Date: "2024-05-03", Weight: 81}},
+		},
+		{
+			name:       "stable weight",
+			weights:    []data.WeightEntry{{Date: "2024-05-01", Weight: 80}, {Date: "2024-05-08", Weight: 80}, {Date: "2024-05-15", Weight: 80}},
+			wantSpread: 14,
+		},
+		{
+			name:       "losing half a kg a week",
+			weights:    []data.WeightEntry{{Date: "2024-05-01", Weight: 80}, {Date: "2024-05-08", Weight: 79.5}, {Date: "2024-05-15", Weight: 79}},
+			wantSlope:  -0.5 / 7,
+			wantSpread: 14,
+		},
+		{
+			// The deviations from the trend of 0.1 kg a day cancel each other out
+			name:       "gaining with noise",
+			weights:    []data.WeightEntry{{Date: "2024-05-02", Weight: 70.3}, {Date: "2024-05-05", Weight: 70.2}, {Date: "2024-05-08", Weight: 70.5}, {Date: "2024-05-11", Weight: 71.2}},
+			wantSlope:  0.1,
+			wantSpread: 9,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			slope, spread := weightTrend(test.weights, start)
+			if math.Abs(slope-test.wantSlope) > 1e-9 {
+				t.Errorf("got slope %v, want %v", slope, test.wantSlope)
+			}
+			if spread != test.wantSpread {
+				t.Errorf("got spread %d, want %d", spread, test.wantSpread)
+			}
+		})
+	}
+}
@@ -1,24 +1,38 @@
 package service
 
 import (
-	"fmt"
+	"errors"
+	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"nutrack/backend/data"
+	"nutrack/backend/messaging"
+	"nutrack/backend/types"
 
 	"github.com/google/uuid"
 )
 
+// withField sets the field of a validation error of a validator which is used for several fields
+func withField(err error, field string) error {
+	var validationErr *types.Error
+	if errors.As(err, &validationErr) && validationErr.Code == types.ErrorCodeValidation {
+		validationErr.Field = field
+	}
+	return err
+}
+
 func ValidateBarcode(barcode string) error {
 	if barcode == "" {
-		return fmt.Errorf("barcode is required")
+		return types.NewValidationError("barcode", "barcode is required")
 	}
 	return nil
 }
 
 func ValidateServingQuantity(servingQuantity float64) error {
 	if servingQuantity < 0 {
-		return fmt.Errorf("serving quantity cannot be negative")
+		return types.NewValidationError("serving_quantity", "serving quantity cannot be negative")
 	}
 	return nil
 }
@@ -26,14 +40,60 @@ func ValidateServingQuantity(servingQuantity float64) error {
 func ValidateServingQuantityUnit(servingQuantityUnit string) error {
 	validUnits := map[string]bool{"g": true, "ml": true}
 	if !validUnits[servingQuantityUnit] {
-		return fmt.Errorf("serving quantity unit must be either 'g' or 'ml'")
+		return types.NewValidationError("serving_quantity_unit", "serving quantity unit must be either 'g' or 'ml'")
+	}
+	return nil
+}
+
+func ValidateDensity(density *float64) error {
+	if density != nil && *density <= 0 {
+		return types.NewValidationError("density", "density must be positive")
+	}
+	return nil
+}
+
+// ValidateFoodPortions checks the named portions of a food item
+func ValidateFoodPortions(portions []data.FoodPortion) error {
+	names := make(map[string]bool)
+	for _, portion := range portions {
+		name := strings.ToLower(strings.TrimSpace(portion.Name))
+		if name == "" {
+			return types.NewValidationError("portions", "portion name is required")
+		}
+		if name == "g" || name == "ml" || name == data.ServingUnit {
+			return types.NewValidationError("portions", "portion name %s is reserved", portion.Name)
+		}
+		if names[name] {
+			return types.NewValidationError("portions", "portion name %s is used more than once", portion.Name)
+		}
+		names[name] = true
+		if portion.Quantity <= 0 {
+			return types.NewValidationError("portions", "portion quantity must be positive")
+		}
+		if err := ValidateServingQuantityUnit(portion.Unit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateTags validates the tags of a food item or dish after normalization
+func ValidateTags(tags []string) error {
+	normalized := data.NormalizeTags(tags)
+	if len(normalized) > MaxTags {
+		return types.NewValidationError("tags", "at most %d tags are allowed", MaxTags)
+	}
+	for _, tag := range normalized {
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return types.NewValidationError("tags", "tag %s is longer than %d characters", tag, MaxTagLength)
+		}
 	}
 	return nil
 }
 
 func ValidateConsumedQuantity(consumedQuantity float64) error {
 	if consumedQuantity <= 0 {
-		return fmt.Errorf("consumed quantity must be positive")
+		return types.NewValidationError("consumed_quantity", "consumed quantity must be positive")
 	}
 	return nil
 }
@@ -41,14 +101,14 @@ func ValidateConsumedQuantity(consumedQuantity float64) error {
 func ValidateDate(date string) error {
 	_, err := time.Parse("2006-01-02", date)
 	if err != nil {
-		return fmt.Errorf("invalid date format: use YYYY-MM-DD")
+		return types.NewValidationError("date", "invalid date format: use YYYY-MM-DD")
 	}
 	return nil
 }
 
 func ValidateActivityLevel(level int) error {
 	if level < 0 || level > 4 {
-		return fmt.Errorf("activity level must be between 0 and 4")
+		return types.NewValidationError("activity_level", "activity level must be between 0 and 4")
 	}
 	return nil
 }
@@ -56,14 +116,30 @@ func ValidateActivityLevel(level int) error {
 func ValidateGender(gender string) error {
 	validGenders := map[string]bool{"male": true, "female": true, "undefined": true}
 	if !validGenders[gender] {
-		return fmt.Errorf("gender must be either 'male', 'female', or 'undefined'")
+		return types.NewValidationError("gender", "gender must be either 'male', 'female', or 'undefined'")
 	}
 	return nil
 }
 
 func ValidateWeeklyWeightChange(weightChange float64) error {
 	if weightChange < -1 || weightChange > 1 {
-		return fmt.Errorf("weekly weight change must be between -1 and 1 kg")
+		return types.NewValidationError("weekly_weight_change", "weekly weight change must be between -1 and 1 kg")
+	}
+	return nil
+}
+
+// ValidateCookedWeight checks the optional cooked weight of a dish
+func ValidateCookedWeight(cookedWeight *float64) error {
+	if cookedWeight != nil && *cookedWeight <= 0 {
+		return types.NewValidationError("cooked_weight", "cooked weight must be positive")
+	}
+	return nil
+}
+
+// ValidatePortions checks the optional portion count of a dish
+func ValidatePortions(portions *int) error {
+	if portions != nil && *portions <= 0 {
+		return types.NewValidationError("portions", "portions must be positive")
 	}
 	return nil
 }
@@ -76,7 +152,7 @@ func ValidateName(name string) error {
 // ValidateNutrientValue checks if the nutrient value is non-negative
 func ValidateNutrientValue(value float64, fieldName string) error {
 	if value < 0 {
-		return fmt.Errorf("%s cannot be negative", fieldName)
+		return types.NewValidationError(fieldName, "%s cannot be negative", fieldName)
 	}
 	return nil
 }
@@ -85,7 +161,7 @@ func ValidateNutrientValue(value float64, fieldName string) error {
 func ValidateTime(timeStr string) error {
 	_, err := time.Parse("15:04:05", timeStr)
 	if err != nil {
-		return fmt.Errorf("invalid time format: use HH:MM:SS")
+		return types.NewValidationError("time", "invalid time format: use HH:MM:SS")
 	}
 	return nil
 }
@@ -93,7 +169,7 @@ func ValidateTime(timeStr string) error {
 // ValidateDateTime checks if the time is valid
 func ValidateDateTime(time time.Time) error {
 	if time.IsZero() {
-		return fmt.Errorf("invalid date time")
+		return types.NewValidationError("insert_date", "invalid date time")
 	}
 	return nil
 }
@@ -101,7 +177,7 @@ func ValidateDateTime(time time.Time) error {
 func ValidateUUID(id string) error {
 	_, err := uuid.Parse(id)
 	if err != nil {
-		return fmt.Errorf("invalid UUID format")
+		return types.NewValidationError("id", "invalid UUID format")
 	}
 	return nil
 }
@@ -109,28 +185,28 @@ func ValidateUUID(id string) error {
 // ValidateSearchTerm checks if the search term is long enough
 func ValidateSearchTerm(term string) error {
 	if len(term) < 3 {
-		return fmt.Errorf("search term must be at least 3 characters long")
+		return types.NewValidationError("q", "search term must be at least 3 characters long")
 	}
 	return nil
 }
 
 func ValidateWeight(weight float64) error {
 	if weight <= 0 {
-		return fmt.Errorf("number must be positive")
+		return types.NewValidationError("weight", "number must be positive")
 	}
 	return nil
 }
 
 func ValidateHeight(height float64) error {
 	if height <= 0 {
-		return fmt.Errorf("number must be positive")
+		return types.NewValidationError("height", "number must be positive")
 	}
 	return nil
 }
 
 func ValidateAge(age int) error {
 	if age <= 0 {
-		return fmt.Errorf("number must be positive")
+		return types.NewValidationError("age", "number must be positive")
 	}
 	return nil
 }
@@ -142,21 +218,30 @@ func ValidatePersistentFoodItem(item data.PersistentFoodItem) error {
 	if err := ValidateName(item.Name); err != nil {
 		return err
 	}
-	if err := ValidateNutrientValue(item.CaloriesPer100g, "calories"); err != nil {
+	if err := ValidateNutrientValue(item.CaloriesPer100g, "energy-kcal_100g"); err != nil {
 		return err
 	}
-	if err := ValidateNutrientValue(item.ProteinPer100g, "protein"); err != nil {
+	if err := ValidateNutrientValue(item.ProteinPer100g, "proteins_100g"); err != nil {
 		return err
 	}
-	if err := ValidateNutrientValue(item.CarbsPer100g, "carbs"); err != nil {
+	if err := ValidateNutrientValue(item.CarbsPer100g, "carbohydrates_100g"); err != nil {
 		return err
 	}
-	if err := ValidateNutrientValue(item.FatPer100g, "fat"); err != nil {
+	if err := ValidateNutrientValue(item.FatPer100g, "fat_100g"); err != nil {
 		return err
 	}
 	if err := ValidateServingQuantity(item.ServingQuantity); err != nil {
 		return err
 	}
+	if err := ValidateDensity(item.Density); err != nil {
+		return err
+	}
+	if err := ValidateFoodPortions(item.Portions); err != nil {
+		return err
+	}
+	if err := ValidateTags(item.Tags); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -209,8 +294,80 @@ func ValidateUserSettings(settings data.UserSettings) error {
 		return err
 	}
 	if err := ValidateDate(settings.BirthDate); err != nil {
+		return withField(err, "birth_date")
+	}
+	return nil
+}
+
+func ValidateBodyFatPercentage(bodyFat *float64) error {
+	if bodyFat != nil && (*bodyFat < 3 || *bodyFat > 70) {
+		return types.NewValidationError("body_fat_percentage", "body fat percentage must be between 3 and 70")
+	}
+	return nil
+}
+
+func ValidateMacroTarget(target data.MacroTarget, fieldName string) error {
+	switch target.Mode {
+	case MacroModePercent:
+		if target.Value < 0 || target.Value > 100 {
+			return types.NewValidationError(fieldName, "%s percentage must be between 0 and 100", fieldName)
+		}
+	case MacroModePerKg, MacroModeGrams:
+		if target.Value < 0 {
+			return types.NewValidationError(fieldName, "%s cannot be negative", fieldName)
+		}
+	case MacroModeRest:
+	default:
+		return types.NewValidationError(fieldName, "%s mode must be 'percent', 'g_per_kg', 'grams' or 'rest'", fieldName)
+	}
+	return nil
+}
+
+func ValidateGoalStrategy(strategy data.GoalStrategy) error {
+	validPresets := map[string]bool{PresetBalanced: true, PresetHighProtein: true, PresetKeto: true, PresetCustom: true}
+	if !validPresets[strategy.Preset] {
+		return types.NewValidationError("preset", "preset must be 'balanced', 'high-protein', 'keto' or 'custom'")
+	}
+
+	validFormulas := map[string]bool{BMRFormulaMifflinStJeor: true, BMRFormulaHarrisBenedict: true, BMRFormulaKatchMcArdle: true}
+	if !validFormulas[strategy.BMRFormula] {
+		return types.NewValidationError("bmr_formula", "bmr formula must be 'mifflin-st-jeor', 'harris-benedict' or 'katch-mcardle'")
+	}
+	if strategy.BMRFormula == BMRFormulaKatchMcArdle && strategy.BodyFatPercentage == nil {
+		return types.NewValidationError("body_fat_percentage", "body fat percentage is required for katch-mcardle")
+	}
+	if err := ValidateBodyFatPercentage(strategy.BodyFatPercentage); err != nil {
 		return err
 	}
+
+	if strategy.MinCarbs < 0 {
+		return types.NewValidationError("min_carbs", "minimum carbs cannot be negative")
+	}
+
+	if strategy.Preset != PresetCustom {
+		return nil
+	}
+
+	targets := map[string]data.MacroTarget{"protein": strategy.Protein, "fat": strategy.Fat, "carbs": strategy.Carbs}
+	restCount := 0
+	totalPercentage := 0.0
+	for name, target := range targets {
+		if err := ValidateMacroTarget(target, name); err != nil {
+			return err
+		}
+		if target.Mode == MacroModeRest {
+			restCount++
+		}
+		if target.Mode == MacroModePercent {
+			totalPercentage += target.Value
+		}
+	}
+	if restCount != 1 {
+		return types.NewValidationError("", "exactly one macro must use the mode 'rest'")
+	}
+	if totalPercentage > 100 {
+		return types.NewValidationError("", "macro percentages must not exceed 100")
+	}
 	return nil
 }
 
@@ -220,43 +377,187 @@ func ValidateField(fieldName string, value interface{}) error {
 		if strValue, ok := value.(string); ok {
 			return ValidateName(strValue)
 		}
-		return fmt.Errorf("invalid type for name: expected string")
+		return types.NewValidationError(fieldName, "invalid type for name: expected string")
 	case "energy-kcal_100g", "proteins_100g", "carbohydrates_100g", "fat_100g":
 		if floatValue, ok := value.(float64); ok {
 			return ValidateNutrientValue(floatValue, fieldName)
 		}
-		return fmt.Errorf("invalid type for %s: expected float64", fieldName)
+		return types.NewValidationError(fieldName, "invalid type for %s: expected float64", fieldName)
 	case "serving_quantity":
 		if floatValue, ok := value.(float64); ok {
 			return ValidateServingQuantity(floatValue)
 		}
-		return fmt.Errorf("invalid type for serving_quantity: expected float64")
+		return types.NewValidationError(fieldName, "invalid type for serving_quantity: expected float64")
 	case "serving_quantity_unit":
 		if strValue, ok := value.(string); ok {
 			return ValidateServingQuantityUnit(strValue)
 		}
-		return fmt.Errorf("invalid type for serving_quantity_unit: expected string")
+		return types.NewValidationError(fieldName, "invalid type for serving_quantity_unit: expected string")
+	case "density":
+		if value == nil {
+			return nil
+		}
+		if floatValue, ok := value.(float64); ok {
+			return ValidateDensity(&floatValue)
+		}
+		return types.NewValidationError(fieldName, "invalid type for density: expected float64")
 	case "consumed_quantity":
 		if floatValue, ok := value.(float64); ok {
 			return ValidateConsumedQuantity(floatValue)
 		}
-		return fmt.Errorf("invalid type for consumed_quantity: expected float64")
+		return types.NewValidationError(fieldName, "invalid type for consumed_quantity: expected float64")
 	case "date":
 		if strValue, ok := value.(string); ok {
 			return ValidateDate(strValue)
 		}
-		return fmt.Errorf("invalid type for date: expected string")
+		return types.NewValidationError(fieldName, "invalid type for date: expected string")
 	case "time":
 		if strValue, ok := value.(string); ok {
 			return ValidateTime(strValue)
 		}
-		return fmt.Errorf("invalid type for time: expected string")
+		return types.NewValidationError(fieldName, "invalid type for time: expected string")
 	case "profile_id":
 		if strValue, ok := value.(string); ok {
 			return ValidateUUID(strValue)
 		}
-		return fmt.Errorf("invalid type for profile_id: expected string")
+		return types.NewValidationError(fieldName, "invalid type for profile_id: expected string")
 	default:
-		return fmt.Errorf("unknown field: %s", fieldName)
+		return types.NewValidationError(fieldName, "unknown field: %s", fieldName)
+	}
+}
+
+func ValidateTDEEWindowDays(days int) error {
+	if days < MinTDEEWindowDays || days > MaxTDEEWindowDays {
+		return types.NewValidationError("days", "days must be between %d and %d", MinTDEEWindowDays, MaxTDEEWindowDays)
+	}
+	return nil
+}
+
+func ValidateTargetOverride(request types.TargetOverrideRequest) error {
+	if len(request.Weekdays) == 0 && request.StartDate == "" && request.EndDate == "" {
+		return types.NewValidationError("", "a target override requires weekdays or a date range")
+	}
+	for _, weekday := range request.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return types.NewValidationError("weekdays", "weekdays must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+	if request.StartDate != "" {
+		if err := ValidateDate(request.StartDate); err != nil {
+			return withField(err, "start_date")
+		}
+	}
+	if request.EndDate != "" {
+		if err := ValidateDate(request.EndDate); err != nil {
+			return withField(err, "end_date")
+		}
+	}
+	if request.StartDate != "" && request.EndDate != "" && request.EndDate < request.StartDate {
+		return types.NewValidationError("end_date", "start date must not be after end date")
+	}
+
+	if request.Calories == nil && request.Proteins == nil && request.Carbs == nil && request.Fat == nil {
+		return types.NewValidationError("", "a target override requires at least one target")
+	}
+	for _, target := range []*float64{request.Calories, request.Proteins, request.Carbs, request.Fat} {
+		if target != nil && *target < 0 {
+			return types.NewValidationError("", "targets must not be negative")
+		}
+	}
+	return nil
+}
+
+// ValidateWebhook checks the URL and the event names of a webhook
+func ValidateWebhook(request types.WebhookRequest) error {
+	target, err := url.Parse(request.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return types.NewValidationError("url", "url must be an absolute http or https URL")
+	}
+
+	for _, name := range request.Events {
+		entity, action, hasAction := strings.Cut(name, ".")
+		if !messaging.IsEntity(entity) || (hasAction && !messaging.IsAction(action)) {
+			return types.NewValidationError("events", "unknown event %s", name)
+		}
+	}
+	for _, profileID := range request.ProfileIDs {
+		if err := ValidateUUID(profileID); err != nil {
+			return withField(err, "profile_ids")
+		}
+	}
+	return nil
+}
+
+func ValidateLimit(limit int, max int) error {
+	if limit < 1 || limit > max {
+		return types.NewValidationError("limit", "limit must be between 1 and %d", max)
 	}
+	return nil
+}
+
+func ValidateOffset(offset int) error {
+	if offset < 0 {
+		return types.NewValidationError("offset", "offset must not be negative")
+	}
+	return nil
+}
+
+func ValidateMealPlanEntry(request types.MealPlanEntryRequest) error {
+	if err := ValidateDate(request.Date); err != nil {
+		return err
+	}
+
+	validMeal := false
+	for _, meal := range MealPlanMeals {
+		if request.Meal == meal {
+			validMeal = true
+			break
+		}
+	}
+	if !validMeal {
+		return types.NewValidationError("meal", "meal must be one of: %s", strings.Join(MealPlanMeals, ", "))
+	}
+
+	if (request.Barcode == "") == (request.DishID == "") {
+		return types.NewValidationError("", "either a barcode or a dish ID is required")
+	}
+	if request.Barcode != "" {
+		if err := ValidateBarcode(request.Barcode); err != nil {
+			return err
+		}
+	}
+	if request.DishID != "" && request.Unit != "" {
+		return types.NewValidationError("unit", "dishes are planned in portions, a unit is not supported")
+	}
+
+	if request.Quantity != 0 {
+		if err := ValidateConsumedQuantity(request.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ValidateMergeFoodItems(request types.MergeFoodItemsRequest) error {
+	if err := ValidateBarcode(request.TargetBarcode); err != nil {
+		return withField(err, "target_barcode")
+	}
+	if len(request.SourceBarcodes) == 0 {
+		return types.NewValidationError("source_barcodes", "at least one source barcode is required")
+	}
+
+	seen := map[string]bool{request.TargetBarcode: true}
+	for _, barcode := range request.SourceBarcodes {
+		if err := ValidateBarcode(barcode); err != nil {
+			return withField(err, "source_barcodes")
+		}
+		if barcode == request.TargetBarcode {
+			return types.NewValidationError("source_barcodes", "the target food item must not be merged into itself")
+		}
+		if seen[barcode] {
+			return types.NewValidationError("source_barcodes", "barcode %s is listed more than once", barcode)
+		}
+		seen[barcode] = true
+	}
+	return nil
 }
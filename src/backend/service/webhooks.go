@@ -0,0 +1,490 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/messaging"
+	"nutrack/backend/settings"
+	"nutrack/backend/types"
+
+	"github.com/google/uuid"
+)
+
+// Headers of webhook deliveries. The signature is the hex encoded HMAC-SHA256 of the body
+// with the secret of the webhook, prefixed with "sha256=".
+const (
+	WebhookEventHeader     = "X-Nutrack-Event"
+	WebhookDeliveryHeader  = "X-Nutrack-Delivery"
+	WebhookSignatureHeader = "X-Nutrack-Signature"
+)
+
+const (
+	webhookMaxAttempts     = 5
+	webhookRetryDelay      = 10 * time.Second // Doubled after every failed attempt
+	webhookTimeout         = 10 * time.Second
+	webhookDeliveryLogSize = 200 // Latest deliveries returned by the delivery log
+	webhookQueueSize       = 100 // Deliveries waiting per webhook, further deliveries fail right away
+	webhookErrorBodyLimit  = 200 // Characters of the response body kept in the error of a delivery
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookSettingsMutex serializes the changes to the webhooks in the local settings
+var webhookSettingsMutex sync.Mutex
+
+// errWebhookStopped cancels the deliveries of a webhook which was deleted or disabled
+var errWebhookStopped = errors.New("webhook was deleted or disabled")
+
+// newWebhookDelivery logs a pending delivery of an event. Errors of the log only fail the
+// log, the event is delivered anyway.
+func newWebhookDelivery(webhookID string, event messaging.Event) types.WebhookDelivery {
+	now := time.Now().UTC()
+	delivery := types.WebhookDelivery{
+		ID:        uuid.New().String(),
+		WebhookID: webhookID,
+		Event:     webhookEventName(event),
+		EventSeq:  event.Seq,
+		Status:    types.WebhookDeliveryPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := data.InsertWebhookDelivery(delivery); err != nil {
+		log.Printf("Failed to log webhook delivery %s: %v", delivery.ID, err)
+	}
+	return delivery
+}
+
+// updateWebhookDelivery records an attempt of a delivery and returns the updated delivery
+func updateWebhookDelivery(delivery types.WebhookDelivery, attempt int, status types.WebhookDeliveryStatus, responseStatus int, err error) types.WebhookDelivery {
+	delivery.Attempts = attempt
+	delivery.Status = status
+	delivery.ResponseStatus = responseStatus
+	delivery.Error = ""
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := data.UpdateWebhookDelivery(delivery); err != nil {
+		log.Printf("Failed to log webhook delivery %s: %v", delivery.ID, err)
+	}
+	return delivery
+}
+
+// webhookJob is a delivery waiting in the queue of a webhook
+type webhookJob struct {
+	event    messaging.Event
+	delivery types.WebhookDelivery
+}
+
+// webhookWorker delivers the events of one webhook in order, so a slow or unreachable
+// receiver only delays its own deliveries. It holds the current settings of the webhook,
+// every attempt uses the URL and secret of the time it is made.
+type webhookWorker struct {
+	queue   chan webhookJob
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	mutex   sync.Mutex
+	webhook settings.Webhook
+}
+
+func (w *webhookWorker) current() settings.Webhook {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.webhook
+}
+
+func (w *webhookWorker) update(webhook settings.Webhook) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.webhook = webhook
+}
+
+// webhookWorkers holds the running workers by webhook ID
+type webhookWorkers struct {
+	mutex   sync.Mutex
+	workers map[string]*webhookWorker
+}
+
+func newWebhookWorkers() *webhookWorkers {
+	return &webhookWorkers{workers: make(map[string]*webhookWorker)}
+}
+
+// enqueue adds a job to the queue of a webhook and starts the worker with run if the
+// webhook has none. Returns false if the queue is full.
+func (w *webhookWorkers) enqueue(webhook settings.Webhook, job webhookJob, run func(worker *webhookWorker)) bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	worker, ok := w.workers[webhook.ID]
+	if !ok {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		worker = &webhookWorker{queue: make(chan webhookJob, webhookQueueSize), ctx: ctx, cancel: cancel}
+		w.workers[webhook.ID] = worker
+		go run(worker)
+	}
+	worker.update(webhook)
+
+	select {
+	case worker.queue <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// retain stops the workers of the webhooks which are not in the list or disabled, their
+// pending deliveries fail. The other workers continue with the changed settings.
+func (w *webhookWorkers) retain(webhooks []settings.Webhook) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for id, worker := range w.workers {
+		keep := false
+		for _, webhook := range webhooks {
+			if webhook.ID == id && webhook.Enabled {
+				worker.update(webhook)
+				keep = true
+				break
+			}
+		}
+		if !keep {
+			worker.cancel(errWebhookStopped)
+			delete(w.workers, id)
+		}
+	}
+}
+
+func webhookEventName(event messaging.Event) string {
+	return event.Entity + "." + event.Action
+}
+
+// webhookMatches reports whether a webhook is fired on an event. Events without a profile,
+// e.g. a sync conflict, match every profile filter.
+func webhookMatches(webhook settings.Webhook, event messaging.Event) bool {
+	if len(webhook.Events) > 0 && !containsString(webhook.Events, event.Entity) && !containsString(webhook.Events, webhookEventName(event)) {
+		return false
+	}
+	return len(webhook.ProfileIDs) == 0 || event.ProfileID == "" || containsString(webhook.ProfileIDs, event.ProfileID)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func toWebhookResponse(webhook settings.Webhook) types.Webhook {
+	response := types.Webhook{
+		ID:         webhook.ID,
+		URL:        webhook.URL,
+		HasSecret:  webhook.Secret != "",
+		Events:     webhook.Events,
+		ProfileIDs: webhook.ProfileIDs,
+		Enabled:    webhook.Enabled,
+		CreatedAt:  webhook.CreatedAt,
+	}
+	if response.Events == nil {
+		response.Events = []string{}
+	}
+	if response.ProfileIDs == nil {
+		response.ProfileIDs = []string{}
+	}
+	return response
+}
+
+// GetWebhooks returns the outgoing webhooks
+func (s *FoodService) GetWebhooks() ([]types.Webhook, error) {
+	current, err := s.settingsStore.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	webhooks := []types.Webhook{}
+	for _, webhook := range current.Webhooks {
+		webhooks = append(webhooks, toWebhookResponse(webhook))
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook adds an outgoing webhook and returns its ID
+func (s *FoodService) CreateWebhook(request types.WebhookRequest) (string, error) {
+	if err := ValidateWebhook(request); err != nil {
+		return "", err
+	}
+
+	webhook := settings.Webhook{
+		ID:         uuid.New().String(),
+		URL:        request.URL,
+		Events:     request.Events,
+		ProfileIDs: request.ProfileIDs,
+		Enabled:    request.Enabled == nil || *request.Enabled,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if request.Secret != nil {
+		webhook.Secret = *request.Secret
+	}
+
+	err := s.updateWebhooks(func(webhooks []settings.Webhook) ([]settings.Webhook, error) {
+		return append(webhooks, webhook), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return webhook.ID, nil
+}
+
+// UpdateWebhook replaces the URL, filters and state of a webhook. The secret is kept if the
+// request has none.
+func (s *FoodService) UpdateWebhook(id string, request types.WebhookRequest) error {
+	if err := ValidateUUID(id); err != nil {
+		return err
+	}
+	if err := ValidateWebhook(request); err != nil {
+		return err
+	}
+
+	return s.updateWebhooks(func(webhooks []settings.Webhook) ([]settings.Webhook, error) {
+		for i := range webhooks {
+			if webhooks[i].ID != id {
+				continue
+			}
+			webhooks[i].URL = request.URL
+			webhooks[i].Events = request.Events
+			webhooks[i].ProfileIDs = request.ProfileIDs
+			if request.Secret != nil {
+				webhooks[i].Secret = *request.Secret
+			}
+			if request.Enabled != nil {
+				webhooks[i].Enabled = *request.Enabled
+			}
+			return webhooks, nil
+		}
+		return nil, types.NewNotFoundError("no webhook found with id %s", id)
+	})
+}
+
+// DeleteWebhook deletes a webhook, its deliveries stay in the log
+func (s *FoodService) DeleteWebhook(id string) error {
+	if err := ValidateUUID(id); err != nil {
+		return err
+	}
+
+	return s.updateWebhooks(func(webhooks []settings.Webhook) ([]settings.Webhook, error) {
+		for i := range webhooks {
+			if webhooks[i].ID == id {
+				return append(webhooks[:i], webhooks[i+1:]...), nil
+			}
+		}
+		return nil, types.NewNotFoundError("no webhook found with id %s", id)
+	})
+}
+
+// updateWebhooks replaces the webhooks of the local settings with the result of fn, which
+// gets a copy of the current webhooks
+func (s *FoodService) updateWebhooks(fn func(webhooks []settings.Webhook) ([]settings.Webhook, error)) error {
+	webhookSettingsMutex.Lock()
+	defer webhookSettingsMutex.Unlock()
+
+	current, err := s.settingsStore.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	webhooks, err := fn(append([]settings.Webhook(nil), current.Webhooks...))
+	if err != nil {
+		return err
+	}
+	current.Webhooks = webhooks
+	if err := s.settingsStore.Save(current); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	s.webhookWorkers.retain(webhooks)
+	return nil
+}
+
+// TestWebhook delivers a test event to a webhook once, even if it is disabled, and returns
+// the delivery
+func (s *FoodService) TestWebhook(ctx context.Context, id string) (types.WebhookDelivery, error) {
+	if err := ValidateUUID(id); err != nil {
+		return types.WebhookDelivery{}, err
+	}
+
+	current, err := s.settingsStore.Load()
+	if err != nil {
+		return types.WebhookDelivery{}, fmt.Errorf("failed to load settings: %w", err)
+	}
+	for _, webhook := range current.Webhooks {
+		if webhook.ID != id {
+			continue
+		}
+		event := messaging.Event{
+			Message: "webhook_test",
+			Entity:  "webhook",
+			Action:  "test",
+			IDs:     []string{webhook.ID},
+			Time:    time.Now().UTC(),
+		}
+		delivery := newWebhookDelivery(webhook.ID, event)
+		return s.deliverWebhook(ctx, func() settings.Webhook { return webhook }, event, delivery, 1), nil
+	}
+	return types.WebhookDelivery{}, types.NewNotFoundError("no webhook found with id %s", id)
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook or, without an ID, of all
+// webhooks. Deliveries are kept for data.WebhookDeliveryRetention.
+func (s *FoodService) GetWebhookDeliveries(webhookID string) ([]types.WebhookDelivery, error) {
+	if webhookID != "" {
+		if err := ValidateUUID(webhookID); err != nil {
+			return nil, withField(err, "webhook_id")
+		}
+	}
+	return data.GetWebhookDeliveries(webhookID, webhookDeliveryLogSize)
+}
+
+// webhookDispatcher queues every published event for the enabled webhooks matching it
+func (s *FoodService) webhookDispatcher() {
+	subscription := messaging.Subscribe(0, messaging.EventFilter{})
+	defer subscription.Close()
+
+	for range subscription.Notify() {
+		events := subscription.Next()
+
+		current, err := s.settingsStore.Load()
+		if err != nil {
+			log.Printf("Failed to load webhooks: %v", err)
+			continue
+		}
+		for _, webhook := range current.Webhooks {
+			if !webhook.Enabled {
+				continue
+			}
+			for _, event := range events {
+				if webhookMatches(webhook, event) {
+					s.enqueueWebhook(webhook, event)
+				}
+			}
+		}
+	}
+}
+
+// enqueueWebhook logs a pending delivery and queues it for the worker of the webhook. If
+// the receiver cannot keep up and the queue is full, the delivery fails right away.
+func (s *FoodService) enqueueWebhook(webhook settings.Webhook, event messaging.Event) {
+	delivery := newWebhookDelivery(webhook.ID, event)
+	if !s.webhookWorkers.enqueue(webhook, webhookJob{event: event, delivery: delivery}, s.runWebhookWorker) {
+		log.Printf("Webhook delivery %s of %s dropped, too many pending deliveries", delivery.ID, delivery.Event)
+		updateWebhookDelivery(delivery, 0, types.WebhookDeliveryFailed, 0, errors.New("too many pending deliveries"))
+	}
+}
+
+// runWebhookWorker delivers the queued jobs of a webhook one after another until the worker
+// is stopped. The deliveries still queued then fail.
+func (s *FoodService) runWebhookWorker(worker *webhookWorker) {
+	for {
+		select {
+		case job := <-worker.queue:
+			s.deliverWebhook(worker.ctx, worker.current, job.event, job.delivery, webhookMaxAttempts)
+		case <-worker.ctx.Done():
+			for {
+				select {
+				case job := <-worker.queue:
+					updateWebhookDelivery(job.delivery, 0, types.WebhookDeliveryFailed, 0, context.Cause(worker.ctx))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliverWebhook posts an event to the webhook returned by webhook, which is called for every
+// attempt. Network errors, server errors and rate limits are retried with an increasing delay,
+// other statuses end the delivery, as does cancelling ctx.
+func (s *FoodService) deliverWebhook(ctx context.Context, webhook func() settings.Webhook, event messaging.Event, delivery types.WebhookDelivery, maxAttempts int) types.WebhookDelivery {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return updateWebhookDelivery(delivery, 0, types.WebhookDeliveryFailed, 0, fmt.Errorf("failed to encode event: %w", err))
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		status, err := postWebhook(ctx, webhook(), delivery, body)
+		switch {
+		case err == nil:
+			return updateWebhookDelivery(delivery, attempt, types.WebhookDeliveryDelivered, status, nil)
+		case ctx.Err() != nil:
+			return updateWebhookDelivery(delivery, attempt, types.WebhookDeliveryFailed, status, context.Cause(ctx))
+		case attempt >= maxAttempts || !retryWebhookStatus(status):
+			log.Printf("Webhook delivery %s of %s failed after %d attempts: %v", delivery.ID, delivery.Event, attempt, err)
+			return updateWebhookDelivery(delivery, attempt, types.WebhookDeliveryFailed, status, err)
+		}
+
+		delivery = updateWebhookDelivery(delivery, attempt, types.WebhookDeliveryPending, status, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return updateWebhookDelivery(delivery, attempt, types.WebhookDeliveryFailed, status, context.Cause(ctx))
+		}
+		delay *= 2
+	}
+}
+
+// retryWebhookStatus reports whether a failed attempt is retried. Status 0 means the
+// receiver could not be reached.
+func retryWebhookStatus(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// postWebhook sends the body of a delivery and returns the response status. Statuses other
+// than 2xx are returned as error with the start of the response body.
+func postWebhook(ctx context.Context, webhook settings.Webhook, delivery types.WebhookDelivery, body []byte) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Nutrack-Webhook")
+	request.Header.Set(WebhookEventHeader, delivery.Event)
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	if webhook.Secret != "" {
+		request.Header.Set(WebhookSignatureHeader, "sha256="+signWebhookPayload(webhook.Secret, body))
+	}
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, webhookErrorBodyLimit))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("receiver answered with status %d: %s", response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+	return response.StatusCode, nil
+}
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 of a body
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
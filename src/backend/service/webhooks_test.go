@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nutrack/backend/data"
+	"nutrack/backend/messaging"
+	"nutrack/backend/settings"
+	"nutrack/backend/types"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	tests := []struct {
+		secret string
+		body   string
+		want   string
+	}{
+		// RFC 4231 test case 2
+		{"Jefe", "what do ya want for nothing?", "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{"key", "The quick brown fox jumps over the lazy dog", "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"},
+		{"secret", "", "f9e66e179b6747ae54108f82f8ade8b3c25d76fd30afde6c395822c530196169"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.secret, func(t *testing.T) {
+			if got := signWebhookPayload(test.secret, []byte(test.body)); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func newWebhookTestService() *FoodService {
+	return &FoodService{webhookWorkers: newWebhookWorkers()}
+}
+
+func fixedWebhook(webhook settings.Webhook) func() settings.Webhook {
+	return func() settings.Webhook { return webhook }
+}
+
+func TestDeliverWebhookSignsTheBody(t *testing.T) {
+	var header http.Header
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer receiver.Close()
+
+	s := newWebhookTestService()
+	webhook := settings.Webhook{ID: "signed", URL: receiver.URL, Secret: "geheim"}
+	event := messaging.Event{Seq: 7, Entity: messaging.EntityFoodItem, Action: messaging.ActionCreated, IDs: []string{"4000417025005"}}
+	delivery := s.deliverWebhook(context.Background(), fixedWebhook(webhook), event, newWebhookDelivery(webhook.ID, event), 1)
+
+	if delivery.Status != types.WebhookDeliveryDelivered || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusOK {
+		t.Fatalf("got delivery %+v, want delivered on the first attempt", delivery)
+	}
+	if got := header.Get(WebhookEventHeader); got != "food_item.created" {
+		t.Errorf("got event header %q", got)
+	}
+	if got := header.Get(WebhookDeliveryHeader); got != delivery.ID {
+		t.Errorf("got delivery header %q, want %q", got, delivery.ID)
+	}
+
+	// Receivers verify the signature with their copy of the secret
+	mac := hmac.New(sha256.New, []byte("geheim"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); header.Get(WebhookSignatureHeader) != want {
+		t.Errorf("got signature %q, want %q", header.Get(WebhookSignatureHeader), want)
+	}
+}
+
+func TestDeliverWebhookWithoutSecret(t *testing.T) {
+	var signature []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Values(WebhookSignatureHeader)
+	}))
+	defer receiver.Close()
+
+	s := newWebhookTestService()
+	webhook := settings.Webhook{ID: "unsigned", URL: receiver.URL}
+	event := messaging.Event{Entity: messaging.EntityDish, Action: messaging.ActionDeleted}
+	s.deliverWebhook(context.Background(), fixedWebhook(webhook), event, newWebhookDelivery(webhook.ID, event), 1)
+
+	if len(signature) != 0 {
+		t.Errorf("got signature %v without a secret", signature)
+	}
+}
+
+func TestDeliverWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		retried bool
+	}{
+		{"client error", http.StatusBadRequest, false},
+		{"gone", http.StatusGone, false},
+		{"server error", http.StatusInternalServerError, true},
+		{"rate limit", http.StatusTooManyRequests, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(test.status)
+			}))
+			defer receiver.Close()
+
+			s := newWebhookTestService()
+			webhook := settings.Webhook{ID: "retried", URL: receiver.URL}
+			event := messaging.Event{Entity: messaging.EntityProfile, Action: messaging.ActionUpdated}
+
+			// Cancelling ends the wait for the first retry
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			time.AfterFunc(50*time.Millisecond, func() { cancel(errWebhookStopped) })
+
+			delivery := s.deliverWebhook(ctx, fixedWebhook(webhook), event, newWebhookDelivery(webhook.ID, event), webhookMaxAttempts)
+			if delivery.Status != types.WebhookDeliveryFailed || delivery.Attempts != 1 || delivery.ResponseStatus != test.status {
+				t.Fatalf("got delivery %+v, want failed after the first attempt", delivery)
+			}
+			if retried := delivery.Error == errWebhookStopped.Error(); retried != test.retried {
+				t.Errorf("got error %q, want retried %v", delivery.Error, test.retried)
+			}
+		})
+	}
+}
+
+func TestWebhookWorkers(t *testing.T) {
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	s := newWebhookTestService()
+	webhook := settings.Webhook{ID: "slow", URL: receiver.URL, Enabled: true}
+	event := messaging.Event{Entity: messaging.EntityConsumedFoodItem, Action: messaging.ActionCreated}
+
+	// The first delivery waits for its retry, the others fill the queue
+	s.enqueueWebhook(webhook, event)
+	for received.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i <= webhookQueueSize; i++ {
+		s.enqueueWebhook(webhook, event)
+	}
+
+	deliveries, err := data.GetWebhookDeliveries(webhook.ID, webhookDeliveryLogSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != webhookQueueSize+2 {
+		t.Fatalf("got %d deliveries, want %d", len(deliveries), webhookQueueSize+2)
+	}
+	if latest := deliveries[0]; latest.Status != types.WebhookDeliveryFailed || latest.Error != "too many pending deliveries" {
+		t.Errorf("got delivery %+v, want it dropped from the full queue", latest)
+	}
+
+	// Disabling the webhook cancels the retry and the queued deliveries
+	webhook.Enabled = false
+	s.webhookWorkers.retain([]settings.Webhook{webhook})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pending := 0
+		deliveries, err := data.GetWebhookDeliveries(webhook.ID, webhookDeliveryLogSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, delivery := range deliveries {
+			if delivery.Status == types.WebhookDeliveryPending {
+				pending++
+			} else if delivery.Error != errWebhookStopped.Error() && delivery.Error != "too many pending deliveries" {
+				t.Fatalf("got delivery %+v, want it stopped", delivery)
+			}
+		}
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d pending deliveries after disabling the webhook", pending)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := received.Load(); got != 1 {
+		t.Errorf("got %d requests, want only the first attempt of the first delivery", got)
+	}
+}
+
+func TestWebhookWorkersUseTheCurrentSettings(t *testing.T) {
+	release := make(chan struct{})
+	var first, old atomic.Int32
+	oldReceiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if first.Add(1) == 1 {
+			<-release
+			return
+		}
+		old.Add(1)
+	}))
+	defer oldReceiver.Close()
+	var secret atomic.Value
+	newReceiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret.Store(r.Header.Get(WebhookSignatureHeader))
+	}))
+	defer newReceiver.Close()
+
+	s := newWebhookTestService()
+	webhook := settings.Webhook{ID: "moved", URL: oldReceiver.URL, Enabled: true}
+	event := messaging.Event{Entity: messaging.EntityDish, Action: messaging.ActionUpdated}
+
+	// The first delivery holds the worker while the second one waits in the queue
+	s.enqueueWebhook(webhook, event)
+	for first.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s.enqueueWebhook(webhook, event)
+
+	webhook.URL, webhook.Secret = newReceiver.URL, "neu"
+	s.webhookWorkers.retain([]settings.Webhook{webhook})
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for secret.Load() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("the queued delivery did not reach the changed URL")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := secret.Load().(string); got == "" {
+		t.Error("got no signature, want the queued delivery signed with the changed secret")
+	}
+	if got := old.Load(); got != 0 {
+		t.Errorf("got %d deliveries to the previous URL after the change", got)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 // Settings contains the local settings of the application
@@ -19,6 +21,8 @@ type Settings struct {
 	ActiveScanner                  *ScannerSettings `json:"active_scanner,omitempty"`
 	ActiveProfileID                string           `json:"active_profile_id,omitempty"`
 	AutoRecalculateNutritionValues bool             `json:"auto_recalculate_nutrition_values,omitempty"`
+	AdaptiveTDEE                   bool             `json:"adaptive_tdee,omitempty"`
+	Webhooks                       []Webhook        `json:"webhooks,omitempty"`
 }
 
 // ScannerSettings contains the settings for the active scanner
@@ -29,6 +33,32 @@ type ScannerSettings struct {
 	Path      string `json:"path"`
 }
 
+// Webhook is an outgoing webhook. The webhooks are local settings, so only the device they
+// were configured on delivers them.
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`      // Key of the HMAC signature of the deliveries
+	Events     []string  `json:"events,omitempty"`      // Entities or entity.action names, e.g. "consumed_food_item.created"
+	ProfileIDs []string  `json:"profile_ids,omitempty"` // Profiles of the events, empty for all
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// String hides the secret and the path of the URL, which may contain a token like the ID of
+// a Home Assistant webhook. The settings are logged.
+func (w Webhook) String() string {
+	host := w.URL
+	if u, err := url.Parse(w.URL); err == nil {
+		host = u.Scheme + "://" + u.Host
+	}
+	secret := ""
+	if w.Secret != "" {
+		secret = "***"
+	}
+	return fmt.Sprintf("{ID:%s URL:%s/... Secret:%s Events:%v ProfileIDs:%v Enabled:%t}", w.ID, host, secret, w.Events, w.ProfileIDs, w.Enabled)
+}
+
 // Store manages the persistent settings
 type Store struct {
 	filePath string
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode is the machine-readable code of an error, sent in API responses so clients can
+// react to errors without parsing their messages
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest      ErrorCode = "INVALID_REQUEST"      // Malformed request body or parameters
+	ErrorCodeValidation          ErrorCode = "VALIDATION_ERROR"     // Invalid value of a field
+	ErrorCodeNotFound            ErrorCode = "NOT_FOUND"            // Missing food item, dish, profile, ...
+	ErrorCodeConflict            ErrorCode = "CONFLICT"             // Conflicts with existing data
+	ErrorCodeSyncConflict        ErrorCode = "SYNC_CONFLICT"        // Local and Dropbox database were both changed
+	ErrorCodeUpstreamUnavailable ErrorCode = "UPSTREAM_UNAVAILABLE" // OpenFoodFacts or Dropbox cannot be reached
+	ErrorCodeRateLimited         ErrorCode = "RATE_LIMITED"         // OpenFoodFacts or Dropbox rejected too many requests, or the client sent them
+	ErrorCodeInternal            ErrorCode = "INTERNAL_ERROR"       // Any other error
+)
+
+// Error is an error with a code. Errors without a code are internal errors.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Field   string // JSON name of the invalid field of validation errors
+	Err     error  // Cause of the error, if any
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewInvalidRequestError returns an error for a request body or parameters which cannot be read
+func NewInvalidRequestError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeInvalidRequest, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewValidationError returns an error for an invalid value of a field
+func NewValidationError(field string, format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeValidation, Message: fmt.Sprintf(format, args...), Field: field}
+}
+
+// NewNotFoundError returns an error for missing data
+func NewNotFoundError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewConflictError returns an error for a change which conflicts with existing data
+func NewConflictError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewSyncConflictError returns an error for a database changed both locally and in Dropbox
+func NewSyncConflictError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeSyncConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewUpstreamUnavailableError returns an error for an external service which cannot be reached
+func NewUpstreamUnavailableError(err error, format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeUpstreamUnavailable, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// NewRateLimitedError returns an error for too many requests to an external service or of a
+// client
+func NewRateLimitedError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeRateLimited, Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorCodeOf returns the code of the first typed error in the chain of err, or
+// ErrorCodeInternal for untyped errors
+func ErrorCodeOf(err error) ErrorCode {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Code
+	}
+	return ErrorCodeInternal
+}
+
+// HasErrorCode reports whether the chain of err contains an error with a code
+func HasErrorCode(err error, code ErrorCode) bool {
+	return err != nil && ErrorCodeOf(err) == code
+}
+
+// ErrorField returns the invalid field of a validation error in the chain of err
+func ErrorField(err error) string {
+	var typed *Error
+	if errors.As(err, &typed) {
+		return typed.Field
+	}
+	return ""
+}
@@ -6,16 +6,36 @@ import (
 
 // PersistentFoodItem represents a food item in the database
 type PersistentFoodItem struct {
-	Barcode             string    `json:"barcode"`
-	Name                string    `json:"name"`
-	CaloriesPer100g     float64   `json:"energy-kcal_100g"`
-	ProteinPer100g      float64   `json:"proteins_100g"`
-	CarbsPer100g        float64   `json:"carbohydrates_100g"`
-	FatPer100g          float64   `json:"fat_100g"`
-	ServingQuantity     float64   `json:"serving_quantity"`
-	ServingQuantityUnit string    `json:"serving_quantity_unit"`
-	CreatedAt           time.Time `json:"created_at"`
-	LastUpdated         time.Time `json:"last_updated"`
+	Barcode             string        `json:"barcode"`
+	Name                string        `json:"name"`
+	CaloriesPer100g     float64       `json:"energy-kcal_100g"`
+	ProteinPer100g      float64       `json:"proteins_100g"`
+	CarbsPer100g        float64       `json:"carbohydrates_100g"`
+	FatPer100g          float64       `json:"fat_100g"`
+	ServingQuantity     float64       `json:"serving_quantity"`
+	ServingQuantityUnit string        `json:"serving_quantity_unit"`
+	SourceDishID        string        `json:"source_dish_id,omitempty"` // Set if the food item was converted from a dish
+	Density             *float64      `json:"density,omitempty"`        // Optional density in g/ml for converting between g and ml
+	Portions            []FoodPortion `json:"portions,omitempty"`       // Named portions like "slice" or "cup"
+	Tags                []string      `json:"tags,omitempty"`           // User-defined tags like "dairy" or "snacks"
+	CreatedAt           time.Time     `json:"created_at"`
+	LastUpdated         time.Time     `json:"last_updated"`
+}
+
+// FoodItemUsage represents a food item with statistics on how a profile logs it
+type FoodItemUsage struct {
+	PersistentFoodItem
+	TimesLogged  int     `json:"times_logged"`  // Number of diary entries in the considered period
+	LastLogged   string  `json:"last_logged"`   // Insert date of the latest diary entry, empty if never logged
+	LastQuantity float64 `json:"last_quantity"` // Consumed quantity of the latest diary entry
+	Favorite     bool    `json:"favorite"`
+}
+
+// FoodPortion represents a named portion of a food item, e.g. 1 slice = 28 g
+type FoodPortion struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"` // Amount of one portion in Unit
+	Unit     string  `json:"unit"`     // "g" or "ml"
 }
 
 // ConsumedFoodItem represents a consumed food item
@@ -30,18 +50,31 @@ type ConsumedFoodItem struct {
 
 // ConsumedFoodItemWithDetails represents a consumed food item with additional details
 type ConsumedFoodItemWithDetails struct {
-	ID                  string  `json:"id"`
-	Barcode             string  `json:"barcode"`
-	Name                string  `json:"name"`
-	ConsumedQuantity    float64 `json:"consumed_quantity"`
-	Date                string  `json:"date"`
-	InsertDate          string  `json:"insert_date"`
-	CaloriesPer100g     float64 `json:"calories_per_100g"`
-	ProteinPer100g      float64 `json:"protein_per_100g"`
-	CarbsPer100g        float64 `json:"carbs_per_100g"`
-	FatPer100g          float64 `json:"fat_per_100g"`
-	ServingQuantity     float64 `json:"serving_quantity"`
-	ServingQuantityUnit string  `json:"serving_quantity_unit"`
+	ID                  string                   `json:"id"`
+	Barcode             string                   `json:"barcode"`
+	Name                string                   `json:"name"`
+	ConsumedQuantity    float64                  `json:"consumed_quantity"`
+	Date                string                   `json:"date"`
+	InsertDate          string                   `json:"insert_date"`
+	CaloriesPer100g     float64                  `json:"calories_per_100g"`
+	ProteinPer100g      float64                  `json:"protein_per_100g"`
+	CarbsPer100g        float64                  `json:"carbs_per_100g"`
+	FatPer100g          float64                  `json:"fat_per_100g"`
+	ServingQuantity     float64                  `json:"serving_quantity"`
+	ServingQuantityUnit string                   `json:"serving_quantity_unit"`
+	DishID              string                   `json:"dish_id,omitempty"`
+	Ingredients         []ConsumedDishIngredient `json:"ingredients,omitempty"`
+}
+
+// ConsumedDishIngredient represents an ingredient of a logged dish
+type ConsumedDishIngredient struct {
+	Barcode         string  `json:"barcode"`
+	Name            string  `json:"name"`
+	Quantity        float64 `json:"quantity"` // in the serving quantity unit of the food item
+	CaloriesPer100g float64 `json:"calories_per_100g"`
+	ProteinPer100g  float64 `json:"protein_per_100g"`
+	CarbsPer100g    float64 `json:"carbs_per_100g"`
+	FatPer100g      float64 `json:"fat_per_100g"`
 }
 
 // UserSettings represents user settings
@@ -58,13 +91,57 @@ type UserSettings struct {
 	WeeklyWeightChange float64 `json:"weekly_weight_change"`
 }
 
+// GoalStrategy represents how the daily targets of a profile are calculated
+type GoalStrategy struct {
+	Preset            string      `json:"preset"`                        // "balanced", "high-protein", "keto" or "custom"
+	BMRFormula        string      `json:"bmr_formula"`                   // "mifflin-st-jeor", "harris-benedict" or "katch-mcardle"
+	BodyFatPercentage *float64    `json:"body_fat_percentage,omitempty"` // Required for Katch-McArdle
+	Protein           MacroTarget `json:"protein"`                       // Only used for the custom preset
+	Fat               MacroTarget `json:"fat"`                           // Only used for the custom preset
+	Carbs             MacroTarget `json:"carbs"`                         // Only used for the custom preset
+	MinCarbs          float64     `json:"min_carbs"`                     // Minimum carbs in g per day, 0 for no minimum
+}
+
+// MacroTarget represents the target of one macronutrient
+type MacroTarget struct {
+	Mode  string  `json:"mode"`  // "percent" of calories, "g_per_kg" body weight, "grams" or "rest" of the calories
+	Value float64 `json:"value"` // Unused for "rest"
+}
+
+// TargetHistoryEntry represents the daily targets a profile had from the effective date on
+type TargetHistoryEntry struct {
+	EffectiveDate string  `json:"effective_date"`
+	Calories      float64 `json:"calories"`
+	Proteins      float64 `json:"proteins"`
+	Carbs         float64 `json:"carbs"`
+	Fat           float64 `json:"fat"`
+}
+
+// TargetOverride represents targets replacing the user settings on certain weekdays, in a date range or both
+type TargetOverride struct {
+	ID        string    `json:"id"`
+	ProfileID string    `json:"profile_id"`
+	Name      string    `json:"name"`
+	Weekdays  []int     `json:"weekdays,omitempty"`   // 0 = Sunday to 6 = Saturday, empty for every day
+	StartDate string    `json:"start_date,omitempty"` // Optional first day (YYYY-MM-DD)
+	EndDate   string    `json:"end_date,omitempty"`   // Optional last day (YYYY-MM-DD)
+	Calories  *float64  `json:"calories,omitempty"`   // Targets which are not set are taken from the user settings
+	Proteins  *float64  `json:"proteins,omitempty"`
+	Carbs     *float64  `json:"carbs,omitempty"`
+	Fat       *float64  `json:"fat,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // Dish represents a dish
 type Dish struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Barcode     *string   `json:"barcode,omitempty"` // Optional Barcode
-	CreatedAt   time.Time `json:"created_at"`
-	LastUpdated time.Time `json:"last_updated"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Barcode      *string   `json:"barcode,omitempty"`       // Optional Barcode
+	CookedWeight *float64  `json:"cooked_weight,omitempty"` // Optional measured weight of the cooked dish in g
+	Portions     *int      `json:"portions,omitempty"`      // Optional number of portions the dish yields
+	Tags         []string  `json:"tags,omitempty"`          // User-defined tags like "homemade"
+	CreatedAt    time.Time `json:"created_at"`
+	LastUpdated  time.Time `json:"last_updated"`
 }
 
 // DishItem represents an ingredient in a dish
@@ -72,22 +149,75 @@ type DishItem struct {
 	DishID   string  `json:"dish_id"`
 	Barcode  string  `json:"barcode"`
 	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"` // Unit of the quantity, defaults to the serving quantity unit of the food item
 }
 
 // DetailedDishItem represents an ingredient with detailed information
 type DetailedDishItem struct {
 	Items    PersistentFoodItem `json:"food_item"`
 	Quantity float64            `json:"quantity"`
+	Unit     string             `json:"unit,omitempty"`
 }
 
 // DishWithDetailedItems represents a dish with detailed ingredients
 type DishWithDetailedItems struct {
-	ID          string             `json:"id"`
-	Name        string             `json:"name"`
-	Barcode     *string            `json:"barcode,omitempty"`
-	CreatedAt   time.Time          `json:"created_at"`
-	LastUpdated time.Time          `json:"last_updated"`
-	Items       []DetailedDishItem `json:"dish_items"`
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	Barcode      *string            `json:"barcode,omitempty"`
+	CookedWeight *float64           `json:"cooked_weight,omitempty"`
+	Portions     *int               `json:"portions,omitempty"`
+	Tags         []string           `json:"tags,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	LastUpdated  time.Time          `json:"last_updated"`
+	Items        []DetailedDishItem `json:"dish_items"`
+}
+
+// DishNutrition represents the nutrition values of a dish per 100g and per portion
+type DishNutrition struct {
+	DishID             string  `json:"dish_id"`
+	Name               string  `json:"name"`
+	RawWeight          float64 `json:"raw_weight"`
+	CookedWeight       float64 `json:"cooked_weight"`
+	Portions           int     `json:"portions"`
+	PortionWeight      float64 `json:"portion_weight"`
+	CaloriesPer100g    float64 `json:"calories_per_100g"`
+	ProteinPer100g     float64 `json:"protein_per_100g"`
+	CarbsPer100g       float64 `json:"carbs_per_100g"`
+	FatPer100g         float64 `json:"fat_per_100g"`
+	CaloriesPerPortion float64 `json:"calories_per_portion"`
+	ProteinPerPortion  float64 `json:"protein_per_portion"`
+	CarbsPerPortion    float64 `json:"carbs_per_portion"`
+	FatPerPortion      float64 `json:"fat_per_portion"`
+}
+
+// SearchResult represents a food item or a dish matching a local search
+type SearchResult struct {
+	Type     string              `json:"type"` // "food_item" or "dish"
+	ID       string              `json:"id"`   // Barcode of the food item or ID of the dish
+	Name     string              `json:"name"`
+	FoodItem *PersistentFoodItem `json:"food_item,omitempty"`
+	Dish     *DishNutrition      `json:"dish,omitempty"`
+}
+
+// DuplicateFoodItemGroup represents food items that are likely the same product
+type DuplicateFoodItemGroup struct {
+	Items           []PersistentFoodItem `json:"items"`
+	Similarity      float64              `json:"similarity"`       // Lowest name similarity between linked items, between 0 and 1
+	SuggestedTarget string               `json:"suggested_target"` // Barcode of the item to keep, the one logged most often
+}
+
+// FoodItemMergeResult reports which references a merge rewrites
+type FoodItemMergeResult struct {
+	Target                PersistentFoodItem   `json:"target"`
+	Sources               []PersistentFoodItem `json:"sources"`
+	ConsumedEntries       int64                `json:"consumed_entries"`        // Diary entries of the source food items
+	DishIngredients       int64                `json:"dish_ingredients"`        // Ingredients of dishes
+	LoggedDishIngredients int64                `json:"logged_dish_ingredients"` // Ingredients of logged dishes
+	MealPlanEntries       int64                `json:"meal_plan_entries"`
+	Favorites             int64                `json:"favorites"`
+	Portions              int64                `json:"portions"` // Portions moved to the target, portions with an existing name are dropped
+	Tags                  int64                `json:"tags"`     // Tags added to the target
+	Applied               bool                 `json:"applied"`  // False for a preview
 }
 
 // Profile represents a user profile
@@ -96,3 +226,10 @@ type Profile struct {
 	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// TagUsage represents a tag with the number of food items and dishes using it
+type TagUsage struct {
+	Tag       string `json:"tag"`
+	FoodItems int    `json:"food_items"`
+	Dishes    int    `json:"dishes"`
+}
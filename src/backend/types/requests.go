@@ -7,17 +7,27 @@ type BarcodeRequest struct {
 
 // NutritionCalculationRequest contains the request for nutrition calculation
 type NutritionCalculationRequest struct {
-	Weight             float64 `json:"weight"`             // in kg
-	Height             float64 `json:"height"`             // in cm
-	Age                int     `json:"age"`                // in years
-	Gender             string  `json:"gender"`             // "male", "female", or "other"
-	ActivityLevel      int     `json:"activityLevel"`      // 0-4 (sedentary to very active)
-	WeeklyWeightChange float64 `json:"weeklyWeightChange"` // in kg per week
+	Weight             float64       `json:"weight"`               // in kg
+	Height             float64       `json:"height"`               // in cm
+	Age                int           `json:"age"`                  // in years
+	Gender             string        `json:"gender"`               // "male", "female", or "other"
+	ActivityLevel      int           `json:"activityLevel"`        // 0-4 (sedentary to very active)
+	WeeklyWeightChange float64       `json:"weeklyWeightChange"`   // in kg per week
+	Strategy           *GoalStrategy `json:"strategy,omitempty"`   // Optional goal strategy
+	ProfileID          string        `json:"profile_id,omitempty"` // Optional, use the goal strategy of the profile
 }
 
 type NutritionCalculationFromCaloriesAndWeightRequest struct {
-	Calories float64 `json:"calories"`
-	Weight   float64 `json:"weight"`
+	Calories  float64       `json:"calories"`
+	Weight    float64       `json:"weight"`
+	Strategy  *GoalStrategy `json:"strategy,omitempty"`   // Optional goal strategy
+	ProfileID string        `json:"profile_id,omitempty"` // Optional, use the goal strategy of the profile
+}
+
+// GoalStrategyRequest contains the goal strategy of a profile
+type GoalStrategyRequest struct {
+	GoalStrategy
+	ProfileID string `json:"profile_id"`
 }
 
 // TokenRequest contains the request for a token
@@ -30,15 +40,36 @@ type TokenRequest struct {
 type ConsumedFoodItemRequest struct {
 	Barcode          string  `json:"barcode"`
 	ConsumedQuantity float64 `json:"consumed_quantity"`
+	Unit             string  `json:"unit,omitempty"` // Unit of the consumed quantity: "serving" (default), "g", "ml" or a portion name
 	Date             string  `json:"date"`
 	ProfileID        string  `json:"profile_id"`
 	ForceSync        bool    `json:"force_sync"`
 }
 
+// ConsumedDishRequest contains the request for logging a dish
+type ConsumedDishRequest struct {
+	DishID    string  `json:"dish_id"`
+	Portions  float64 `json:"portions"` // Number of portions, fractions are allowed
+	Date      string  `json:"date"`
+	ProfileID string  `json:"profile_id"`
+	ForceSync bool    `json:"force_sync"`
+}
+
+// RepriceHistoryRequest contains the request for applying the current nutrition values
+// of a food item to already consumed entries
+type RepriceHistoryRequest struct {
+	Barcode   string `json:"barcode"`
+	StartDate string `json:"start_date,omitempty"` // Optional, first date to update
+	EndDate   string `json:"end_date,omitempty"`   // Optional, last date to update
+	ProfileID string `json:"profile_id,omitempty"` // Optional, all profiles if empty
+	ForceSync bool   `json:"force_sync"`
+}
+
 // BatchConsumedFoodItemRequest contains multiple consumed food items
 type BatchConsumedFoodItemRequest struct {
 	Items     []ConsumedFoodItemRequest `json:"items"`
 	ForceSync bool                      `json:"force_sync"`
+	Partial   bool                      `json:"partial"` // Log the valid items even if others fail, by default a failing item fails the whole batch
 }
 
 // DropboxAutosyncRequest contains the request for dropbox autosync
@@ -66,6 +97,62 @@ type AutoRecalculateNutritionValuesRequest struct {
 	Enabled bool `json:"enabled"`
 }
 
+// AdaptiveTDEERequest sets whether the daily recalculation uses the estimated TDEE
+type AdaptiveTDEERequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// TargetOverrideRequest contains the request to create or update a target override
+type TargetOverrideRequest struct {
+	Name      string   `json:"name"`
+	Weekdays  []int    `json:"weekdays"`   // 0 = Sunday to 6 = Saturday
+	StartDate string   `json:"start_date"` // YYYY-MM-DD
+	EndDate   string   `json:"end_date"`   // YYYY-MM-DD
+	Calories  *float64 `json:"calories"`
+	Proteins  *float64 `json:"proteins"`
+	Carbs     *float64 `json:"carbs"`
+	Fat       *float64 `json:"fat"`
+	ProfileID string   `json:"profile_id"`
+}
+
+// CopyConsumedFoodItemsRequest contains the request to copy diary entries to another date or profile
+type CopyConsumedFoodItemsRequest struct {
+	SourceDate      string   `json:"source_date"`
+	SourceProfileID string   `json:"source_profile_id"` // Defaults to the active profile
+	EntryIDs        []string `json:"entry_ids"`         // Optional subset of the entries of the source date
+	TargetDate      string   `json:"target_date"`
+	TargetEndDate   string   `json:"target_end_date"`   // Optional, copies to every day from the target date to this date
+	Weekdays        []int    `json:"weekdays"`          // Optional, only copies to these weekdays (0 = Sunday) of the date range
+	TargetProfileID string   `json:"target_profile_id"` // Defaults to the source profile
+	ForceSync       bool     `json:"force_sync"`
+}
+
+// MealPlanEntryRequest contains the request to plan a food item or a dish for a meal
+type MealPlanEntryRequest struct {
+	ProfileID string  `json:"profile_id"` // Defaults to the active profile
+	Date      string  `json:"date"`
+	Meal      string  `json:"meal"`              // "breakfast", "lunch", "dinner" or "snack"
+	Barcode   string  `json:"barcode,omitempty"` // Either a food item or a dish is planned
+	DishID    string  `json:"dish_id,omitempty"`
+	Quantity  float64 `json:"quantity"`       // Servings of the food item or portions of the dish, defaults to 1
+	Unit      string  `json:"unit,omitempty"` // Unit of the quantity of a food item: "serving" (default), "g", "ml" or a portion name
+	ForceSync bool    `json:"force_sync"`
+}
+
+// MarkMealPlanEntryEatenRequest contains the request to log a plan entry as consumed
+type MarkMealPlanEntryEatenRequest struct {
+	Quantity  float64 `json:"quantity"` // Optional, defaults to the planned servings or portions
+	ForceSync bool    `json:"force_sync"`
+}
+
+// MergeFoodItemsRequest contains the request to replace duplicate food items by one food item
+type MergeFoodItemsRequest struct {
+	TargetBarcode  string   `json:"target_barcode"`  // Food item to keep
+	SourceBarcodes []string `json:"source_barcodes"` // Food items to merge into the target and delete
+	Apply          bool     `json:"apply"`           // False only previews the merge
+	ForceSync      bool     `json:"force_sync"`
+}
+
 // ForceSyncRequest contains the request for a force sync
 type ForceSyncRequest struct {
 	Force bool `json:"force"`
@@ -74,11 +161,48 @@ type ForceSyncRequest struct {
 // DishRequest contains the request for a dish
 type DishRequest struct {
 	Dish struct {
-		Name    string  `json:"name"`
-		Barcode *string `json:"barcode,omitempty"`
+		Name         string   `json:"name"`
+		Barcode      *string  `json:"barcode,omitempty"`
+		CookedWeight *float64 `json:"cooked_weight,omitempty"`
+		Portions     *int     `json:"portions,omitempty"`
+		Tags         []string `json:"tags,omitempty"` // Optional, on update the tags are only replaced if given
 	} `json:"dish"`
 	Items []struct {
 		Barcode  string  `json:"barcode"`
 		Quantity float64 `json:"quantity"`
+		Unit     string  `json:"unit,omitempty"` // Optional, "g", "ml", "serving" or a portion name
 	} `json:"items"`
 }
+
+// FoodPortionsRequest contains the named portions of a food item
+type FoodPortionsRequest struct {
+	Portions []FoodPortion `json:"portions"`
+}
+
+// TagsRequest contains the tags of a food item or dish
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// ListQuery selects a page of a list with its sort order and filters
+type ListQuery struct {
+	Limit       int    `json:"limit" form:"limit"`   // Maximum number of entries, 0 for all
+	Offset      int    `json:"offset" form:"offset"` // Number of entries to skip
+	Sort        string `json:"sort" form:"sort"`     // Sort key, the keys depend on the list
+	Order       string `json:"order" form:"order"`   // "asc" or "desc", defaults to asc for name and desc otherwise
+	Unit        string `json:"unit" form:"unit"`     // Only entries with this serving quantity unit ("g" or "ml")
+	Incomplete  *bool  `json:"incomplete" form:"incomplete"`
+	CreatedFrom string `json:"created_from" form:"created_from"` // First creation day (YYYY-MM-DD)
+	CreatedTo   string `json:"created_to" form:"created_to"`     // Last creation day (YYYY-MM-DD)
+	Tag         string `json:"tag" form:"tag"`
+	Kind        string `json:"kind" form:"kind"` // Only diary entries of food items ("food") or dishes ("dish")
+}
+
+// WebhookRequest contains the request to create or update an outgoing webhook
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     *string  `json:"secret"`      // Key of the HMAC signature, kept on updates if omitted
+	Events     []string `json:"events"`      // Entities or entity.action names, e.g. "consumed_food_item.created", empty for all events
+	ProfileIDs []string `json:"profile_ids"` // Profiles of the events, empty for all
+	Enabled    *bool    `json:"enabled"`     // Defaults to true
+}
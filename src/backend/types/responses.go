@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // OpenFoodFactsResponse represents the response from the OpenFoodFacts API
 type OpenFoodFactsResponse struct {
 	Product struct {
@@ -13,6 +15,8 @@ type OpenFoodFactsResponse struct {
 		} `json:"nutriments"`
 		ServingQuantity     interface{} `json:"serving_quantity"`
 		ServingQuantityUnit string      `json:"serving_quantity_unit"`
+		ServingSize         string      `json:"serving_size"`
+		CategoriesTags      []string    `json:"categories_tags"` // Ordered from general to specific, e.g. "en:dairies"
 	} `json:"product"`
 	Status interface{} `json:"status"`
 }
@@ -26,9 +30,193 @@ type NutritionCalculationResponse struct {
 	Fat      int `json:"fat"`      // Daily fat target in grams
 }
 
+// TDEEEstimate contains the total daily energy expenditure estimated from the logged intake and the weight trend
+type TDEEEstimate struct {
+	ProfileID       string  `json:"profile_id"`
+	StartDate       string  `json:"start_date"`
+	EndDate         string  `json:"end_date"`
+	WindowDays      int     `json:"window_days"`
+	LoggedDays      int     `json:"logged_days"`      // Days with logged food
+	WeightEntries   int     `json:"weight_entries"`   // Days with a tracked weight
+	AverageIntake   float64 `json:"average_intake"`   // Average kcal per logged day
+	WeightTrend     float64 `json:"weight_trend"`     // Weight change in kg per week according to the trend
+	TDEE            float64 `json:"tdee"`             // Estimated maintenance calories, 0 if there is not enough data
+	FormulaTDEE     float64 `json:"formula_tdee"`     // Maintenance calories from BMR and activity level, 0 if the settings are incomplete
+	SufficientData  bool    `json:"sufficient_data"`  // False if there are too few logged days or weight entries for an estimate
+	Confidence      string  `json:"confidence"`       // "none", "low", "medium" or "high"
+	ConfidenceScore float64 `json:"confidence_score"` // Between 0 and 1
+	Message         string  `json:"message,omitempty"`
+}
+
+// DailyTargets contains the targets in effect on a date
+type DailyTargets struct {
+	Date         string  `json:"date"`
+	Calories     float64 `json:"calories"`
+	Proteins     float64 `json:"proteins"`
+	Carbs        float64 `json:"carbs"`
+	Fat          float64 `json:"fat"`
+	Source       string  `json:"source"`                  // "settings", "history", "weekday" or "date_range"
+	OverrideID   string  `json:"override_id,omitempty"`   // Set if a target override applies
+	OverrideName string  `json:"override_name,omitempty"` // Set if a target override applies
+}
+
+// DailySummary compares the intake of a day with the targets in effect on that day
+type DailySummary struct {
+	Date      string       `json:"date"`
+	Entries   int          `json:"entries"`
+	Calories  float64      `json:"calories"`
+	Proteins  float64      `json:"proteins"`
+	Carbs     float64      `json:"carbs"`
+	Fat       float64      `json:"fat"`
+	Targets   DailyTargets `json:"targets"`
+	Remaining float64      `json:"remaining_calories"` // Negative if the target was exceeded
+}
+
+// NutritionTotals contains summed up calories and macros
+type NutritionTotals struct {
+	Calories float64 `json:"calories"`
+	Proteins float64 `json:"proteins"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+}
+
+// MealPlanEntry contains a planned food item or dish with its projected nutrition
+type MealPlanEntry struct {
+	ID         string          `json:"id"`
+	ProfileID  string          `json:"profile_id"`
+	Date       string          `json:"date"`
+	Meal       string          `json:"meal"`
+	Barcode    string          `json:"barcode,omitempty"`
+	DishID     string          `json:"dish_id,omitempty"`
+	Name       string          `json:"name"`
+	Quantity   float64         `json:"quantity"` // Servings of the food item or portions of the dish
+	Weight     float64         `json:"weight"`   // Planned amount in the base unit of the food item or in grams of the dish
+	Nutrition  NutritionTotals `json:"nutrition"`
+	Eaten      bool            `json:"eaten"`
+	ConsumedID string          `json:"consumed_id,omitempty"` // Diary entry of an eaten plan entry
+}
+
+// MealPlanDay contains the plan entries of a day with their projected totals against the targets
+type MealPlanDay struct {
+	Date      string          `json:"date"`
+	Entries   []MealPlanEntry `json:"entries"`
+	Planned   NutritionTotals `json:"planned"`
+	Targets   DailyTargets    `json:"targets"`
+	Remaining float64         `json:"remaining_calories"` // Calories left after the planned entries, negative if the plan exceeds the target
+}
+
+// MealPlanReportDay compares the planned with the actually logged intake of a day
+type MealPlanReportDay struct {
+	Date           string          `json:"date"`
+	PlannedEntries int             `json:"planned_entries"`
+	EatenEntries   int             `json:"eaten_entries"` // Plan entries marked as eaten
+	LoggedEntries  int             `json:"logged_entries"`
+	Planned        NutritionTotals `json:"planned"`
+	Actual         NutritionTotals `json:"actual"`
+	Difference     NutritionTotals `json:"difference"` // Actual minus planned
+	Targets        DailyTargets    `json:"targets"`
+}
+
+// ConsumedCopyResult reports the outcome of copying diary entries
+type ConsumedCopyResult struct {
+	Inserted int      `json:"inserted"` // New diary entries
+	Merged   int      `json:"merged"`   // Entries added to an existing entry of the same food item
+	Dates    []string `json:"dates"`    // Dates entries were copied to
+}
+
 type ApiResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    ErrorCode   `json:"code,omitempty"`  // Code of the error, see ErrorCode
+	Field   string      `json:"field,omitempty"` // Invalid field of validation errors
+}
+
+// TagIntake contains the intake logged from food items and dishes with a tag
+type TagIntake struct {
+	Tag      string  `json:"tag"`
+	Entries  int     `json:"entries"`
+	Calories float64 `json:"calories"`
+	Proteins float64 `json:"proteins"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+	Share    float64 `json:"share"` // Percentage of the total calories
+}
+
+// TagIntakeSummary breaks down the intake between two dates by the tags of the logged food
+// items and dishes. An entry with several tags counts for each of its tags, so the shares
+// can add up to more than 100 percent.
+type TagIntakeSummary struct {
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	Entries   int             `json:"entries"`
+	Total     NutritionTotals `json:"total"`
+	Tags      []TagIntake     `json:"tags"`
+	Untagged  TagIntake       `json:"untagged"` // Entries without any tag
+}
+
+// BatchItemStatus is the outcome of an item of a consumption batch
+type BatchItemStatus string
+
+const (
+	BatchItemInserted        BatchItemStatus = "inserted"         // The food item was added from OpenFoodFacts and logged
+	BatchItemLogged          BatchItemStatus = "logged"           // The known food item was logged as new diary entry
+	BatchItemMerged          BatchItemStatus = "merged"           // The quantity was added to the diary entry of the same food item and date
+	BatchItemLookupFailed    BatchItemStatus = "lookup_failed"    // OpenFoodFacts had no data, an empty food item was added and logged
+	BatchItemValidationError BatchItemStatus = "validation_error" // The item is invalid and was not logged
+	BatchItemFailed          BatchItemStatus = "error"            // The item was not logged because of another error
+)
+
+// BatchItemResult contains the outcome of an item of a consumption batch
+type BatchItemResult struct {
+	Index      int             `json:"index"`
+	Barcode    string          `json:"barcode"`
+	Status     BatchItemStatus `json:"status"`
+	ConsumedID string          `json:"consumed_id,omitempty"` // Diary entry of logged items
+	Error      string          `json:"error,omitempty"`
+	Code       ErrorCode       `json:"code,omitempty"`
+	Field      string          `json:"field,omitempty"`
+}
+
+// BatchConsumeResponse contains the outcome of all items of a consumption batch
+type BatchConsumeResponse struct {
+	Message string            `json:"message"`
+	Logged  int               `json:"logged"` // Number of logged items
+	Failed  int               `json:"failed"` // Number of items which were not logged, only in partial mode
+	Results []BatchItemResult `json:"results"`
+}
+
+// Webhook is an outgoing webhook, its secret is not returned
+type Webhook struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	HasSecret  bool      `json:"has_secret"`
+	Events     []string  `json:"events"`
+	ProfileIDs []string  `json:"profile_ids"`
+	Enabled    bool      `json:"enabled"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDeliveryStatus is the state of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"   // Not yet delivered, attempts remain
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered" // The receiver answered with a 2xx status
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"    // All attempts failed or the receiver rejected the event
+)
+
+// WebhookDelivery is an entry of the webhook delivery log
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	WebhookID      string                `json:"webhook_id"`
+	Event          string                `json:"event"` // entity.action, e.g. "consumed_food_item.created"
+	EventSeq       uint64                `json:"event_seq"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseStatus int                   `json:"response_status,omitempty"` // HTTP status of the last attempt
+	Error          string                `json:"error,omitempty"`           // Error of the last attempt
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
 }
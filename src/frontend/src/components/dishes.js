@@ -214,6 +214,7 @@ const Dishes = ({ onDishesChange }) => {
         ...item.food_item,
         quantity: item.quantity,
         unit: item.food_item.serving_quantity_unit,
+        dish_unit: item.unit, // Unit stored in the dish, e.g. a named portion
       }))
     );
     updateDishNutrients(
@@ -232,10 +233,13 @@ const Dishes = ({ onDishesChange }) => {
         dish: {
           name: newDish.name,
           barcode: newDish.barcode || null,
+          cooked_weight: editingDish.cooked_weight,
+          portions: editingDish.portions,
         },
         items: dishItems.map((item) => ({
           barcode: item.barcode,
           quantity: item.quantity,
+          unit: item.dish_unit,
         })),
       };
 
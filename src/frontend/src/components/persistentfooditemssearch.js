@@ -74,7 +74,8 @@ const PersistentFoodItemSearch = ({ onItemSelect }) => {
           "GET",
           `/foodItems/search`,
           [],
-          [`q=${encodeURIComponent(term)}`]
+          // Rank the foods the active profile eats often first
+          [`q=${encodeURIComponent(term)}`, "boost=true"]
         );
 
         if (!response) {
@@ -3,9 +3,9 @@ import { sseService } from "../services/sseService";
 
 export function useSSE(messageHandler) {
   const handleMessage = useCallback(
-    (message) => {
+    (message, event) => {
       if (messageHandler) {
-        messageHandler(message);
+        messageHandler(message, event);
       }
     },
     [messageHandler]
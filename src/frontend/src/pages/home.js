@@ -208,10 +208,20 @@ const Home = () => {
     }
   }, []);
 
-  const fetchUserSettings = async () => {
+  const fetchUserSettings = async (date) => {
     try {
       await configPromise;
-      const data = await apiService.makeRequest("GET", "/settings");
+      // Targets can differ per weekday or date range, so use the targets of the selected day
+      const adjustedDate = new Date(
+        date.getTime() - date.getTimezoneOffset() * 60000
+      );
+      const formattedDate = adjustedDate.toISOString().split("T")[0];
+      const data = await apiService.makeRequest(
+        "GET",
+        "/settings/targets",
+        [],
+        [formattedDate]
+      );
       setUserSettings(data);
     } catch (error) {
       console.error("Error fetching user settings:", error);
@@ -544,7 +554,7 @@ const Home = () => {
   };
   useEffect(() => {
     fetchConsumedItems(selectedDate, false, false);
-    fetchUserSettings();
+    fetchUserSettings(selectedDate);
   }, [fetchConsumedItems, selectedDate]);
 
   useEffect(() => {
@@ -573,6 +583,23 @@ const Home = () => {
       width: 200,
       headerAlign: "left",
       align: "left",
+      renderCell: (params) =>
+        params.row.ingredients && params.row.ingredients.length > 0 ? (
+          <Tooltip
+            title={params.row.ingredients
+              .map(
+                (ingredient) =>
+                  `${ingredient.name}: ${formatNumberForDisplay(
+                    ingredient.quantity
+                  )} g`
+              )
+              .join(", ")}
+          >
+            <span>{params.value}</span>
+          </Tooltip>
+        ) : (
+          params.value
+        ),
     },
     {
       field: "consumed_quantity",
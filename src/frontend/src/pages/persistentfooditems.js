@@ -9,6 +9,7 @@ import {
   DialogContent,
   DialogTitle,
   TextField,
+  Chip,
 } from "@mui/material";
 import MuiAlert from "@mui/material/Alert";
 import DeleteIcon from "@mui/icons-material/Delete";
@@ -375,6 +376,17 @@ const PersistentFoodItems = () => {
       editable: true,
       headerAlign: "left",
       align: "left",
+      renderCell: (params) =>
+        params.row.source_dish_id ? (
+          <Tooltip title="Converted from a dish, values are kept in sync with the dish">
+            <span>
+              {params.value}{" "}
+              <Chip label="Dish" size="small" color="primary" variant="outlined" />
+            </span>
+          </Tooltip>
+        ) : (
+          params.value
+        ),
     },
     {
       field: "serving_quantity",
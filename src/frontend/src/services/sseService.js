@@ -8,6 +8,8 @@ class SSEService {
     this.reconnectAttempts = 0;
     this.maxReconnectAttempts = 5;
     this.reconnectDelay = 1000;
+    // Sequence number of the last received event, a new connection replays the missed ones
+    this.lastEventId = null;
   }
 
   async connect() {
@@ -25,7 +27,10 @@ class SSEService {
         this.eventSource.close();
       }
 
-      this.eventSource = new EventSource(`${BACKEND_URL}/sse`, {
+      const query = this.lastEventId
+        ? `?lastEventId=${encodeURIComponent(this.lastEventId)}`
+        : "";
+      this.eventSource = new EventSource(`${BACKEND_URL}/sse${query}`, {
         withCredentials: true,
       });
 
@@ -36,6 +41,9 @@ class SSEService {
       };
 
       this.eventSource.onmessage = (event) => {
+        if (event.lastEventId) {
+          this.lastEventId = event.lastEventId;
+        }
         this.handleMessage(event.data);
       };
 
@@ -94,20 +102,29 @@ class SSEService {
     }
   }
 
+  // Events are objects with the entity, action, ids, profile_id and seq of a change. Their
+  // plain message, e.g. "food_items_updated", is passed as first argument to the handlers.
   handleMessage(data) {
     let message;
+    let event = null;
     try {
       if (typeof data === "string") {
-        message = data;
+        event = data.startsWith("{") ? JSON.parse(data) : null;
       } else if (data.type === "sse") {
-        message = data.data;
+        event = data.data;
+      } else {
+        event = data;
+      }
+      if (event && typeof event === "object") {
+        message = event.message;
       } else {
-        message = data;
+        message = event ?? data;
+        event = null;
       }
 
       this.messageHandlers.forEach((handler) => {
         try {
-          handler(message);
+          handler(message, event);
         } catch (error) {
           console.error("Error in SSE message handler:", error);
         }